@@ -0,0 +1,219 @@
+// Package clientlist parses the reply of the redis CLIENT LIST and CLIENT
+// INFO commands: a line per client made of space separated `name=value`
+// fields.
+package clientlist
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+var (
+	// ErrInvalidReply is returned when the reply is not a string
+	ErrInvalidReply = errors.New("clientlist: invalid reply")
+
+	// ErrInvalidLine is returned when a line is not made of name=value
+	// fields or when a numeric field can not be parsed
+	ErrInvalidLine = errors.New("clientlist: invalid client line")
+)
+
+// Client is a client connection. The fields which are not reported by the
+// version of the server are left empty; every field, including those this
+// package does not know about, is held by Fields.
+type Client struct {
+	ID   int64
+	Addr string
+	// LAddr is the local address the client connected to, since redis 6.2
+	LAddr string
+	FD    int64
+	Name  string
+	Age   time.Duration
+	Idle  time.Duration
+	// Flags holds the client flags, such as "N" for a normal client or "S"
+	// for a replica
+	Flags string
+	DB    int
+	// Sub, PSub and SSub are the number of channel, pattern and shard
+	// channel subscriptions
+	Sub  int64
+	PSub int64
+	SSub int64
+	// Multi is the number of commands queued in a transaction, -1 outside
+	// of one
+	Multi int64
+	// QueryBuf, OBL, OMem and TotMem are the sizes, in bytes, of the query
+	// buffer, of the fixed output buffer, of the output list and of the
+	// memory used by the client
+	QueryBuf int64
+	OBL      int64
+	OMem     int64
+	TotMem   int64
+	// OLL is the number of replies in the output list
+	OLL    int64
+	Events string
+	// Cmd is the last command run by the client
+	Cmd string
+	// User is the ACL user of the client, since redis 6.0
+	User string
+	// Resp is the protocol version of the client, since redis 7.0
+	Resp int
+	// LibName and LibVer are set with CLIENT SETINFO, since redis 7.2
+	LibName string
+	LibVer  string
+	// Fields holds the fields of the line in order
+	Fields []Field
+}
+
+// Field is a field of a client line.
+type Field struct {
+	Name, Value string
+}
+
+// Get returns the value of a field.
+func (c *Client) Get(name string) (string, bool) {
+	for _, f := range c.Fields {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// HasFlag reports whether the client has a flag, such as 'M' for a master
+// or 'x' for a client in a transaction.
+func (c *Client) HasFlag(flag byte) bool {
+	return strings.IndexByte(c.Flags, flag) >= 0
+}
+
+// Parse parses the reply to CLIENT LIST or CLIENT INFO. An error reply is
+// returned as the error.
+func Parse(m *resp.Message) ([]*Client, error) {
+	if m == nil || m.IsNil {
+		return nil, ErrInvalidReply
+	}
+	switch m.Type {
+	case resp.BulkHeader, resp.VerbatimHeader:
+		return ParseString(string(m.Bytes))
+	case resp.StringHeader:
+		return ParseString(m.Status)
+	case resp.ErrorHeader:
+		return nil, m.Error
+	}
+	return nil, ErrInvalidReply
+}
+
+// ParseString parses the text of a CLIENT LIST or CLIENT INFO reply.
+func ParseString(s string) ([]*Client, error) {
+	var clients []*Client
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		c, err := ParseLine(line)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, c)
+	}
+	return clients, nil
+}
+
+// ParseLine parses the line of a client.
+func ParseLine(line string) (*Client, error) {
+	c := &Client{}
+	for _, field := range strings.Fields(line) {
+		eq := strings.IndexByte(field, '=')
+		if eq <= 0 {
+			return nil, ErrInvalidLine
+		}
+		f := Field{Name: field[:eq], Value: field[eq+1:]}
+		c.Fields = append(c.Fields, f)
+		if err := c.set(f); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// set sets the typed field matching f, unknown fields being ignored.
+func (c *Client) set(f Field) error {
+	var i *int64
+	var d *time.Duration
+	switch f.Name {
+	case "id":
+		i = &c.ID
+	case "addr":
+		c.Addr = f.Value
+	case "laddr":
+		c.LAddr = f.Value
+	case "fd":
+		i = &c.FD
+	case "name":
+		c.Name = f.Value
+	case "age":
+		d = &c.Age
+	case "idle":
+		d = &c.Idle
+	case "flags":
+		c.Flags = f.Value
+	case "db":
+		db, err := strconv.Atoi(f.Value)
+		if err != nil {
+			return ErrInvalidLine
+		}
+		c.DB = db
+	case "sub":
+		i = &c.Sub
+	case "psub":
+		i = &c.PSub
+	case "ssub":
+		i = &c.SSub
+	case "multi":
+		i = &c.Multi
+	case "qbuf":
+		i = &c.QueryBuf
+	case "obl":
+		i = &c.OBL
+	case "oll":
+		i = &c.OLL
+	case "omem":
+		i = &c.OMem
+	case "tot-mem":
+		i = &c.TotMem
+	case "events":
+		c.Events = f.Value
+	case "cmd":
+		c.Cmd = f.Value
+	case "user":
+		c.User = f.Value
+	case "resp":
+		v, err := strconv.Atoi(f.Value)
+		if err != nil {
+			return ErrInvalidLine
+		}
+		c.Resp = v
+	case "lib-name":
+		c.LibName = f.Value
+	case "lib-ver":
+		c.LibVer = f.Value
+	}
+	if i == nil && d == nil {
+		return nil
+	}
+	v, err := strconv.ParseInt(f.Value, 10, 64)
+	if err != nil {
+		return ErrInvalidLine
+	}
+	if i != nil {
+		*i = v
+	} else {
+		// age and idle are reported in seconds
+		*d = time.Duration(v) * time.Second
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package clientlist
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestParseGolden(t *testing.T) {
+	for _, name := range []string{"client-list-4.0", "client-list-7.2"} {
+		data, err := ioutil.ReadFile("testdata/" + name + ".txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		clients, err := Parse(resp.Bulk(data))
+		if err != nil {
+			t.Fatalf("error parsing %s: %v", name, err)
+		}
+		got, err := json.MarshalIndent(clients, "", "\t")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, '\n')
+		golden := "testdata/" + name + ".golden"
+		if *update {
+			if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("error parsing %s, got:\n%s", name, got)
+		}
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	c, err := ParseLine("id=12 addr=10.0.1.20:42312 name=api age=5400 idle=3 flags=xM db=1 multi=2 cmd=client|info resp=3 tot-cmds=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ID != 12 || c.Addr != "10.0.1.20:42312" || c.Name != "api" || c.DB != 1 || c.Multi != 2 || c.Resp != 3 {
+		t.Errorf("error client: %+v", c)
+	}
+	if c.Age != 5400*time.Second || c.Idle != 3*time.Second {
+		t.Errorf("error age %v idle %v", c.Age, c.Idle)
+	}
+	if !c.HasFlag('x') || !c.HasFlag('M') || c.HasFlag('S') {
+		t.Errorf("error flags: %s", c.Flags)
+	}
+	if c.Cmd != "client|info" {
+		t.Errorf("error cmd: %s", c.Cmd)
+	}
+	if v, ok := c.Get("tot-cmds"); !ok || v != "3" {
+		t.Error("error unknown field")
+	}
+	if _, ok := c.Get("laddr"); ok {
+		t.Error("error missing field")
+	}
+	if len(c.Fields) != 11 || c.Fields[0].Name != "id" || c.Fields[10].Name != "tot-cmds" {
+		t.Errorf("error fields: %v", c.Fields)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	lines := []string{
+		"id=1 addr",
+		"id=1 =x",
+		"id=x addr=127.0.0.1:6379",
+		"id=1 age=1.5",
+		"id=1 db=x",
+		"id=1 resp=x",
+	}
+	for _, line := range lines {
+		if _, err := ParseString(line); err != ErrInvalidLine {
+			t.Errorf("error parsing %q: %v", line, err)
+		}
+	}
+	if _, err := Parse(resp.Int(1)); err != ErrInvalidReply {
+		t.Errorf("error parsing an integer: %v", err)
+	}
+	if _, err := Parse(resp.Nil()); err != ErrInvalidReply {
+		t.Errorf("error parsing nil: %v", err)
+	}
+	if _, err := Parse(resp.Err(errors.New("ERR unknown"))); err == nil || err.Error() != "ERR unknown" {
+		t.Errorf("error parsing an error reply: %v", err)
+	}
+	clients, err := Parse(resp.Str("id=1 addr=127.0.0.1:6379"))
+	if err != nil || len(clients) != 1 || clients[0].ID != 1 {
+		t.Errorf("error parsing a status reply: %v", err)
+	}
+}
+
+func TestParseVerbatim(t *testing.T) {
+	clients, err := Parse(verbatim(t, "id=3 addr=127.0.0.1:50188 name=x\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clients) != 1 || clients[0].ID != 3 || clients[0].Name != "x" {
+		t.Errorf("error clients from a RESP3 reply: %+v", clients)
+	}
+}
+
+// verbatim returns the RESP3 verbatim string of text, as decoded.
+func verbatim(t *testing.T, text string) *resp.Message {
+	msgQ, _, err := resp.Decode([]byte(fmt.Sprintf("=%d\r\ntxt:%s\r\n", len(text)+4, text)))
+	if err != nil || len(msgQ) != 1 || msgQ[0].Type != resp.VerbatimHeader {
+		t.Fatalf("error decoding a verbatim string: %v %v", msgQ, err)
+	}
+	return msgQ[0]
+}
@@ -0,0 +1,173 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ChangeKind is the kind of a topology change.
+type ChangeKind int
+
+const (
+	// NodeAdded is a node which joined the cluster
+	NodeAdded ChangeKind = iota
+	// NodeRemoved is a node which left the cluster
+	NodeRemoved
+	// AddrChanged is a node whose address or bus port changed
+	AddrChanged
+	// RoleChanged is a node which was promoted, demoted or which replicates
+	// another master
+	RoleChanged
+	// FlagsChanged is a node whose flags changed, such as a node failing
+	FlagsChanged
+	// SlotsChanged is a master which serves other slots
+	SlotsChanged
+	// MigrationChanged is a node whose migrating or importing slots changed
+	MigrationChanged
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case NodeAdded:
+		return "added"
+	case NodeRemoved:
+		return "removed"
+	case AddrChanged:
+		return "address changed"
+	case RoleChanged:
+		return "role changed"
+	case FlagsChanged:
+		return "flags changed"
+	case SlotsChanged:
+		return "slots changed"
+	case MigrationChanged:
+		return "migration changed"
+	}
+	return "unknown"
+}
+
+// Change is a change of a node between two topologies.
+type Change struct {
+	Kind ChangeKind
+	ID   string
+	// Old and New are the node in each topology, Old being nil for an added
+	// node and New for a removed one
+	Old, New *Node
+}
+
+func (c *Change) String() string {
+	prefix := "node " + c.ID + " " + c.Kind.String()
+	switch c.Kind {
+	case NodeAdded:
+		return fmt.Sprintf("%s: %s %s", prefix, c.New.Addr(), role(c.New))
+	case NodeRemoved:
+		return prefix
+	case AddrChanged:
+		return fmt.Sprintf("%s: %s@%d -> %s@%d", prefix, c.Old.Addr(), c.Old.BusPort, c.New.Addr(), c.New.BusPort)
+	case RoleChanged:
+		return fmt.Sprintf("%s: %s -> %s", prefix, role(c.Old), role(c.New))
+	case FlagsChanged:
+		return fmt.Sprintf("%s: %s -> %s", prefix, flags(c.Old), flags(c.New))
+	case SlotsChanged:
+		return fmt.Sprintf("%s: %s -> %s", prefix, ranges(c.Old.Slots), ranges(c.New.Slots))
+	case MigrationChanged:
+		return fmt.Sprintf("%s: %s -> %s", prefix, migrations(c.Old), migrations(c.New))
+	}
+	return prefix
+}
+
+// Diff returns the changes from the old topology to the new one, ordered by
+// node ID and kind. The "myself" flag is ignored, as it depends on the node
+// which replied.
+func Diff(old, new *Topology) []*Change {
+	var changes []*Change
+	add := func(kind ChangeKind, id string, o, n *Node) {
+		changes = append(changes, &Change{Kind: kind, ID: id, Old: o, New: n})
+	}
+	i, j := 0, 0
+	for i < len(old.Nodes) || j < len(new.Nodes) {
+		switch {
+		case j == len(new.Nodes) || (i < len(old.Nodes) && old.Nodes[i].ID < new.Nodes[j].ID):
+			add(NodeRemoved, old.Nodes[i].ID, old.Nodes[i], nil)
+			i++
+		case i == len(old.Nodes) || new.Nodes[j].ID < old.Nodes[i].ID:
+			add(NodeAdded, new.Nodes[j].ID, nil, new.Nodes[j])
+			j++
+		default:
+			o, n := old.Nodes[i], new.Nodes[j]
+			if o.IP != n.IP || o.Port != n.Port || o.BusPort != n.BusPort {
+				add(AddrChanged, o.ID, o, n)
+			}
+			if o.Role != n.Role || o.MasterID != n.MasterID {
+				add(RoleChanged, o.ID, o, n)
+			}
+			if flags(o) != flags(n) {
+				add(FlagsChanged, o.ID, o, n)
+			}
+			if ranges(o.Slots) != ranges(n.Slots) {
+				add(SlotsChanged, o.ID, o, n)
+			}
+			if migrations(o) != migrations(n) {
+				add(MigrationChanged, o.ID, o, n)
+			}
+			i++
+			j++
+		}
+	}
+	return changes
+}
+
+func role(n *Node) string {
+	if n.Role == RoleReplica {
+		return "replica of " + n.MasterID
+	}
+	if n.Role == "" {
+		return "no role"
+	}
+	return n.Role
+}
+
+// flags returns the sorted flags of a node but "myself".
+func flags(n *Node) string {
+	var fs []string
+	for _, f := range n.Flags {
+		if f != "myself" {
+			fs = append(fs, f)
+		}
+	}
+	if len(fs) == 0 {
+		return "-"
+	}
+	sort.Strings(fs)
+	return strings.Join(fs, ",")
+}
+
+func ranges(slots []SlotRange) string {
+	if len(slots) == 0 {
+		return "-"
+	}
+	parts := make([]string, len(slots))
+	for i, r := range slots {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+// migrations describes the slot migrations of a node the way CLUSTER NODES
+// does.
+func migrations(n *Node) string {
+	var parts []string
+	for slot, id := range n.Migrating {
+		parts = append(parts, "["+strconv.Itoa(slot)+"->-"+id+"]")
+	}
+	for slot, id := range n.Importing {
+		parts = append(parts, "["+strconv.Itoa(slot)+"-<-"+id+"]")
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,69 @@
+package cluster
+
+import (
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old, err := ParseNodesString(
+		"a 127.0.0.1:30001@31001 myself,master - 0 0 1 connected 0-8191\n" +
+			"b 127.0.0.1:30002@31002 master - 0 0 2 connected 8192-16383\n" +
+			"c 127.0.0.1:30003@31003 slave a 0 0 1 connected\n" +
+			"d 127.0.0.1:30004@31004 slave b 0 0 2 connected\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	new, err := ParseNodesString(
+		"a 127.0.0.1:30001@31001 master - 0 0 1 connected 0-8190 [8191->-b]\n" +
+			"b 127.0.0.1:30002@31002 myself,master - 0 0 2 connected 8192-16383 [8191-<-a]\n" +
+			"c 127.0.0.1:30003@31003 master,fail? - 0 0 3 connected\n" +
+			"e 127.0.0.1:30005@31005 slave b 0 0 2 connected\n" +
+			"f 127.0.0.2:30006@31006 slave a 0 0 1 connected\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	new.Node("a").IP = "127.0.0.9"
+
+	want := []string{
+		"node a address changed: 127.0.0.1:30001@31001 -> 127.0.0.9:30001@31001",
+		"node a slots changed: 0-8191 -> 0-8190",
+		"node a migration changed: - -> [8191->-b]",
+		"node b migration changed: - -> [8191-<-a]",
+		"node c role changed: replica of a -> master",
+		"node c flags changed: slave -> fail?,master",
+		"node d removed",
+		"node e added: 127.0.0.1:30005 replica of b",
+		"node f added: 127.0.0.2:30006 replica of a",
+	}
+	changes := Diff(old, new)
+	if len(changes) != len(want) {
+		for _, c := range changes {
+			t.Log(c)
+		}
+		t.Fatalf("error changes: %d", len(changes))
+	}
+	for i, c := range changes {
+		if c.String() != want[i] {
+			t.Errorf("error change %d: %s", i, c)
+		}
+	}
+	if changes[6].Kind != NodeRemoved || changes[6].New != nil || changes[6].Old == nil {
+		t.Error("error removed node change")
+	}
+	if changes[7].Kind != NodeAdded || changes[7].Old != nil || changes[7].New == nil {
+		t.Error("error added node change")
+	}
+
+	if changes := Diff(old, old); len(changes) != 0 {
+		t.Errorf("error changes of the same topology: %d", len(changes))
+	}
+}
+
+func TestChangeKindString(t *testing.T) {
+	if NodeAdded.String() != "added" || MigrationChanged.String() != "migration changed" {
+		t.Error("error change kind names")
+	}
+	if ChangeKind(100).String() != "unknown" {
+		t.Error("error unknown change kind")
+	}
+}
@@ -0,0 +1,174 @@
+package cluster
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// ParseNodes parses the reply to CLUSTER NODES, a line per node:
+//
+//	<id> <ip:port@cport[,hostname[,k=v...]]> <flags> <master> <ping-sent> <pong-recv> <config-epoch> <link-state> <slot> ...
+//
+// The address of redis before 4.0 has no bus port. An error reply is
+// returned as the error.
+func ParseNodes(m *resp.Message) (*Topology, error) {
+	if m == nil || m.IsNil {
+		return nil, ErrInvalidReply
+	}
+	var text string
+	switch m.Type {
+	case resp.BulkHeader, resp.VerbatimHeader:
+		text = string(m.Bytes)
+	case resp.StringHeader:
+		text = m.Status
+	case resp.ErrorHeader:
+		return nil, m.Error
+	default:
+		return nil, ErrInvalidReply
+	}
+	return ParseNodesString(text)
+}
+
+// ParseNodesString parses the text of a CLUSTER NODES reply.
+func ParseNodesString(text string) (*Topology, error) {
+	var nodes []*Node
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		n, err := parseNodeLine(line)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return newTopology(nodes), nil
+}
+
+func parseNodeLine(line string) (*Node, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 8 {
+		return nil, ErrInvalidNode
+	}
+	n := &Node{ID: fields[0], LinkState: fields[7]}
+	if err := n.parseAddr(fields[1]); err != nil {
+		return nil, err
+	}
+
+	n.Flags = strings.Split(fields[2], ",")
+	switch {
+	case n.HasFlag("master"):
+		n.Role = RoleMaster
+	case n.HasFlag("slave"):
+		n.Role = RoleReplica
+	}
+	if fields[3] != "-" {
+		n.MasterID = fields[3]
+	}
+
+	var err error
+	if n.PingSent, err = strconv.ParseInt(fields[4], 10, 64); err != nil {
+		return nil, ErrInvalidNode
+	}
+	if n.PongRecv, err = strconv.ParseInt(fields[5], 10, 64); err != nil {
+		return nil, ErrInvalidNode
+	}
+	if n.ConfigEpoch, err = strconv.ParseInt(fields[6], 10, 64); err != nil {
+		return nil, ErrInvalidNode
+	}
+	for _, slot := range fields[8:] {
+		if err := n.parseSlot(slot); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// parseAddr parses ip:port@cport,hostname,k=v..., the bus port, hostname
+// and auxiliary fields being optional. Since redis 7.2 the hostname is
+// empty rather than missing when auxiliary fields follow. The address of
+// nodes whose address is not known yet is ":0".
+func (n *Node) parseAddr(addr string) error {
+	if comma := strings.IndexByte(addr, ','); comma >= 0 {
+		fields := strings.Split(addr[comma+1:], ",")
+		addr, n.Hostname = addr[:comma], fields[0]
+		for _, field := range fields[1:] {
+			eq := strings.IndexByte(field, '=')
+			if eq < 0 {
+				return ErrInvalidNode
+			}
+			if n.Aux == nil {
+				n.Aux = make(map[string]string)
+			}
+			n.Aux[field[:eq]] = field[eq+1:]
+		}
+	}
+	if at := strings.IndexByte(addr, '@'); at >= 0 {
+		bus, err := strconv.Atoi(addr[at+1:])
+		if err != nil {
+			return ErrInvalidNode
+		}
+		addr, n.BusPort = addr[:at], bus
+	}
+	// IPv6 addresses are not enclosed in brackets
+	colon := strings.LastIndexByte(addr, ':')
+	if colon < 0 {
+		return ErrInvalidNode
+	}
+	port, err := strconv.Atoi(addr[colon+1:])
+	if err != nil {
+		return ErrInvalidNode
+	}
+	n.IP, n.Port = strings.Trim(addr[:colon], "[]"), port
+	return nil
+}
+
+// parseSlot parses a slot, a range of slots, or a slot being migrated to
+// another node, [slot->-id], or imported from it, [slot-<-id].
+func (n *Node) parseSlot(s string) error {
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		s = s[1 : len(s)-1]
+		migrating := true
+		sep := strings.Index(s, "->-")
+		if sep < 0 {
+			migrating = false
+			if sep = strings.Index(s, "-<-"); sep < 0 {
+				return ErrInvalidNode
+			}
+		}
+		slot, err := strconv.ParseInt(s[:sep], 10, 64)
+		if err != nil || !validSlot(slot) {
+			return ErrInvalidNode
+		}
+		if migrating {
+			if n.Migrating == nil {
+				n.Migrating = make(map[int]string)
+			}
+			n.Migrating[int(slot)] = s[sep+3:]
+		} else {
+			if n.Importing == nil {
+				n.Importing = make(map[int]string)
+			}
+			n.Importing[int(slot)] = s[sep+3:]
+		}
+		return nil
+	}
+
+	start, end := s, s
+	if dash := strings.IndexByte(s, '-'); dash >= 0 {
+		start, end = s[:dash], s[dash+1:]
+	}
+	first, err := strconv.ParseInt(start, 10, 64)
+	if err != nil || !validSlot(first) {
+		return ErrInvalidNode
+	}
+	last, err := strconv.ParseInt(end, 10, 64)
+	if err != nil || !validSlot(last) || last < first {
+		return ErrInvalidNode
+	}
+	n.Slots = append(n.Slots, SlotRange{Start: int(first), End: int(last)})
+	return nil
+}
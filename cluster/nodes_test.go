@@ -0,0 +1,162 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+const nodesReply = `07c37dfeb235213a872192d90877d0cd55635b91 127.0.0.1:30004@31004,host-4 slave e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 0 1426238317239 4 connected
+67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1 127.0.0.1:30002@31002,host-2 master - 0 1426238316232 2 connected 5461-10922
+292f8b365bb7edb5e285caf0b7e6ddc7265d2f4f 127.0.0.1:30003@31003,host-3 master - 0 1426238318243 3 connected 10923-16383 [10923-<-67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1]
+6ec23923021cf3ffec47632106199cb7f496ce01 127.0.0.1:30005@31005,host-5 slave 67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1 0 1426238316232 5 connected
+824fe116063bc5fcf9f4ffd895bc17aee7731ac3 ::1:30006@31006 slave,fail 292f8b365bb7edb5e285caf0b7e6ddc7265d2f4f 1426238317000 1426238317741 6 disconnected
+e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 127.0.0.1:30001@31001,host-1 myself,master - 0 0 1 connected 0-5459 5460 [5460->-292f8b365bb7edb5e285caf0b7e6ddc7265d2f4f]
+`
+
+func TestParseNodes(t *testing.T) {
+	topo, err := ParseNodes(resp.Bulk([]byte(nodesReply)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topo.Nodes) != 6 {
+		t.Fatalf("error nodes: %d", len(topo.Nodes))
+	}
+	if len(topo.Masters()) != 3 {
+		t.Errorf("error masters: %d", len(topo.Masters()))
+	}
+
+	me := topo.Node("e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca")
+	if me == nil {
+		t.Fatal("error node 1 not found")
+	}
+	if me.IP != "127.0.0.1" || me.Port != 30001 || me.BusPort != 31001 || me.Hostname != "host-1" {
+		t.Errorf("error address: %s@%d,%s", me.Addr(), me.BusPort, me.Hostname)
+	}
+	if me.Role != RoleMaster || me.MasterID != "" || !me.HasFlag("myself") || me.HasFlag("fail") {
+		t.Errorf("error role %s flags %v", me.Role, me.Flags)
+	}
+	if me.ConfigEpoch != 1 || me.LinkState != "connected" {
+		t.Errorf("error epoch %d link %s", me.ConfigEpoch, me.LinkState)
+	}
+	if len(me.Slots) != 1 || me.Slots[0] != (SlotRange{0, 5460}) {
+		t.Errorf("error slots: %v", me.Slots)
+	}
+	if me.Migrating[5460] != "292f8b365bb7edb5e285caf0b7e6ddc7265d2f4f" || len(me.Importing) != 0 {
+		t.Errorf("error migrating: %v", me.Migrating)
+	}
+
+	n3 := topo.Node("292f8b365bb7edb5e285caf0b7e6ddc7265d2f4f")
+	if n3.Importing[10923] != "67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1" {
+		t.Errorf("error importing: %v", n3.Importing)
+	}
+
+	n6 := topo.Node("824fe116063bc5fcf9f4ffd895bc17aee7731ac3")
+	if n6.IP != "::1" || n6.Port != 30006 || n6.Addr() != "[::1]:30006" {
+		t.Errorf("error IPv6 address: %s", n6.Addr())
+	}
+	if n6.Role != RoleReplica || n6.MasterID != "292f8b365bb7edb5e285caf0b7e6ddc7265d2f4f" || !n6.HasFlag("fail") {
+		t.Errorf("error replica %s of %s", n6.Role, n6.MasterID)
+	}
+	if n6.PingSent != 1426238317000 || n6.PongRecv != 1426238317741 {
+		t.Errorf("error ping %d pong %d", n6.PingSent, n6.PongRecv)
+	}
+
+	if r := topo.Replicas("67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1"); len(r) != 1 || r[0].Port != 30005 {
+		t.Error("error replicas of node 2")
+	}
+	if n := topo.SlotOwner(5460); n != me {
+		t.Error("error owner of slot 5460")
+	}
+}
+
+func TestParseNodesOldAddress(t *testing.T) {
+	topo, err := ParseNodesString("97a3a64667477371c4479320d683e4c8db5858b1 :0 myself,master - 0 0 0 connected\n" +
+		"3e3a6cb0d9a9a87168e266b0a0b24026c0aae3f0 127.0.0.1:7001 master,noaddr - 1318428930 1318428931 0 disconnected\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := topo.Node("97a3a64667477371c4479320d683e4c8db5858b1")
+	if n.IP != "" || n.Port != 0 || n.BusPort != 0 {
+		t.Errorf("error unknown address: %s", n.Addr())
+	}
+	n = topo.Node("3e3a6cb0d9a9a87168e266b0a0b24026c0aae3f0")
+	if n.IP != "127.0.0.1" || n.Port != 7001 || n.BusPort != 0 {
+		t.Errorf("error address without bus port: %s@%d", n.Addr(), n.BusPort)
+	}
+}
+
+func TestParseNodes72Address(t *testing.T) {
+	topo, err := ParseNodesString("e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 127.0.0.1:30001@31001,,shard-id=69bc080733d1355567173199cff4a6a039a2f024 myself,master - 0 0 1 connected 0-5460\n" +
+		"67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1 127.0.0.1:30002@31002,host-2,shard-id=114f6674a35b84949fe567f5dfd41415ee776261,tls-port=0 master - 0 1426238316232 2 connected 5461-10922\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	me := topo.Node("e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca")
+	if me.Addr() != "127.0.0.1:30001" || me.BusPort != 31001 || me.Hostname != "" ||
+		me.Aux["shard-id"] != "69bc080733d1355567173199cff4a6a039a2f024" {
+		t.Errorf("error address: %s@%d,%s,%v", me.Addr(), me.BusPort, me.Hostname, me.Aux)
+	}
+	n := topo.Node("67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1")
+	if n.Hostname != "host-2" || len(n.Aux) != 2 || n.Aux["shard-id"] != "114f6674a35b84949fe567f5dfd41415ee776261" ||
+		n.Aux["tls-port"] != "0" {
+		t.Errorf("error address: %s,%v", n.Hostname, n.Aux)
+	}
+	if _, err := ParseNodesString("a 127.0.0.1:30001@31001,h,shard-id master - 0 0 1 connected"); err != ErrInvalidNode {
+		t.Errorf("should return ErrInvalidNode, not: %v", err)
+	}
+}
+
+func TestParseNodesInvalid(t *testing.T) {
+	lines := []string{
+		"a 127.0.0.1:30001@31001 master - 0 0 1",
+		"a 127.0.0.1 master - 0 0 1 connected",
+		"a 127.0.0.1:x master - 0 0 1 connected",
+		"a 127.0.0.1:30001@x master - 0 0 1 connected",
+		"a 127.0.0.1:30001 master - x 0 1 connected",
+		"a 127.0.0.1:30001 master - 0 x 1 connected",
+		"a 127.0.0.1:30001 master - 0 0 x connected",
+		"a 127.0.0.1:30001 master - 0 0 1 connected 16384",
+		"a 127.0.0.1:30001 master - 0 0 1 connected 10-5",
+		"a 127.0.0.1:30001 master - 0 0 1 connected x-5",
+		"a 127.0.0.1:30001 master - 0 0 1 connected [5-x-b]",
+		"a 127.0.0.1:30001 master - 0 0 1 connected [x->-b]",
+	}
+	for _, line := range lines {
+		if _, err := ParseNodesString(line); err != ErrInvalidNode {
+			t.Errorf("error parsing %q: %v", line, err)
+		}
+	}
+
+	if _, err := ParseNodes(resp.Int(1)); err != ErrInvalidReply {
+		t.Errorf("error parsing an integer: %v", err)
+	}
+	if _, err := ParseNodes(resp.Nil()); err != ErrInvalidReply {
+		t.Errorf("error parsing nil: %v", err)
+	}
+	_, err := ParseNodes(resp.Err(errors.New("ERR This instance has cluster support disabled")))
+	if err == nil || err.Error() != "ERR This instance has cluster support disabled" {
+		t.Errorf("error parsing an error reply: %v", err)
+	}
+}
+
+func TestParseNodesVerbatim(t *testing.T) {
+	topo, err := ParseNodes(verbatim(t, nodesReply))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topo.Nodes) != 6 {
+		t.Errorf("error nodes from a RESP3 reply: %d", len(topo.Nodes))
+	}
+}
+
+// verbatim returns the RESP3 verbatim string of text, as decoded.
+func verbatim(t *testing.T, text string) *resp.Message {
+	msgQ, _, err := resp.Decode([]byte(fmt.Sprintf("=%d\r\ntxt:%s\r\n", len(text)+4, text)))
+	if err != nil || len(msgQ) != 1 || msgQ[0].Type != resp.VerbatimHeader {
+		t.Fatalf("error decoding a verbatim string: %v %v", msgQ, err)
+	}
+	return msgQ[0]
+}
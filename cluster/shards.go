@@ -0,0 +1,107 @@
+package cluster
+
+import (
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// ParseShards parses the reply to CLUSTER SHARDS of redis 7.0, an array of
+// shards, each a map of its slots, as a flat list of range bounds, and of
+// its nodes:
+//
+//	[["slots", [0, 5460],
+//	  "nodes", [["id", "e10b7051d6bf2d5febd39a2be297bbaea6084111",
+//	             "port", 30001, "ip", "127.0.0.1", "endpoint", "127.0.0.1",
+//	             "role", "master", "replication-offset", 72156,
+//	             "health", "online"], ...]], ...]
+//
+// An error reply is returned as the error.
+func ParseShards(m *resp.Message) (*Topology, error) {
+	if err := checkReply(m); err != nil {
+		return nil, err
+	}
+	var nodes []*Node
+	for _, entry := range m.Array {
+		shard, err := flatMap(entry)
+		if err != nil {
+			return nil, err
+		}
+		slots, err := parseShardSlots(shard["slots"])
+		if err != nil {
+			return nil, err
+		}
+		list := shard["nodes"]
+		if list == nil || list.Type != resp.ArrayHeader {
+			return nil, ErrInvalidReply
+		}
+		var shardNodes []*Node
+		var masterID string
+		for _, desc := range list.Array {
+			n, err := parseShardNode(desc)
+			if err != nil {
+				return nil, err
+			}
+			if n.Role == RoleMaster {
+				masterID = n.ID
+				n.Slots = slots
+			}
+			shardNodes = append(shardNodes, n)
+		}
+		for _, n := range shardNodes {
+			if n.Role == RoleReplica {
+				n.MasterID = masterID
+			}
+		}
+		nodes = append(nodes, shardNodes...)
+	}
+	return newTopology(nodes), nil
+}
+
+func parseShardSlots(m *resp.Message) ([]SlotRange, error) {
+	if m == nil || m.Type != resp.ArrayHeader || len(m.Array)%2 != 0 {
+		return nil, ErrInvalidReply
+	}
+	var slots []SlotRange
+	for i := 0; i < len(m.Array); i += 2 {
+		start, end := m.Array[i], m.Array[i+1]
+		if start.Type != resp.IntegerHeader || end.Type != resp.IntegerHeader ||
+			!validSlot(start.Integer) || !validSlot(end.Integer) || end.Integer < start.Integer {
+			return nil, ErrInvalidReply
+		}
+		slots = append(slots, SlotRange{Start: int(start.Integer), End: int(end.Integer)})
+	}
+	return slots, nil
+}
+
+func parseShardNode(m *resp.Message) (*Node, error) {
+	fields, err := flatMap(m)
+	if err != nil {
+		return nil, err
+	}
+	n := &Node{
+		ID:       text(fields["id"]),
+		IP:       text(fields["ip"]),
+		Hostname: text(fields["hostname"]),
+		Health:   text(fields["health"]),
+	}
+	if n.ID == "" {
+		return nil, ErrInvalidReply
+	}
+	// the port is missing when only the TLS port is used
+	for _, name := range []string{"port", "tls-port"} {
+		if p := fields[name]; p != nil && p.Type == resp.IntegerHeader && n.Port == 0 {
+			n.Port = int(p.Integer)
+		}
+	}
+	if off := fields["replication-offset"]; off != nil && off.Type == resp.IntegerHeader {
+		n.ReplicationOffset = off.Integer
+	}
+	switch text(fields["role"]) {
+	case "master":
+		n.Role = RoleMaster
+	case "replica", "slave":
+		n.Role = RoleReplica
+	default:
+		return nil, ErrInvalidReply
+	}
+	return n, nil
+}
@@ -0,0 +1,79 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+func shardNode(fields ...interface{}) *resp.Message {
+	m := resp.Array()
+	for _, f := range fields {
+		switch v := f.(type) {
+		case string:
+			m.Array = append(m.Array, b(v))
+		case int:
+			m.Array = append(m.Array, resp.Int(int64(v)))
+		}
+	}
+	return m
+}
+
+func TestParseShards(t *testing.T) {
+	reply := resp.Array(
+		resp.Array(
+			b("slots"), resp.Array(resp.Int(0), resp.Int(5460), resp.Int(10923), resp.Int(10923)),
+			b("nodes"), resp.Array(
+				shardNode("id", "id1", "port", 30001, "ip", "127.0.0.1", "endpoint", "127.0.0.1",
+					"hostname", "host-1", "role", "master", "replication-offset", 72156, "health", "online"),
+				shardNode("id", "id4", "port", 30004, "ip", "127.0.0.1", "endpoint", "127.0.0.1",
+					"role", "replica", "replication-offset", 72156, "health", "loading"),
+			),
+		),
+		resp.Array(
+			b("slots"), resp.Array(),
+			b("nodes"), resp.Array(
+				shardNode("id", "id2", "tls-port", 31002, "ip", "127.0.0.1", "role", "master", "health", "online"),
+			),
+		),
+	)
+	topo, err := ParseShards(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topo.Nodes) != 3 {
+		t.Fatalf("error nodes: %d", len(topo.Nodes))
+	}
+	n1 := topo.Node("id1")
+	if n1.Role != RoleMaster || n1.Hostname != "host-1" || n1.Health != "online" || n1.ReplicationOffset != 72156 {
+		t.Errorf("error node 1: %s %s %s %d", n1.Role, n1.Hostname, n1.Health, n1.ReplicationOffset)
+	}
+	if len(n1.Slots) != 2 || n1.Slots[0] != (SlotRange{0, 5460}) || n1.Slots[1] != (SlotRange{10923, 10923}) {
+		t.Errorf("error slots of node 1: %v", n1.Slots)
+	}
+	if n4 := topo.Node("id4"); n4.Role != RoleReplica || n4.MasterID != "id1" || n4.Health != "loading" || n4.Slots != nil {
+		t.Errorf("error node 4: %s of %s", n4.Role, n4.MasterID)
+	}
+	if n2 := topo.Node("id2"); n2.Port != 31002 || len(n2.Slots) != 0 {
+		t.Errorf("error node 2: %s %v", n2.Addr(), n2.Slots)
+	}
+}
+
+func TestParseShardsInvalid(t *testing.T) {
+	node := shardNode("id", "id1", "port", 30001, "role", "master")
+	replies := []*resp.Message{
+		resp.Str("OK"),
+		resp.Array(resp.Array(b("slots"))),
+		resp.Array(resp.Array(b("slots"), resp.Array(resp.Int(0)), b("nodes"), resp.Array(node))),
+		resp.Array(resp.Array(b("slots"), resp.Array(resp.Int(5), resp.Int(0)), b("nodes"), resp.Array(node))),
+		resp.Array(resp.Array(b("slots"), resp.Array(), b("nodes"), b("none"))),
+		resp.Array(resp.Array(b("slots"), resp.Array(), b("nodes"), resp.Array(shardNode("port", 30001, "role", "master")))),
+		resp.Array(resp.Array(b("slots"), resp.Array(), b("nodes"), resp.Array(shardNode("id", "id1", "role", "arbiter")))),
+		resp.Array(resp.Array(resp.Int(1), resp.Array())),
+	}
+	for i, reply := range replies {
+		if _, err := ParseShards(reply); err != ErrInvalidReply {
+			t.Errorf("error parsing reply %d: %v", i, err)
+		}
+	}
+}
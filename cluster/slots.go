@@ -0,0 +1,120 @@
+package cluster
+
+import (
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// ParseSlots parses the reply to CLUSTER SLOTS, an array of slot ranges with
+// the nodes serving them, the master first:
+//
+//	[[0, 5460,
+//	  ["127.0.0.1", 30001, "09dbe9720cda62f7865eabc5fd8857c5d2678366",
+//	   ["hostname", "host-1.redis.example.com"]],
+//	  [replica...], ...], ...]
+//
+// The node IDs are reported since redis 4.0 and the metadata since redis
+// 7.0. An error reply is returned as the error.
+func ParseSlots(m *resp.Message) (*Topology, error) {
+	if err := checkReply(m); err != nil {
+		return nil, err
+	}
+	nodes := make(map[string]*Node)
+	var order []*Node
+	for _, entry := range m.Array {
+		if entry.Type != resp.ArrayHeader || len(entry.Array) < 3 {
+			return nil, ErrInvalidReply
+		}
+		start, end := entry.Array[0], entry.Array[1]
+		if start.Type != resp.IntegerHeader || end.Type != resp.IntegerHeader ||
+			!validSlot(start.Integer) || !validSlot(end.Integer) || end.Integer < start.Integer {
+			return nil, ErrInvalidReply
+		}
+		var masterID string
+		for i, desc := range entry.Array[2:] {
+			n, err := parseSlotsNode(desc)
+			if err != nil {
+				return nil, err
+			}
+			if known, ok := nodes[n.ID]; ok {
+				n = known
+			} else {
+				nodes[n.ID] = n
+				order = append(order, n)
+			}
+			if i == 0 {
+				n.Role, masterID = RoleMaster, n.ID
+				n.Slots = append(n.Slots, SlotRange{Start: int(start.Integer), End: int(end.Integer)})
+			} else {
+				n.Role, n.MasterID = RoleReplica, masterID
+			}
+		}
+	}
+	return newTopology(order), nil
+}
+
+// parseSlotsNode parses the description of a node in a CLUSTER SLOTS reply.
+func parseSlotsNode(m *resp.Message) (*Node, error) {
+	if m.Type != resp.ArrayHeader || len(m.Array) < 2 {
+		return nil, ErrInvalidReply
+	}
+	ip, port := m.Array[0], m.Array[1]
+	if (ip.Type != resp.BulkHeader && ip.Type != resp.StringHeader) || port.Type != resp.IntegerHeader {
+		return nil, ErrInvalidReply
+	}
+	n := &Node{Port: int(port.Integer)}
+	// the endpoint is nil or "?" when unknown
+	if !ip.IsNil && text(ip) != "?" {
+		n.IP = text(ip)
+	}
+	if len(m.Array) > 2 {
+		if m.Array[2].Type != resp.BulkHeader {
+			return nil, ErrInvalidReply
+		}
+		n.ID = string(m.Array[2].Bytes)
+	}
+	if len(m.Array) > 3 {
+		meta, err := flatMap(m.Array[3])
+		if err != nil {
+			return nil, err
+		}
+		n.Hostname = text(meta["hostname"])
+		if ip := text(meta["ip"]); ip != "" {
+			n.IP = ip
+		}
+	}
+	if n.ID == "" {
+		n.ID = n.Addr()
+	}
+	return n, nil
+}
+
+// text returns the content of a bulk or status message, "" for others.
+func text(m *resp.Message) string {
+	if m == nil {
+		return ""
+	}
+	switch m.Type {
+	case resp.BulkHeader:
+		return string(m.Bytes)
+	case resp.StringHeader:
+		return m.Status
+	}
+	return ""
+}
+
+// flatMap converts an array of alternating names and values, as RESP2
+// encodes maps, to a map.
+func flatMap(m *resp.Message) (map[string]*resp.Message, error) {
+	if m.Type != resp.ArrayHeader || len(m.Array)%2 != 0 {
+		return nil, ErrInvalidReply
+	}
+	fields := make(map[string]*resp.Message, len(m.Array)/2)
+	for i := 0; i < len(m.Array); i += 2 {
+		name := m.Array[i]
+		if name.Type != resp.BulkHeader && name.Type != resp.StringHeader {
+			return nil, ErrInvalidReply
+		}
+		fields[text(name)] = m.Array[i+1]
+	}
+	return fields, nil
+}
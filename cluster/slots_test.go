@@ -0,0 +1,101 @@
+package cluster
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+func b(s string) *resp.Message {
+	return resp.Bulk([]byte(s))
+}
+
+func TestParseSlots(t *testing.T) {
+	reply := resp.Array(
+		resp.Array(resp.Int(0), resp.Int(5460),
+			resp.Array(b("127.0.0.1"), resp.Int(30001), b("id1"), resp.Array(b("hostname"), b("host-1"))),
+			resp.Array(b("127.0.0.1"), resp.Int(30004), b("id4"), resp.Array())),
+		resp.Array(resp.Int(5461), resp.Int(10922),
+			resp.Array(b("127.0.0.1"), resp.Int(30002), b("id2"), resp.Array(b("ip"), b("10.0.0.2")))),
+		resp.Array(resp.Int(10923), resp.Int(10923),
+			resp.Array(b("127.0.0.1"), resp.Int(30001), b("id1"), resp.Array(b("hostname"), b("host-1")))),
+		resp.Array(resp.Int(10924), resp.Int(16383),
+			resp.Array(resp.Nil(), resp.Int(30003), b("id3")),
+			resp.Array(b("?"), resp.Int(30005), b("id5"))),
+	)
+	topo, err := ParseSlots(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topo.Nodes) != 5 {
+		t.Fatalf("error nodes: %d", len(topo.Nodes))
+	}
+	n1 := topo.Node("id1")
+	if n1.Role != RoleMaster || n1.Hostname != "host-1" || n1.Addr() != "127.0.0.1:30001" {
+		t.Errorf("error node 1: %s %s %s", n1.Role, n1.Hostname, n1.Addr())
+	}
+	if len(n1.Slots) != 2 || n1.Slots[0] != (SlotRange{0, 5460}) || n1.Slots[1] != (SlotRange{10923, 10923}) {
+		t.Errorf("error slots of node 1: %v", n1.Slots)
+	}
+	if n4 := topo.Node("id4"); n4.Role != RoleReplica || n4.MasterID != "id1" {
+		t.Errorf("error node 4: %s of %s", n4.Role, n4.MasterID)
+	}
+	if n2 := topo.Node("id2"); n2.IP != "10.0.0.2" {
+		t.Errorf("error IP of node 2: %s", n2.IP)
+	}
+	if n3 := topo.Node("id3"); n3.IP != "" || n3.Port != 30003 {
+		t.Errorf("error unknown endpoint of node 3: %s", n3.Addr())
+	}
+	if n5 := topo.Node("id5"); n5.IP != "" || n5.MasterID != "id3" {
+		t.Errorf("error node 5: %s of %s", n5.Addr(), n5.MasterID)
+	}
+	if n := topo.SlotOwner(10923); n != n1 {
+		t.Error("error owner of slot 10923")
+	}
+}
+
+func TestParseSlotsWithoutID(t *testing.T) {
+	reply := resp.Array(
+		resp.Array(resp.Int(0), resp.Int(8191),
+			resp.Array(b("127.0.0.1"), resp.Int(7000)),
+			resp.Array(b("127.0.0.1"), resp.Int(7002))),
+		resp.Array(resp.Int(8192), resp.Int(16383),
+			resp.Array(b("127.0.0.1"), resp.Int(7001))),
+	)
+	topo, err := ParseSlots(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topo.Nodes) != 3 {
+		t.Fatalf("error nodes: %d", len(topo.Nodes))
+	}
+	n := topo.Node("127.0.0.1:7002")
+	if n == nil || n.Role != RoleReplica || n.MasterID != "127.0.0.1:7000" {
+		t.Error("error node identified by its address")
+	}
+}
+
+func TestParseSlotsInvalid(t *testing.T) {
+	replies := []*resp.Message{
+		resp.Int(1),
+		resp.NilArray(),
+		resp.Array(resp.Int(0)),
+		resp.Array(resp.Array(resp.Int(0), resp.Int(5460))),
+		resp.Array(resp.Array(resp.Int(5460), resp.Int(0), resp.Array(b("127.0.0.1"), resp.Int(7000)))),
+		resp.Array(resp.Array(resp.Int(0), resp.Int(16384), resp.Array(b("127.0.0.1"), resp.Int(7000)))),
+		resp.Array(resp.Array(resp.Int(0), resp.Int(5460), resp.Array(b("127.0.0.1")))),
+		resp.Array(resp.Array(resp.Int(0), resp.Int(5460), resp.Array(b("127.0.0.1"), b("7000")))),
+		resp.Array(resp.Array(resp.Int(0), resp.Int(5460), resp.Array(b("127.0.0.1"), resp.Int(7000), resp.Int(1)))),
+		resp.Array(resp.Array(resp.Int(0), resp.Int(5460), resp.Array(b("127.0.0.1"), resp.Int(7000), b("id"), resp.Array(b("ip"))))),
+	}
+	for i, reply := range replies {
+		if _, err := ParseSlots(reply); err != ErrInvalidReply {
+			t.Errorf("error parsing reply %d: %v", i, err)
+		}
+	}
+	_, err := ParseSlots(resp.Err(errors.New("ERR This instance has cluster support disabled")))
+	if err == nil || err == ErrInvalidReply {
+		t.Errorf("error parsing an error reply: %v", err)
+	}
+}
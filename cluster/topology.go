@@ -0,0 +1,205 @@
+// Package cluster parses the replies describing the topology of a redis
+// cluster, CLUSTER NODES, CLUSTER SLOTS and CLUSTER SHARDS, into a common
+// Topology and reports the changes between two topologies.
+package cluster
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// SlotCount is the number of hash slots of a cluster.
+const SlotCount = 16384
+
+// Roles of the nodes.
+const (
+	RoleMaster  = "master"
+	RoleReplica = "replica"
+)
+
+var (
+	// ErrInvalidReply is returned when a reply does not have the layout of
+	// the command it was parsed for
+	ErrInvalidReply = errors.New("cluster: invalid reply")
+
+	// ErrInvalidNode is returned when a line of CLUSTER NODES can not be
+	// parsed
+	ErrInvalidNode = errors.New("cluster: invalid node line")
+)
+
+// SlotRange is a range of hash slots, both bounds included.
+type SlotRange struct {
+	Start, End int
+}
+
+func (r SlotRange) String() string {
+	if r.Start == r.End {
+		return strconv.Itoa(r.Start)
+	}
+	return strconv.Itoa(r.Start) + "-" + strconv.Itoa(r.End)
+}
+
+// Node is a node of a cluster. The fields which are not part of a reply are
+// left empty: only CLUSTER NODES reports the flags, ping times, epoch, link
+// state and slot migrations, and only CLUSTER SHARDS the health and the
+// replication offset.
+type Node struct {
+	// ID is the node ID. The replies to CLUSTER SLOTS of redis before 4.0
+	// do not report it, the address being used instead.
+	ID       string
+	IP       string
+	Port     int
+	BusPort  int
+	Hostname string
+	Role     string
+	// MasterID is the ID of the master of a replica
+	MasterID    string
+	Flags       []string
+	PingSent    int64
+	PongRecv    int64
+	ConfigEpoch int64
+	LinkState   string
+	Health      string
+	// ReplicationOffset is reported by CLUSTER SHARDS
+	ReplicationOffset int64
+	// Slots holds the slots served by a master, sorted and merged
+	Slots []SlotRange
+	// Migrating and Importing map the slots being moved out of and into the
+	// node to the ID of the other node
+	Migrating map[int]string
+	Importing map[int]string
+	// Aux holds the auxiliary fields following the hostname in the address
+	// of CLUSTER NODES since redis 7.2, such as shard-id
+	Aux map[string]string
+}
+
+// Addr returns the address of the node, IP:port.
+func (n *Node) Addr() string {
+	return net.JoinHostPort(n.IP, strconv.Itoa(n.Port))
+}
+
+// HasFlag reports whether the node has a flag, such as "myself" or "fail".
+func (n *Node) HasFlag(flag string) bool {
+	for _, f := range n.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// Topology is the set of nodes of a cluster.
+type Topology struct {
+	// Nodes holds the nodes sorted by ID
+	Nodes []*Node
+}
+
+// Node returns the node with the given ID, nil if there is none.
+func (t *Topology) Node(id string) *Node {
+	i := sort.Search(len(t.Nodes), func(i int) bool { return t.Nodes[i].ID >= id })
+	if i < len(t.Nodes) && t.Nodes[i].ID == id {
+		return t.Nodes[i]
+	}
+	return nil
+}
+
+// Masters returns the master nodes.
+func (t *Topology) Masters() []*Node {
+	var masters []*Node
+	for _, n := range t.Nodes {
+		if n.Role == RoleMaster {
+			masters = append(masters, n)
+		}
+	}
+	return masters
+}
+
+// Replicas returns the replicas of a master.
+func (t *Topology) Replicas(masterID string) []*Node {
+	var replicas []*Node
+	for _, n := range t.Nodes {
+		if n.Role == RoleReplica && n.MasterID == masterID {
+			replicas = append(replicas, n)
+		}
+	}
+	return replicas
+}
+
+// SlotOwner returns the master serving a slot, nil if none does.
+func (t *Topology) SlotOwner(slot int) *Node {
+	for _, n := range t.Nodes {
+		if n.Role != RoleMaster {
+			continue
+		}
+		for _, r := range n.Slots {
+			if slot >= r.Start && slot <= r.End {
+				return n
+			}
+		}
+	}
+	return nil
+}
+
+type byID []*Node
+
+func (s byID) Len() int           { return len(s) }
+func (s byID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byID) Less(i, j int) bool { return s[i].ID < s[j].ID }
+
+type byStart []SlotRange
+
+func (s byStart) Len() int           { return len(s) }
+func (s byStart) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byStart) Less(i, j int) bool { return s[i].Start < s[j].Start }
+
+// newTopology sorts the nodes and their slots.
+func newTopology(nodes []*Node) *Topology {
+	for _, n := range nodes {
+		n.Slots = mergeRanges(n.Slots)
+	}
+	sort.Sort(byID(nodes))
+	return &Topology{Nodes: nodes}
+}
+
+// mergeRanges sorts slot ranges and merges the adjacent ones.
+func mergeRanges(ranges []SlotRange) []SlotRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+	sort.Sort(byStart(ranges))
+	merged := []SlotRange{ranges[0]}
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start <= last.End+1 {
+			if r.End > last.End {
+				last.End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+func validSlot(slot int64) bool {
+	return slot >= 0 && slot < SlotCount
+}
+
+// checkReply returns the error of an error reply, and ErrInvalidReply for
+// other messages than arrays.
+func checkReply(m *resp.Message) error {
+	if m == nil || m.IsNil {
+		return ErrInvalidReply
+	}
+	if m.Type == resp.ErrorHeader {
+		return m.Error
+	}
+	if m.Type != resp.ArrayHeader {
+		return ErrInvalidReply
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package cluster
+
+import (
+	"testing"
+)
+
+func TestMergeRanges(t *testing.T) {
+	got := mergeRanges([]SlotRange{{10, 20}, {0, 5}, {6, 9}, {15, 30}, {40, 40}})
+	want := []SlotRange{{0, 30}, {40, 40}}
+	if len(got) != len(want) {
+		t.Fatalf("error merged ranges: %v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("error range %d: %v", i, got[i])
+		}
+	}
+	if mergeRanges(nil) != nil {
+		t.Error("error merging no range")
+	}
+}
+
+func TestSlotRangeString(t *testing.T) {
+	if s := (SlotRange{5, 5}).String(); s != "5" {
+		t.Errorf("error single slot: %s", s)
+	}
+	if s := (SlotRange{0, 5460}).String(); s != "0-5460" {
+		t.Errorf("error range: %s", s)
+	}
+}
+
+func TestTopology(t *testing.T) {
+	topo := newTopology([]*Node{
+		{ID: "c", Role: RoleReplica, MasterID: "a"},
+		{ID: "b", Role: RoleMaster, Slots: []SlotRange{{8192, 16383}}},
+		{ID: "a", Role: RoleMaster, Slots: []SlotRange{{100, 8191}, {0, 99}}},
+	})
+	if topo.Nodes[0].ID != "a" || topo.Nodes[2].ID != "c" {
+		t.Error("error nodes are not sorted")
+	}
+	if len(topo.Nodes[0].Slots) != 1 || topo.Nodes[0].Slots[0] != (SlotRange{0, 8191}) {
+		t.Errorf("error slots: %v", topo.Nodes[0].Slots)
+	}
+	if n := topo.Node("b"); n == nil || n.ID != "b" {
+		t.Error("error finding node b")
+	}
+	if topo.Node("d") != nil {
+		t.Error("error finding unknown node")
+	}
+	if len(topo.Masters()) != 2 {
+		t.Errorf("error masters: %d", len(topo.Masters()))
+	}
+	if r := topo.Replicas("a"); len(r) != 1 || r[0].ID != "c" {
+		t.Error("error replicas of a")
+	}
+	if len(topo.Replicas("b")) != 0 {
+		t.Error("error replicas of b")
+	}
+	if n := topo.SlotOwner(8191); n == nil || n.ID != "a" {
+		t.Error("error owner of slot 8191")
+	}
+	if n := topo.SlotOwner(16383); n == nil || n.ID != "b" {
+		t.Error("error owner of slot 16383")
+	}
+	if topo.SlotOwner(SlotCount) != nil {
+		t.Error("error owner of invalid slot")
+	}
+}
+
+func TestNodeAddr(t *testing.T) {
+	n := &Node{IP: "127.0.0.1", Port: 30001}
+	if n.Addr() != "127.0.0.1:30001" {
+		t.Errorf("error address: %s", n.Addr())
+	}
+	n = &Node{IP: "::1", Port: 30001}
+	if n.Addr() != "[::1]:30001" {
+		t.Errorf("error IPv6 address: %s", n.Addr())
+	}
+}
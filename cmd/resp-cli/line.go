@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// errInterrupted is returned by readLine when Ctrl-C is pressed.
+var errInterrupted = errors.New("interrupted")
+
+// maxHistory is the number of lines kept in the history.
+const maxHistory = 1000
+
+// lineEditor reads the lines typed in the REPL. On a terminal it edits them
+// in raw mode, with the usual emacs key bindings and the arrows browsing
+// the history, otherwise it reads plain lines without prompt.
+type lineEditor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	terminal bool
+	history  []string
+	// file is the history file, appended with each line, if any
+	file string
+}
+
+func newLineEditor(histfile string) *lineEditor {
+	e := &lineEditor{
+		in:       bufio.NewReader(os.Stdin),
+		out:      os.Stdout,
+		terminal: isTerminal(os.Stdin.Fd()) && isTerminal(os.Stdout.Fd()),
+		file:     histfile,
+	}
+	if histfile != "" {
+		if data, err := ioutil.ReadFile(histfile); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if line != "" {
+					e.history = append(e.history, line)
+				}
+			}
+			if len(e.history) > maxHistory {
+				e.history = e.history[len(e.history)-maxHistory:]
+			}
+		}
+	}
+	return e
+}
+
+// add appends a line to the history and to the history file.
+func (e *lineEditor) add(line string) {
+	if line == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[1:]
+	}
+	if e.file == "" {
+		return
+	}
+	f, err := os.OpenFile(e.file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return
+	}
+	f.WriteString(line + "\n")
+	f.Close()
+}
+
+// readLine returns the next line, io.EOF at the end of the input or on
+// Ctrl-D, and errInterrupted on Ctrl-C.
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	if !e.terminal {
+		line, err := e.in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	restore, err := makeRaw(os.Stdin.Fd())
+	if err != nil {
+		e.terminal = false
+		io.WriteString(e.out, prompt)
+		return e.readLine(prompt)
+	}
+	defer restore()
+	return e.edit(prompt)
+}
+
+// edit runs the line editing loop, the terminal being in raw mode.
+func (e *lineEditor) edit(prompt string) (string, error) {
+	var buf []rune
+	pos := 0
+	// hist is the index of the history line shown, len(history) being the
+	// line being typed, which is saved while browsing
+	hist := len(e.history)
+	var typed []rune
+
+	refresh := func() {
+		var b bytes.Buffer
+		b.WriteString("\r")
+		b.WriteString(prompt)
+		b.WriteString(string(buf))
+		b.WriteString("\x1b[0K\r")
+		if n := len([]rune(prompt)) + pos; n > 0 {
+			b.WriteString("\x1b[" + strconv.Itoa(n) + "C")
+		}
+		e.out.Write(b.Bytes())
+	}
+	browse := func(to int) {
+		if to < 0 || to > len(e.history) || to == hist {
+			return
+		}
+		if hist == len(e.history) {
+			typed = buf
+		}
+		hist = to
+		if hist == len(e.history) {
+			buf = typed
+		} else {
+			buf = []rune(e.history[hist])
+		}
+		pos = len(buf)
+		refresh()
+	}
+
+	refresh()
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			io.WriteString(e.out, "\r\n")
+			return string(buf), nil
+		case 3: // Ctrl-C
+			io.WriteString(e.out, "^C\r\n")
+			return "", errInterrupted
+		case 4: // Ctrl-D
+			if len(buf) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			if pos < len(buf) {
+				buf = append(buf[:pos], buf[pos+1:]...)
+			}
+		case 127, 8: // Backspace, Ctrl-H
+			if pos > 0 {
+				buf = append(buf[:pos-1], buf[pos:]...)
+				pos--
+			}
+		case 1: // Ctrl-A
+			pos = 0
+		case 5: // Ctrl-E
+			pos = len(buf)
+		case 2: // Ctrl-B
+			if pos > 0 {
+				pos--
+			}
+		case 6: // Ctrl-F
+			if pos < len(buf) {
+				pos++
+			}
+		case 16: // Ctrl-P
+			browse(hist - 1)
+			continue
+		case 14: // Ctrl-N
+			browse(hist + 1)
+			continue
+		case 21: // Ctrl-U
+			buf, pos = buf[:0], 0
+		case 11: // Ctrl-K
+			buf = buf[:pos]
+		case 23: // Ctrl-W
+			start := pos
+			for start > 0 && buf[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && buf[start-1] != ' ' {
+				start--
+			}
+			buf = append(buf[:start], buf[pos:]...)
+			pos = start
+		case 12: // Ctrl-L
+			io.WriteString(e.out, "\x1b[H\x1b[2J")
+		case 27: // escape sequences of the arrows, home, end and delete
+			switch e.escape() {
+			case 'A':
+				browse(hist - 1)
+				continue
+			case 'B':
+				browse(hist + 1)
+				continue
+			case 'C':
+				if pos < len(buf) {
+					pos++
+				}
+			case 'D':
+				if pos > 0 {
+					pos--
+				}
+			case 'H':
+				pos = 0
+			case 'F':
+				pos = len(buf)
+			case '3':
+				if pos < len(buf) {
+					buf = append(buf[:pos], buf[pos+1:]...)
+				}
+			}
+		default:
+			if r < 0x20 {
+				continue
+			}
+			buf = append(buf, 0)
+			copy(buf[pos+1:], buf[pos:])
+			buf[pos] = r
+			pos++
+		}
+		refresh()
+	}
+}
+
+// escape reads the rest of an escape sequence and returns its final key:
+// the letter of the arrows, home and end, or '3' for delete.
+func (e *lineEditor) escape() byte {
+	b, err := e.in.ReadByte()
+	if err != nil || (b != '[' && b != 'O') {
+		return 0
+	}
+	c, err := e.in.ReadByte()
+	if err != nil {
+		return 0
+	}
+	if c >= '0' && c <= '9' {
+		// extended sequences such as ESC [ 3 ~
+		if t, err := e.in.ReadByte(); err != nil || t != '~' {
+			return 0
+		}
+		switch c {
+		case '1', '7':
+			return 'H'
+		case '4', '8':
+			return 'F'
+		}
+	}
+	return c
+}
@@ -0,0 +1,304 @@
+// Command resp-cli is an interactive client for redis and any other server
+// speaking RESP, which works like redis-cli:
+//
+//	resp-cli -h 127.0.0.1 -p 6379
+//	resp-cli -r 5 -i 1 INCR counter
+//	resp-cli --pipe < commands.txt
+//	resp-cli --resp3 HELLO
+//
+// Without command arguments it starts a REPL, with the history kept in
+// ~/.respcli_history. Arguments are quoted the way redis-cli does, and a
+// line starting with a number repeats the command that many times. Replies
+// are rendered like redis-cli, or unquoted with --raw, which is the default
+// when the output is not a terminal.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// options holds the command line flags.
+type options struct {
+	network, addr string
+	user, pass    string
+	db            int
+	resp3         bool
+	mode          resp.FormatMode
+}
+
+func main() {
+	host := flag.String("h", "127.0.0.1", "server hostname")
+	port := flag.Int("p", 6379, "server port")
+	socket := flag.String("s", "", "server socket, overriding hostname and port")
+	user := flag.String("user", "", "user to authenticate with, needs -a")
+	pass := flag.String("a", "", "password to authenticate with")
+	db := flag.Int("n", 0, "database number")
+	repeat := flag.Int("r", 1, "execute the command this number of times, -1 to repeat it forever")
+	interval := flag.Float64("i", 0, "interval between the repeated commands, in seconds")
+	pipe := flag.Bool("pipe", false, "send the commands read from stdin and count the replies")
+	resp3 := flag.Bool("resp3", false, "switch to the version 3 of the protocol with HELLO 3")
+	raw := flag.Bool("raw", false, "render the replies unquoted")
+	noRaw := flag.Bool("no-raw", false, "render the replies like in a terminal even when the output is not one")
+	flag.Parse()
+
+	opts := &options{
+		network: "tcp",
+		addr:    net.JoinHostPort(*host, strconv.Itoa(*port)),
+		user:    *user,
+		pass:    *pass,
+		db:      *db,
+		resp3:   *resp3,
+	}
+	if *socket != "" {
+		opts.network, opts.addr = "unix", *socket
+	}
+	if *raw || (!*noRaw && !isTerminal(os.Stdout.Fd())) {
+		opts.mode = resp.FormatRaw
+	}
+
+	var err error
+	switch {
+	case *pipe:
+		err = runPipe(opts, os.Stdin, os.Stdout)
+	case flag.NArg() > 0:
+		err = runCommand(opts, flag.Args(), *repeat, time.Duration(*interval*float64(time.Second)))
+	default:
+		err = runREPL(opts)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// client is a connection to the server, reopened after a failure.
+type client struct {
+	opts *options
+	conn *resp.Conn
+	// db is the database selected, as shown by the prompt
+	db int
+}
+
+// connect opens the connection if it is not open, and authenticates,
+// switches the protocol and selects the database.
+func (c *client) connect(ctx context.Context) error {
+	if c.conn != nil && c.conn.Err() == nil {
+		return nil
+	}
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+	conn, err := resp.DialContext(ctx, c.opts.network, c.opts.addr)
+	if err != nil {
+		return err
+	}
+	var setup []*resp.Message
+	if c.opts.resp3 {
+		hello := resp.Cmd("HELLO", 3)
+		if c.opts.pass != "" {
+			hello.Arg("AUTH", c.user(), c.opts.pass)
+		}
+		setup = append(setup, hello)
+	} else if c.opts.pass != "" {
+		auth := resp.Cmd("AUTH", c.opts.pass)
+		if c.opts.user != "" {
+			auth = resp.Cmd("AUTH", c.opts.user, c.opts.pass)
+		}
+		setup = append(setup, auth)
+	}
+	if c.db != 0 {
+		setup = append(setup, resp.Cmd("SELECT", c.db))
+	}
+	for _, cmd := range setup {
+		reply, err := conn.DoContext(ctx, cmd)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+		if reply.Type != resp.ErrorHeader {
+			continue
+		}
+		name := strings.ToUpper(string(cmd.Array[0].Bytes))
+		if name == "HELLO" {
+			// servers before redis 6.0 only speak RESP2
+			fmt.Fprintf(os.Stderr, "Warning: HELLO 3 failed, using RESP2: %v\n", reply.Error)
+			continue
+		}
+		conn.Close()
+		return fmt.Errorf("%s failed: %v", name, reply.Error)
+	}
+	c.conn = conn
+	return nil
+}
+
+func (c *client) user() string {
+	if c.opts.user == "" {
+		return "default"
+	}
+	return c.opts.user
+}
+
+// do runs a command and writes its reply. The commands which subscribe or
+// monitor keep writing the messages received until ctx is done.
+func (c *client) do(ctx context.Context, w io.Writer, args []string) error {
+	if err := c.connect(ctx); err != nil {
+		return err
+	}
+	cmd := resp.Cmd(args[0])
+	for _, arg := range args[1:] {
+		cmd.Arg(arg)
+	}
+	reply, err := c.conn.DoContext(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	if err := resp.Format(w, reply, resp.FormatOptions{Mode: c.opts.mode}); err != nil {
+		return err
+	}
+
+	name := strings.ToUpper(args[0])
+	switch name {
+	case "SELECT":
+		if reply.Type != resp.ErrorHeader && len(args) == 2 {
+			c.db, _ = strconv.Atoi(args[1])
+		}
+	case "SUBSCRIBE", "PSUBSCRIBE", "SSUBSCRIBE", "MONITOR":
+		if reply.Type == resp.ErrorHeader {
+			return nil
+		}
+		fmt.Fprintln(os.Stderr, "Reading messages... (press Ctrl-C to quit)")
+		for {
+			m, err := c.conn.ReceiveContext(ctx)
+			if err != nil {
+				return err
+			}
+			if err := resp.Format(w, m, resp.FormatOptions{Mode: c.opts.mode}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// interruptible returns a context canceled by Ctrl-C, and a function to
+// release it.
+func interruptible() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(interrupt)
+		cancel()
+	}
+}
+
+// runCommand runs the command of the arguments repeat times, forever if
+// repeat is negative.
+func runCommand(opts *options, args []string, repeat int, interval time.Duration) error {
+	ctx, cancel := interruptible()
+	defer cancel()
+	c := &client{opts: opts, db: opts.db}
+	for i := 0; repeat < 0 || i < repeat; i++ {
+		if i > 0 && interval > 0 {
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		if err := c.do(ctx, os.Stdout, args); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// runREPL reads commands from the terminal until Ctrl-C, Ctrl-D or quit.
+func runREPL(opts *options) error {
+	histfile := ""
+	if home := os.Getenv("HOME"); home != "" {
+		histfile = filepath.Join(home, ".respcli_history")
+	}
+	editor := newLineEditor(histfile)
+	c := &client{opts: opts, db: opts.db}
+	if err := c.connect(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not connect to %s: %v\n", opts.addr, err)
+	}
+	for {
+		line, err := editor.readLine(c.prompt())
+		if err == io.EOF || err == errInterrupted {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		editor.add(line)
+		args, err := resp.SplitArgs(line)
+		if err != nil {
+			fmt.Println("Invalid argument(s)")
+			continue
+		}
+		name := strings.ToLower(args[0])
+		if name == "quit" || name == "exit" {
+			return nil
+		}
+		if name == "clear" {
+			fmt.Print("\x1b[H\x1b[2J")
+			continue
+		}
+		// a leading number repeats the command
+		repeat := 1
+		if n, err := strconv.Atoi(args[0]); err == nil && len(args) > 1 {
+			repeat, args = n, args[1:]
+		}
+		ctx, cancel := interruptible()
+		for i := 0; i < repeat && ctx.Err() == nil; i++ {
+			if err := c.do(ctx, os.Stdout, args); err != nil {
+				if ctx.Err() == nil {
+					fmt.Printf("Error: %v\n", err)
+				}
+				break
+			}
+		}
+		cancel()
+	}
+}
+
+// prompt returns the prompt of the REPL, the address of the server followed
+// by the database selected, if not 0.
+func (c *client) prompt() string {
+	p := c.opts.addr
+	if c.conn == nil {
+		p = "not connected"
+	} else if c.db != 0 {
+		p += "[" + strconv.Itoa(c.db) + "]"
+	}
+	return p + "> "
+}
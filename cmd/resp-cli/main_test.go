@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/amyangfei/resp-go/resp"
+	"github.com/amyangfei/resp-go/resptest"
+)
+
+// setupServer records the commands received, fails HELLO as the servers
+// before redis 6.0 do, and AUTH for the password "bad".
+type setupServer struct {
+	mu       sync.Mutex
+	commands []string
+}
+
+func (s *setupServer) ServeRESP(c *resp.ServerConn, cmd *resp.Message) {
+	args, _ := cmd.Strings()
+	s.mu.Lock()
+	s.commands = append(s.commands, strings.Join(args, " "))
+	s.mu.Unlock()
+	switch {
+	case strings.ToUpper(args[0]) == "HELLO":
+		c.WriteMessage(resp.Err(errors.New("ERR unknown command 'HELLO'")))
+	case strings.ToUpper(args[0]) == "AUTH" && args[len(args)-1] == "bad":
+		c.WriteMessage(resp.Err(errors.New("WRONGPASS invalid username-password pair")))
+	default:
+		c.WriteMessage(resp.Str("OK"))
+	}
+}
+
+func TestClientConnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &setupServer{}
+	srv := &resp.Server{Handler: h}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	tests := []struct {
+		opts     options
+		db       int
+		commands string
+		err      string
+	}{
+		{options{}, 0, "", ""},
+		{options{resp3: true}, 2, "HELLO 3|SELECT 2", ""},
+		{options{resp3: true, pass: "pw"}, 0, "HELLO 3 AUTH default pw", ""},
+		{options{resp3: true, user: "u", pass: "pw"}, 0, "HELLO 3 AUTH u pw", ""},
+		{options{pass: "pw"}, 1, "AUTH pw|SELECT 1", ""},
+		{options{user: "u", pass: "pw"}, 0, "AUTH u pw", ""},
+		{options{pass: "bad"}, 1, "AUTH bad", "AUTH failed: WRONGPASS invalid username-password pair"},
+	}
+	for _, test := range tests {
+		h.mu.Lock()
+		h.commands = nil
+		h.mu.Unlock()
+		opts := test.opts
+		opts.network, opts.addr = "tcp", l.Addr().String()
+		c := &client{opts: &opts, db: test.db}
+		err := c.connect(context.Background())
+		if test.err != "" {
+			if err == nil || err.Error() != test.err || c.conn != nil {
+				t.Errorf("error connecting with %+v: %v", test.opts, err)
+			}
+		} else if err != nil || c.conn == nil {
+			t.Errorf("error connecting with %+v: %v", test.opts, err)
+		}
+		h.mu.Lock()
+		commands := strings.Join(h.commands, "|")
+		h.mu.Unlock()
+		if commands != test.commands {
+			t.Errorf("error setup commands with %+v: %q", test.opts, commands)
+		}
+		if c.conn != nil {
+			c.conn.Close()
+		}
+	}
+}
+
+func TestClientDo(t *testing.T) {
+	srv := resptest.NewServer()
+	defer srv.Close()
+	opts := &options{network: "tcp", addr: srv.Addr(), resp3: true}
+	c := &client{opts: opts, db: 1}
+	defer func() {
+		if c.conn != nil {
+			c.conn.Close()
+		}
+	}()
+
+	// resptest does not know HELLO, the client falls back to RESP2
+	var out bytes.Buffer
+	for _, line := range []string{"SET k v", "GET k", "SELECT 3", "GET k", "LPUSH"} {
+		args, _ := resp.SplitArgs(line)
+		if err := c.do(context.Background(), &out, args); err != nil {
+			t.Fatalf("error running %q: %v", line, err)
+		}
+	}
+	want := "OK\n\"v\"\nOK\n(nil)\n(error) ERR wrong number of arguments for 'lpush' command\n"
+	if out.String() != want {
+		t.Errorf("error output: %q", out.String())
+	}
+	if c.prompt() != srv.Addr()+"[3]> " {
+		t.Errorf("error prompt: %q", c.prompt())
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// runPipe sends the commands read from in without waiting for their
+// replies, as redis-cli --pipe does. The input is either RESP, sent as is,
+// or a command per line, quoted the way redis-cli does. An ECHO of a random
+// marker is sent last: its reply tells that every reply was received.
+func runPipe(opts *options, in io.Reader, out io.Writer) error {
+	conn, err := net.Dial(opts.network, opts.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var marker [20]byte
+	if _, err := rand.Read(marker[:]); err != nil {
+		return err
+	}
+	mark := []byte(hex.EncodeToString(marker[:]))
+
+	// out is written by both the sender and the reader
+	out = &syncWriter{w: out}
+	sent := make(chan error, 1)
+	go func() {
+		err := sendPipe(conn, in, out, mark)
+		sent <- err
+		if err != nil {
+			// the marker was not sent: closing the connection stops the
+			// reader waiting for its reply
+			conn.Close()
+		}
+	}()
+
+	r := resp.NewReader(conn)
+	replies, errors := 0, 0
+	done := false
+	for !done {
+		m, err := r.ReadMessage()
+		if err != nil {
+			select {
+			case serr := <-sent:
+				if serr != nil {
+					return serr
+				}
+			default:
+			}
+			return err
+		}
+		switch {
+		case m.Type == resp.BulkHeader && bytes.Equal(m.Bytes, mark):
+			done = true
+		case m.Type == resp.ErrorHeader:
+			errors++
+			fmt.Fprintln(out, m.Error)
+			replies++
+		default:
+			replies++
+		}
+	}
+	if err := <-sent; err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "Last reply received from server.")
+	fmt.Fprintf(out, "errors: %d, replies: %d\n", errors, replies)
+	return nil
+}
+
+// sendPipe writes the commands of in, then the ECHO of the marker, to w.
+func sendPipe(w io.Writer, in io.Reader, out io.Writer, mark []byte) error {
+	bw := bufio.NewWriterSize(w, 64*1024)
+	br := bufio.NewReaderSize(in, 64*1024)
+	first, err := br.Peek(1)
+	switch {
+	case err == io.EOF:
+	case err != nil:
+		return err
+	case first[0] == resp.ArrayHeader:
+		if _, err := io.Copy(bw, br); err != nil {
+			return err
+		}
+	default:
+		enc := resp.NewEncoder(bw)
+		scanner := bufio.NewScanner(br)
+		scanner.Buffer(make([]byte, 64*1024), 512<<20)
+		for scanner.Scan() {
+			args, err := resp.SplitArgs(scanner.Text())
+			if err != nil {
+				return fmt.Errorf("invalid line %q: %v", scanner.Text(), err)
+			}
+			if len(args) == 0 {
+				continue
+			}
+			cmd := resp.Cmd(args[0])
+			for _, arg := range args[1:] {
+				cmd.Arg(arg)
+			}
+			if err := enc.Encode(cmd); err != nil {
+				return err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+	if err := resp.NewEncoder(bw).Encode(resp.Cmd("ECHO", mark)); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "All data transferred. Waiting for the last reply...")
+	return nil
+}
+
+// syncWriter serializes the writes to w.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *syncWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(b)
+}
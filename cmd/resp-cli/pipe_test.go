@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+	"github.com/amyangfei/resp-go/resptest"
+)
+
+func TestRunPipe(t *testing.T) {
+	srv := resptest.NewServer()
+	defer srv.Close()
+	opts := &options{network: "tcp", addr: srv.Addr()}
+
+	tests := []struct {
+		in     string
+		counts string
+	}{
+		{"", "errors: 0, replies: 0"},
+		{"SET k 1\nINCR k\n\nLPUSH k x\n\"GET\" 'k'\nNOSUCH\n", "errors: 2, replies: 5"},
+		{"*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n*2\r\n$4\r\nINCR\r\n$1\r\nk\r\n", "errors: 1, replies: 2"},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		if err := runPipe(opts, strings.NewReader(test.in), &out); err != nil {
+			t.Errorf("error piping %q: %v", test.in, err)
+			continue
+		}
+		if !strings.Contains(out.String(), "All data transferred") ||
+			!strings.HasSuffix(out.String(), "Last reply received from server.\n"+test.counts+"\n") {
+			t.Errorf("error output of %q: %s", test.in, out.String())
+		}
+	}
+	c, err := resp.Dial("tcp", srv.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if m, err := c.Do(resp.Cmd("GET", "k")); err != nil || string(m.Bytes) != "v" {
+		t.Errorf("error value piped: %v %v", m, err)
+	}
+}
+
+func TestRunPipeInvalidLine(t *testing.T) {
+	srv := resptest.NewServer()
+	defer srv.Close()
+	opts := &options{network: "tcp", addr: srv.Addr()}
+
+	done := make(chan error, 1)
+	go func() {
+		var out bytes.Buffer
+		done <- runPipe(opts, strings.NewReader("SET k 1\nSET \"k 2\n"), &out)
+	}()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "invalid line") {
+			t.Errorf("should return the invalid line error, not: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("error piping an invalid line hangs")
+	}
+}
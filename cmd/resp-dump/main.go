@@ -0,0 +1,264 @@
+// Command resp-dump prints the RESP messages read from a file or stdin in
+// redis-cli form, unquoted, as JSON Lines in the tagged form of the resp
+// package, or as an annotated hex dump:
+//
+//	resp-dump capture.bin
+//	resp-dump -format json < capture.bin > capture.jsonl
+//	resp-dump -format hex capture.bin
+//
+// An incomplete message at the end of the input is reported once the
+// complete ones are printed. With -reverse it converts commands, one per
+// line and quoted the way redis-cli does, or JSON Lines with -format json,
+// to RESP, for instance to feed redis-cli --pipe:
+//
+//	resp-dump -reverse commands.txt | redis-cli --pipe
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+func main() {
+	format := flag.String("format", "cli", "output format: cli, raw, json or hex; with -reverse, text or json input")
+	reverse := flag.Bool("reverse", false, "convert commands, one per line, or JSON Lines to RESP")
+	flag.Parse()
+
+	in := os.Stdin
+	if flag.NArg() > 0 && flag.Arg(0) != "-" {
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		fatal(err)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	if *reverse {
+		err = encode(out, data, *format)
+	} else {
+		err = dump(out, data, *format)
+	}
+	if ferr := out.Flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "resp-dump:", err)
+	os.Exit(1)
+}
+
+// dump prints the messages of data, then reports the error which stopped
+// the decoding, if any.
+func dump(w io.Writer, data []byte, format string) error {
+	var print func(i int, m *resp.Message) error
+	switch format {
+	case "cli", "raw":
+		opts := resp.FormatOptions{Mode: resp.FormatCLI}
+		if format == "raw" {
+			opts.Mode = resp.FormatRaw
+		}
+		print = func(i int, m *resp.Message) error {
+			return resp.Format(w, m, opts)
+		}
+	case "json":
+		enc := json.NewEncoder(w)
+		print = func(i int, m *resp.Message) error {
+			return enc.Encode(m)
+		}
+	case "hex":
+		h := &hexDumper{w: w, data: data, lost: -1}
+		print = h.message
+		defer h.flush()
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+
+	msgs, pos, err := resp.Decode(data)
+	for i, m := range msgs {
+		if err := print(i, m); err != nil {
+			return err
+		}
+	}
+	switch {
+	case err == nil:
+		return nil
+	case resp.MaybeSegmentError(err):
+		return fmt.Errorf("incomplete message at offset %d, %d bytes left: %v", pos, len(data)-pos, err)
+	default:
+		return fmt.Errorf("invalid data after %d messages: %v", len(msgs), err)
+	}
+}
+
+// hexDumper prints the bytes of each message in hex, preceded by a summary
+// of the message. The bytes of a message are found by encoding it again,
+// which gives back the input unless it holds RESP3 attributes or another
+// encoding of the same value, such as an integer with a leading +: the
+// remaining messages are then only summarized, and their bytes dumped as a
+// whole at the end.
+type hexDumper struct {
+	w    io.Writer
+	data []byte
+	off  int
+	// lost is the index of the first message whose bytes were not found,
+	// -1 if none
+	lost  int
+	count int
+}
+
+func (h *hexDumper) message(i int, m *resp.Message) error {
+	h.count = i + 1
+	n := -1
+	if h.lost < 0 {
+		if b, err := resp.Marshal(m); err == nil && bytes.HasPrefix(h.data[h.off:], b) {
+			n = len(b)
+		} else {
+			h.lost = i
+		}
+	}
+	if n < 0 {
+		_, err := fmt.Fprintf(h.w, "# message %d: %s\n", i+1, summary(m))
+		return err
+	}
+	if _, err := fmt.Fprintf(h.w, "# message %d, offset %d, %d bytes: %s\n", i+1, h.off, n, summary(m)); err != nil {
+		return err
+	}
+	hexDump(h.w, h.data[h.off:h.off+n], h.off)
+	h.off += n
+	return nil
+}
+
+// flush dumps the bytes of the messages which could not be delimited.
+func (h *hexDumper) flush() {
+	if h.count == 0 || h.lost < 0 {
+		return
+	}
+	fmt.Fprintf(h.w, "# messages %d to %d, offset %d\n", h.lost+1, h.count, h.off)
+	// the incomplete message which may follow is left out
+	_, pos, _ := resp.Decode(h.data[h.off:])
+	hexDump(h.w, h.data[h.off:h.off+pos], h.off)
+}
+
+// hexDump writes b in the form of hexdump -C, 16 bytes per line prefixed
+// by their offset, off being the offset of b in the input.
+func hexDump(w io.Writer, b []byte, off int) {
+	var line bytes.Buffer
+	for i := 0; i < len(b); i += 16 {
+		line.Reset()
+		fmt.Fprintf(&line, "%08x ", off+i)
+		for j := 0; j < 16; j++ {
+			if j == 8 {
+				line.WriteByte(' ')
+			}
+			if i+j < len(b) {
+				fmt.Fprintf(&line, " %02x", b[i+j])
+			} else {
+				line.WriteString("   ")
+			}
+		}
+		line.WriteString("  |")
+		for j := i; j < i+16 && j < len(b); j++ {
+			if b[j] >= 0x20 && b[j] <= 0x7e {
+				line.WriteByte(b[j])
+			} else {
+				line.WriteByte('.')
+			}
+		}
+		line.WriteString("|\n")
+		w.Write(line.Bytes())
+	}
+}
+
+// summary returns a one line description of a message: the arguments of a
+// command, or the first line of its redis-cli form.
+func summary(m *resp.Message) string {
+	var s string
+	if args, ok := command(m); ok {
+		s = args
+	} else {
+		s = m.String()
+		if nl := strings.IndexByte(s, '\n'); nl >= 0 {
+			s = s[:nl] + " ..."
+		}
+	}
+	if utf8.RuneCountInString(s) > 80 {
+		s = string([]rune(s)[:77]) + "..."
+	}
+	return s
+}
+
+// command returns the arguments of an array of bulk strings, quoted the way
+// redis-cli does.
+func command(m *resp.Message) (string, bool) {
+	if m.Type != resp.ArrayHeader || m.IsNil || len(m.Array) == 0 {
+		return "", false
+	}
+	args := make([]string, len(m.Array))
+	for i, arg := range m.Array {
+		if arg.Type != resp.BulkHeader || arg.IsNil {
+			return "", false
+		}
+		args[i] = arg.String()
+	}
+	return strings.Join(args, " "), true
+}
+
+// encode converts commands, one per line, or JSON Lines in the tagged form
+// to RESP. Blank lines and lines starting with # are skipped.
+func encode(w io.Writer, data []byte, format string) error {
+	if format != "cli" && format != "text" && format != "json" {
+		return fmt.Errorf("unknown input format %q", format)
+	}
+	var buf bytes.Buffer
+	enc := resp.NewEncoder(&buf)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 512<<20)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m := &resp.Message{}
+		if format == "json" {
+			if err := json.Unmarshal([]byte(line), m); err != nil {
+				return fmt.Errorf("line %d: %v", n, err)
+			}
+		} else {
+			args, err := resp.SplitArgs(line)
+			if err != nil {
+				return fmt.Errorf("line %d: %v", n, err)
+			}
+			m = resp.Cmd(args[0])
+			for _, arg := range args[1:] {
+				m.Arg(arg)
+			}
+		}
+		buf.Reset()
+		if err := enc.Encode(m); err != nil {
+			return fmt.Errorf("line %d: %v", n, err)
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
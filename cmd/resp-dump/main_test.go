@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const dumpInput = "+OK\r\n:1\r\n*2\r\n$3\r\nGET\r\n$1\r\nk\r\n$-1\r\n"
+
+func TestDump(t *testing.T) {
+	tests := []struct {
+		format, data, out, err string
+	}{
+		{"cli", dumpInput, "OK\n(integer) 1\n1) \"GET\"\n2) \"k\"\n(nil)\n", ""},
+		{"raw", dumpInput, "OK\n1\nGET\nk\n\n", ""},
+		{"json", dumpInput, `{"type":"status","value":"OK"}` + "\n" +
+			`{"type":"integer","value":1}` + "\n" +
+			`{"type":"array","value":[{"type":"bulk","value":"GET"},{"type":"bulk","value":"k"}]}` + "\n" +
+			`{"type":"bulk","value":null}` + "\n", ""},
+		{"hex", dumpInput, "# message 1, offset 0, 5 bytes: OK\n" +
+			"00000000  2b 4f 4b 0d 0a                                    |+OK..|\n" +
+			"# message 2, offset 5, 4 bytes: (integer) 1\n" +
+			"00000005  3a 31 0d 0a                                       |:1..|\n" +
+			"# message 3, offset 9, 20 bytes: \"GET\" \"k\"\n" +
+			"00000009  2a 32 0d 0a 24 33 0d 0a  47 45 54 0d 0a 24 31 0d  |*2..$3..GET..$1.|\n" +
+			"00000019  0a 6b 0d 0a                                       |.k..|\n" +
+			"# message 4, offset 29, 5 bytes: (nil)\n" +
+			"0000001d  24 2d 31 0d 0a                                    |$-1..|\n", ""},
+		// the complete messages are printed before the incomplete one is
+		// reported
+		{"cli", "+OK\r\n$5\r\nhel", "OK\n", "incomplete message at offset 5, 7 bytes left: "},
+		{"cli", "+OK\r\n?x\r\n", "OK\n", "invalid data after 1 messages: "},
+		// an integer with a leading + is encoded again without it: the
+		// messages from it are summarized and dumped as a whole, without
+		// the incomplete message following them
+		{"hex", ":+1\r\n+OK\r\n$3\r\nab", "# message 1: (integer) 1\n" +
+			"# message 2: OK\n" +
+			"# messages 1 to 2, offset 0\n" +
+			"00000000  3a 2b 31 0d 0a 2b 4f 4b  0d 0a                    |:+1..+OK..|\n",
+			"incomplete message at offset 10, 6 bytes left: "},
+		{"yaml", dumpInput, "", "unknown format \"yaml\""},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		err := dump(&out, []byte(test.data), test.format)
+		if out.String() != test.out {
+			t.Errorf("error %s dump of %q: %q", test.format, test.data, out.String())
+		}
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("error %s dump of %q: %v", test.format, test.data, err)
+			}
+		} else if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("error %s dump of %q should fail with %q: %v", test.format, test.data, test.err, err)
+		}
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		format, in, out string
+	}{
+		{"text", "# comment\nSET k \"a b\"\n\nget 'k'\n", "1) \"SET\"\n2) \"k\"\n3) \"a b\"\n1) \"get\"\n2) \"k\"\n"},
+		{"json", `{"type":"status","value":"OK"}` + "\n" + `{"type":"bulk","value":null}` + "\n", "OK\n(nil)\n"},
+	}
+	for _, test := range tests {
+		var encoded, out bytes.Buffer
+		if err := encode(&encoded, []byte(test.in), test.format); err != nil {
+			t.Fatalf("error encoding %q: %v", test.in, err)
+		}
+		if err := dump(&out, encoded.Bytes(), "cli"); err != nil {
+			t.Fatalf("error dumping %q: %v", encoded.Bytes(), err)
+		}
+		if out.String() != test.out {
+			t.Errorf("error round trip of %q: %q", test.in, out.String())
+		}
+	}
+
+	for _, in := range []string{"SET \"k\n", `{"type":"integer"}` + "\n"} {
+		format := "text"
+		if strings.HasPrefix(in, "{") {
+			format = "json"
+		}
+		var encoded bytes.Buffer
+		if err := encode(&encoded, []byte(in), format); err == nil || !strings.HasPrefix(err.Error(), "line 1: ") {
+			t.Errorf("error encoding %q should fail: %v", in, err)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Command resp-monitor reports the commands, keys and clients used the most
+// on a redis server, either by running MONITOR on the server until it is
+// interrupted, or by reading the output of `redis-cli monitor` saved in a
+// file:
+//
+//	resp-monitor -addr 127.0.0.1:6379 -duration 30s
+//	redis-cli monitor > monitor.log
+//	resp-monitor -f monitor.log -top 20
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/amyangfei/resp-go/monitor"
+	"github.com/amyangfei/resp-go/resp"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:6379", "address of the redis server")
+	file := flag.String("f", "", "read the output of MONITOR from a file instead, - for stdin")
+	duration := flag.Duration("duration", 0, "stop monitoring after this duration")
+	count := flag.Int("count", 0, "stop after this number of commands")
+	n := flag.Int("top", 10, "number of commands, keys and clients reported")
+	flag.Parse()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if *duration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		cancel()
+	}()
+
+	stats := monitor.NewStats()
+	add := func(e *monitor.Event) error {
+		stats.Add(e)
+		if *count > 0 && stats.Events >= *count {
+			return io.EOF
+		}
+		return nil
+	}
+	var err error
+	if *file != "" {
+		err = readFile(ctx, *file, add)
+	} else {
+		err = monitorServer(ctx, *addr, add)
+	}
+	if err == io.EOF || ctx.Err() != nil {
+		err = nil
+	}
+	report(os.Stdout, stats, *n)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func monitorServer(ctx context.Context, addr string, fn func(*monitor.Event) error) error {
+	c, err := resp.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return monitor.Monitor(ctx, c, fn)
+}
+
+// readFile reads the events of a file, skipping the lines which are not
+// events such as the OK reply to MONITOR.
+func readFile(ctx context.Context, name string, fn func(*monitor.Event) error) error {
+	f := os.Stdin
+	if name != "-" {
+		var err error
+		if f, err = os.Open(name); err != nil {
+			return err
+		}
+		defer f.Close()
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 512<<20)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		e, err := monitor.Parse(scanner.Text())
+		if err != nil {
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func report(w io.Writer, s *monitor.Stats, n int) {
+	d := s.Duration()
+	fmt.Fprintf(w, "%d commands in %s", s.Events, d-d%time.Millisecond)
+	if d > 0 {
+		fmt.Fprintf(w, " (%.1f/s)", float64(s.Events)/d.Seconds())
+	}
+	fmt.Fprintln(w)
+	section(w, "Top commands", s.TopCommands(n), s.Events)
+	section(w, "Top keys", s.TopKeys(n), s.Events)
+	section(w, "Top clients", s.TopClients(n), s.Events)
+}
+
+func section(w io.Writer, title string, counts []monitor.Count, total int) {
+	if len(counts) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s:\n", title)
+	width := 0
+	names := make([]string, len(counts))
+	for i, c := range counts {
+		names[i] = c.Name
+		if !printable(c.Name) {
+			names[i] = strconv.Quote(c.Name)
+		}
+		if len(names[i]) > width {
+			width = len(names[i])
+		}
+	}
+	for i, c := range counts {
+		fmt.Fprintf(w, "  %-*s %10d %6.2f%%\n", width, names[i], c.Count, 100*float64(c.Count)/float64(total))
+	}
+}
+
+func printable(s string) bool {
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f || r == utf8.RuneError {
+			return false
+		}
+	}
+	return true
+}
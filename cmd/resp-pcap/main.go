@@ -0,0 +1,150 @@
+// Command resp-pcap reads pcap or pcapng captures of redis traffic, such as
+// written by tcpdump, and prints a line for each command with its latency
+// and a summary of its reply:
+//
+//	tcpdump -i eth0 -w redis.pcap port 6379
+//	resp-pcap -ports 6379 -slow 10ms redis.pcap
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/amyangfei/resp-go/pcap"
+	"github.com/amyangfei/resp-go/resp"
+)
+
+const maxArgLen = 64
+
+func main() {
+	ports := flag.String("ports", "6379", "comma separated list of the redis server ports")
+	slow := flag.Duration("slow", 0, "only print the commands slower than this duration")
+	stats := flag.Bool("stats", false, "print the statistics of each capture")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [flags] capture...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var portList []int
+	for _, p := range strings.Split(*ports, ",") {
+		port, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || port <= 0 || port > 65535 {
+			fmt.Fprintf(os.Stderr, "invalid port %q\n", p)
+			os.Exit(2)
+		}
+		portList = append(portList, port)
+	}
+
+	status := 0
+	for _, name := range flag.Args() {
+		if err := process(name, portList, *slow, *stats); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
+
+func process(name string, ports []int, slow time.Duration, stats bool) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r, err := pcap.NewReader(f)
+	if err != nil {
+		return err
+	}
+	a := pcap.NewAssembler(ports, func(cmd *pcap.Command) {
+		if cmd.Reply != nil && cmd.Latency() < slow {
+			return
+		}
+		fmt.Println(line(cmd))
+	})
+	for {
+		p, err := r.Next()
+		if err != nil {
+			a.Flush()
+			if stats {
+				printStats(name, a.Stats())
+			}
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		a.Add(p)
+	}
+}
+
+// line formats a command as a line of the latency log.
+func line(cmd *pcap.Command) string {
+	latency, reply := "-", "(no reply)"
+	if cmd.Reply != nil {
+		latency = fmt.Sprintf("%.3fms", float64(cmd.Latency())/float64(time.Millisecond))
+		reply = summary(cmd.Reply)
+	}
+	return fmt.Sprintf("%s %s > %s %s %s => %s",
+		cmd.Start.Format("2006-01-02T15:04:05.000000"), cmd.Client, cmd.Server,
+		latency, commandLine(cmd.Request), reply)
+}
+
+// commandLine returns the arguments of a request separated by spaces, quoted
+// when needed and shortened when too long.
+func commandLine(req *resp.Message) string {
+	args, err := req.Strings()
+	if err != nil {
+		return summary(req)
+	}
+	for i, arg := range args {
+		if len(arg) > maxArgLen {
+			arg = arg[:maxArgLen] + "..."
+		}
+		if arg == "" || strings.ContainsAny(arg, " \"'\\") || !printable(arg) {
+			arg = strconv.Quote(arg)
+		}
+		args[i] = arg
+	}
+	return strings.Join(args, " ")
+}
+
+// summary returns a single line describing a reply.
+func summary(m *resp.Message) string {
+	if m.Type == resp.ArrayHeader && !m.IsNil && len(m.Array) > 0 {
+		return fmt.Sprintf("(array of %d)", len(m.Array))
+	}
+	s := m.String()
+	if len(s) > maxArgLen {
+		s = s[:maxArgLen] + "..."
+	}
+	return s
+}
+
+func printable(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+func printStats(name string, s pcap.Stats) {
+	fmt.Fprintf(os.Stderr, "%s: %d packets, %d redis segments, %d connections, %d gaps, "+
+		"%d decode errors, %d commands without reply, %d replies without command\n",
+		name, s.Packets, s.Segments, s.Connections, s.Gaps, s.DecodeErrors, s.Unanswered, s.Unpaired)
+}
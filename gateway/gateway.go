@@ -0,0 +1,182 @@
+// Package gateway implements an HTTP gateway to a RESP server, in the
+// spirit of Webdis, for clients which can not open TCP connections.
+//
+// A command is given by the path of a GET request, each segment being an
+// argument, such as GET /SET/key/value, or by the body of a POST request to
+// /, a JSON array such as ["SET", "key", "value"]. The reply is returned in
+// its tagged JSON form, which keeps its type, or as chosen with the Accept
+// header or the format query parameter: application/x-resp for the RESP
+// encoding, text/plain for the redis-cli representation, or format=natural
+// for a plain JSON value.
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+var (
+	// ErrEmptyCommand is returned for a request without command
+	ErrEmptyCommand = errors.New("gateway: empty command")
+
+	// ErrInvalidPath is returned for a GET request whose path can not be
+	// unescaped, or a POST request to another path than /
+	ErrInvalidPath = errors.New("gateway: invalid path")
+
+	// ErrInvalidBody is returned when the body of a POST request is not a
+	// JSON array of strings and numbers
+	ErrInvalidBody = errors.New("gateway: invalid body")
+
+	// ErrBodyTooLarge is returned when the body of a POST request is larger
+	// than the MaxBodySize of the gateway
+	ErrBodyTooLarge = errors.New("gateway: body too large")
+
+	// ErrMethod is returned for requests with another method than GET and
+	// POST
+	ErrMethod = errors.New("gateway: method not allowed")
+
+	// ErrFormat is returned when the reply format requested is not supported
+	ErrFormat = errors.New("gateway: unsupported format")
+
+	// ErrUnsupported is returned for the commands which can not go through
+	// the gateway: those changing the state of the connection, which is
+	// shared by the requests, and those making the server push messages
+	ErrUnsupported = errors.New("gateway: command not supported")
+)
+
+const (
+	// DefaultTimeout bounds the time spent sending a command and reading
+	// its reply when the Timeout field of a Gateway is zero.
+	DefaultTimeout = 5 * time.Second
+	// DefaultMaxBodySize is the maximum size of the body of a POST request
+	// when the MaxBodySize field of a Gateway is zero.
+	DefaultMaxBodySize = 1 << 20
+)
+
+var unsupported = map[string]bool{
+	"SELECT": true, "AUTH": true, "HELLO": true, "RESET": true, "QUIT": true,
+	"MULTI": true, "EXEC": true, "DISCARD": true, "WATCH": true,
+	"UNWATCH": true, "CLIENT": true, "SUBSCRIBE": true, "PSUBSCRIBE": true,
+	"SSUBSCRIBE": true, "UNSUBSCRIBE": true, "PUNSUBSCRIBE": true,
+	"SUNSUBSCRIBE": true, "MONITOR": true, "SYNC": true, "PSYNC": true,
+}
+
+// Gateway is an http.Handler sending the commands of HTTP requests to a
+// RESP server.
+//
+// Error replies are returned with the 200 status, as any reply. Requests
+// which can not be turned into a command get a 400 status, commands
+// rejected by Check a 403 status and failures to reach the server a 502
+// status, the errors being formatted as error replies.
+type Gateway struct {
+	// Pool provides the connections to the server
+	Pool *resp.Pool
+	// Check returns an error for the commands which must not be sent, such
+	// as the Check method of a policy.Policy, if not nil. The error is
+	// returned as an error reply.
+	Check func(cmd *resp.Message) error
+	// Timeout bounds the time spent sending a command and reading its
+	// reply, DefaultTimeout when zero
+	Timeout time.Duration
+	// MaxBodySize is the maximum size of the body of a POST request,
+	// DefaultMaxBodySize when zero
+	MaxBodySize int64
+}
+
+// ServeHTTP implements http.Handler.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	format, err := replyFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotAcceptable)
+		return
+	}
+	maxBody := g.MaxBodySize
+	if maxBody <= 0 {
+		maxBody = DefaultMaxBodySize
+	}
+	cmd, err := parseCommand(r, maxBody)
+	switch err {
+	case nil:
+	case ErrMethod:
+		w.Header().Set("Allow", "GET, POST")
+		writeReply(w, format, http.StatusMethodNotAllowed, resp.Err(err))
+		return
+	case ErrBodyTooLarge:
+		writeReply(w, format, http.StatusRequestEntityTooLarge, resp.Err(err))
+		return
+	default:
+		writeReply(w, format, http.StatusBadRequest, resp.Err(err))
+		return
+	}
+	args, _ := cmd.Strings()
+	if unsupported[strings.ToUpper(args[0])] {
+		writeReply(w, format, http.StatusBadRequest, resp.Err(ErrUnsupported))
+		return
+	}
+	if g.Check != nil {
+		if err := g.Check(cmd); err != nil {
+			writeReply(w, format, http.StatusForbidden, resp.Err(err))
+			return
+		}
+	}
+
+	reply, err := g.do(r.Context(), cmd)
+	if err != nil {
+		writeReply(w, format, http.StatusBadGateway, resp.Err(errors.New("gateway: "+err.Error())))
+		return
+	}
+	writeReply(w, format, http.StatusOK, reply)
+}
+
+func (g *Gateway) do(ctx context.Context, cmd *resp.Message) (*resp.Message, error) {
+	timeout := g.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := g.Pool.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// broken connections are closed by the pool
+	defer g.Pool.Put(conn)
+	return conn.DoContext(ctx, cmd)
+}
+
+// writeReply writes the message in the given format.
+func writeReply(w http.ResponseWriter, format string, status int, m *resp.Message) {
+	var (
+		b           []byte
+		err         error
+		contentType = "application/json"
+	)
+	switch format {
+	case FormatRESP:
+		contentType = "application/x-resp"
+		b, err = resp.Marshal(m)
+	case FormatText:
+		contentType = "text/plain; charset=utf-8"
+		var buf bytes.Buffer
+		err = resp.Format(&buf, m, resp.FormatOptions{})
+		b = buf.Bytes()
+	case FormatNatural:
+		b, err = resp.MarshalNaturalJSON(m)
+	default:
+		b, err = json.Marshal(m)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	w.Write(b)
+}
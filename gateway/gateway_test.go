@@ -0,0 +1,135 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amyangfei/resp-go/resp"
+	"github.com/amyangfei/resp-go/resptest"
+)
+
+func newPool(addr string) *resp.Pool {
+	return &resp.Pool{
+		Dial: func(ctx context.Context) (*resp.Conn, error) {
+			return resp.DialContext(ctx, "tcp", addr)
+		},
+		MaxIdle: 2,
+	}
+}
+
+// request sends an HTTP request and returns the status, content type and
+// body of the response.
+func request(t *testing.T, method, url, accept, body string) (int, string, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.StatusCode, res.Header.Get("Content-Type"), string(b)
+}
+
+func TestGateway(t *testing.T) {
+	backend := resptest.NewServer()
+	defer backend.Close()
+	pool := newPool(backend.Addr())
+	defer pool.Close()
+	srv := httptest.NewServer(&Gateway{Pool: pool})
+	defer srv.Close()
+
+	tests := []struct {
+		method, path, accept, body string
+		contentType, want          string
+	}{
+		{"GET", "/SET/k/hello%20world", "", "", "application/json", `{"type":"status","value":"OK"}`},
+		{"GET", "/GET/k", "", "", "application/json", `{"type":"bulk","value":"hello world"}`},
+		{"GET", "/GET/k?format=resp", "", "", "application/x-resp", "$11\r\nhello world\r\n"},
+		{"GET", "/GET/k", "application/x-resp", "", "application/x-resp", "$11\r\nhello world\r\n"},
+		{"GET", "/GET/k", "text/html, text/plain;q=0.9", "", "text/plain; charset=utf-8", "\"hello world\"\n"},
+		{"GET", "/GET/missing", "", "", "application/json", `{"type":"bulk","value":null}`},
+		{"POST", "/", "", `["HSET", "h", "a/b", 1]`, "application/json", `{"type":"integer","value":1}`},
+		{"GET", "/HGETALL/h?format=natural", "", "", "application/json", `["a/b","1"]`},
+		{"GET", "/HGET/h/a%2Fb", "", "", "application/json", `{"type":"bulk","value":"1"}`},
+		{"GET", "/LPUSH/k/x", "", "", "application/json",
+			`{"type":"error","value":"WRONGTYPE Operation against a key holding the wrong kind of value"}`},
+	}
+	for _, test := range tests {
+		status, contentType, body := request(t, test.method, srv.URL+test.path, test.accept, test.body)
+		if status != http.StatusOK || contentType != test.contentType || body != test.want {
+			t.Errorf("error response to %s %s: %d %s %q", test.method, test.path, status, contentType, body)
+		}
+	}
+}
+
+func TestGatewayErrors(t *testing.T) {
+	backend := resptest.NewServer()
+	defer backend.Close()
+	pool := newPool(backend.Addr())
+	defer pool.Close()
+	srv := httptest.NewServer(&Gateway{
+		Pool: pool,
+		Check: func(cmd *resp.Message) error {
+			args, _ := cmd.Strings()
+			if strings.ToUpper(args[0]) == "FLUSHALL" {
+				return errors.New("NOPERM flushall is denied")
+			}
+			return nil
+		},
+		MaxBodySize: 32,
+	})
+	defer srv.Close()
+
+	tests := []struct {
+		method, path, accept, body string
+		status                     int
+		want                       string
+	}{
+		{"GET", "/", "", "", http.StatusBadRequest, `{"type":"error","value":"gateway: empty command"}`},
+		{"POST", "/", "", `[]`, http.StatusBadRequest, `{"type":"error","value":"gateway: empty command"}`},
+		{"POST", "/GET", "", `["GET", "k"]`, http.StatusBadRequest, `{"type":"error","value":"gateway: invalid path"}`},
+		{"POST", "/", "", `{"cmd": "GET"}`, http.StatusBadRequest, `{"type":"error","value":"gateway: invalid body"}`},
+		{"POST", "/", "", `["GET", ["k"]]`, http.StatusBadRequest, `{"type":"error","value":"gateway: invalid body"}`},
+		{"POST", "/", "", `["SET", "k", "` + strings.Repeat("x", 32) + `"]`, http.StatusRequestEntityTooLarge,
+			`{"type":"error","value":"gateway: body too large"}`},
+		{"PUT", "/SET/k/v", "", "", http.StatusMethodNotAllowed, `{"type":"error","value":"gateway: method not allowed"}`},
+		{"GET", "/select/1", "", "", http.StatusBadRequest, `{"type":"error","value":"gateway: command not supported"}`},
+		{"GET", "/FLUSHALL", "", "", http.StatusForbidden, `{"type":"error","value":"NOPERM flushall is denied"}`},
+		{"GET", "/FLUSHALL?format=resp", "", "", http.StatusForbidden, "-NOPERM flushall is denied\r\n"},
+		{"GET", "/GET/k", "image/png", "", http.StatusNotAcceptable, "gateway: unsupported format\n"},
+		{"GET", "/GET/k?format=xml", "", "", http.StatusNotAcceptable, "gateway: unsupported format\n"},
+	}
+	for _, test := range tests {
+		status, _, body := request(t, test.method, srv.URL+test.path, test.accept, test.body)
+		if status != test.status || body != test.want {
+			t.Errorf("error response to %s %s: %d %q", test.method, test.path, status, body)
+		}
+	}
+}
+
+func TestGatewayBackendDown(t *testing.T) {
+	pool := &resp.Pool{Dial: func(ctx context.Context) (*resp.Conn, error) {
+		return nil, errors.New("connection refused")
+	}}
+	srv := httptest.NewServer(&Gateway{Pool: pool})
+	defer srv.Close()
+
+	status, _, body := request(t, "GET", srv.URL+"/PING", "", "")
+	if status != http.StatusBadGateway || body != `{"type":"error","value":"gateway: connection refused"}` {
+		t.Errorf("error response: %d %q", status, body)
+	}
+}
@@ -0,0 +1,151 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// Reply formats, chosen with the format query parameter or the Accept
+// header.
+const (
+	// FormatJSON is the tagged JSON form of the reply, which keeps its type,
+	// served as application/json
+	FormatJSON = "json"
+	// FormatNatural is the natural JSON form of the reply, served as
+	// application/json
+	FormatNatural = "natural"
+	// FormatRESP is the RESP encoding of the reply, served as
+	// application/x-resp
+	FormatRESP = "resp"
+	// FormatText is the redis-cli representation of the reply, served as
+	// text/plain
+	FormatText = "text"
+)
+
+// contentTypes maps the media types accepted by the gateway to the reply
+// formats.
+var contentTypes = map[string]string{
+	"application/json":   FormatJSON,
+	"application/x-resp": FormatRESP,
+	"text/plain":         FormatText,
+	"text/*":             FormatText,
+	"application/*":      FormatJSON,
+	"*/*":                FormatJSON,
+}
+
+// parseCommand builds the command of a request: from the path for a GET
+// request, such as /SET/key/value, each segment being an argument, or from
+// the body of a POST request to /, a JSON array of strings and numbers.
+func parseCommand(r *http.Request, maxBody int64) (*resp.Message, error) {
+	var args []string
+	switch r.Method {
+	case http.MethodGet:
+		path := strings.TrimPrefix(r.URL.EscapedPath(), "/")
+		if path == "" {
+			return nil, ErrEmptyCommand
+		}
+		for _, segment := range strings.Split(path, "/") {
+			arg, err := url.PathUnescape(segment)
+			if err != nil {
+				return nil, ErrInvalidPath
+			}
+			args = append(args, arg)
+		}
+	case http.MethodPost:
+		if r.URL.Path != "/" {
+			return nil, ErrInvalidPath
+		}
+		var err error
+		if args, err = parseBody(io.LimitReader(r.Body, maxBody+1), maxBody); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrMethod
+	}
+	if args[0] == "" {
+		return nil, ErrEmptyCommand
+	}
+	cmd := resp.Cmd(args[0])
+	for _, arg := range args[1:] {
+		cmd.Arg(arg)
+	}
+	return cmd, nil
+}
+
+// parseBody decodes a JSON array of strings and numbers, numbers being kept
+// as written.
+func parseBody(r io.Reader, maxBody int64) ([]string, error) {
+	var body bytes.Buffer
+	if n, err := body.ReadFrom(r); err != nil {
+		return nil, err
+	} else if n > maxBody {
+		return nil, ErrBodyTooLarge
+	}
+	dec := json.NewDecoder(&body)
+	dec.UseNumber()
+	var values []interface{}
+	if err := dec.Decode(&values); err != nil {
+		return nil, ErrInvalidBody
+	}
+	if len(values) == 0 {
+		return nil, ErrEmptyCommand
+	}
+	args := make([]string, len(values))
+	for i, v := range values {
+		switch v := v.(type) {
+		case string:
+			args[i] = v
+		case json.Number:
+			args[i] = v.String()
+		default:
+			return nil, ErrInvalidBody
+		}
+	}
+	return args, nil
+}
+
+// replyFormat returns the format of the reply to a request: the format
+// query parameter if set, else the supported media type of the Accept
+// header with the highest quality, FormatJSON by default.
+func replyFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		switch format {
+		case FormatJSON, FormatNatural, FormatRESP, FormatText:
+			return format, nil
+		}
+		return "", ErrFormat
+	}
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return FormatJSON, nil
+	}
+	format, best := "", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		f, ok := contentTypes[mediaType]
+		if ok && q > best {
+			format, best = f, q
+		}
+	}
+	if format == "" {
+		return "", ErrFormat
+	}
+	return format, nil
+}
@@ -0,0 +1,73 @@
+package gateway
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		method, target, body string
+		args                 string
+		err                  error
+	}{
+		{"GET", "/GET/key", "", "GET key", nil},
+		{"GET", "/SET/a%2Fb/hello%20world", "", "SET|a/b|hello world", nil},
+		{"GET", "/ECHO/", "", "ECHO|", nil},
+		{"GET", "/", "", "", ErrEmptyCommand},
+		{"GET", "//x", "", "", ErrEmptyCommand},
+		{"POST", "/", `["INCRBY", "n", 10]`, "INCRBY n 10", nil},
+		{"POST", "/", `["SET", "k", 1.50]`, "SET k 1.50", nil},
+		{"POST", "/", `["SET", "k", null]`, "", ErrInvalidBody},
+		{"POST", "/", `not json`, "", ErrInvalidBody},
+		{"DELETE", "/DEL/k", "", "", ErrMethod},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+		cmd, err := parseCommand(r, 1024)
+		if err != test.err {
+			t.Errorf("error parsing %s %s: %v", test.method, test.target, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		args, _ := cmd.Strings()
+		sep := " "
+		if strings.Contains(test.args, "|") {
+			sep = "|"
+		}
+		if got := strings.Join(args, sep); got != test.args {
+			t.Errorf("error args of %s %s: %q", test.method, test.target, args)
+		}
+	}
+}
+
+func TestReplyFormat(t *testing.T) {
+	tests := []struct {
+		target, accept string
+		format         string
+		err            error
+	}{
+		{"/GET/k", "", FormatJSON, nil},
+		{"/GET/k", "*/*", FormatJSON, nil},
+		{"/GET/k", "application/x-resp", FormatRESP, nil},
+		{"/GET/k", "text/plain;q=0.5, application/json;q=0.8", FormatJSON, nil},
+		{"/GET/k", "text/html, text/*;q=0.9, */*;q=0.1", FormatText, nil},
+		{"/GET/k", "Application/X-RESP; q=1", FormatRESP, nil},
+		{"/GET/k", "image/png", "", ErrFormat},
+		{"/GET/k?format=natural", "application/x-resp", FormatNatural, nil},
+		{"/GET/k?format=yaml", "", "", ErrFormat},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", test.target, nil)
+		if test.accept != "" {
+			r.Header.Set("Accept", test.accept)
+		}
+		format, err := replyFormat(r)
+		if format != test.format || err != test.err {
+			t.Errorf("error format of %s with Accept %q: %s %v", test.target, test.accept, format, err)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// Package info parses the reply of the redis INFO command: a text made of
+// `# Section` headers followed by `field:value` lines, some values being
+// lists of `k=v` pairs, as for the keyspace and the command statistics.
+package info
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+var (
+	// ErrInvalidReply is returned when the reply to INFO is not a string
+	ErrInvalidReply = errors.New("info: invalid reply")
+
+	// ErrNoField is returned when a field is not part of the reply
+	ErrNoField = errors.New("info: no such field")
+)
+
+// Info is a parsed INFO reply.
+type Info struct {
+	// Sections holds the sections in the order of the reply. The fields
+	// which precede the first header, as sent by redis 2.4 which has no
+	// sections, are held by a section without name.
+	Sections []*Section
+}
+
+// Section is a section of an INFO reply.
+type Section struct {
+	Name string
+	// Fields holds the names of the fields in the order of the reply
+	Fields []string
+	values map[string]string
+}
+
+// Parse parses the reply to INFO. An error reply is returned as the error.
+func Parse(m *resp.Message) (*Info, error) {
+	if m == nil || m.IsNil {
+		return nil, ErrInvalidReply
+	}
+	switch m.Type {
+	case resp.BulkHeader, resp.VerbatimHeader:
+		return ParseString(string(m.Bytes)), nil
+	case resp.StringHeader:
+		return ParseString(m.Status), nil
+	case resp.ErrorHeader:
+		return nil, m.Error
+	}
+	return nil, ErrInvalidReply
+}
+
+// ParseString parses the text of an INFO reply. Lines which are neither
+// headers nor fields are ignored.
+func ParseString(s string) *Info {
+	info := &Info{}
+	var section *Section
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			section = &Section{
+				Name:   strings.TrimSpace(line[1:]),
+				values: make(map[string]string),
+			}
+			info.Sections = append(info.Sections, section)
+			continue
+		}
+		colon := strings.IndexByte(line, ':')
+		if colon <= 0 {
+			continue
+		}
+		if section == nil {
+			section = &Section{values: make(map[string]string)}
+			info.Sections = append(info.Sections, section)
+		}
+		name := line[:colon]
+		if _, ok := section.values[name]; !ok {
+			section.Fields = append(section.Fields, name)
+		}
+		section.values[name] = line[colon+1:]
+	}
+	return info
+}
+
+// Section returns the section with the given name, case insensitively, or
+// nil if the reply has none.
+func (i *Info) Section(name string) *Section {
+	for _, s := range i.Sections {
+		if strings.EqualFold(s.Name, name) {
+			return s
+		}
+	}
+	return nil
+}
+
+// Get returns the value of a field, looked up in every section.
+func (i *Info) Get(field string) (string, bool) {
+	for _, s := range i.Sections {
+		if v, ok := s.Get(field); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// Int returns the value of a field as an integer.
+func (i *Info) Int(field string) (int64, error) {
+	v, ok := i.Get(field)
+	if !ok {
+		return 0, ErrNoField
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
+
+// Float returns the value of a field as a float.
+func (i *Info) Float(field string) (float64, error) {
+	v, ok := i.Get(field)
+	if !ok {
+		return 0, ErrNoField
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+// Map returns the `k=v` pairs of the value of a field.
+func (i *Info) Map(field string) (map[string]string, error) {
+	v, ok := i.Get(field)
+	if !ok {
+		return nil, ErrNoField
+	}
+	return ParseMap(v), nil
+}
+
+// Version returns the redis_version field.
+func (i *Info) Version() string {
+	v, _ := i.Get("redis_version")
+	return v
+}
+
+// Get returns the value of a field of the section.
+func (s *Section) Get(field string) (string, bool) {
+	v, ok := s.values[field]
+	return v, ok
+}
+
+// Int returns the value of a field of the section as an integer.
+func (s *Section) Int(field string) (int64, error) {
+	v, ok := s.values[field]
+	if !ok {
+		return 0, ErrNoField
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
+
+// Float returns the value of a field of the section as a float.
+func (s *Section) Float(field string) (float64, error) {
+	v, ok := s.values[field]
+	if !ok {
+		return 0, ErrNoField
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+// Map returns the `k=v` pairs of the value of a field of the section.
+func (s *Section) Map(field string) (map[string]string, error) {
+	v, ok := s.values[field]
+	if !ok {
+		return nil, ErrNoField
+	}
+	return ParseMap(v), nil
+}
+
+// ParseMap parses a value made of comma separated `k=v` pairs, such as
+// `keys=1,expires=0,avg_ttl=0`. Elements without "=" are given an empty
+// value.
+func ParseMap(v string) map[string]string {
+	m := make(map[string]string)
+	for _, pair := range strings.Split(v, ",") {
+		if pair == "" {
+			continue
+		}
+		if eq := strings.IndexByte(pair, '='); eq >= 0 {
+			m[pair[:eq]] = pair[eq+1:]
+		} else {
+			m[pair] = ""
+		}
+	}
+	return m
+}
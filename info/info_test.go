@@ -0,0 +1,130 @@
+package info
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+func loadFixture(t *testing.T, name string) *Info {
+	data, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := Parse(resp.Bulk(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestParseFixtures(t *testing.T) {
+	cases := []struct {
+		file, version string
+		sections      int
+		clients       int64
+		fragmentation float64
+	}{
+		{"info-2.6.txt", "2.6.17", 9, 3, 2.17},
+		{"info-4.0.txt", "4.0.14", 10, 12, 2.38},
+		{"info-7.2.txt", "7.2.4", 13, 57, 1.20},
+	}
+	for _, c := range cases {
+		info := loadFixture(t, c.file)
+		if info.Version() != c.version {
+			t.Errorf("error version of %s: %s", c.file, info.Version())
+		}
+		if len(info.Sections) != c.sections {
+			t.Errorf("error sections of %s: %d", c.file, len(info.Sections))
+		}
+		if n, err := info.Int("connected_clients"); err != nil || n != c.clients {
+			t.Errorf("error clients of %s: %d %v", c.file, n, err)
+		}
+		if f, err := info.Section("memory").Float("mem_fragmentation_ratio"); err != nil || f != c.fragmentation {
+			t.Errorf("error fragmentation of %s: %v %v", c.file, f, err)
+		}
+		if s := info.Sections[0]; s.Name != "Server" || s.Fields[0] != "redis_version" {
+			t.Errorf("error first section of %s: %s %v", c.file, s.Name, s.Fields)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	info := loadFixture(t, "info-7.2.txt")
+	if v, ok := info.Get("config_file"); !ok || v != "" {
+		t.Errorf("error empty value: %q %v", v, ok)
+	}
+	if v, _ := info.Get("executable"); v != "/data/redis-server" {
+		t.Errorf("error value: %q", v)
+	}
+	if _, err := info.Int("used_memory_human"); err == nil {
+		t.Error("error expected converting a size")
+	}
+	if _, err := info.Int("missing"); err != ErrNoField {
+		t.Errorf("error missing field error: %v", err)
+	}
+	m, err := info.Map("listener0")
+	if err != nil || m["name"] != "tcp" || m["port"] != "6379" {
+		t.Errorf("error map: %v %v", m, err)
+	}
+	if info.Section("modules") == nil || len(info.Section("modules").Fields) != 0 {
+		t.Error("error empty section")
+	}
+	if info.Section("nope") != nil {
+		t.Error("error missing section")
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	if _, err := Parse(resp.Nil()); err != ErrInvalidReply {
+		t.Errorf("error nil reply error: %v", err)
+	}
+	if _, err := Parse(resp.Int(1)); err != ErrInvalidReply {
+		t.Errorf("error integer reply error: %v", err)
+	}
+	if _, err := Parse(resp.Err(errors.New("NOAUTH Authentication required."))); err == nil ||
+		err.Error() != "NOAUTH Authentication required." {
+		t.Errorf("error error reply error: %v", err)
+	}
+}
+
+func TestParseWithoutSections(t *testing.T) {
+	// redis 2.4 sends no section headers
+	info := ParseString("redis_version:2.4.17\r\nrole:master\r\ninvalid line\r\ndb0:keys=1,expires=0\r\n")
+	if len(info.Sections) != 1 || info.Sections[0].Name != "" || info.Version() != "2.4.17" {
+		t.Errorf("error sections: %+v", info.Sections)
+	}
+	ks, err := info.Keyspace()
+	if err != nil || len(ks) != 1 || ks[0].Keys != 1 {
+		t.Errorf("error keyspace: %v %v", ks, err)
+	}
+}
+
+func TestParseMap(t *testing.T) {
+	m := ParseMap("a=1,b=,c,d=x=y")
+	if len(m) != 4 || m["a"] != "1" || m["b"] != "" || m["c"] != "" || m["d"] != "x=y" {
+		t.Errorf("error map: %v", m)
+	}
+}
+
+func TestParseVerbatim(t *testing.T) {
+	info, err := Parse(verbatim(t, "# Server\r\nredis_version:7.2.4\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Version() != "7.2.4" || info.Section("Server") == nil {
+		t.Errorf("error info from a RESP3 reply: %+v", info)
+	}
+}
+
+// verbatim returns the RESP3 verbatim string of text, as decoded.
+func verbatim(t *testing.T, text string) *resp.Message {
+	msgQ, _, err := resp.Decode([]byte(fmt.Sprintf("=%d\r\ntxt:%s\r\n", len(text)+4, text)))
+	if err != nil || len(msgQ) != 1 || msgQ[0].Type != resp.VerbatimHeader {
+		t.Fatalf("error decoding a verbatim string: %v %v", msgQ, err)
+	}
+	return msgQ[0]
+}
@@ -0,0 +1,189 @@
+package info
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Keyspace is the number of keys of a database, from the keyspace section.
+type Keyspace struct {
+	DB      int
+	Keys    int64
+	Expires int64
+	// AvgTTL is the average time to live of the keys with an expire, in
+	// milliseconds
+	AvgTTL int64
+}
+
+// CommandStat is the usage of a command, from the commandstats section.
+type CommandStat struct {
+	Calls int64
+	// Usec is the total time spent running the command, in microseconds
+	Usec        int64
+	UsecPerCall float64
+	// RejectedCalls and FailedCalls are reported since redis 6.2
+	RejectedCalls int64
+	FailedCalls   int64
+}
+
+// Replica is a replica connected to a master, from the replication section.
+type Replica struct {
+	// ID is the index of the replica, N in the slaveN field
+	ID    int
+	IP    string
+	Port  int
+	State string
+	// Offset is the replication offset acknowledged by the replica and Lag
+	// the seconds since its last acknowledgment, both reported since redis
+	// 2.8
+	Offset int64
+	Lag    int64
+}
+
+// fields calls fn for the fields of every section whose name starts with
+// prefix, with the rest of the name.
+func (i *Info) fields(prefix string, fn func(name, value string) error) error {
+	for _, s := range i.Sections {
+		for _, field := range s.Fields {
+			if strings.HasPrefix(field, prefix) {
+				if err := fn(field[len(prefix):], s.values[field]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// intValues parses the integer values of a map, missing keys being left
+// unchanged.
+func intValues(m map[string]string, targets map[string]*int64) error {
+	for k, target := range targets {
+		v, ok := m[k]
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return err
+		}
+		*target = n
+	}
+	return nil
+}
+
+type byDB []Keyspace
+
+func (s byDB) Len() int           { return len(s) }
+func (s byDB) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byDB) Less(i, j int) bool { return s[i].DB < s[j].DB }
+
+// Keyspace returns the databases holding keys, ordered by number.
+func (i *Info) Keyspace() ([]Keyspace, error) {
+	var dbs []Keyspace
+	err := i.fields("db", func(name, value string) error {
+		db, err := strconv.Atoi(name)
+		if err != nil {
+			// another field starting with db
+			return nil
+		}
+		ks := Keyspace{DB: db}
+		err = intValues(ParseMap(value), map[string]*int64{
+			"keys":    &ks.Keys,
+			"expires": &ks.Expires,
+			"avg_ttl": &ks.AvgTTL,
+		})
+		dbs = append(dbs, ks)
+		return err
+	})
+	sort.Sort(byDB(dbs))
+	return dbs, err
+}
+
+// CommandStats returns the usage of the commands, by lower case name.
+// Subcommands are named after their command, such as "config|get".
+func (i *Info) CommandStats() (map[string]CommandStat, error) {
+	stats := make(map[string]CommandStat)
+	err := i.fields("cmdstat_", func(name, value string) error {
+		var stat CommandStat
+		m := ParseMap(value)
+		err := intValues(m, map[string]*int64{
+			"calls":          &stat.Calls,
+			"usec":           &stat.Usec,
+			"rejected_calls": &stat.RejectedCalls,
+			"failed_calls":   &stat.FailedCalls,
+		})
+		if err != nil {
+			return err
+		}
+		if v, ok := m["usec_per_call"]; ok {
+			if stat.UsecPerCall, err = strconv.ParseFloat(v, 64); err != nil {
+				return err
+			}
+		}
+		stats[strings.ToLower(name)] = stat
+		return nil
+	})
+	return stats, err
+}
+
+// ErrorStats returns the number of error replies by error prefix, such as
+// "ERR" or "WRONGTYPE", as reported since redis 6.2.
+func (i *Info) ErrorStats() (map[string]int64, error) {
+	stats := make(map[string]int64)
+	err := i.fields("errorstat_", func(name, value string) error {
+		var count int64
+		if err := intValues(ParseMap(value), map[string]*int64{"count": &count}); err != nil {
+			return err
+		}
+		stats[name] = count
+		return nil
+	})
+	return stats, err
+}
+
+type byID []Replica
+
+func (s byID) Len() int           { return len(s) }
+func (s byID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byID) Less(i, j int) bool { return s[i].ID < s[j].ID }
+
+// Replicas returns the replicas connected to a master, ordered by index.
+// Both the `ip=...,port=...` format and the `ip,port,state` format of redis
+// 2.6 are parsed.
+func (i *Info) Replicas() ([]Replica, error) {
+	var replicas []Replica
+	err := i.fields("slave", func(name, value string) error {
+		id, err := strconv.Atoi(name)
+		if err != nil {
+			// slave_repl_offset and such
+			return nil
+		}
+		r := Replica{ID: id}
+		if !strings.Contains(value, "=") {
+			parts := strings.Split(value, ",")
+			if len(parts) < 3 {
+				return ErrInvalidReply
+			}
+			r.IP, r.State = parts[0], parts[2]
+			if r.Port, err = strconv.Atoi(parts[1]); err != nil {
+				return err
+			}
+			replicas = append(replicas, r)
+			return nil
+		}
+		m := ParseMap(value)
+		r.IP, r.State = m["ip"], m["state"]
+		if r.Port, err = strconv.Atoi(m["port"]); err != nil {
+			return err
+		}
+		if err := intValues(m, map[string]*int64{"offset": &r.Offset, "lag": &r.Lag}); err != nil {
+			return err
+		}
+		replicas = append(replicas, r)
+		return nil
+	})
+	sort.Sort(byID(replicas))
+	return replicas, err
+}
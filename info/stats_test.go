@@ -0,0 +1,79 @@
+package info
+
+import (
+	"testing"
+)
+
+func TestKeyspace(t *testing.T) {
+	ks, err := loadFixture(t, "info-2.6.txt").Keyspace()
+	if err != nil || len(ks) != 2 {
+		t.Fatalf("error keyspace: %v %v", ks, err)
+	}
+	if ks[0] != (Keyspace{DB: 0, Keys: 150, Expires: 10}) || ks[1] != (Keyspace{DB: 2, Keys: 3}) {
+		t.Errorf("error keyspace: %v", ks)
+	}
+
+	ks, err = loadFixture(t, "info-7.2.txt").Keyspace()
+	if err != nil || len(ks) != 2 || ks[0] != (Keyspace{DB: 0, Keys: 1000000, Expires: 250000, AvgTTL: 3600000}) {
+		t.Errorf("error keyspace: %v %v", ks, err)
+	}
+
+	if _, err := ParseString("db0:keys=x").Keyspace(); err == nil {
+		t.Error("error expected")
+	}
+}
+
+func TestCommandStats(t *testing.T) {
+	stats, err := loadFixture(t, "info-4.0.txt").CommandStats()
+	if err != nil || len(stats) != 4 {
+		t.Fatalf("error command stats: %v %v", stats, err)
+	}
+	if stats["get"] != (CommandStat{Calls: 60000, Usec: 90000, UsecPerCall: 1.5}) {
+		t.Errorf("error get stats: %+v", stats["get"])
+	}
+
+	stats, err = loadFixture(t, "info-7.2.txt").CommandStats()
+	if err != nil || len(stats) != 4 {
+		t.Fatalf("error command stats: %v %v", stats, err)
+	}
+	if s := stats["set"]; s.RejectedCalls != 12 || s.FailedCalls != 3 || s.UsecPerCall != 3 {
+		t.Errorf("error set stats: %+v", s)
+	}
+	if s := stats["config|get"]; s.Calls != 20 {
+		t.Errorf("error subcommand stats: %+v", s)
+	}
+}
+
+func TestErrorStats(t *testing.T) {
+	stats, err := loadFixture(t, "info-7.2.txt").ErrorStats()
+	if err != nil || len(stats) != 2 || stats["ERR"] != 1500 || stats["WRONGTYPE"] != 30 {
+		t.Errorf("error error stats: %v %v", stats, err)
+	}
+	stats, err = loadFixture(t, "info-4.0.txt").ErrorStats()
+	if err != nil || len(stats) != 0 {
+		t.Errorf("error error stats: %v %v", stats, err)
+	}
+}
+
+func TestReplicas(t *testing.T) {
+	replicas, err := loadFixture(t, "info-2.6.txt").Replicas()
+	if err != nil || len(replicas) != 2 {
+		t.Fatalf("error replicas: %v %v", replicas, err)
+	}
+	if replicas[1] != (Replica{ID: 1, IP: "10.0.0.3", Port: 6380, State: "wait_bgsave"}) {
+		t.Errorf("error replica: %+v", replicas[1])
+	}
+
+	replicas, err = loadFixture(t, "info-7.2.txt").Replicas()
+	if err != nil || len(replicas) != 2 {
+		t.Fatalf("error replicas: %v %v", replicas, err)
+	}
+	expected := Replica{ID: 1, IP: "10.1.0.12", Port: 6379, State: "online", Offset: 987654300, Lag: 1}
+	if replicas[1] != expected {
+		t.Errorf("error replica: %+v", replicas[1])
+	}
+
+	if _, err := ParseString("slave0:ip=a,port=x").Replicas(); err == nil {
+		t.Error("error expected")
+	}
+}
@@ -0,0 +1,11 @@
+package monitor
+
+import (
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// Keys returns the keys used by the command of the event. The first argument
+// is taken as the key of the commands which are not known.
+func (e *Event) Keys() []string {
+	return resp.CommandKeys(e.Args)
+}
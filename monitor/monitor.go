@@ -0,0 +1,195 @@
+// Package monitor parses the output of the redis MONITOR command into
+// events and aggregates them into reports of the most used commands, keys
+// and clients.
+//
+// MONITOR streams a status line for each command run by the server:
+//
+//	+1339518083.107412 [0 127.0.0.1:60866] "keys" "*"
+//
+// redis-cli prints the same lines without the leading "+".
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+var (
+	// ErrInvalidLine is returned when a line is not an event of MONITOR
+	ErrInvalidLine = errors.New("monitor: invalid line")
+
+	// ErrUnexpectedReply is returned when the server replies to MONITOR
+	// with another message than a status
+	ErrUnexpectedReply = errors.New("monitor: unexpected reply")
+)
+
+// Source is the kind of client which ran a command.
+type Source int
+
+const (
+	// SourceTCP is a client connected over TCP
+	SourceTCP Source = iota
+	// SourceUnix is a client connected to a unix socket
+	SourceUnix
+	// SourceLua is a script, run by EVAL or a function
+	SourceLua
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceUnix:
+		return "unix"
+	case SourceLua:
+		return "lua"
+	}
+	return "tcp"
+}
+
+// Event is a command run by the server.
+type Event struct {
+	Time time.Time
+	// DB is the database selected by the client, -1 if the server did not
+	// report it, as before redis 2.6
+	DB     int
+	Source Source
+	// Client is the address of the client for TCP, the path of the socket
+	// for unix sockets and "lua" for scripts
+	Client string
+	Args   []string
+}
+
+// Command returns the name of the command, in upper case.
+func (e *Event) Command() string {
+	if len(e.Args) == 0 {
+		return ""
+	}
+	return strings.ToUpper(e.Args[0])
+}
+
+// Host returns the address of the client without its port, or Client for
+// clients which are not connected over TCP.
+func (e *Event) Host() string {
+	if e.Source != SourceTCP {
+		return e.Client
+	}
+	host, _, err := net.SplitHostPort(e.Client)
+	if err != nil {
+		return e.Client
+	}
+	return host
+}
+
+// Parse parses a line printed by MONITOR, with or without the leading "+"
+// of the status message.
+func Parse(line string) (*Event, error) {
+	line = strings.TrimPrefix(strings.TrimRight(line, "\r\n"), "+")
+	sp := strings.IndexByte(line, ' ')
+	if sp < 0 {
+		return nil, ErrInvalidLine
+	}
+	t, err := parseTime(line[:sp])
+	if err != nil {
+		return nil, err
+	}
+	e := &Event{Time: t, DB: -1}
+	rest := line[sp+1:]
+
+	if strings.HasPrefix(rest, "[") {
+		// the first "] " ends the client, IPv6 addresses being written as
+		// [::1]:6379
+		end := strings.Index(rest, "] ")
+		if end < 0 {
+			if !strings.HasSuffix(rest, "]") {
+				return nil, ErrInvalidLine
+			}
+			end = len(rest) - 1
+		}
+		fields := strings.SplitN(rest[1:end], " ", 2)
+		if len(fields) != 2 {
+			return nil, ErrInvalidLine
+		}
+		if e.DB, err = strconv.Atoi(fields[0]); err != nil {
+			return nil, ErrInvalidLine
+		}
+		e.Client = fields[1]
+		switch {
+		case e.Client == "lua":
+			e.Source = SourceLua
+		case strings.HasPrefix(e.Client, "unix:"):
+			e.Source = SourceUnix
+			e.Client = e.Client[len("unix:"):]
+		}
+		rest = rest[end+1:]
+	}
+
+	if e.Args, err = resp.SplitArgs(rest); err != nil {
+		return nil, ErrInvalidLine
+	}
+	if len(e.Args) == 0 {
+		return nil, ErrInvalidLine
+	}
+	return e, nil
+}
+
+// parseTime parses the time of an event, in seconds with a fractional part.
+func parseTime(s string) (time.Time, error) {
+	sec, frac := s, ""
+	if dot := strings.IndexByte(s, '.'); dot >= 0 {
+		sec, frac = s[:dot], s[dot+1:]
+	}
+	secs, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil || len(frac) > 9 {
+		return time.Time{}, ErrInvalidLine
+	}
+	var nsec int64
+	if frac != "" {
+		if nsec, err = strconv.ParseInt(frac, 10, 64); err != nil || nsec < 0 {
+			return time.Time{}, ErrInvalidLine
+		}
+		for i := len(frac); i < 9; i++ {
+			nsec *= 10
+		}
+	}
+	return time.Unix(secs, nsec), nil
+}
+
+// ParseMessage parses an event streamed by MONITOR.
+func ParseMessage(m *resp.Message) (*Event, error) {
+	if m == nil || m.Type != resp.StringHeader {
+		return nil, ErrInvalidLine
+	}
+	return Parse(m.Status)
+}
+
+// Monitor sends MONITOR on c and calls fn for each event streamed by the
+// server, until ctx is done, the connection fails or fn returns an error,
+// which is returned. The connection can not be used for other commands
+// afterwards.
+func Monitor(ctx context.Context, c *resp.Conn, fn func(*Event) error) error {
+	reply, err := c.DoContext(ctx, resp.Cmd("MONITOR"))
+	if err != nil {
+		return err
+	}
+	if reply.Type == resp.ErrorHeader {
+		return reply.Error
+	}
+	for {
+		m, err := c.ReceiveContext(ctx)
+		if err != nil {
+			return err
+		}
+		e, err := ParseMessage(m)
+		if err != nil {
+			return ErrUnexpectedReply
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		line   string
+		db     int
+		source Source
+		client string
+		args   []string
+	}{
+		{`+1339518083.107412 [0 127.0.0.1:60866] "keys" "*"`,
+			0, SourceTCP, "127.0.0.1:60866", []string{"keys", "*"}},
+		{`1339518083.107412 [3 lua] "set" "a b" "\x00\r\n\"x\""`,
+			3, SourceLua, "lua", []string{"set", "a b", "\x00\r\n\"x\""}},
+		{`1339518083.107412 [0 unix:/tmp/redis.sock] "ping"`,
+			0, SourceUnix, "/tmp/redis.sock", []string{"ping"}},
+		{`1339518083.107412 [15 [::1]:52000] "get" "k"`,
+			15, SourceTCP, "[::1]:52000", []string{"get", "k"}},
+		{`1339518083.107412 "get" "k"`,
+			-1, SourceTCP, "", []string{"get", "k"}},
+	}
+	for _, c := range cases {
+		e, err := Parse(c.line)
+		if err != nil {
+			t.Errorf("error parsing %q: %v", c.line, err)
+			continue
+		}
+		if !e.Time.Equal(time.Unix(1339518083, 107412000)) {
+			t.Errorf("error time of %q: %v", c.line, e.Time)
+		}
+		if e.DB != c.db || e.Source != c.source || e.Client != c.client {
+			t.Errorf("error client of %q: %+v", c.line, e)
+		}
+		if len(e.Args) != len(c.args) {
+			t.Errorf("error args of %q: %q", c.line, e.Args)
+			continue
+		}
+		for i := range e.Args {
+			if e.Args[i] != c.args[i] {
+				t.Errorf("error arg %d of %q: %q", i, c.line, e.Args[i])
+			}
+		}
+	}
+
+	for _, line := range []string{
+		"OK",
+		`abc [0 lua] "get"`,
+		`1339518083.107412 [x lua] "get"`,
+		`1339518083.107412 [0 lua]`,
+		`1339518083.107412 [0 lua] "get`,
+		`1339518083.107412 [0lua] "get"`,
+	} {
+		if _, err := Parse(line); err != ErrInvalidLine {
+			t.Errorf("error parsing %q: %v", line, err)
+		}
+	}
+}
+
+func TestEvent(t *testing.T) {
+	e := &Event{Client: "10.0.0.1:52000", Args: []string{"get", "k"}}
+	if e.Command() != "GET" || e.Host() != "10.0.0.1" {
+		t.Errorf("error event: %s %s", e.Command(), e.Host())
+	}
+	e = &Event{Source: SourceUnix, Client: "/tmp/redis.sock"}
+	if e.Command() != "" || e.Host() != "/tmp/redis.sock" {
+		t.Errorf("error event: %s %s", e.Command(), e.Host())
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	e, err := ParseMessage(resp.Str(`1339518083.107412 [0 lua] "get" "k"`))
+	if err != nil || e.Command() != "GET" {
+		t.Errorf("error parsing status: %v", err)
+	}
+	if _, err := ParseMessage(resp.Bulk([]byte(`1339518083.107412 [0 lua] "get"`))); err != ErrInvalidLine {
+		t.Errorf("error parsing bulk: %v", err)
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	client, server := net.Pipe()
+	s := &resp.Server{Handler: resp.HandlerFunc(func(c *resp.ServerConn, cmd *resp.Message) {
+		c.WriteMessage(resp.Str("OK"))
+		c.WriteMessage(resp.Str(`1339518083.107412 [0 127.0.0.1:60866] "get" "a"`))
+		c.WriteMessage(resp.Str(`1339518083.107413 [0 127.0.0.1:60866] "get" "b"`))
+		c.WriteMessage(resp.Int(1))
+	})}
+	go s.ServeConn(server)
+	c := resp.NewConn(client)
+	defer c.Close()
+
+	var keys []string
+	stop := errors.New("stop")
+	err := Monitor(context.Background(), c, func(e *Event) error {
+		keys = append(keys, e.Keys()...)
+		if len(keys) == 1 {
+			return nil
+		}
+		return stop
+	})
+	if err != stop || len(keys) != 2 || keys[1] != "b" {
+		t.Errorf("error monitoring: %v %q", err, keys)
+	}
+
+	keys = nil
+	client, server = net.Pipe()
+	go s.ServeConn(server)
+	c = resp.NewConn(client)
+	defer c.Close()
+	err = Monitor(context.Background(), c, func(e *Event) error {
+		keys = append(keys, e.Keys()...)
+		return nil
+	})
+	if err != ErrUnexpectedReply || len(keys) != 2 {
+		t.Errorf("error monitoring: %v %q", err, keys)
+	}
+}
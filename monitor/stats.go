@@ -0,0 +1,93 @@
+package monitor
+
+import (
+	"sort"
+	"time"
+)
+
+// Count is a name with the number of events it appeared in.
+type Count struct {
+	Name  string
+	Count int
+}
+
+type byCount []Count
+
+func (s byCount) Len() int      { return len(s) }
+func (s byCount) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byCount) Less(i, j int) bool {
+	if s[i].Count != s[j].Count {
+		return s[i].Count > s[j].Count
+	}
+	return s[i].Name < s[j].Name
+}
+
+// Stats aggregates events by command, key and client.
+type Stats struct {
+	// Events is the number of events added
+	Events int
+	// First and Last are the times of the first and last events
+	First, Last time.Time
+
+	commands map[string]int
+	keys     map[string]int
+	clients  map[string]int
+}
+
+// NewStats returns empty Stats.
+func NewStats() *Stats {
+	return &Stats{
+		commands: make(map[string]int),
+		keys:     make(map[string]int),
+		clients:  make(map[string]int),
+	}
+}
+
+// Add counts an event.
+func (s *Stats) Add(e *Event) {
+	if s.Events == 0 || e.Time.Before(s.First) {
+		s.First = e.Time
+	}
+	if e.Time.After(s.Last) {
+		s.Last = e.Time
+	}
+	s.Events++
+	s.commands[e.Command()]++
+	for _, key := range e.Keys() {
+		s.keys[key]++
+	}
+	s.clients[e.Host()]++
+}
+
+// Duration returns the time between the first and the last events.
+func (s *Stats) Duration() time.Duration {
+	return s.Last.Sub(s.First)
+}
+
+// TopCommands returns the n commands run the most, all of them if n is 0.
+func (s *Stats) TopCommands(n int) []Count {
+	return top(s.commands, n)
+}
+
+// TopKeys returns the n keys used the most, all of them if n is 0.
+func (s *Stats) TopKeys(n int) []Count {
+	return top(s.keys, n)
+}
+
+// TopClients returns the n clients which ran the most commands, all of them
+// if n is 0. Clients connected over TCP are counted by host.
+func (s *Stats) TopClients(n int) []Count {
+	return top(s.clients, n)
+}
+
+func top(counts map[string]int, n int) []Count {
+	sorted := make([]Count, 0, len(counts))
+	for name, count := range counts {
+		sorted = append(sorted, Count{Name: name, Count: count})
+	}
+	sort.Sort(byCount(sorted))
+	if n > 0 && n < len(sorted) {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
@@ -0,0 +1,41 @@
+package monitor
+
+import (
+	"testing"
+)
+
+func checkCounts(t *testing.T, name string, got []Count, expected ...Count) {
+	if len(got) != len(expected) {
+		t.Errorf("error %s: %v", name, got)
+		return
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Errorf("error %s %d: %v", name, i, got[i])
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	s := NewStats()
+	for _, line := range []string{
+		`1339518083.5 [0 10.0.0.1:52000] "get" "a"`,
+		`1339518081.0 [0 10.0.0.1:52001] "GET" "b"`,
+		`1339518084.0 [0 lua] "get" "a"`,
+		`1339518082.0 [0 10.0.0.2:52000] "mset" "a" "1" "c" "2"`,
+	} {
+		e, err := Parse(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Add(e)
+	}
+	if s.Events != 4 || s.First.Unix() != 1339518081 || s.Last.Unix() != 1339518084 ||
+		s.Duration().Seconds() != 3 {
+		t.Errorf("error stats: %+v", s)
+	}
+	checkCounts(t, "commands", s.TopCommands(0), Count{"GET", 3}, Count{"MSET", 1})
+	checkCounts(t, "keys", s.TopKeys(2), Count{"a", 3}, Count{"b", 1})
+	checkCounts(t, "clients", s.TopClients(0),
+		Count{"10.0.0.1", 2}, Count{"10.0.0.2", 1}, Count{"lua", 1})
+}
@@ -0,0 +1,422 @@
+package pcap
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// maxOutOfOrder is the number of segments buffered by a direction of a
+// connection waiting for missing data, before the data is given up as lost.
+const maxOutOfOrder = 256
+
+// Command is a command read from a capture along with its reply.
+type Command struct {
+	Client, Server Endpoint
+	// Start is the time of the packet completing the request, End the time
+	// of the packet completing its reply, zero if no reply was captured
+	Start, End     time.Time
+	Request, Reply *resp.Message
+}
+
+// Latency returns the time between the request and its reply, 0 if no reply
+// was captured.
+func (c *Command) Latency() time.Duration {
+	if c.Reply == nil {
+		return 0
+	}
+	return c.End.Sub(c.Start)
+}
+
+// Stats counts what an Assembler saw.
+type Stats struct {
+	// Packets is the number of packets added, Segments the number of those
+	// which are TCP segments of a redis connection
+	Packets, Segments int
+	// Connections is the number of redis connections seen
+	Connections int
+	// Gaps is the number of times data was missing from a stream, because
+	// packets were dropped or truncated by the capture
+	Gaps int
+	// DecodeErrors is the number of times data of a stream could not be
+	// decoded and was skipped
+	DecodeErrors int
+	// Unanswered is the number of commands whose reply was not captured,
+	// Unpaired the number of replies received while no command was waiting,
+	// such as published messages
+	Unanswered, Unpaired int
+}
+
+// Assembler reassembles the TCP streams of redis connections from captured
+// packets and pairs the commands sent by clients with the replies of the
+// server. The server side of a connection is the one using one of the
+// ports of the Assembler.
+//
+// When replies are missing from a capture, the commands waiting for one are
+// reported without a reply. When requests are missing, the replies to the
+// lost requests are paired with the requests which follow.
+type Assembler struct {
+	ports map[int]bool
+	fn    func(*Command)
+	conns map[connKey]*conn
+	stats Stats
+}
+
+// NewAssembler returns an Assembler for the redis servers listening on
+// ports, calling fn for each command as soon as its reply is reassembled.
+func NewAssembler(ports []int, fn func(*Command)) *Assembler {
+	a := &Assembler{
+		ports: make(map[int]bool),
+		fn:    fn,
+		conns: make(map[connKey]*conn),
+	}
+	for _, port := range ports {
+		a.ports[port] = true
+	}
+	return a
+}
+
+type connKey struct {
+	client, server Endpoint
+}
+
+// conn is a redis connection being reassembled.
+type conn struct {
+	key        connKey
+	req, reply half
+	pending    []*Command
+}
+
+// half is a direction of a connection.
+type half struct {
+	started bool
+	// next is the sequence number of the next byte expected
+	next uint32
+	// buf holds the data of an incomplete message, scanned by framer
+	buf    []byte
+	framer resp.Framer
+	// waiting holds the segments received ahead of missing data
+	waiting []*timedSegment
+	fin     bool
+}
+
+type timedSegment struct {
+	*segment
+	t time.Time
+}
+
+// Add adds a captured packet, which is ignored unless it is a TCP segment
+// of a redis connection.
+func (a *Assembler) Add(p *Packet) {
+	a.stats.Packets++
+	seg, ok := decodePacket(p)
+	if !ok {
+		return
+	}
+	var key connKey
+	fromClient := true
+	switch {
+	case a.ports[seg.dst.Port]:
+		key = connKey{client: seg.src, server: seg.dst}
+	case a.ports[seg.src.Port]:
+		key = connKey{client: seg.dst, server: seg.src}
+		fromClient = false
+	default:
+		return
+	}
+	a.stats.Segments++
+
+	c := a.conns[key]
+	if c != nil && fromClient && seg.flags&tcpSyn != 0 && c.req.started {
+		// the port was reused by a new connection, the end of the previous
+		// one having not been captured
+		a.finish(c)
+		c = nil
+	}
+	if c == nil {
+		c = &conn{key: key}
+		a.conns[key] = c
+		a.stats.Connections++
+	}
+	h, peer := &c.reply, &c.req
+	if fromClient {
+		h, peer = &c.req, &c.reply
+	}
+	if seg.flags&tcpAck != 0 && peer.started && int32(seg.ack-peer.next) > 0 &&
+		!(peer.fin && seg.ack == peer.next+1) {
+		// the data acknowledged, but not the FIN which takes a sequence
+		// number, was received by the other side but not captured
+		a.lost(c, peer, seg.ack)
+	}
+	a.segment(c, h, &timedSegment{seg, p.Time})
+
+	if seg.flags&tcpRst != 0 || (c.req.fin && c.reply.fin) {
+		a.finish(c)
+	}
+}
+
+// segment adds a segment to a direction of a connection, delivering the data
+// which follows the data already received.
+func (a *Assembler) segment(c *conn, h *half, s *timedSegment) {
+	seq := s.seq
+	if s.flags&tcpSyn != 0 {
+		seq++
+		h.started, h.next = true, seq
+		h.buf, h.waiting = nil, nil
+	} else if !h.started {
+		// the capture started after the connection
+		h.started, h.next = true, seq
+	}
+	if s.flags&tcpFin != 0 {
+		h.fin = true
+	}
+	if s.size == 0 {
+		return
+	}
+	if diff := int32(seq - h.next); diff > 0 {
+		h.waiting = append(h.waiting, s)
+		if len(h.waiting) > maxOutOfOrder {
+			a.skip(c, h)
+		}
+		return
+	}
+	a.deliver(c, h, seq, s)
+	a.drain(c, h)
+}
+
+// deliver appends the new data of a segment starting at seq to a direction,
+// dropping the data already received.
+func (a *Assembler) deliver(c *conn, h *half, seq uint32, s *timedSegment) {
+	overlap := int(h.next - seq)
+	end := seq + uint32(s.size)
+	if int32(end-h.next) <= 0 {
+		// a retransmission
+		return
+	}
+	h.next = end
+	if overlap >= len(s.payload) {
+		a.gap(c, h)
+		return
+	}
+	a.decode(c, h, s.payload[overlap:], s.t)
+	if len(s.payload) < s.size {
+		// the packet was truncated by the capture
+		a.gap(c, h)
+	}
+}
+
+// drain delivers the waiting segments made contiguous by the last one.
+func (a *Assembler) drain(c *conn, h *half) {
+	for {
+		found := false
+		for i := 0; i < len(h.waiting); i++ {
+			s := h.waiting[i]
+			if int32(s.seq-h.next) > 0 {
+				continue
+			}
+			h.waiting = append(h.waiting[:i], h.waiting[i+1:]...)
+			a.deliver(c, h, s.seq, s)
+			found = true
+			break
+		}
+		if !found {
+			return
+		}
+	}
+}
+
+// skip gives up the data missing before the first waiting segment.
+func (a *Assembler) skip(c *conn, h *half) {
+	first := h.waiting[0].seq
+	for _, s := range h.waiting[1:] {
+		if int32(s.seq-first) < 0 {
+			first = s.seq
+		}
+	}
+	a.lost(c, h, first)
+}
+
+// lost gives up the data missing before seq, delivering the waiting segments
+// which follow.
+func (a *Assembler) lost(c *conn, h *half, seq uint32) {
+	for _, s := range h.waiting {
+		if int32(s.seq-seq) < 0 {
+			seq = s.seq
+		}
+	}
+	h.next = seq
+	a.gap(c, h)
+	a.drain(c, h)
+}
+
+// gap drops the incomplete message of a direction, data being missing. The
+// commands waiting for a reply are reported without one when replies are
+// missing.
+func (a *Assembler) gap(c *conn, h *half) {
+	a.stats.Gaps++
+	h.buf = nil
+	h.framer.Reset()
+	if h == &c.reply {
+		a.unanswered(c)
+	}
+}
+
+// decode feeds data to a direction, handling the messages it completes.
+// The data is only decoded once it holds whole messages, or data which is
+// not valid.
+func (a *Assembler) decode(c *conn, h *half, data []byte, t time.Time) {
+	h.buf = append(h.buf, data...)
+	for len(h.buf) > 0 {
+		end, ok := h.framer.Scan(h.buf)
+		if ok && end == 0 {
+			return
+		}
+		data := h.buf[:end]
+		if !ok {
+			data = h.buf
+		}
+		msgQ, pos, err := resp.DecodePrefix(data)
+		for _, msg := range msgQ {
+			a.message(c, h, msg, t)
+		}
+		if err == nil || resp.MaybeSegmentError(err) {
+			// keep the incomplete message until the rest of its data, in a
+			// new buffer as the messages decoded refer to the current one
+			h.buf = append([]byte(nil), h.buf[pos:]...)
+			if ok {
+				h.framer.Discard(pos)
+			} else {
+				h.framer.Reset()
+			}
+			return
+		}
+		a.stats.DecodeErrors++
+		if h == &c.reply {
+			// the replies can not be paired anymore
+			a.unanswered(c)
+		}
+		h.buf = resync(h.buf[pos:], h == &c.req)
+		h.framer.Reset()
+	}
+}
+
+// message handles a message decoded from a direction.
+func (a *Assembler) message(c *conn, h *half, msg *resp.Message, t time.Time) {
+	if h == &c.req {
+		c.pending = append(c.pending, &Command{
+			Client:  c.key.client,
+			Server:  c.key.server,
+			Start:   t,
+			Request: msg,
+		})
+		return
+	}
+	if len(c.pending) == 0 {
+		a.stats.Unpaired++
+		return
+	}
+	cmd := c.pending[0]
+	c.pending = c.pending[1:]
+	cmd.End, cmd.Reply = t, msg
+	a.fn(cmd)
+}
+
+// unanswered reports the commands waiting for a reply without one.
+func (a *Assembler) unanswered(c *conn) {
+	for _, cmd := range c.pending {
+		a.stats.Unanswered++
+		a.fn(cmd)
+	}
+	c.pending = nil
+}
+
+// finish reports the commands of a closed connection and forgets it.
+func (a *Assembler) finish(c *conn) {
+	for _, h := range []*half{&c.req, &c.reply} {
+		for len(h.waiting) > 0 {
+			a.skip(c, h)
+		}
+	}
+	a.unanswered(c)
+	delete(a.conns, c.key)
+}
+
+// Flush reports the commands of the connections still open, without a reply
+// if none was captured, and forgets the connections. It is called once the
+// last packet of a capture is added.
+func (a *Assembler) Flush() {
+	var conns connsByKey
+	for _, c := range a.conns {
+		conns = append(conns, c)
+	}
+	sort.Sort(conns)
+	for _, c := range conns {
+		a.finish(c)
+	}
+}
+
+// Stats returns what the Assembler saw so far.
+func (a *Assembler) Stats() Stats {
+	return a.stats
+}
+
+type connsByKey []*conn
+
+func (s connsByKey) Len() int      { return len(s) }
+func (s connsByKey) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s connsByKey) Less(i, j int) bool {
+	return s[i].key.client.String()+s[i].key.server.String() <
+		s[j].key.client.String()+s[j].key.server.String()
+}
+
+// resync drops the data of an undecodable message, up to the next line
+// which may start a message: an array for requests, any type for replies.
+func resync(b []byte, request bool) []byte {
+	for {
+		i := bytes.Index(b, []byte("\r\n"))
+		if i < 0 {
+			return nil
+		}
+		b = b[i+2:]
+		if len(b) == 0 {
+			return nil
+		}
+		switch b[0] {
+		case resp.ArrayHeader:
+			return b
+		case resp.StringHeader, resp.ErrorHeader, resp.IntegerHeader, resp.BulkHeader:
+			if !request {
+				return b
+			}
+		}
+	}
+}
+
+// Commands reads a capture file and returns the commands sent to the redis
+// servers listening on ports, in the order their reply was captured,
+// followed by the commands without a reply.
+func Commands(r io.Reader, ports ...int) ([]*Command, Stats, error) {
+	pr, err := NewReader(r)
+	if err != nil {
+		return nil, Stats{}, err
+	}
+	var cmds []*Command
+	a := NewAssembler(ports, func(cmd *Command) {
+		cmds = append(cmds, cmd)
+	})
+	for {
+		p, err := pr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			a.Flush()
+			return cmds, a.Stats(), err
+		}
+		a.Add(p)
+	}
+	a.Flush()
+	return cmds, a.Stats(), nil
+}
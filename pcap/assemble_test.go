@@ -0,0 +1,225 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+)
+
+var epoch = time.Unix(1500000000, 0)
+
+// capture builds the packets of a redis connection for tests.
+type capture struct {
+	packets        []*Packet
+	cseq, sseq     uint32
+	client, server int
+}
+
+func newCapture(clientPort int) *capture {
+	return &capture{cseq: 1000, sseq: 5000, client: clientPort, server: 6379}
+}
+
+func (c *capture) add(ms int, data []byte) {
+	c.packets = append(c.packets, &Packet{
+		Time:     epoch.Add(time.Duration(ms) * time.Millisecond),
+		LinkType: LinkEthernet,
+		Data:     data,
+	})
+}
+
+func (c *capture) handshake(ms int) *capture {
+	c.add(ms, ethernet(ipv4("10.0.0.1", "10.0.0.2", tcpHeader(c.client, c.server, c.cseq, 0, tcpSyn, ""))))
+	c.add(ms, ethernet(ipv4("10.0.0.2", "10.0.0.1", tcpHeader(c.server, c.client, c.sseq, c.cseq+1, tcpSyn, ""))))
+	c.cseq++
+	c.sseq++
+	return c
+}
+
+// request sends data from the client, acknowledging the data sent by the
+// server so far.
+func (c *capture) request(ms int, data string) *capture {
+	c.add(ms, ethernet(ipv4("10.0.0.1", "10.0.0.2", tcpHeader(c.client, c.server, c.cseq, c.sseq, 0, data))))
+	c.cseq += uint32(len(data))
+	return c
+}
+
+func (c *capture) reply(ms int, data string) *capture {
+	c.add(ms, ethernet(ipv4("10.0.0.2", "10.0.0.1", tcpHeader(c.server, c.client, c.sseq, c.cseq, 0, data))))
+	c.sseq += uint32(len(data))
+	return c
+}
+
+func (c *capture) close(ms int) *capture {
+	c.add(ms, ethernet(ipv4("10.0.0.1", "10.0.0.2", tcpHeader(c.client, c.server, c.cseq, c.sseq, tcpFin, ""))))
+	c.add(ms, ethernet(ipv4("10.0.0.2", "10.0.0.1", tcpHeader(c.server, c.client, c.sseq, c.cseq+1, tcpFin, ""))))
+	return c
+}
+
+func (c *capture) swap(i, j int) *capture {
+	c.packets[i], c.packets[j] = c.packets[j], c.packets[i]
+	return c
+}
+
+func assemble(packets []*Packet) ([]string, Stats) {
+	var got []string
+	a := NewAssembler([]int{6379}, func(cmd *Command) {
+		line := strings.Replace(cmd.Request.String(), "\n", " ", -1)
+		if cmd.Reply != nil {
+			line += " => " + strings.Replace(cmd.Reply.String(), "\n", " ", -1) + " in " + cmd.Latency().String()
+		}
+		got = append(got, line)
+	})
+	for _, p := range packets {
+		a.Add(p)
+	}
+	a.Flush()
+	return got, a.Stats()
+}
+
+func checkLines(t *testing.T, got []string, expected ...string) {
+	if len(got) != len(expected) {
+		t.Errorf("error commands: %q", got)
+		return
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Errorf("error command %d: %q != %q", i, got[i], expected[i])
+		}
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	c := newCapture(52000).handshake(0).
+		request(1, "*2\r\n$3\r\nGET\r\n$1\r\na\r\n").
+		reply(3, "$1\r\n1\r\n").
+		// a request and a reply split in several segments
+		request(10, "*3\r\n$3\r\nSET\r\n$1\r\na\r\n").
+		request(11, "$2\r\n10\r\n").
+		reply(12, "+O").
+		reply(14, "K\r\n").
+		// pipelined requests
+		request(20, "*1\r\n$4\r\nPING\r\n*1\r\n$4\r\nPING\r\n").
+		reply(21, "+PONG\r\n").
+		reply(22, "+PONG\r\n").
+		request(30, "*1\r\n$4\r\nQUIT\r\n").
+		close(31)
+	got, stats := assemble(c.packets)
+	checkLines(t, got,
+		`1) "GET" 2) "a" => "1" in 2ms`,
+		`1) "SET" 2) "a" 3) "10" => OK in 3ms`,
+		`1) "PING" => PONG in 1ms`,
+		`1) "PING" => PONG in 2ms`,
+		`1) "QUIT"`,
+	)
+	if stats.Connections != 1 || stats.Unanswered != 1 || stats.Gaps != 0 || stats.Segments != 14 {
+		t.Errorf("error stats: %+v", stats)
+	}
+}
+
+func TestAssembleOutOfOrder(t *testing.T) {
+	c := newCapture(52000).handshake(0).
+		request(1, "*2\r\n$3\r\nGET\r\n").
+		request(2, "$1\r\na\r\n").
+		reply(3, "$1\r\n").
+		reply(4, "1\r\n")
+	// the second half of the request arrives first, the first half of the
+	// reply twice
+	c.swap(2, 3)
+	c.packets = append(c.packets[:5], append([]*Packet{c.packets[4]}, c.packets[5:]...)...)
+	got, stats := assemble(c.packets)
+	// the request is completed by the segment which arrived first
+	checkLines(t, got, `1) "GET" 2) "a" => "1" in 2ms`)
+	if stats.Gaps != 0 || stats.Unpaired != 0 {
+		t.Errorf("error stats: %+v", stats)
+	}
+}
+
+func TestAssembleConnections(t *testing.T) {
+	a := newCapture(52000).handshake(0).request(1, "*1\r\n$4\r\nPING\r\n")
+	b := newCapture(52001).handshake(2).request(3, "*1\r\n$4\r\nPING\r\n").reply(4, "+PONG\r\n")
+	a.reply(5, "+PONG\r\n")
+	var packets []*Packet
+	packets = append(packets, a.packets[:3]...)
+	packets = append(packets, b.packets...)
+	packets = append(packets, a.packets[3:]...)
+	got, stats := assemble(packets)
+	checkLines(t, got,
+		`1) "PING" => PONG in 1ms`,
+		`1) "PING" => PONG in 4ms`,
+	)
+	if stats.Connections != 2 {
+		t.Errorf("error stats: %+v", stats)
+	}
+}
+
+func TestAssembleGap(t *testing.T) {
+	c := newCapture(52000).handshake(0).
+		request(1, "*1\r\n$4\r\nPING\r\n").
+		reply(2, "+PO").
+		reply(2, "NG\r\n").
+		request(3, "*1\r\n$4\r\nPING\r\n").
+		reply(4, "+PONG\r\n")
+	// the first segment of the first reply was not captured
+	c.packets = append(c.packets[:3], c.packets[4:]...)
+	got, stats := assemble(c.packets)
+	checkLines(t, got, `1) "PING"`, `1) "PING" => PONG in 1ms`)
+	// the rest of the reply can not be decoded
+	if stats.Gaps != 1 || stats.Unanswered != 1 || stats.DecodeErrors != 1 {
+		t.Errorf("error stats: %+v", stats)
+	}
+}
+
+func TestAssembleResync(t *testing.T) {
+	// the reply with attributes is longer than its encoding, the decoding
+	// resumes after it
+	c := newCapture(52000).handshake(0).
+		request(1, "*2\r\n$3\r\nGET\r\n$1\r\na\r\n*1\r\n$4\r\nPING\r\n").
+		reply(2, "|1\r\n+ttl\r\n:3\r\n+OK\r\n?bad\r\n+PONG\r\n")
+	got, stats := assemble(c.packets)
+	checkLines(t, got, `1) "GET" 2) "a" => OK in 1ms`, `1) "PING"`)
+	if stats.DecodeErrors != 1 || stats.Unanswered != 1 || stats.Unpaired != 1 {
+		t.Errorf("error stats: %+v", stats)
+	}
+}
+
+func TestAssembleMidStream(t *testing.T) {
+	// the capture starts in the middle of a request
+	c := newCapture(52000).
+		request(1, "a\r\n*1\r\n$4\r\nPING\r\n").
+		reply(2, "$1\r\n1\r\n+PONG\r\n").
+		request(3, "*2\r\n$9\r\nSUBSCRIBE\r\n$1\r\nc\r\n").
+		reply(4, "*3\r\n$9\r\nsubscribe\r\n$1\r\nc\r\n:1\r\n").
+		reply(5, "*3\r\n$7\r\nmessage\r\n$1\r\nc\r\n$1\r\nm\r\n")
+	got, stats := assemble(c.packets)
+	// the reply to the truncated request is paired with PING
+	checkLines(t, got,
+		`1) "PING" => "1" in 1ms`,
+		`1) "SUBSCRIBE" 2) "c" => 1) "subscribe" 2) "c" 3) (integer) 1 in 1ms`,
+	)
+	if stats.DecodeErrors != 1 || stats.Unpaired != 2 {
+		t.Errorf("error stats: %+v", stats)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	c := newCapture(52000).handshake(0).
+		request(1, "*1\r\n$4\r\nPING\r\n").
+		reply(3, "+PONG\r\n")
+	f := newPcap(binary.LittleEndian, false, LinkEthernet)
+	for _, p := range c.packets {
+		f.packet(p.Time, p.Data)
+	}
+	cmds, stats, err := Commands(bytes.NewReader(f.Bytes()), 6379)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cmds) != 1 || cmds[0].Latency() != 2*time.Millisecond ||
+		cmds[0].Client.String() != "10.0.0.1:52000" || cmds[0].Server.String() != "10.0.0.2:6379" {
+		t.Errorf("error commands: %v", cmds)
+	}
+	if stats.Packets != 4 {
+		t.Errorf("error stats: %+v", stats)
+	}
+}
@@ -0,0 +1,173 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+)
+
+const (
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86dd
+	etherTypeVLAN = 0x8100
+	etherTypeQinQ = 0x88a8
+
+	protoTCP = 6
+
+	tcpFin = 0x01
+	tcpSyn = 0x02
+	tcpRst = 0x04
+	tcpAck = 0x10
+)
+
+// Endpoint is an IP address and a TCP port.
+type Endpoint struct {
+	IP   string
+	Port int
+}
+
+func (e Endpoint) String() string {
+	return net.JoinHostPort(e.IP, strconv.Itoa(e.Port))
+}
+
+// segment is a TCP segment decoded from a packet.
+type segment struct {
+	src, dst Endpoint
+	seq, ack uint32
+	flags    byte
+	payload  []byte
+	// size is the length of the payload on the wire, larger than the
+	// payload captured when the packet was truncated
+	size int
+}
+
+// decodePacket decodes the TCP segment of a packet, returning false for
+// packets of other protocols or link types, for fragmented packets and for
+// packets truncated before the end of the TCP header.
+func decodePacket(p *Packet) (*segment, bool) {
+	b := p.Data
+	var etherType uint16
+	switch p.LinkType {
+	case LinkEthernet:
+		if len(b) < 14 {
+			return nil, false
+		}
+		etherType, b = binary.BigEndian.Uint16(b[12:]), b[14:]
+		for etherType == etherTypeVLAN || etherType == etherTypeQinQ {
+			if len(b) < 4 {
+				return nil, false
+			}
+			etherType, b = binary.BigEndian.Uint16(b[2:]), b[4:]
+		}
+	case LinkLinuxSLL:
+		if len(b) < 16 {
+			return nil, false
+		}
+		etherType, b = binary.BigEndian.Uint16(b[14:]), b[16:]
+	case LinkLinuxSLL2:
+		if len(b) < 20 {
+			return nil, false
+		}
+		etherType, b = binary.BigEndian.Uint16(b), b[20:]
+	case LinkNull, LinkLoop:
+		// the address family, in the byte order of the capturing host
+		if len(b) < 4 {
+			return nil, false
+		}
+		family := binary.LittleEndian.Uint32(b)
+		if family > 0xffff {
+			family = binary.BigEndian.Uint32(b)
+		}
+		if family == 2 {
+			etherType = etherTypeIPv4
+		} else {
+			// AF_INET6 differs between systems
+			etherType = etherTypeIPv6
+		}
+		b = b[4:]
+	case LinkRaw:
+		if len(b) == 0 {
+			return nil, false
+		}
+		etherType = etherTypeIPv4
+		if b[0]>>4 == 6 {
+			etherType = etherTypeIPv6
+		}
+	case LinkIPv4:
+		etherType = etherTypeIPv4
+	case LinkIPv6:
+		etherType = etherTypeIPv6
+	default:
+		return nil, false
+	}
+
+	var src, dst net.IP
+	// size is the length of the IP payload on the wire
+	var size int
+	switch etherType {
+	case etherTypeIPv4:
+		if len(b) < 20 || b[0]>>4 != 4 {
+			return nil, false
+		}
+		hl := int(b[0]&0x0f) * 4
+		total := int(binary.BigEndian.Uint16(b[2:]))
+		flags := binary.BigEndian.Uint16(b[6:])
+		if hl < 20 || total < hl || len(b) < hl || b[9] != protoTCP {
+			return nil, false
+		}
+		// more fragments or a fragment offset
+		if flags&0x3fff != 0 {
+			return nil, false
+		}
+		src, dst = net.IP(b[12:16]), net.IP(b[16:20])
+		if total < len(b) {
+			// ethernet padding
+			b = b[:total]
+		}
+		size, b = total-hl, b[hl:]
+	case etherTypeIPv6:
+		if len(b) < 40 || b[0]>>4 != 6 {
+			return nil, false
+		}
+		next := b[6]
+		src, dst = net.IP(b[8:24]), net.IP(b[24:40])
+		size = int(binary.BigEndian.Uint16(b[4:]))
+		if size+40 < len(b) {
+			b = b[:size+40]
+		}
+		b = b[40:]
+		for next != protoTCP {
+			switch next {
+			case 0, 43, 60:
+				// hop-by-hop, routing and destination options
+				if len(b) < 8 || len(b) < (int(b[1])+1)*8 {
+					return nil, false
+				}
+				n := (int(b[1]) + 1) * 8
+				next, b, size = b[0], b[n:], size-n
+			default:
+				// fragments and other protocols
+				return nil, false
+			}
+		}
+	default:
+		return nil, false
+	}
+
+	if len(b) < 20 {
+		return nil, false
+	}
+	off := int(b[12]>>4) * 4
+	if off < 20 || len(b) < off || size < off {
+		return nil, false
+	}
+	return &segment{
+		src:     Endpoint{IP: src.String(), Port: int(binary.BigEndian.Uint16(b))},
+		dst:     Endpoint{IP: dst.String(), Port: int(binary.BigEndian.Uint16(b[2:]))},
+		seq:     binary.BigEndian.Uint32(b[4:]),
+		ack:     binary.BigEndian.Uint32(b[8:]),
+		flags:   b[13],
+		payload: b[off:],
+		size:    size - off,
+	}, true
+}
@@ -0,0 +1,130 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+// tcpHeader returns a TCP header without options followed by the payload,
+// acknowledging ack unless it is 0.
+func tcpHeader(sport, dport int, seq, ack uint32, flags byte, payload string) []byte {
+	b := make([]byte, 20)
+	binary.BigEndian.PutUint16(b, uint16(sport))
+	binary.BigEndian.PutUint16(b[2:], uint16(dport))
+	binary.BigEndian.PutUint32(b[4:], seq)
+	binary.BigEndian.PutUint32(b[8:], ack)
+	b[12] = 5 << 4
+	b[13] = flags
+	if ack != 0 {
+		b[13] |= tcpAck
+	}
+	return append(b, payload...)
+}
+
+// ipv4 returns an IPv4 packet carrying a TCP segment.
+func ipv4(src, dst string, tcp []byte) []byte {
+	b := make([]byte, 20)
+	b[0] = 0x45
+	binary.BigEndian.PutUint16(b[2:], uint16(20+len(tcp)))
+	b[8] = 64
+	b[9] = protoTCP
+	copy(b[12:], net.ParseIP(src).To4())
+	copy(b[16:], net.ParseIP(dst).To4())
+	return append(b, tcp...)
+}
+
+// ipv6 returns an IPv6 packet carrying a TCP segment.
+func ipv6(src, dst string, tcp []byte) []byte {
+	b := make([]byte, 40)
+	b[0] = 0x60
+	binary.BigEndian.PutUint16(b[4:], uint16(len(tcp)))
+	b[6] = protoTCP
+	b[7] = 64
+	copy(b[8:], net.ParseIP(src))
+	copy(b[24:], net.ParseIP(dst))
+	return append(b, tcp...)
+}
+
+// ethernet returns an ethernet frame carrying an IP packet.
+func ethernet(ip []byte) []byte {
+	b := make([]byte, 14)
+	etherType := uint16(etherTypeIPv4)
+	if ip[0]>>4 == 6 {
+		etherType = etherTypeIPv6
+	}
+	binary.BigEndian.PutUint16(b[12:], etherType)
+	return append(b, ip...)
+}
+
+func TestDecodePacket(t *testing.T) {
+	tcp := tcpHeader(52000, 6379, 100, 0, tcpSyn, "PING")
+	v4 := ipv4("10.0.0.1", "10.0.0.2", tcp)
+	v6 := ipv6("fe80::1", "fe80::2", tcp)
+
+	vlan := make([]byte, 18)
+	binary.BigEndian.PutUint16(vlan[12:], etherTypeVLAN)
+	binary.BigEndian.PutUint16(vlan[16:], etherTypeIPv4)
+	sll := make([]byte, 16)
+	binary.BigEndian.PutUint16(sll[14:], etherTypeIPv6)
+	null := []byte{2, 0, 0, 0}
+	// ethernet padding after a short packet
+	padded := append(ethernet(v4), 0, 0, 0)
+
+	cases := []struct {
+		linkType int
+		data     []byte
+		src, dst string
+	}{
+		{LinkEthernet, ethernet(v4), "10.0.0.1:52000", "10.0.0.2:6379"},
+		{LinkEthernet, ethernet(v6), "[fe80::1]:52000", "[fe80::2]:6379"},
+		{LinkEthernet, padded, "10.0.0.1:52000", "10.0.0.2:6379"},
+		{LinkEthernet, append(vlan, v4...), "10.0.0.1:52000", "10.0.0.2:6379"},
+		{LinkLinuxSLL, append(sll, v6...), "[fe80::1]:52000", "[fe80::2]:6379"},
+		{LinkNull, append(null, v4...), "10.0.0.1:52000", "10.0.0.2:6379"},
+		{LinkRaw, v6, "[fe80::1]:52000", "[fe80::2]:6379"},
+		{LinkIPv4, v4, "10.0.0.1:52000", "10.0.0.2:6379"},
+	}
+	for i, c := range cases {
+		seg, ok := decodePacket(&Packet{LinkType: c.linkType, Data: c.data})
+		if !ok {
+			t.Errorf("error decoding packet %d", i)
+			continue
+		}
+		if seg.src.String() != c.src || seg.dst.String() != c.dst {
+			t.Errorf("error endpoints of packet %d: %s > %s", i, seg.src, seg.dst)
+		}
+		if seg.seq != 100 || seg.flags&tcpSyn == 0 || string(seg.payload) != "PING" || seg.size != 4 {
+			t.Errorf("error segment of packet %d: %+v", i, seg)
+		}
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	data := ethernet(ipv4("10.0.0.1", "10.0.0.2", tcpHeader(52000, 6379, 1, 0, 0, "PING")))
+	seg, ok := decodePacket(&Packet{LinkType: LinkEthernet, Data: data[:len(data)-2]})
+	if !ok || string(seg.payload) != "PI" || seg.size != 4 {
+		t.Errorf("error truncated payload: %+v", seg)
+	}
+	if _, ok := decodePacket(&Packet{LinkType: LinkEthernet, Data: data[:40]}); ok {
+		t.Error("a truncated TCP header should not be decoded")
+	}
+}
+
+func TestDecodeIgnored(t *testing.T) {
+	tcp := tcpHeader(52000, 6379, 1, 0, 0, "PING")
+	udp := ipv4("10.0.0.1", "10.0.0.2", tcp)
+	udp[9] = 17
+	fragment := ipv4("10.0.0.1", "10.0.0.2", tcp)
+	fragment[6] = 0x20
+	for i, p := range []*Packet{
+		{LinkType: LinkEthernet, Data: ethernet(udp)},
+		{LinkType: LinkEthernet, Data: ethernet(fragment)},
+		{LinkType: 147, Data: ethernet(tcp)},
+		{LinkType: LinkEthernet, Data: []byte{1, 2, 3}},
+	} {
+		if _, ok := decodePacket(p); ok {
+			t.Errorf("error packet %d should be ignored", i)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+// Package pcap reads pcap and pcapng capture files, such as written by
+// tcpdump, reassembles the TCP streams of redis connections and pairs the
+// commands they carry with their replies, measuring the latency of each
+// command as seen on the wire.
+//
+// Only the standard library is used, libpcap is not needed.
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	magicMicros        = 0xa1b2c3d4
+	magicNanos         = 0xa1b23c4d
+	magicSectionHeader = 0x0a0d0d0a
+	magicByteOrder     = 0x1a2b3c4d
+
+	blockInterface      = 0x00000001
+	blockPacket         = 0x00000002
+	blockSimplePacket   = 0x00000003
+	blockEnhancedPacket = 0x00000006
+
+	optionEnd         = 0
+	optionTsResol     = 9
+	maxBlockLen       = 64 << 20
+	defaultResolution = 1000000
+)
+
+// Link types of the captured packets, as registered by tcpdump.org.
+const (
+	LinkNull      = 0
+	LinkEthernet  = 1
+	LinkRaw       = 101
+	LinkLoop      = 108
+	LinkLinuxSLL  = 113
+	LinkIPv4      = 228
+	LinkIPv6      = 229
+	LinkLinuxSLL2 = 276
+)
+
+var (
+	// ErrInvalidFormat is returned when a file is neither a pcap nor a
+	// pcapng file.
+	ErrInvalidFormat = errors.New("pcap: unknown capture file format")
+
+	// ErrInvalidBlock is returned when a pcapng block or a pcap record is
+	// malformed.
+	ErrInvalidBlock = errors.New("pcap: invalid block")
+
+	// ErrUnknownInterface is returned when a pcapng packet refers to an
+	// interface which was not described.
+	ErrUnknownInterface = errors.New("pcap: unknown interface")
+)
+
+// Packet is a captured packet.
+type Packet struct {
+	Time     time.Time
+	LinkType int
+	// Data holds the captured bytes, which may be truncated to the snapshot
+	// length of the capture
+	Data []byte
+}
+
+type iface struct {
+	linkType int
+	// units is the number of timestamp units per second
+	units int64
+}
+
+// Reader reads the packets of a pcap or pcapng file, the format being
+// detected from the first bytes of the file.
+type Reader struct {
+	r     *bufio.Reader
+	order binary.ByteOrder
+	ng    bool
+	// pcap
+	linkType int
+	nanos    bool
+	// pcapng
+	ifaces []iface
+}
+
+// NewReader returns a Reader reading a capture file from r, after reading
+// its header.
+func NewReader(r io.Reader) (*Reader, error) {
+	pr := &Reader{r: bufio.NewReader(r)}
+	magic, err := pr.r.Peek(4)
+	if err == io.EOF {
+		return nil, ErrInvalidFormat
+	} else if err != nil {
+		return nil, err
+	}
+	if binary.BigEndian.Uint32(magic) == magicSectionHeader {
+		pr.ng = true
+		return pr, nil
+	}
+	if err := pr.readFileHeader(); err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+// readFileHeader reads the header of a pcap file.
+func (r *Reader) readFileHeader() error {
+	h := make([]byte, 24)
+	if _, err := io.ReadFull(r.r, h); err != nil {
+		return ErrInvalidFormat
+	}
+	switch {
+	case binary.LittleEndian.Uint32(h) == magicMicros:
+		r.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(h) == magicMicros:
+		r.order = binary.BigEndian
+	case binary.LittleEndian.Uint32(h) == magicNanos:
+		r.order, r.nanos = binary.LittleEndian, true
+	case binary.BigEndian.Uint32(h) == magicNanos:
+		r.order, r.nanos = binary.BigEndian, true
+	default:
+		return ErrInvalidFormat
+	}
+	r.linkType = int(r.order.Uint32(h[20:]) & 0xffff)
+	return nil
+}
+
+// Next returns the next packet of the file, or io.EOF at its end.
+func (r *Reader) Next() (*Packet, error) {
+	if r.ng {
+		return r.nextBlock()
+	}
+	return r.nextRecord()
+}
+
+// nextRecord reads a packet record of a pcap file.
+func (r *Reader) nextRecord() (*Packet, error) {
+	h := make([]byte, 16)
+	if _, err := io.ReadFull(r.r, h); err == io.EOF {
+		return nil, io.EOF
+	} else if err != nil {
+		return nil, ErrInvalidBlock
+	}
+	sec, frac := int64(r.order.Uint32(h)), int64(r.order.Uint32(h[4:]))
+	n := r.order.Uint32(h[8:])
+	if n > maxBlockLen {
+		return nil, ErrInvalidBlock
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return nil, ErrInvalidBlock
+	}
+	if !r.nanos {
+		frac *= int64(time.Microsecond)
+	}
+	return &Packet{Time: time.Unix(sec, frac), LinkType: r.linkType, Data: data}, nil
+}
+
+// nextBlock reads the blocks of a pcapng file until a packet block.
+func (r *Reader) nextBlock() (*Packet, error) {
+	for {
+		typ, body, err := r.readBlock()
+		if err != nil {
+			return nil, err
+		}
+		switch typ {
+		case magicSectionHeader:
+			// a new section, interfaces are numbered from zero again
+			r.ifaces = r.ifaces[:0]
+		case blockInterface:
+			if len(body) < 8 {
+				return nil, ErrInvalidBlock
+			}
+			i := iface{
+				linkType: int(r.order.Uint16(body)),
+				units:    defaultResolution,
+			}
+			r.parseOptions(body[8:], func(code uint16, value []byte) {
+				if code == optionTsResol && len(value) == 1 {
+					i.units = resolution(value[0])
+				}
+			})
+			r.ifaces = append(r.ifaces, i)
+		case blockEnhancedPacket:
+			if len(body) < 20 {
+				return nil, ErrInvalidBlock
+			}
+			id := r.order.Uint32(body)
+			if int(id) >= len(r.ifaces) {
+				return nil, ErrUnknownInterface
+			}
+			ts := uint64(r.order.Uint32(body[4:]))<<32 | uint64(r.order.Uint32(body[8:]))
+			n := r.order.Uint32(body[12:])
+			if uint32(len(body)-20) < n {
+				return nil, ErrInvalidBlock
+			}
+			return r.ifaces[id].packet(ts, body[20:20+n]), nil
+		case blockPacket:
+			// obsolete packet block, written by old versions of tools
+			if len(body) < 20 {
+				return nil, ErrInvalidBlock
+			}
+			id := r.order.Uint16(body)
+			if int(id) >= len(r.ifaces) {
+				return nil, ErrUnknownInterface
+			}
+			ts := uint64(r.order.Uint32(body[4:]))<<32 | uint64(r.order.Uint32(body[8:]))
+			n := r.order.Uint32(body[12:])
+			if uint32(len(body)-20) < n {
+				return nil, ErrInvalidBlock
+			}
+			return r.ifaces[id].packet(ts, body[20:20+n]), nil
+		case blockSimplePacket:
+			// simple packets have no timestamp and belong to the first
+			// interface
+			if len(body) < 4 || len(r.ifaces) == 0 {
+				return nil, ErrInvalidBlock
+			}
+			n := int(r.order.Uint32(body))
+			if n > len(body)-4 {
+				n = len(body) - 4
+			}
+			return &Packet{LinkType: r.ifaces[0].linkType, Data: body[4 : 4+n]}, nil
+		}
+	}
+}
+
+// readBlock reads a pcapng block, returning its type and body. The byte
+// order is set by each section header block.
+func (r *Reader) readBlock() (uint32, []byte, error) {
+	h := make([]byte, 8)
+	if _, err := io.ReadFull(r.r, h); err == io.EOF {
+		return 0, nil, io.EOF
+	} else if err != nil {
+		return 0, nil, ErrInvalidBlock
+	}
+	typ := binary.BigEndian.Uint32(h)
+	if typ == magicSectionHeader {
+		bom, err := r.r.Peek(4)
+		if err != nil {
+			return 0, nil, ErrInvalidBlock
+		}
+		switch {
+		case binary.LittleEndian.Uint32(bom) == magicByteOrder:
+			r.order = binary.LittleEndian
+		case binary.BigEndian.Uint32(bom) == magicByteOrder:
+			r.order = binary.BigEndian
+		default:
+			return 0, nil, ErrInvalidFormat
+		}
+	} else if r.order == nil {
+		return 0, nil, ErrInvalidFormat
+	} else {
+		typ = r.order.Uint32(h)
+	}
+	n := r.order.Uint32(h[4:])
+	if n < 12 || n%4 != 0 || n > maxBlockLen {
+		return 0, nil, ErrInvalidBlock
+	}
+	body := make([]byte, n-8)
+	if _, err := io.ReadFull(r.r, body); err != nil {
+		return 0, nil, ErrInvalidBlock
+	}
+	if r.order.Uint32(body[len(body)-4:]) != n {
+		return 0, nil, ErrInvalidBlock
+	}
+	return typ, body[:len(body)-4], nil
+}
+
+// parseOptions calls fn for each option of a block, options being padded to
+// 32 bits.
+func (r *Reader) parseOptions(b []byte, fn func(code uint16, value []byte)) {
+	for len(b) >= 4 {
+		code, n := r.order.Uint16(b), int(r.order.Uint16(b[2:]))
+		if code == optionEnd || 4+n > len(b) {
+			return
+		}
+		fn(code, b[4:4+n])
+		b = b[4+(n+3)&^3:]
+	}
+}
+
+// resolution returns the number of timestamp units per second, given the
+// value of the if_tsresol option: a power of 10, or of 2 when the most
+// significant bit is set.
+func resolution(v byte) int64 {
+	exp := uint(v & 0x7f)
+	if v&0x80 != 0 {
+		if exp > 62 {
+			exp = 62
+		}
+		return 1 << exp
+	}
+	if exp > 18 {
+		exp = 18
+	}
+	units := int64(1)
+	for i := uint(0); i < exp; i++ {
+		units *= 10
+	}
+	return units
+}
+
+func (i iface) packet(ts uint64, data []byte) *Packet {
+	sec, frac := int64(ts/uint64(i.units)), int64(ts%uint64(i.units))
+	var nsec int64
+	if int64(time.Second)%i.units == 0 {
+		nsec = frac * (int64(time.Second) / i.units)
+	} else {
+		nsec = int64(float64(frac) * float64(time.Second) / float64(i.units))
+	}
+	return &Packet{
+		Time:     time.Unix(sec, nsec),
+		LinkType: i.linkType,
+		Data:     data,
+	}
+}
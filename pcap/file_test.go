@@ -0,0 +1,218 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+)
+
+// pcapFile builds pcap files for tests.
+type pcapFile struct {
+	bytes.Buffer
+	order binary.ByteOrder
+	nanos bool
+}
+
+func newPcap(order binary.ByteOrder, nanos bool, linkType int) *pcapFile {
+	f := &pcapFile{order: order, nanos: nanos}
+	magic := uint32(magicMicros)
+	if nanos {
+		magic = magicNanos
+	}
+	f.u32(magic)
+	f.u16(2)
+	f.u16(4)
+	f.u32(0)
+	f.u32(0)
+	f.u32(65535)
+	f.u32(uint32(linkType))
+	return f
+}
+
+func (f *pcapFile) u16(v uint16) {
+	b := make([]byte, 2)
+	f.order.PutUint16(b, v)
+	f.Write(b)
+}
+
+func (f *pcapFile) u32(v uint32) {
+	b := make([]byte, 4)
+	f.order.PutUint32(b, v)
+	f.Write(b)
+}
+
+func (f *pcapFile) packet(t time.Time, data []byte) *pcapFile {
+	f.u32(uint32(t.Unix()))
+	if f.nanos {
+		f.u32(uint32(t.Nanosecond()))
+	} else {
+		f.u32(uint32(t.Nanosecond() / 1000))
+	}
+	f.u32(uint32(len(data)))
+	f.u32(uint32(len(data)))
+	f.Write(data)
+	return f
+}
+
+// pcapngFile builds pcapng files for tests.
+type pcapngFile struct {
+	bytes.Buffer
+	order binary.ByteOrder
+}
+
+func newPcapng(order binary.ByteOrder) *pcapngFile {
+	f := &pcapngFile{order: order}
+	body := make([]byte, 16)
+	order.PutUint32(body, magicByteOrder)
+	order.PutUint16(body[4:], 1)
+	binary.BigEndian.PutUint64(body[8:], 0xffffffffffffffff)
+	f.block(magicSectionHeader, body)
+	return f
+}
+
+func (f *pcapngFile) block(typ uint32, body []byte) *pcapngFile {
+	for len(body)%4 != 0 {
+		body = append(body, 0)
+	}
+	b := make([]byte, 8)
+	f.order.PutUint32(b, typ)
+	f.order.PutUint32(b[4:], uint32(len(body)+12))
+	f.Write(b)
+	f.Write(body)
+	f.Write(b[4:])
+	return f
+}
+
+func (f *pcapngFile) iface(linkType int, tsresol int) *pcapngFile {
+	body := make([]byte, 8)
+	f.order.PutUint16(body, uint16(linkType))
+	if tsresol >= 0 {
+		opt := make([]byte, 8)
+		f.order.PutUint16(opt, optionTsResol)
+		f.order.PutUint16(opt[2:], 1)
+		opt[4] = byte(tsresol)
+		body = append(body, opt...)
+		body = append(body, 0, 0, 0, 0)
+	}
+	return f.block(blockInterface, body)
+}
+
+func (f *pcapngFile) packet(id int, ts uint64, data []byte) *pcapngFile {
+	body := make([]byte, 20)
+	f.order.PutUint32(body, uint32(id))
+	f.order.PutUint32(body[4:], uint32(ts>>32))
+	f.order.PutUint32(body[8:], uint32(ts))
+	f.order.PutUint32(body[12:], uint32(len(data)))
+	f.order.PutUint32(body[16:], uint32(len(data)))
+	return f.block(blockEnhancedPacket, append(body, data...))
+}
+
+func readPackets(t *testing.T, data []byte) []*Packet {
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var packets []*Packet
+	for {
+		p, err := r.Next()
+		if err == io.EOF {
+			return packets
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		packets = append(packets, p)
+	}
+}
+
+func TestReadPcap(t *testing.T) {
+	ts := time.Unix(1500000000, 123456789)
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		for _, nanos := range []bool{false, true} {
+			f := newPcap(order, nanos, LinkEthernet)
+			f.packet(ts, []byte("abc")).packet(ts.Add(time.Second), []byte("de"))
+			packets := readPackets(t, f.Bytes())
+			if len(packets) != 2 {
+				t.Fatalf("error packets: %d", len(packets))
+			}
+			expected := ts
+			if !nanos {
+				expected = time.Unix(1500000000, 123456000)
+			}
+			p := packets[0]
+			if !p.Time.Equal(expected) || p.LinkType != LinkEthernet || string(p.Data) != "abc" {
+				t.Errorf("error packet %v/%v: %v", order, nanos, p)
+			}
+			if string(packets[1].Data) != "de" {
+				t.Errorf("error packet %v/%v: %v", order, nanos, packets[1])
+			}
+		}
+	}
+}
+
+func TestReadPcapng(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		f := newPcapng(order)
+		f.iface(LinkEthernet, -1).iface(LinkRaw, 9)
+		// a name resolution block, skipped
+		f.block(4, []byte{0, 0, 0, 0})
+		f.packet(0, 1500000000123456, []byte("abc"))
+		f.packet(1, 1500000000123456789, []byte("defg"))
+		packets := readPackets(t, f.Bytes())
+		if len(packets) != 2 {
+			t.Fatalf("error packets: %d", len(packets))
+		}
+		if p := packets[0]; !p.Time.Equal(time.Unix(1500000000, 123456000)) ||
+			p.LinkType != LinkEthernet || string(p.Data) != "abc" {
+			t.Errorf("error packet %v: %v", order, p)
+		}
+		if p := packets[1]; !p.Time.Equal(time.Unix(1500000000, 123456789)) ||
+			p.LinkType != LinkRaw || string(p.Data) != "defg" {
+			t.Errorf("error packet %v: %v", order, p)
+		}
+	}
+}
+
+func TestResolution(t *testing.T) {
+	if resolution(6) != 1000000 || resolution(0) != 1 || resolution(0x80|10) != 1024 {
+		t.Error("error resolution")
+	}
+	i := iface{units: 1024}
+	if p := i.packet(1024*3+512, nil); !p.Time.Equal(time.Unix(3, int64(500*time.Millisecond))) {
+		t.Errorf("error binary resolution: %v", p.Time)
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	if _, err := NewReader(bytes.NewReader(nil)); err != ErrInvalidFormat {
+		t.Errorf("error empty file error: %v", err)
+	}
+	if _, err := NewReader(bytes.NewReader(make([]byte, 24))); err != ErrInvalidFormat {
+		t.Errorf("error unknown magic error: %v", err)
+	}
+
+	f := newPcap(binary.LittleEndian, false, LinkEthernet)
+	f.packet(time.Unix(0, 0), []byte("abc"))
+	r, _ := NewReader(bytes.NewReader(f.Bytes()[:f.Len()-1]))
+	if _, err := r.Next(); err != ErrInvalidBlock {
+		t.Errorf("error truncated record error: %v", err)
+	}
+
+	ng := newPcapng(binary.LittleEndian)
+	ng.packet(0, 0, []byte("abc"))
+	r, _ = NewReader(bytes.NewReader(ng.Bytes()))
+	if _, err := r.Next(); err != ErrUnknownInterface {
+		t.Errorf("error unknown interface error: %v", err)
+	}
+
+	ng = newPcapng(binary.LittleEndian)
+	ng.iface(LinkEthernet, -1)
+	data := ng.Bytes()
+	// the trailing length differs from the leading one
+	data[len(data)-4]++
+	r, _ = NewReader(bytes.NewReader(data))
+	if _, err := r.Next(); err != ErrInvalidBlock {
+		t.Errorf("error invalid block error: %v", err)
+	}
+}
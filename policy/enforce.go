@@ -0,0 +1,35 @@
+package policy
+
+import (
+	"context"
+
+	"github.com/amyangfei/resp-go/proxy"
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// Middleware is a proxy middleware replying with a NOPERM error to the
+// commands breaking the policy, instead of forwarding them.
+func (p *Policy) Middleware(next proxy.Handler) proxy.Handler {
+	return proxy.HandlerFunc(func(ctx context.Context, cmd *resp.Message) (*resp.Message, error) {
+		if err := p.Check(cmd); err != nil {
+			return resp.Err(err), nil
+		}
+		return next.Handle(ctx, cmd)
+	})
+}
+
+// Hook returns a hook enforcing the policy on the commands of a
+// resp.Server, which replies with the NOPERM error instead of handling the
+// commands breaking it.
+func (p *Policy) Hook() resp.Hook {
+	return hook{p: p}
+}
+
+type hook struct {
+	resp.NopHook
+	p *Policy
+}
+
+func (h hook) BeforeProcess(ctx context.Context, cmd *resp.Message) (context.Context, error) {
+	return ctx, h.p.Check(cmd)
+}
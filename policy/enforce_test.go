@@ -0,0 +1,86 @@
+package policy
+
+import (
+	"net"
+	"testing"
+
+	"github.com/amyangfei/resp-go/proxy"
+	"github.com/amyangfei/resp-go/resp"
+	"github.com/amyangfei/resp-go/resptest"
+)
+
+var denyFlush = &Policy{Deny: []string{"FLUSHALL"}, Keys: []string{"app:*"}}
+
+func TestMiddleware(t *testing.T) {
+	backend := resptest.NewServer()
+	defer backend.Close()
+	p := &proxy.Proxy{
+		Backends:    []proxy.Backend{{Addr: backend.Addr()}},
+		Middlewares: []proxy.Middleware{denyFlush.Middleware},
+	}
+	defer p.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go p.Serve(l)
+	c, err := resp.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tests := []struct {
+		cmd  *resp.Message
+		want string
+	}{
+		{resp.Cmd("SET", "app:k", "v"), "OK"},
+		{resp.Cmd("SET", "other", "v"), "(error) NOPERM this user has no permissions to access one of the keys used as arguments"},
+		{resp.Cmd("FLUSHALL"), "(error) NOPERM this user has no permissions to run the 'flushall' command"},
+		{resp.Cmd("DBSIZE"), "(integer) 1"},
+	}
+	for _, test := range tests {
+		reply, err := c.Do(test.cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reply.String() != test.want {
+			t.Errorf("error reply: %s", reply)
+		}
+	}
+}
+
+func TestHook(t *testing.T) {
+	handled := 0
+	s := &resp.Server{
+		Handler: resp.HandlerFunc(func(c *resp.ServerConn, cmd *resp.Message) {
+			handled++
+			c.WriteMessage(resp.Str("OK"))
+		}),
+		Hooks: []resp.Hook{denyFlush.Hook()},
+	}
+	client, server := net.Pipe()
+	go s.ServeConn(server)
+	c := resp.NewConn(client)
+	defer c.Close()
+
+	// the NOPERM error is encoded as the reply
+	c.Send(resp.Cmd("FLUSHALL"))
+	c.Send(resp.Cmd("GET", "app:k"))
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := c.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := resp.Marshal(reply); string(b) != "-NOPERM this user has no permissions to run the 'flushall' command\r\n" {
+		t.Errorf("error encoded reply: %q", b)
+	}
+	if reply, err = c.Receive(); err != nil || reply.Status != "OK" {
+		t.Errorf("error reply: %v %v", reply, err)
+	}
+	if handled != 1 {
+		t.Errorf("error commands handled: %d", handled)
+	}
+}
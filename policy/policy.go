@@ -0,0 +1,245 @@
+// Package policy checks commands against a declarative policy before they
+// reach the server: commands allowed or denied, by name or subcommand, key
+// patterns the keys of the commands must match and limits on the size of
+// the arguments. Policies are written in JSON, such as:
+//
+//	{
+//		"deny": ["KEYS", "FLUSHALL", "DEBUG", "CONFIG SET"],
+//		"keys": ["tenant:{id}:*"],
+//		"max_arg_size": 1048576
+//	}
+//
+// YAML policies are out of scope: like the rest of the module, the package
+// only depends on the standard library, so they must be converted to JSON
+// before being parsed.
+//
+// Commands breaking the policy are replied with a NOPERM error, like the
+// ones of the redis ACLs.
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+var (
+	// ErrInvalidPolicy is returned when a policy holds an empty command or
+	// key pattern, or a negative limit
+	ErrInvalidPolicy = errors.New("policy: invalid policy")
+)
+
+// Policy is a set of rules commands must follow. The zero value allows any
+// command.
+type Policy struct {
+	// Allow lists the commands allowed, any command being allowed when
+	// empty. A command is given by its name, allowing all of its
+	// subcommands, or by its name and a subcommand such as "CONFIG GET".
+	Allow []string `json:"allow,omitempty"`
+	// Deny lists the commands denied, given like in Allow
+	Deny []string `json:"deny,omitempty"`
+	// Keys lists the glob-style patterns the keys used by the commands must
+	// match, any key being allowed when empty. The patterns may hold
+	// placeholders such as {id}, replaced by Bind. When set, the commands
+	// whose keys are not known, and SORT with BY or GET patterns, are
+	// denied.
+	Keys []string `json:"keys,omitempty"`
+	// MaxArgSize is the maximum size of an argument in bytes, unlimited
+	// when zero
+	MaxArgSize int `json:"max_arg_size,omitempty"`
+	// MaxArgs is the maximum number of arguments of a command, unlimited
+	// when zero
+	MaxArgs int `json:"max_args,omitempty"`
+}
+
+// Parse parses a policy written in JSON. Unknown fields are rejected, so
+// that a misspelled rule does not go unnoticed.
+func Parse(data []byte) (*Policy, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name := range fields {
+		if !policyFields[name] {
+			return nil, errors.New("policy: unknown field " + strconv.Quote(name))
+		}
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// policyFields are the JSON names of the fields of Policy.
+var policyFields = map[string]bool{
+	"allow": true, "deny": true, "keys": true, "max_arg_size": true, "max_args": true,
+}
+
+// Load reads and parses the policy file at path.
+func Load(path string) (*Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+func (p *Policy) validate() error {
+	if p.MaxArgSize < 0 || p.MaxArgs < 0 {
+		return ErrInvalidPolicy
+	}
+	for _, list := range [][]string{p.Allow, p.Deny, p.Keys} {
+		for _, s := range list {
+			if strings.TrimSpace(s) == "" {
+				return ErrInvalidPolicy
+			}
+		}
+	}
+	return nil
+}
+
+// Bind returns a copy of the policy with the placeholders of the key
+// patterns, such as {id}, replaced by the values of vars. The glob special
+// characters of the values are escaped, so that they match literally.
+func (p *Policy) Bind(vars map[string]string) *Policy {
+	bound := *p
+	bound.Keys = make([]string, len(p.Keys))
+	for i, pattern := range p.Keys {
+		for name, value := range vars {
+			pattern = strings.Replace(pattern, "{"+name+"}", escapePattern(value), -1)
+		}
+		bound.Keys[i] = pattern
+	}
+	return &bound
+}
+
+func escapePattern(s string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '*', '?', '[', ']', '\\':
+			buf.WriteByte('\\')
+		}
+		buf.WriteByte(s[i])
+	}
+	return buf.String()
+}
+
+// Violation is the error returned for a command breaking a policy. Its
+// message starts with the NOPERM error code, so that it can be replied as
+// is.
+type Violation struct {
+	// Command is the lower cased name of the command
+	Command string
+	// Rule is the rule broken: "command" for a command not allowed, "key"
+	// for a key not allowed, "max_arg_size", "max_args" or "protocol" for
+	// a message which is not a command, whose rules can not be checked
+	Rule string
+	// Key is the key not allowed, for the "key" rule
+	Key string
+	// Arg is the position of the argument too large, for the
+	// "max_arg_size" rule
+	Arg int
+}
+
+func (v *Violation) Error() string {
+	switch v.Rule {
+	case "key":
+		return "NOPERM this user has no permissions to access one of the keys used as arguments"
+	case "max_arg_size":
+		return "NOPERM argument " + strconv.Itoa(v.Arg) + " of the '" + v.Command + "' command is too large"
+	case "max_args":
+		return "NOPERM too many arguments for the '" + v.Command + "' command"
+	case "protocol":
+		return "NOPERM the command is not an array of strings"
+	}
+	return "NOPERM this user has no permissions to run the '" + v.Command + "' command"
+}
+
+// Check returns a *Violation if cmd, a command as decoded by a server,
+// breaks the policy, nil otherwise. Messages which are not a non-empty
+// array of strings break the "protocol" rule, so that no rule is skipped.
+func (p *Policy) Check(cmd *resp.Message) error {
+	if cmd == nil || cmd.Type != resp.ArrayHeader {
+		return &Violation{Rule: "protocol"}
+	}
+	args, err := cmd.Strings()
+	if err != nil || len(args) == 0 {
+		return &Violation{Rule: "protocol"}
+	}
+	name := strings.ToUpper(args[0])
+	sub := ""
+	if len(args) > 1 {
+		sub = name + " " + strings.ToUpper(args[1])
+	}
+	v := &Violation{Command: strings.ToLower(args[0]), Rule: "command"}
+	if len(p.Allow) > 0 && !listed(p.Allow, name, sub) {
+		return v
+	}
+	if listed(p.Deny, name, sub) {
+		return v
+	}
+	if p.MaxArgs > 0 && len(args)-1 > p.MaxArgs {
+		v.Rule = "max_args"
+		return v
+	}
+	if p.MaxArgSize > 0 {
+		for i, arg := range args[1:] {
+			if len(arg) > p.MaxArgSize {
+				v.Rule, v.Arg = "max_arg_size", i+1
+				return v
+			}
+		}
+	}
+	if len(p.Keys) > 0 {
+		// the keys of an unknown command can not be checked, and the
+		// patterns of SORT read the keys they match
+		keys, ok := resp.KnownCommandKeys(args)
+		if !ok {
+			return v
+		}
+		if name == "SORT" || name == "SORT_RO" {
+			if patterns := resp.SortPatterns(args); len(patterns) > 0 {
+				v.Rule, v.Key = "key", patterns[0]
+				return v
+			}
+		}
+		for _, key := range keys {
+			if !p.keyAllowed(key) {
+				v.Rule, v.Key = "key", key
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Policy) keyAllowed(key string) bool {
+	for _, pattern := range p.Keys {
+		if resp.MatchPattern(pattern, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// listed reports whether the command, given by its upper cased name and
+// subcommand, is in list.
+func listed(list []string, name, sub string) bool {
+	for _, s := range list {
+		s = strings.ToUpper(strings.Join(strings.Fields(s), " "))
+		if s == name || (sub != "" && s == sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+package policy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+func TestLoad(t *testing.T) {
+	p, err := Load("testdata/tenant.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Deny) != 5 || p.Deny[4] != "config set" || len(p.Keys) != 2 ||
+		p.MaxArgSize != 16 || p.MaxArgs != 8 || len(p.Allow) != 0 {
+		t.Errorf("error policy: %+v", p)
+	}
+	if _, err := Load("testdata/missing.json"); err == nil {
+		t.Error("error loading a missing file should fail")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, data := range []string{
+		`{"deny": ["KEYS"], "key": ["a:*"]}`,
+		`{"deny": [""]}`,
+		`{"keys": [" "]}`,
+		`{"max_args": -1}`,
+		`{"deny": "KEYS"}`,
+		`[]`,
+	} {
+		if p, err := Parse([]byte(data)); err == nil {
+			t.Errorf("error parsing %s should fail: %+v", data, p)
+		}
+	}
+	if _, err := Parse([]byte(`{"max_arg_size": -1}`)); err != ErrInvalidPolicy {
+		t.Errorf("should return ErrInvalidPolicy, not: %v", err)
+	}
+}
+
+func TestPolicyFields(t *testing.T) {
+	typ := reflect.TypeOf(Policy{})
+	for i := 0; i < typ.NumField(); i++ {
+		name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+		if !policyFields[name] {
+			t.Errorf("error field %s missing from policyFields", name)
+		}
+	}
+	if len(policyFields) != typ.NumField() {
+		t.Errorf("error policyFields: %v", policyFields)
+	}
+	if _, err := Parse([]byte(`{"Keys": ["a:*"]}`)); err == nil {
+		t.Error("error parsing a field in another case should fail")
+	}
+}
+
+func TestBind(t *testing.T) {
+	p := &Policy{Keys: []string{"tenant:{id}:*", "{id}:{region}", "shared:*"}}
+	bound := p.Bind(map[string]string{"id": "4*2", "region": "eu"})
+	want := []string{`tenant:4\*2:*`, `4\*2:eu`, "shared:*"}
+	if strings.Join(bound.Keys, " ") != strings.Join(want, " ") {
+		t.Errorf("error bound patterns: %q", bound.Keys)
+	}
+	if p.Keys[0] != "tenant:{id}:*" {
+		t.Errorf("error original policy modified: %q", p.Keys)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	p, err := Load("testdata/tenant.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p = p.Bind(map[string]string{"id": "42"})
+	tests := []struct {
+		cmd  string
+		rule string
+		key  string
+	}{
+		{"GET tenant:42:user", "", ""},
+		{"get shared:config", "", ""},
+		{"MSET tenant:42:a 1 shared:b 2", "", ""},
+		{"PING", "", ""},
+		{"CONFIG GET maxmemory", "", ""},
+		{"keys *", "command", ""},
+		{"FLUSHALL", "command", ""},
+		{"Config Set maxmemory 1", "command", ""},
+		{"DEBUG SLEEP 1", "command", ""},
+		{"GET tenant:43:user", "key", "tenant:43:user"},
+		{"MSET tenant:42:a 1 other 2", "key", "other"},
+		{"EVAL script 2 tenant:42:a tenant:7:b", "key", "tenant:7:b"},
+		{"MEMORY USAGE tenant:42:a", "", ""},
+		{"MEMORY USAGE other:key", "key", "other:key"},
+		{"LCS tenant:42:a tenant:42:b", "", ""},
+		{"LCS tenant:42:a other", "key", "other"},
+		{"BITOP OR tenant:42:d tenant:42:a other", "key", "other"},
+		{"SORT tenant:42:a LIMIT 0 5 STORE tenant:42:d", "", ""},
+		{"SORT tenant:42:a STORE other", "key", "other"},
+		{"SORT tenant:42:a BY other:* GET #", "key", "other:*"},
+		{"SORT_RO tenant:42:a GET other:*", "key", "other:*"},
+		{"GEORADIUS tenant:42:a 15 37 200 km STORE tenant:42:d", "", ""},
+		{"GEORADIUS tenant:42:a 15 37 200 km STOREDIST other", "key", "other"},
+		{"GEOSEARCHSTORE other tenant:42:a FROMMEMBER m BYRADIUS 1 km", "key", "other"},
+		{"XREAD COUNT 10 STREAMS tenant:42:s 0", "", ""},
+		{"XREAD BLOCK 0 STREAMS tenant:42:s other $ $", "key", "other"},
+		{"XREADGROUP GROUP g c COUNT 1 STREAMS tenant:42:s >", "", ""},
+		{"XREADGROUP GROUP g c STREAMS other >", "key", "other"},
+		{"UNKNOWN tenant:42:a other", "command", ""},
+		{"SET tenant:42:a 01234567890123456789", "max_arg_size", ""},
+		{"DEL tenant:42:1 tenant:42:2 tenant:42:3 tenant:42:4 tenant:42:5 tenant:42:6 tenant:42:7 tenant:42:8 tenant:42:9", "max_args", ""},
+	}
+	for _, test := range tests {
+		args := strings.Fields(test.cmd)
+		err := p.Check(resp.Cmd(args[0], strArgs(args[1:])...))
+		if test.rule == "" {
+			if err != nil {
+				t.Errorf("error %q should be allowed: %v", test.cmd, err)
+			}
+			continue
+		}
+		v, ok := err.(*Violation)
+		if !ok || v.Rule != test.rule || v.Key != test.key {
+			t.Errorf("error violation of %q: %#v", test.cmd, err)
+		}
+	}
+}
+
+func strArgs(args []string) []interface{} {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg
+	}
+	return values
+}
+
+func TestCheckAllow(t *testing.T) {
+	p := &Policy{Allow: []string{"GET", "SET", "CLIENT  SETNAME"}, Deny: []string{"SET"}}
+	for cmd, allowed := range map[string]bool{
+		"GET a":            true,
+		"SET a 1":          false,
+		"DEL a":            false,
+		"CLIENT SETNAME x": true,
+		"CLIENT KILL x":    false,
+	} {
+		args := strings.Fields(cmd)
+		if err := p.Check(resp.Cmd(args[0], strArgs(args[1:])...)); (err == nil) != allowed {
+			t.Errorf("error checking %q: %v", cmd, err)
+		}
+	}
+	var zero Policy
+	if err := zero.Check(resp.Cmd("FLUSHALL")); err != nil {
+		t.Errorf("error zero policy should allow any command: %v", err)
+	}
+}
+
+func TestCheckProtocol(t *testing.T) {
+	nested := resp.Cmd("GET")
+	nested.Array = append(nested.Array, resp.Cmd("x"))
+	var nilArray resp.Message
+	nilArray.SetNil()
+	nilArray.Type = resp.ArrayHeader
+	for _, cmd := range []*resp.Message{
+		nil,
+		resp.Str("PING"),
+		resp.Int(1),
+		&nilArray,
+		resp.Cmd("GET").Array[0],
+		{Type: resp.ArrayHeader},
+		nested,
+	} {
+		var zero Policy
+		for _, p := range []*Policy{&zero, {Keys: []string{"*"}}} {
+			v, ok := p.Check(cmd).(*Violation)
+			if !ok || v.Rule != "protocol" {
+				t.Errorf("error checking %v: %#v", cmd, v)
+			}
+		}
+	}
+}
+
+func TestViolationError(t *testing.T) {
+	tests := []struct {
+		v    *Violation
+		want string
+	}{
+		{&Violation{Command: "keys", Rule: "command"}, "NOPERM this user has no permissions to run the 'keys' command"},
+		{&Violation{Command: "get", Rule: "key", Key: "a"}, "NOPERM this user has no permissions to access one of the keys used as arguments"},
+		{&Violation{Command: "set", Rule: "max_arg_size", Arg: 2}, "NOPERM argument 2 of the 'set' command is too large"},
+		{&Violation{Command: "del", Rule: "max_args"}, "NOPERM too many arguments for the 'del' command"},
+		{&Violation{Rule: "protocol"}, "NOPERM the command is not an array of strings"},
+	}
+	for _, test := range tests {
+		if got := test.v.Error(); got != test.want {
+			t.Errorf("error violation message: %s", got)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package proxy
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// uncompared lists the commands whose replies differ from a server to
+// another by design: random, time or server dependent replies and scan
+// cursors.
+var uncompared = map[string]bool{
+	"RANDOMKEY":   true,
+	"SRANDMEMBER": true,
+	"SPOP":        true,
+	"HRANDFIELD":  true,
+	"ZRANDMEMBER": true,
+	"TIME":        true,
+	"INFO":        true,
+	"CLIENT":      true,
+	"CONFIG":      true,
+	"MEMORY":      true,
+	"OBJECT":      true,
+	"DEBUG":       true,
+	"SCAN":        true,
+	"SSCAN":       true,
+	"HSCAN":       true,
+	"ZSCAN":       true,
+}
+
+// ordering tells how the elements of an array reply are compared.
+type ordering int
+
+const (
+	ordered ordering = iota
+	// unorderedElems compares the elements in any order
+	unorderedElems
+	// unorderedPairs compares the pairs of elements in any order, the
+	// elements of a pair being kept together
+	unorderedPairs
+)
+
+// unordered lists the commands replying with an array whose order is not
+// defined, in RESP2, RESP3 sets and maps being unordered whatever the
+// command.
+var unordered = map[string]ordering{
+	"SMEMBERS": unorderedElems,
+	"SINTER":   unorderedElems,
+	"SUNION":   unorderedElems,
+	"SDIFF":    unorderedElems,
+	"KEYS":     unorderedElems,
+	"HKEYS":    unorderedElems,
+	"HVALS":    unorderedElems,
+	"HGETALL":  unorderedPairs,
+}
+
+// Equivalent reports whether two replies to cmd, from different servers,
+// are the same for a client. It is less strict than resp.Equal:
+//
+// The elements of the replies whose order is not defined, such as the
+// members of SMEMBERS or the fields of HGETALL, may come in any order.
+//
+// Floating point numbers, given as doubles or as strings holding a number
+// which is not an integer, are equal when they differ by rounding only, so
+// that "0.30000000000000004" is equal to "0.3" or "10" to "10.0".
+//
+// Errors are equal when they have the same code, their first word such as
+// ERR or WRONGTYPE, as the messages vary between versions.
+//
+// Nil bulk strings, nil arrays and RESP3 nulls are equal.
+//
+// The replies to the commands whose result depends on the server, such as
+// TIME, INFO, RANDOMKEY or SCAN, are always equivalent.
+func Equivalent(cmd, a, b *resp.Message) bool {
+	name := CommandName(cmd)
+	if uncompared[name] {
+		return true
+	}
+	return equivalent(a, b, unordered[name])
+}
+
+func equivalent(a, b *resp.Message, order ordering) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.IsNil || b.IsNil {
+		return a.IsNil == b.IsNil
+	}
+	if x, ok := float(a); ok {
+		if y, ok := float(b); ok && (isFloat(a) || isFloat(b)) {
+			return floatEqual(x, y)
+		}
+	}
+	if a.Type != b.Type {
+		return false
+	}
+	switch a.Type {
+	case resp.ErrorHeader:
+		return errorCode(a) == errorCode(b)
+	case resp.ArrayHeader, resp.SetHeader, resp.MapHeader, resp.PushHeader:
+		if len(a.Array) != len(b.Array) {
+			return false
+		}
+		switch {
+		case a.Type == resp.SetHeader:
+			order = unorderedElems
+		case a.Type == resp.MapHeader:
+			order = unorderedPairs
+		}
+		step := 1
+		if order == unorderedPairs {
+			step = 2
+			if len(a.Array)%2 != 0 {
+				return false
+			}
+		}
+		x, y := a.Array, b.Array
+		if order != ordered {
+			x, y = sortedElems(x, step), sortedElems(y, step)
+		}
+		for i := range x {
+			if !equivalent(x[i], y[i], ordered) {
+				return false
+			}
+		}
+		return true
+	}
+	return resp.Equal(a, b)
+}
+
+// float returns the number held by a double or a string message.
+func float(m *resp.Message) (float64, bool) {
+	var text string
+	switch m.Type {
+	case resp.DoubleHeader, resp.StringHeader:
+		text = m.Status
+	case resp.BulkHeader:
+		text = string(m.Bytes)
+	default:
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	return f, err == nil
+}
+
+// isFloat reports whether m is a double, or a string holding a number which
+// is not written as an integer.
+func isFloat(m *resp.Message) bool {
+	if m.Type == resp.DoubleHeader {
+		return true
+	}
+	text := m.Status
+	if m.Type == resp.BulkHeader {
+		text = string(m.Bytes)
+	}
+	_, err := strconv.ParseInt(text, 10, 64)
+	return err != nil
+}
+
+func floatEqual(x, y float64) bool {
+	if x == y || (math.IsNaN(x) && math.IsNaN(y)) {
+		return true
+	}
+	return math.Abs(x-y) <= 1e-9*math.Max(math.Abs(x), math.Abs(y))
+}
+
+// errorCode returns the first word of an error reply.
+func errorCode(m *resp.Message) string {
+	if m.Error == nil {
+		return ""
+	}
+	code := m.Error.Error()
+	if i := strings.IndexByte(code, ' '); i >= 0 {
+		code = code[:i]
+	}
+	return code
+}
+
+// sortedElems returns a copy of elems with its groups of step elements
+// sorted by the encoding of their first element.
+func sortedElems(elems []*resp.Message, step int) []*resp.Message {
+	groups := make(elemGroups, 0, len(elems)/step)
+	for i := 0; i+step <= len(elems); i += step {
+		key, _ := resp.Marshal(elems[i])
+		groups = append(groups, elemGroup{key, elems[i : i+step]})
+	}
+	sort.Sort(groups)
+	sorted := make([]*resp.Message, 0, len(elems))
+	for _, g := range groups {
+		sorted = append(sorted, g.elems...)
+	}
+	return sorted
+}
+
+type elemGroup struct {
+	key   []byte
+	elems []*resp.Message
+}
+
+type elemGroups []elemGroup
+
+func (g elemGroups) Len() int           { return len(g) }
+func (g elemGroups) Less(i, j int) bool { return bytes.Compare(g[i].key, g[j].key) < 0 }
+func (g elemGroups) Swap(i, j int)      { g[i], g[j] = g[j], g[i] }
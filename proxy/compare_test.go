@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+func bulks(elems ...string) *resp.Message {
+	m := resp.Array()
+	for _, e := range elems {
+		m.Array = append(m.Array, resp.Bulk([]byte(e)))
+	}
+	return m
+}
+
+func TestEquivalent(t *testing.T) {
+	set := func(elems ...string) *resp.Message {
+		m := bulks(elems...)
+		m.Type = resp.SetHeader
+		return m
+	}
+	double := func(s string) *resp.Message {
+		return &resp.Message{Type: resp.DoubleHeader, Status: s}
+	}
+	tests := []struct {
+		cmd  *resp.Message
+		a, b *resp.Message
+		want bool
+	}{
+		{resp.Cmd("GET", "k"), resp.Bulk([]byte("v")), resp.Bulk([]byte("v")), true},
+		{resp.Cmd("GET", "k"), resp.Bulk([]byte("v")), resp.Bulk([]byte("w")), false},
+		{resp.Cmd("GET", "k"), resp.Bulk([]byte("1")), resp.Bulk([]byte("01")), false},
+		{resp.Cmd("GET", "k"), resp.Nil(), resp.NilArray(), true},
+		{resp.Cmd("GET", "k"), resp.Nil(), resp.Bulk(nil), false},
+		{resp.Cmd("INCR", "k"), resp.Int(1), resp.Bulk([]byte("1")), false},
+		{resp.Cmd("SMEMBERS", "s"), bulks("a", "b", "c"), bulks("c", "a", "b"), true},
+		{resp.Cmd("SMEMBERS", "s"), bulks("a", "b", "c"), bulks("c", "a", "a"), false},
+		{resp.Cmd("LRANGE", "l", 0, -1), bulks("a", "b"), bulks("b", "a"), false},
+		{resp.Cmd("HGETALL", "h"), bulks("f1", "1", "f2", "2"), bulks("f2", "2", "f1", "1"), true},
+		{resp.Cmd("HGETALL", "h"), bulks("f1", "1", "f2", "2"), bulks("f1", "2", "f2", "1"), false},
+		{resp.Cmd("SINTER", "a", "b"), set("x", "y"), set("y", "x"), true},
+		{resp.Cmd("ZSCORE", "z", "m"), resp.Bulk([]byte("0.30000000000000004")), resp.Bulk([]byte("0.3")), true},
+		{resp.Cmd("ZSCORE", "z", "m"), resp.Bulk([]byte("0.31")), resp.Bulk([]byte("0.3")), false},
+		{resp.Cmd("INCRBYFLOAT", "k", 1), resp.Bulk([]byte("10")), resp.Bulk([]byte("10.0")), true},
+		{resp.Cmd("ZSCORE", "z", "m"), double("inf"), resp.Bulk([]byte("inf")), true},
+		{resp.Cmd("ZRANGE", "z", 0, -1, "WITHSCORES"), bulks("a", "1.5", "b", "2"), bulks("a", "1.50", "b", "2"), true},
+		{resp.Cmd("LPUSH", "k", "x"), resp.Err(errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")),
+			resp.Err(errors.New("WRONGTYPE wrong type")), true},
+		{resp.Cmd("LPUSH", "k", "x"), resp.Err(errors.New("ERR syntax error")),
+			resp.Err(errors.New("WRONGTYPE wrong type")), false},
+		{resp.Cmd("TIME"), bulks("1700000000", "1"), bulks("1700000001", "2"), true},
+		{resp.Cmd("SCAN", 0), resp.Array(resp.Bulk([]byte("12")), bulks("a")), resp.Array(resp.Bulk([]byte("0")), bulks("a")), true},
+	}
+	for _, test := range tests {
+		if got := Equivalent(test.cmd, test.a, test.b); got != test.want {
+			t.Errorf("error Equivalent(%s, %v, %v): %v", commandLine(test.cmd), test.a, test.b, got)
+		}
+	}
+}
+
+func TestEquivalentMap(t *testing.T) {
+	a := bulks("f1", "1", "f2", "2")
+	a.Type = resp.MapHeader
+	b := bulks("f2", "2", "f1", "1")
+	b.Type = resp.MapHeader
+	// maps are unordered whatever the command
+	if !Equivalent(resp.Cmd("CUSTOM.GET"), a, b) {
+		t.Error("error maps with fields in another order should be equivalent")
+	}
+	if Equivalent(resp.Cmd("CUSTOM.GET"), a, bulks("f1", "1", "f2", "2")) {
+		t.Error("error map and array should not be equivalent")
+	}
+}
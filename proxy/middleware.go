@@ -0,0 +1,91 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// Handler processes a command received by the proxy and returns its reply.
+// Error replies are returned as messages, the returned error reporting a
+// failure to get a reply, such as a broken backend connection.
+type Handler interface {
+	Handle(ctx context.Context, cmd *resp.Message) (*resp.Message, error)
+}
+
+// HandlerFunc adapts a function to the Handler interface.
+type HandlerFunc func(ctx context.Context, cmd *resp.Message) (*resp.Message, error)
+
+// Handle calls f(ctx, cmd).
+func (f HandlerFunc) Handle(ctx context.Context, cmd *resp.Message) (*resp.Message, error) {
+	return f(ctx, cmd)
+}
+
+// Middleware wraps the handler of the next middleware in the chain, the
+// last one wrapping the handler forwarding commands to the backends. A
+// middleware can inspect or rewrite the command before calling next,
+// inspect or rewrite the reply it returns, or return a reply of its own
+// without calling next, to block or answer a command.
+type Middleware func(next Handler) Handler
+
+// Chain returns h wrapped by the middlewares, the first one being the first
+// to see the commands and the last to see the replies.
+func Chain(h Handler, middlewares ...Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// Block returns a middleware replying with an error to the commands with
+// one of the given names, compared case insensitively, instead of
+// forwarding them.
+func Block(names ...string) Middleware {
+	blocked := make(map[string]bool, len(names))
+	for _, name := range names {
+		blocked[strings.ToUpper(name)] = true
+	}
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, cmd *resp.Message) (*resp.Message, error) {
+			if name := CommandName(cmd); blocked[name] {
+				return resp.Err(errors.New("ERR command '" + strings.ToLower(name) + "' is blocked by the proxy")), nil
+			}
+			return next.Handle(ctx, cmd)
+		})
+	}
+}
+
+// CommandName returns the upper cased name of a command, or an empty string
+// if cmd is not an array.
+func CommandName(cmd *resp.Message) string {
+	args := Args(cmd)
+	if len(args) == 0 {
+		return ""
+	}
+	return string(bytes.ToUpper(args[0]))
+}
+
+// Args returns the name and arguments of a command, or nil if cmd is not an
+// array of strings.
+func Args(cmd *resp.Message) [][]byte {
+	if cmd == nil || cmd.Type != resp.ArrayHeader {
+		return nil
+	}
+	args := make([][]byte, 0, len(cmd.Array))
+	for _, arg := range cmd.Array {
+		switch {
+		case arg == nil:
+			return nil
+		case arg.Type == resp.BulkHeader:
+			args = append(args, arg.Bytes)
+		case arg.Type == resp.StringHeader:
+			args = append(args, []byte(arg.Status))
+		default:
+			return nil
+		}
+	}
+	return args
+}
@@ -0,0 +1,146 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/amyangfei/resp-go/resp"
+	"github.com/amyangfei/resp-go/resptest"
+)
+
+func TestChainOrder(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return HandlerFunc(func(ctx context.Context, cmd *resp.Message) (*resp.Message, error) {
+				calls = append(calls, name+" in")
+				reply, err := next.Handle(ctx, cmd)
+				calls = append(calls, name+" out")
+				return reply, err
+			})
+		}
+	}
+	h := Chain(HandlerFunc(func(ctx context.Context, cmd *resp.Message) (*resp.Message, error) {
+		calls = append(calls, "handler")
+		return resp.Str("OK"), nil
+	}), trace("a"), trace("b"))
+
+	if reply, err := h.Handle(context.Background(), resp.Cmd("PING")); err != nil || reply.Status != "OK" {
+		t.Errorf("error reply: %v %v", reply, err)
+	}
+	if got := strings.Join(calls, ", "); got != "a in, b in, handler, b out, a out" {
+		t.Errorf("error calls order: %s", got)
+	}
+}
+
+// prefixKeys rewrites the key of the commands and removes the prefix from
+// the KEYS replies, as a namespacing proxy would.
+func prefixKeys(prefix string) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, cmd *resp.Message) (*resp.Message, error) {
+			args := Args(cmd)
+			if CommandName(cmd) == "KEYS" {
+				reply, err := next.Handle(ctx, resp.Cmd("KEYS", prefix+string(args[1])))
+				if err != nil || reply.Type != resp.ArrayHeader {
+					return reply, err
+				}
+				for _, key := range reply.Array {
+					key.Bytes = bytes.TrimPrefix(key.Bytes, []byte(prefix))
+				}
+				return reply, nil
+			}
+			if len(args) > 1 {
+				rewritten := resp.Cmd(string(args[0]), prefix+string(args[1]))
+				for _, arg := range args[2:] {
+					rewritten.Arg(arg)
+				}
+				cmd = rewritten
+			}
+			return next.Handle(ctx, cmd)
+		})
+	}
+}
+
+func TestMiddlewareRewrite(t *testing.T) {
+	backend := resptest.NewServer()
+	defer backend.Close()
+	p := &Proxy{
+		Backends:    []Backend{{Addr: backend.Addr()}},
+		Middlewares: []Middleware{prefixKeys("tenant:")},
+	}
+	defer p.Close()
+	c := serve(t, p)
+	defer c.Close()
+
+	c.Do(resp.Cmd("SET", "k", "v"))
+	if reply, err := c.Do(resp.Cmd("KEYS", "*")); err != nil || reply.String() != `1) "k"` {
+		t.Errorf("error KEYS reply through the proxy: %v %v", reply, err)
+	}
+
+	direct, err := resp.Dial("tcp", backend.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer direct.Close()
+	if reply, err := direct.Do(resp.Cmd("GET", "tenant:k")); err != nil || string(reply.Bytes) != "v" {
+		t.Errorf("error GET reply on the backend: %v %v", reply, err)
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	// without backend, only the commands answered by the middlewares work
+	p := &Proxy{Middlewares: []Middleware{
+		Block("flushall", "CONFIG"),
+		func(next Handler) Handler {
+			return HandlerFunc(func(ctx context.Context, cmd *resp.Message) (*resp.Message, error) {
+				if CommandName(cmd) == "PING" {
+					return resp.Str("PONG"), nil
+				}
+				if Client(ctx) == nil {
+					return nil, errors.New("no client")
+				}
+				return next.Handle(ctx, cmd)
+			})
+		},
+	}}
+	defer p.Close()
+	c := serve(t, p)
+	defer c.Close()
+
+	tests := []struct {
+		cmd  *resp.Message
+		want string
+	}{
+		{resp.Cmd("ping"), "PONG"},
+		{resp.Cmd("FlushAll"), "(error) ERR command 'flushall' is blocked by the proxy"},
+		{resp.Cmd("CONFIG", "SET", "x", "y"), "(error) ERR command 'config' is blocked by the proxy"},
+		{resp.Cmd("GET", "k"), "(error) ERR " + ErrNoBackend.Error()},
+	}
+	for _, test := range tests {
+		reply, err := c.Do(test.cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reply.String() != test.want {
+			t.Errorf("error reply to %s: %s", CommandName(test.cmd), reply)
+		}
+	}
+}
+
+func TestArgs(t *testing.T) {
+	cmd := resp.Array(resp.Str("get"), resp.Bulk([]byte("k")))
+	if args := Args(cmd); len(args) != 2 || string(args[0]) != "get" || string(args[1]) != "k" {
+		t.Errorf("error args: %q", args)
+	}
+	if name := CommandName(cmd); name != "GET" {
+		t.Errorf("error command name: %s", name)
+	}
+	for _, cmd := range []*resp.Message{nil, resp.Str("PING"), resp.Array(resp.Int(1))} {
+		if Args(cmd) != nil || CommandName(cmd) != "" {
+			t.Errorf("error args of invalid command: %v", cmd)
+		}
+	}
+}
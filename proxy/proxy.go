@@ -0,0 +1,260 @@
+// Package proxy implements a RESP proxy: it accepts client connections,
+// forwards their commands to one or more backend servers and writes the
+// replies back, a chain of middlewares being able to inspect, rewrite,
+// block or answer any command or reply on the way.
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"hash/crc32"
+	"net"
+	"sync"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+var (
+	// ErrNoBackend is returned when a command is routed to a backend which
+	// does not exist
+	ErrNoBackend = errors.New("proxy: no backend for command")
+
+	// ErrUnsupported is returned for the commands which can not be proxied,
+	// as their replies are not one reply per command
+	ErrUnsupported = errors.New("proxy: command not supported")
+
+	// ErrNoClient is returned when a command is forwarded with a context
+	// which does not come from a client connection of the proxy
+	ErrNoClient = errors.New("proxy: no client connection in context")
+)
+
+// unsupported lists the commands which do not get exactly one reply: those
+// switching the connection to a mode where the server pushes messages, which
+// the proxy does not relay, and the unsubscriptions, replied once per
+// channel even when none is subscribed.
+var unsupported = map[string]bool{
+	"SUBSCRIBE":    true,
+	"PSUBSCRIBE":   true,
+	"SSUBSCRIBE":   true,
+	"UNSUBSCRIBE":  true,
+	"PUNSUBSCRIBE": true,
+	"SUNSUBSCRIBE": true,
+	"MONITOR":      true,
+	"SYNC":         true,
+	"PSYNC":        true,
+}
+
+// isUnsupported reports whether the command can not be proxied: the
+// unsupported commands, CLIENT REPLY, which turns off or skips replies, and
+// HELLO switching to RESP3, which enables push messages.
+func isUnsupported(cmd *resp.Message) bool {
+	name := CommandName(cmd)
+	if unsupported[name] {
+		return true
+	}
+	args := Args(cmd)
+	switch name {
+	case "CLIENT":
+		return len(args) > 1 && bytes.EqualFold(args[1], []byte("REPLY"))
+	case "HELLO":
+		return len(args) > 1 && string(args[1]) != "2"
+	}
+	return false
+}
+
+// Backend is a server the proxy forwards commands to.
+type Backend struct {
+	// Addr is the TCP address of the server
+	Addr string
+	// Dial connects to the server, instead of dialing Addr, if not nil
+	Dial func(ctx context.Context) (*resp.Conn, error)
+}
+
+func (b *Backend) dial(ctx context.Context) (*resp.Conn, error) {
+	if b.Dial != nil {
+		return b.Dial(ctx)
+	}
+	return resp.DialContext(ctx, "tcp", b.Addr)
+}
+
+// Proxy forwards the commands of its clients to the backends. Each client
+// gets its own connection to each backend, dialed on its first command
+// routed there, so that connection state such as the selected database or
+// a transaction is kept as if the client was connected to the backend.
+//
+// Pipelined commands are read together and their replies written back at
+// once, each command going through the middlewares and being forwarded in
+// turn. Commands which make the server push messages, such as SUBSCRIBE or
+// MONITOR, or which do not get exactly one reply, such as UNSUBSCRIBE or
+// CLIENT REPLY, are replied with an error.
+type Proxy struct {
+	// Backends are the servers the commands are forwarded to
+	Backends []Backend
+	// Route returns the index of the backend a command is forwarded to. The
+	// commands are forwarded to the first backend when nil.
+	Route func(cmd *resp.Message) int
+	// Middlewares wrap the handler forwarding the commands, the first one
+	// seeing the commands first
+	Middlewares []Middleware
+	// Hooks and Metrics are passed to the server accepting the clients
+	Hooks   []resp.Hook
+	Metrics resp.Metrics
+
+	once     sync.Once
+	handler  Handler
+	srv      *resp.Server
+	mu       sync.Mutex
+	sessions map[*resp.ServerConn]*session
+}
+
+func (p *Proxy) init() {
+	p.once.Do(func() {
+		p.handler = Chain(HandlerFunc(p.forward), p.Middlewares...)
+		p.srv = &resp.Server{Handler: p, Hooks: p.Hooks, Metrics: p.Metrics}
+	})
+}
+
+// Serve accepts client connections on l and serves each of them in a new
+// goroutine. It returns resp.ErrServerClosed once the proxy is closed.
+func (p *Proxy) Serve(l net.Listener) error {
+	p.init()
+	return p.srv.Serve(l)
+}
+
+// ServeConn serves a single client connection until it is closed.
+func (p *Proxy) ServeConn(conn net.Conn) error {
+	p.init()
+	return p.srv.ServeConn(conn)
+}
+
+// Close closes the listeners and the client connections of the proxy, and
+// the backend connections along with them.
+func (p *Proxy) Close() error {
+	p.init()
+	return p.srv.Close()
+}
+
+// ServeRESP implements resp.Handler, so that a proxy can be served by a
+// resp.Server of its own. Failures to get a reply are replied as errors.
+func (p *Proxy) ServeRESP(c *resp.ServerConn, cmd *resp.Message) {
+	p.init()
+	ctx := context.WithValue(c.Context(), clientKey{}, c)
+	reply, err := p.handler.Handle(ctx, cmd)
+	if err != nil {
+		reply = resp.Err(errors.New("ERR " + err.Error()))
+	} else if reply == nil {
+		reply = resp.Nil()
+	}
+	c.WriteMessage(reply)
+}
+
+type clientKey struct{}
+
+// Client returns the client connection whose command is being handled, or
+// nil if ctx does not come from the proxy.
+func Client(ctx context.Context) *resp.ServerConn {
+	c, _ := ctx.Value(clientKey{}).(*resp.ServerConn)
+	return c
+}
+
+// forward is the handler at the end of the chain, sending the command to
+// its backend on the connection of the client.
+func (p *Proxy) forward(ctx context.Context, cmd *resp.Message) (*resp.Message, error) {
+	c := Client(ctx)
+	if c == nil {
+		return nil, ErrNoClient
+	}
+	if isUnsupported(cmd) {
+		return nil, ErrUnsupported
+	}
+	i := 0
+	if p.Route != nil {
+		i = p.Route(cmd)
+	}
+	if i < 0 || i >= len(p.Backends) {
+		return nil, ErrNoBackend
+	}
+	return p.session(c).do(ctx, &p.Backends[i], i, cmd)
+}
+
+// session returns the backend connections of a client, closed once the
+// client connection is.
+func (p *Proxy) session(c *resp.ServerConn) *session {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if s := p.sessions[c]; s != nil {
+		return s
+	}
+	if p.sessions == nil {
+		p.sessions = make(map[*resp.ServerConn]*session)
+	}
+	s := &session{conns: make([]*resp.Conn, len(p.Backends))}
+	p.sessions[c] = s
+	go func() {
+		<-c.Context().Done()
+		p.mu.Lock()
+		delete(p.sessions, c)
+		p.mu.Unlock()
+		s.close()
+	}()
+	return s
+}
+
+// session holds the backend connections of a client. The lock is held
+// while a command is forwarded, so that closing the session, which happens
+// once the client context is canceled, waits for the command to be
+// interrupted.
+type session struct {
+	mu     sync.Mutex
+	conns  []*resp.Conn
+	closed bool
+}
+
+func (s *session) do(ctx context.Context, b *Backend, i int, cmd *resp.Message) (*resp.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, resp.ErrConnClosed
+	}
+	conn := s.conns[i]
+	if conn == nil {
+		var err error
+		if conn, err = b.dial(ctx); err != nil {
+			return nil, err
+		}
+		s.conns[i] = conn
+	}
+	reply, err := conn.DoContext(ctx, cmd)
+	if err != nil {
+		// a broken connection is dialed again by the next command
+		conn.Close()
+		s.conns[i] = nil
+	}
+	return reply, err
+}
+
+func (s *session) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for i, conn := range s.conns {
+		if conn != nil {
+			conn.Close()
+			s.conns[i] = nil
+		}
+	}
+}
+
+// HashRoute returns a Route spreading the keys over n backends by the CRC32
+// checksum of the first argument of the commands, commands without
+// argument going to the first backend.
+func HashRoute(n int) func(cmd *resp.Message) int {
+	return func(cmd *resp.Message) int {
+		args := Args(cmd)
+		if len(args) < 2 || n <= 0 {
+			return 0
+		}
+		return int(crc32.ChecksumIEEE(args[1]) % uint32(n))
+	}
+}
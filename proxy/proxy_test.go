@@ -0,0 +1,288 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+	"github.com/amyangfei/resp-go/resptest"
+)
+
+// serve starts p on a loopback address and returns a client connected to
+// it.
+func serve(t *testing.T, p *Proxy) *resp.Conn {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go p.Serve(l)
+	c, err := resp.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestProxyForward(t *testing.T) {
+	backends := []*resptest.Server{resptest.NewServer(), resptest.NewServer()}
+	defer backends[0].Close()
+	defer backends[1].Close()
+	p := &Proxy{
+		Backends: []Backend{{Addr: backends[0].Addr()}, {Addr: backends[1].Addr()}},
+		Route:    HashRoute(2),
+	}
+	defer p.Close()
+	c := serve(t, p)
+	defer c.Close()
+
+	keys := []string{"a", "b", "c", "d", "e", "f"}
+	for _, key := range keys {
+		if reply, err := c.Do(resp.Cmd("SET", key, "v-"+key)); err != nil || reply.Status != "OK" {
+			t.Fatalf("error SET reply: %v %v", reply, err)
+		}
+	}
+	for _, key := range keys {
+		reply, err := c.Do(resp.Cmd("GET", key))
+		if err != nil || string(reply.Bytes) != "v-"+key {
+			t.Errorf("error GET %s reply: %v %v", key, reply, err)
+		}
+	}
+
+	// each key is stored on the backend it is routed to only
+	route := HashRoute(2)
+	for _, key := range keys {
+		for i, b := range backends {
+			conn, err := resp.Dial("tcp", b.Addr())
+			if err != nil {
+				t.Fatal(err)
+			}
+			reply, err := conn.Do(resp.Cmd("EXISTS", key))
+			conn.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := int64(0)
+			if route(resp.Cmd("GET", key)) == i {
+				want = 1
+			}
+			if reply.Integer != want {
+				t.Errorf("error EXISTS %s on backend %d: %d", key, i, reply.Integer)
+			}
+		}
+	}
+}
+
+func TestProxyPipeline(t *testing.T) {
+	backend := resptest.NewServer()
+	defer backend.Close()
+	p := &Proxy{Backends: []Backend{{Addr: backend.Addr()}}}
+	defer p.Close()
+	c := serve(t, p)
+	defer c.Close()
+
+	c.Send(resp.Cmd("SET", "k", "1"))
+	c.Send(resp.Cmd("INCR", "k"))
+	c.Send(resp.Cmd("GET", "k"))
+	c.Send(resp.Cmd("LPUSH", "k", "x"))
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	var replies []string
+	for i := 0; i < 4; i++ {
+		reply, err := c.Receive()
+		if err != nil {
+			t.Fatal(err)
+		}
+		replies = append(replies, reply.String())
+	}
+	want := []string{"OK", "(integer) 2", `"2"`,
+		"(error) WRONGTYPE Operation against a key holding the wrong kind of value"}
+	if strings.Join(replies, "\n") != strings.Join(want, "\n") {
+		t.Errorf("error pipeline replies: %q", replies)
+	}
+}
+
+func TestProxyConnectionState(t *testing.T) {
+	backend := resptest.NewServer()
+	defer backend.Close()
+	p := &Proxy{Backends: []Backend{{Addr: backend.Addr()}}}
+	defer p.Close()
+	c := serve(t, p)
+	defer c.Close()
+	other := serve(t, p)
+	defer other.Close()
+
+	// the database selected by a client does not leak to the other ones
+	if reply, err := c.Do(resp.Cmd("SELECT", 1)); err != nil || reply.Status != "OK" {
+		t.Fatalf("error SELECT reply: %v %v", reply, err)
+	}
+	c.Do(resp.Cmd("SET", "k", "db1"))
+	if reply, err := other.Do(resp.Cmd("GET", "k")); err != nil || !reply.IsNil {
+		t.Errorf("error GET reply of the other client: %v %v", reply, err)
+	}
+	if reply, err := c.Do(resp.Cmd("GET", "k")); err != nil || string(reply.Bytes) != "db1" {
+		t.Errorf("error GET reply: %v %v", reply, err)
+	}
+}
+
+func TestProxyErrors(t *testing.T) {
+	p := &Proxy{
+		Backends: []Backend{{Dial: func(ctx context.Context) (*resp.Conn, error) {
+			return nil, errors.New("backend down")
+		}}},
+		Route: func(cmd *resp.Message) int {
+			if CommandName(cmd) == "NOWHERE" {
+				return 3
+			}
+			return 0
+		},
+	}
+	defer p.Close()
+	c := serve(t, p)
+	defer c.Close()
+
+	tests := []struct {
+		cmd  *resp.Message
+		want string
+	}{
+		{resp.Cmd("NOWHERE"), "ERR " + ErrNoBackend.Error()},
+		{resp.Cmd("SUBSCRIBE", "ch"), "ERR " + ErrUnsupported.Error()},
+		{resp.Cmd("PING"), "ERR backend down"},
+	}
+	for _, test := range tests {
+		reply, err := c.Do(test.cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reply.Type != resp.ErrorHeader || !strings.HasPrefix(reply.Error.Error(), test.want) {
+			t.Errorf("error reply to %s: %v", CommandName(test.cmd), reply)
+		}
+	}
+
+	// forwarding outside of the proxy has no client connection
+	if _, err := p.forward(context.Background(), resp.Cmd("PING")); err != ErrNoClient {
+		t.Errorf("should return ErrNoClient, not: %v", err)
+	}
+}
+
+func TestProxyUnsupported(t *testing.T) {
+	backend := resptest.NewServer()
+	defer backend.Close()
+	p := &Proxy{Backends: []Backend{{Addr: backend.Addr()}}}
+	defer p.Close()
+	c := serve(t, p)
+	defer c.Close()
+
+	for _, cmd := range []*resp.Message{
+		resp.Cmd("UNSUBSCRIBE", "a", "b"),
+		resp.Cmd("PUNSUBSCRIBE", "a.*", "b.*"),
+		resp.Cmd("unsubscribe"),
+		resp.Cmd("SUNSUBSCRIBE", "a"),
+		resp.Cmd("CLIENT", "REPLY", "OFF"),
+		resp.Cmd("CLIENT", "reply", "SKIP"),
+		resp.Cmd("HELLO", "3"),
+	} {
+		c.Send(cmd)
+		c.Send(resp.Cmd("ECHO", CommandName(cmd)))
+		if err := c.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		reply, err := c.Receive()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reply.Type != resp.ErrorHeader || reply.Error.Error() != "ERR "+ErrUnsupported.Error() {
+			t.Errorf("error reply to %s: %v", CommandName(cmd), reply)
+		}
+		// the next reply is the one of the next command
+		if reply, err = c.Receive(); err != nil {
+			t.Fatal(err)
+		} else if string(reply.Bytes) != CommandName(cmd) {
+			t.Errorf("error reply following %s: %v", CommandName(cmd), reply)
+		}
+	}
+
+	if isUnsupported(resp.Cmd("CLIENT", "SETNAME", "x")) || isUnsupported(resp.Cmd("HELLO", "2")) {
+		t.Error("error CLIENT SETNAME and HELLO 2 should be supported")
+	}
+}
+
+// closeCounter counts the backend connections closed.
+type closeCounter struct {
+	net.Conn
+	mu     *sync.Mutex
+	closed *int
+}
+
+func (c closeCounter) Close() error {
+	c.mu.Lock()
+	*c.closed++
+	c.mu.Unlock()
+	return c.Conn.Close()
+}
+
+func TestProxyClosesBackendConns(t *testing.T) {
+	var (
+		mu             sync.Mutex
+		dialed, closed int
+	)
+	backend := resptest.NewServer()
+	defer backend.Close()
+	p := &Proxy{Backends: []Backend{{Dial: func(ctx context.Context) (*resp.Conn, error) {
+		conn, err := net.Dial("tcp", backend.Addr())
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		dialed++
+		mu.Unlock()
+		return resp.NewConn(closeCounter{conn, &mu, &closed}), nil
+	}}}}
+	defer p.Close()
+	c := serve(t, p)
+
+	for i := 0; i < 3; i++ {
+		if reply, err := c.Do(resp.Cmd("PING")); err != nil || reply.Status != "PONG" {
+			t.Fatalf("error PING reply: %v %v", reply, err)
+		}
+	}
+	c.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		d, n := dialed, closed
+		mu.Unlock()
+		if d == 1 && n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("error backend connections dialed %d closed %d", d, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestProxyServeConn(t *testing.T) {
+	backend := resptest.NewServer()
+	defer backend.Close()
+	p := &Proxy{Backends: []Backend{{Addr: backend.Addr()}}}
+	client, server := net.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- p.ServeConn(server) }()
+
+	c := resp.NewConn(client)
+	if reply, err := c.Do(resp.Cmd("ECHO", "hi")); err != nil || string(reply.Bytes) != "hi" {
+		t.Errorf("error ECHO reply: %v %v", reply, err)
+	}
+	c.Close()
+	if err := <-done; err != nil && err != io.EOF {
+		t.Errorf("error ServeConn result: %v", err)
+	}
+}
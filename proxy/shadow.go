@@ -0,0 +1,192 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+const (
+	// DefaultShadowQueue is the number of commands of a client waiting to
+	// be mirrored when the Queue field of a Shadow is zero.
+	DefaultShadowQueue = 1024
+	// DefaultShadowTimeout bounds the time spent mirroring a command when
+	// the Timeout field of a Shadow is zero.
+	DefaultShadowTimeout = time.Second
+)
+
+// Shadow mirrors the commands of the proxy clients to a secondary backend
+// and compares its replies to the ones of the primary, as a check before
+// migrating to another version or vendor of the server.
+//
+// Commands are mirrored asynchronously, once the primary replied, so that
+// the clients are not slowed down: each client gets its own connection to
+// the shadow backend, the commands of a client being mirrored in order.
+// When the shadow lags behind by more than Queue commands, the commands
+// are dropped instead of being queued, and counted by Dropped.
+type Shadow struct {
+	// Backend is the secondary backend
+	Backend Backend
+	// Compare reports whether the replies of the primary and of the shadow
+	// to a command match, Equivalent being used when nil
+	Compare func(cmd, primary, shadow *resp.Message) bool
+	// OnMismatch is called with the commands whose replies do not match,
+	// from the goroutine mirroring the commands of the client. Mismatches
+	// are logged with the log package when nil.
+	OnMismatch func(m *Mismatch)
+	// Queue is the number of commands of a client waiting to be mirrored,
+	// DefaultShadowQueue when zero
+	Queue int
+	// Timeout bounds the time spent mirroring a command,
+	// DefaultShadowTimeout when zero
+	Timeout time.Duration
+
+	mu      sync.Mutex
+	queues  map[*resp.ServerConn]chan mirrored
+	dropped uint64
+}
+
+// Mismatch is a command whose reply from the shadow does not match the one
+// of the primary.
+type Mismatch struct {
+	// Command is the command mirrored
+	Command *resp.Message
+	// Primary and Shadow are the replies of the backends, Shadow being nil
+	// if the shadow failed to reply
+	Primary, Shadow *resp.Message
+	// Err is the error which prevented the shadow from replying, if any
+	Err error
+}
+
+func (m *Mismatch) String() string {
+	if m.Err != nil {
+		return fmt.Sprintf("%s: shadow error: %v", commandLine(m.Command), m.Err)
+	}
+	return fmt.Sprintf("%s: %s", commandLine(m.Command), strings.Join(resp.Diff(m.Primary, m.Shadow), "; "))
+}
+
+// commandLine returns the arguments of a command separated by spaces.
+func commandLine(cmd *resp.Message) string {
+	args, err := cmd.Strings()
+	if err != nil {
+		return cmd.String()
+	}
+	return strings.Join(args, " ")
+}
+
+type mirrored struct {
+	cmd, reply *resp.Message
+}
+
+// Middleware is the middleware mirroring the commands, to add to the
+// Middlewares of a proxy. It mirrors the commands as they are forwarded to
+// the primary, rewritten by the middlewares before it, and only those the
+// primary replied to.
+func (s *Shadow) Middleware(next Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, cmd *resp.Message) (*resp.Message, error) {
+		reply, err := next.Handle(ctx, cmd)
+		if c := Client(ctx); c != nil && err == nil && reply != nil {
+			// the messages are copied as the middlewares before may still
+			// modify them once returned
+			s.mirror(c, mirrored{cmd.Clone(), reply.Clone()})
+		}
+		return reply, err
+	})
+}
+
+// Dropped returns the number of commands which were not mirrored because
+// the shadow lagged behind.
+func (s *Shadow) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+func (s *Shadow) mirror(c *resp.ServerConn, m mirrored) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.queues[c]
+	if !ok {
+		if c.Context().Err() != nil {
+			return
+		}
+		if s.queues == nil {
+			s.queues = make(map[*resp.ServerConn]chan mirrored)
+		}
+		n := s.Queue
+		if n <= 0 {
+			n = DefaultShadowQueue
+		}
+		q = make(chan mirrored, n)
+		s.queues[c] = q
+		go s.run(q)
+		go func() {
+			// the commands queued are still mirrored once the client is
+			// gone
+			<-c.Context().Done()
+			s.mu.Lock()
+			delete(s.queues, c)
+			close(q)
+			s.mu.Unlock()
+		}()
+	}
+	select {
+	case q <- m:
+	default:
+		s.dropped++
+	}
+}
+
+// run mirrors the commands of a client until its queue is closed.
+func (s *Shadow) run(q chan mirrored) {
+	var conn *resp.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultShadowTimeout
+	}
+	for m := range q {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		var (
+			reply *resp.Message
+			err   error
+		)
+		if conn == nil {
+			conn, err = s.Backend.dial(ctx)
+		}
+		if err == nil {
+			if reply, err = conn.DoContext(ctx, m.cmd); err != nil {
+				// a broken connection is dialed again by the next command
+				conn.Close()
+				conn = nil
+			}
+		}
+		cancel()
+		s.compare(m, reply, err)
+	}
+}
+
+func (s *Shadow) compare(m mirrored, reply *resp.Message, err error) {
+	compare := s.Compare
+	if compare == nil {
+		compare = Equivalent
+	}
+	if err == nil && compare(m.cmd, m.reply, reply) {
+		return
+	}
+	mismatch := &Mismatch{Command: m.cmd, Primary: m.reply, Shadow: reply, Err: err}
+	if s.OnMismatch != nil {
+		s.OnMismatch(mismatch)
+	} else {
+		log.Printf("proxy: shadow mismatch: %v", mismatch)
+	}
+}
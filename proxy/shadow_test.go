@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+	"github.com/amyangfei/resp-go/resptest"
+)
+
+func TestShadow(t *testing.T) {
+	primary, secondary := resptest.NewServer(), resptest.NewServer()
+	defer primary.Close()
+	defer secondary.Close()
+	mismatches := make(chan *Mismatch, 10)
+	shadow := &Shadow{
+		Backend:    Backend{Addr: secondary.Addr()},
+		OnMismatch: func(m *Mismatch) { mismatches <- m },
+	}
+	p := &Proxy{
+		Backends:    []Backend{{Addr: primary.Addr()}},
+		Middlewares: []Middleware{shadow.Middleware},
+	}
+	defer p.Close()
+	c := serve(t, p)
+	defer c.Close()
+
+	direct, err := resp.Dial("tcp", secondary.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer direct.Close()
+
+	// writes reach the shadow, and matching replies are not reported
+	c.Do(resp.Cmd("SADD", "s", "a", "b", "c"))
+	c.Do(resp.Cmd("SET", "k", "v"))
+	c.Do(resp.Cmd("SMEMBERS", "s"))
+	deadline := time.Now().Add(time.Second)
+	for {
+		reply, err := direct.Do(resp.Cmd("GET", "k"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(reply.Bytes) == "v" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("error SET not mirrored to the shadow")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// the shadow diverges
+	direct.Do(resp.Cmd("SET", "k", "other"))
+	if reply, err := c.Do(resp.Cmd("GET", "k")); err != nil || string(reply.Bytes) != "v" {
+		t.Fatalf("error GET reply: %v %v", reply, err)
+	}
+	select {
+	case m := <-mismatches:
+		if commandLine(m.Command) != "GET k" || string(m.Primary.Bytes) != "v" ||
+			string(m.Shadow.Bytes) != "other" || m.Err != nil {
+			t.Errorf("error mismatch: %v", m)
+		}
+		if got := m.String(); got != `GET k: $: bulk "v" != bulk "other"` {
+			t.Errorf("error mismatch string: %s", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("error mismatch not reported")
+	}
+	select {
+	case m := <-mismatches:
+		t.Errorf("error unexpected mismatch: %v", m)
+	default:
+	}
+}
+
+func TestShadowDrops(t *testing.T) {
+	backend := resptest.NewServer()
+	defer backend.Close()
+	release := make(chan struct{})
+	mismatches := make(chan *Mismatch, 10)
+	shadow := &Shadow{
+		Backend: Backend{Dial: func(ctx context.Context) (*resp.Conn, error) {
+			<-release
+			return nil, errors.New("shadow down")
+		}},
+		OnMismatch: func(m *Mismatch) { mismatches <- m },
+		Queue:      1,
+	}
+	p := &Proxy{
+		Backends:    []Backend{{Addr: backend.Addr()}},
+		Middlewares: []Middleware{shadow.Middleware},
+	}
+	defer p.Close()
+	c := serve(t, p)
+	defer c.Close()
+
+	// the primary path does not wait for the shadow
+	for i := 0; i < 4; i++ {
+		if reply, err := c.Do(resp.Cmd("INCR", "n")); err != nil || reply.Integer != int64(i+1) {
+			t.Fatalf("error INCR reply: %v %v", reply, err)
+		}
+	}
+	if n := shadow.Dropped(); n < 2 {
+		t.Errorf("error dropped commands: %d", n)
+	}
+	close(release)
+	select {
+	case m := <-mismatches:
+		if m.Err == nil || m.Shadow != nil || !strings.HasSuffix(m.String(), "shadow error: shadow down") {
+			t.Errorf("error mismatch: %v", m)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("error shadow failure not reported")
+	}
+}
@@ -0,0 +1,168 @@
+package rdb
+
+import (
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// itemsPerCommand is the number of elements pushed by a single command, the
+// same batching redis uses when rewriting the AOF.
+const itemsPerCommand = 64
+
+func formatScore(f float64) []byte {
+	switch {
+	case math.IsInf(f, 1):
+		return []byte("+inf")
+	case math.IsInf(f, -1):
+		return []byte("-inf")
+	}
+	return []byte(strconv.FormatFloat(f, 'g', 17, 64))
+}
+
+func cmd(args ...[]byte) [][]byte {
+	return args
+}
+
+// batch splits items in commands of at most n items each, every command
+// starting with prefix.
+func batch(prefix [][]byte, items [][]byte, n int) [][][]byte {
+	var cmds [][][]byte
+	for len(items) > 0 {
+		l := n
+		if l > len(items) {
+			l = len(items)
+		}
+		c := make([][]byte, 0, len(prefix)+l)
+		c = append(c, prefix...)
+		c = append(c, items[:l]...)
+		cmds = append(cmds, c)
+		items = items[l:]
+	}
+	return cmds
+}
+
+// Commands returns the commands that recreate the entry, followed by a
+// PEXPIREAT if the key has an expiry. Each command is an array of bulk
+// strings that resp.Encoder encodes as a RESP request.
+func (e *Entry) Commands() [][][]byte {
+	var cmds [][][]byte
+	switch e.Kind {
+	case KindString:
+		cmds = append(cmds, cmd([]byte("SET"), e.Key, e.String))
+	case KindList:
+		cmds = batch(cmd([]byte("RPUSH"), e.Key), e.Values, itemsPerCommand)
+	case KindSet:
+		cmds = batch(cmd([]byte("SADD"), e.Key), e.Values, itemsPerCommand)
+	case KindZSet:
+		items := make([][]byte, 0, len(e.ZSet)*2)
+		for _, m := range e.ZSet {
+			items = append(items, formatScore(m.Score), m.Member)
+		}
+		cmds = batch(cmd([]byte("ZADD"), e.Key), items, itemsPerCommand*2)
+	case KindHash:
+		items := make([][]byte, 0, len(e.Hash)*2)
+		for _, f := range e.Hash {
+			items = append(items, f.Field, f.Value)
+		}
+		cmds = batch(cmd([]byte("HSET"), e.Key), items, itemsPerCommand*2)
+	case KindStream:
+		cmds = e.streamCommands()
+	}
+	if e.Expiry > 0 {
+		cmds = append(cmds, cmd([]byte("PEXPIREAT"), e.Key, intBytes(e.Expiry)))
+	}
+	return cmds
+}
+
+func (e *Entry) streamCommands() [][][]byte {
+	s := e.Stream
+	var cmds [][][]byte
+	lastID := []byte(s.LastID.String())
+
+	if len(s.Entries) == 0 {
+		// Create the key with a dummy entry trimmed right away, as XADD is
+		// the only way to create an empty stream. Its ID is 0-1, as in the
+		// AOF rewrite of redis, since XADD rejects 0-0, the last ID of a
+		// stream which never had entries: XSETID sets the last ID below.
+		cmds = append(cmds, cmd([]byte("XADD"), e.Key, []byte("MAXLEN"), []byte("0"),
+			[]byte("0-1"), []byte("x"), []byte("y")))
+	}
+	for _, entry := range s.Entries {
+		c := cmd([]byte("XADD"), e.Key, []byte(entry.ID.String()))
+		cmds = append(cmds, append(c, entry.Fields...))
+	}
+
+	setid := cmd([]byte("XSETID"), e.Key, lastID)
+	if s.HasMeta {
+		setid = append(setid, []byte("ENTRIESADDED"), intBytes(int64(s.EntriesAdded)),
+			[]byte("MAXDELETEDID"), []byte(s.MaxDeletedID.String()))
+	}
+	cmds = append(cmds, setid)
+
+	for _, g := range s.Groups {
+		create := cmd([]byte("XGROUP"), []byte("CREATE"), e.Key, g.Name, []byte(g.LastID.String()))
+		if s.HasMeta {
+			create = append(create, []byte("ENTRIESREAD"), intBytes(g.EntriesRead))
+		}
+		cmds = append(cmds, create)
+
+		pending := make(map[StreamID]StreamPending, len(g.Pending))
+		for _, pe := range g.Pending {
+			pending[pe.ID] = pe
+		}
+		for _, c := range g.Consumers {
+			if len(c.Pending) == 0 {
+				cmds = append(cmds, cmd([]byte("XGROUP"), []byte("CREATECONSUMER"), e.Key, g.Name, c.Name))
+				continue
+			}
+			// XCLAIM with FORCE recreates the pending entries along with
+			// their delivery time and count.
+			for _, id := range c.Pending {
+				pe := pending[id]
+				cmds = append(cmds, cmd([]byte("XCLAIM"), e.Key, g.Name, c.Name, []byte("0"),
+					[]byte(id.String()), []byte("TIME"), intBytes(pe.DeliveryTime),
+					[]byte("RETRYCOUNT"), intBytes(int64(pe.DeliveryCount)),
+					[]byte("JUSTID"), []byte("FORCE")))
+			}
+		}
+	}
+	return cmds
+}
+
+// Convert reads the RDB file from r and writes to w the RESP commands which
+// restore its content, selecting databases and loading functions as they
+// appear.
+func Convert(r io.Reader, w io.Writer) error {
+	p := NewParser(r)
+	enc := resp.NewEncoder(w)
+	db := -1
+	functions := 0
+	for {
+		e, err := p.Next()
+		for ; functions < len(p.Functions); functions++ {
+			load := cmd([]byte("FUNCTION"), []byte("LOAD"), []byte("REPLACE"), p.Functions[functions])
+			if werr := enc.Encode(load); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if e.DB != db {
+			db = e.DB
+			if err = enc.Encode(cmd([]byte("SELECT"), intBytes(int64(db)))); err != nil {
+				return err
+			}
+		}
+		for _, c := range e.Commands() {
+			if err = enc.Encode(c); err != nil {
+				return err
+			}
+		}
+	}
+}
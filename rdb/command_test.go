@@ -0,0 +1,175 @@
+package rdb
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// commandStrings decodes a RESP stream of commands into space joined strings.
+func commandStrings(t *testing.T, data []byte) []string {
+	msgQ, pos, err := resp.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	} else if pos != len(data) {
+		t.Fatal("error consume pos")
+	}
+	var cmds []string
+	for _, msg := range msgQ {
+		var args []string
+		for _, arg := range msg.Array {
+			args = append(args, string(arg.Bytes))
+		}
+		cmds = append(cmds, strings.Join(args, " "))
+	}
+	return cmds
+}
+
+func checkCommands(t *testing.T, got []string, want ...string) {
+	if len(got) != len(want) {
+		t.Errorf("got %d commands, should be %d: %q", len(got), len(want), got)
+		return
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("command %d is %q, should be %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	w := newRDB("0010")
+	w.raw(opFunction2).str("#!lua name=lib")
+	w.raw(opSelectDB).length(0)
+	w.raw(opExpireTimeMs).u64(1700000000123)
+	w.raw(TypeString).str("str").str("value")
+	w.raw(TypeListZiplist).str("list").str(string(ziplist("a", 1)))
+	w.raw(opSelectDB).length(2)
+	w.raw(TypeZSet2).str("zset").length(1).str("m").u64(0x3FF8000000000000)
+	w.raw(TypeHashListpack).str("hash").str(string(listpack("f", "v")))
+	w.raw(TypeSetIntset).str("set").str(string(intset(2, 7)))
+
+	var buf bytes.Buffer
+	if err := Convert(bytes.NewReader(w.end()), &buf); err != nil {
+		t.Fatal(err)
+	}
+	checkCommands(t, commandStrings(t, buf.Bytes()),
+		"FUNCTION LOAD REPLACE #!lua name=lib",
+		"SELECT 0",
+		"SET str value",
+		"PEXPIREAT str 1700000000123",
+		"RPUSH list a 1",
+		"SELECT 2",
+		"ZADD zset 1.5 m",
+		"HSET hash f v",
+		"SADD set 7",
+	)
+}
+
+// limitedWriter fails once n bytes were written.
+type limitedWriter struct {
+	n int
+}
+
+func (w *limitedWriter) Write(b []byte) (int, error) {
+	if len(b) > w.n {
+		return 0, errors.New("write failed")
+	}
+	w.n -= len(b)
+	return len(b), nil
+}
+
+func TestConvertWriteError(t *testing.T) {
+	w := newRDB("0010")
+	w.raw(opFunction2).str("#!lua name=lib")
+	w.raw(opSelectDB).length(0)
+	w.raw(TypeString).str("str").str("value")
+	data := w.end()
+
+	// the FUNCTION LOAD, then the SELECT, then the SET fail
+	for _, n := range []int{0, 62, 85} {
+		if err := Convert(bytes.NewReader(data), &limitedWriter{n}); err == nil {
+			t.Errorf("error expected when the writer fails after %d bytes", n)
+		}
+	}
+}
+
+func TestCommandsBatch(t *testing.T) {
+	e := &Entry{Key: []byte("list"), Kind: KindList}
+	for i := 0; i < itemsPerCommand+1; i++ {
+		e.Values = append(e.Values, []byte(strconv.Itoa(i)))
+	}
+	cmds := e.Commands()
+	if len(cmds) != 2 {
+		t.Fatalf("should split in two commands, not %d", len(cmds))
+	}
+	if len(cmds[0]) != itemsPerCommand+2 || len(cmds[1]) != 3 || string(cmds[1][2]) != "64" {
+		t.Errorf("error batched commands: %q", cmds[1])
+	}
+}
+
+func TestStreamCommands(t *testing.T) {
+	e := &Entry{
+		Key:  []byte("s"),
+		Kind: KindStream,
+		Stream: &Stream{
+			Entries: []StreamEntry{
+				{ID: StreamID{1, 0}, Fields: [][]byte{[]byte("f"), []byte("v")}},
+			},
+			Length:       1,
+			LastID:       StreamID{2, 0},
+			HasMeta:      true,
+			MaxDeletedID: StreamID{2, 0},
+			EntriesAdded: 2,
+			Groups: []StreamGroup{{
+				Name:        []byte("g"),
+				LastID:      StreamID{1, 0},
+				EntriesRead: 1,
+				Pending:     []StreamPending{{ID: StreamID{1, 0}, DeliveryTime: 42, DeliveryCount: 3}},
+				Consumers: []StreamConsumer{
+					{Name: []byte("c1"), Pending: []StreamID{{1, 0}}},
+					{Name: []byte("c2")},
+				},
+			}},
+		},
+	}
+	var buf bytes.Buffer
+	enc := resp.NewEncoder(&buf)
+	for _, c := range e.Commands() {
+		enc.Encode(c)
+	}
+	checkCommands(t, commandStrings(t, buf.Bytes()),
+		"XADD s 1-0 f v",
+		"XSETID s 2-0 ENTRIESADDED 2 MAXDELETEDID 2-0",
+		"XGROUP CREATE s g 1-0 ENTRIESREAD 1",
+		"XCLAIM s g c1 0 1-0 TIME 42 RETRYCOUNT 3 JUSTID FORCE",
+		"XGROUP CREATECONSUMER s g c2",
+	)
+
+	e.Stream.Entries = nil
+	e.Stream.HasMeta = false
+	e.Stream.Groups = nil
+	buf.Reset()
+	for _, c := range e.Commands() {
+		enc.Encode(c)
+	}
+	checkCommands(t, commandStrings(t, buf.Bytes()),
+		"XADD s MAXLEN 0 0-1 x y",
+		"XSETID s 2-0",
+	)
+
+	// a stream which never had entries
+	e.Stream.LastID = StreamID{}
+	buf.Reset()
+	for _, c := range e.Commands() {
+		enc.Encode(c)
+	}
+	checkCommands(t, commandStrings(t, buf.Bytes()),
+		"XADD s MAXLEN 0 0-1 x y",
+		"XSETID s 0-0",
+	)
+}
@@ -0,0 +1,334 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"strconv"
+)
+
+// slice returns b[pos:pos+n] or false if b is too short.
+func slice(b []byte, pos, n int) ([]byte, bool) {
+	if n < 0 || pos < 0 || pos+n > len(b) || pos+n < pos {
+		return nil, false
+	}
+	return b[pos : pos+n], true
+}
+
+func intBytes(v int64) []byte {
+	return []byte(strconv.FormatInt(v, 10))
+}
+
+// parseZiplist returns the elements of a ziplist, integers being converted to
+// their decimal representation.
+func parseZiplist(b []byte) ([][]byte, error) {
+	// zlbytes(4) zltail(4) zllen(2) entries... zlend(1)
+	if len(b) < 11 {
+		return nil, ErrInvalidEncoding
+	}
+	var res [][]byte
+	pos := 10
+	for {
+		if pos >= len(b) {
+			return nil, ErrInvalidEncoding
+		}
+		if b[pos] == 0xFF {
+			return res, nil
+		}
+		// skip prevlen
+		if b[pos] < 254 {
+			pos++
+		} else {
+			pos += 5
+		}
+		if pos >= len(b) {
+			return nil, ErrInvalidEncoding
+		}
+		enc := b[pos]
+		var (
+			l    int
+			data []byte
+			ok   bool
+		)
+		switch enc >> 6 {
+		case 0:
+			l = int(enc & 0x3f)
+			pos++
+		case 1:
+			if data, ok = slice(b, pos+1, 1); !ok {
+				return nil, ErrInvalidEncoding
+			}
+			l = int(enc&0x3f)<<8 | int(data[0])
+			pos += 2
+		case 2:
+			if data, ok = slice(b, pos+1, 4); !ok {
+				return nil, ErrInvalidEncoding
+			}
+			l = int(binary.BigEndian.Uint32(data))
+			pos += 5
+		default:
+			var v int64
+			pos++
+			switch {
+			case enc == 0xC0:
+				if data, ok = slice(b, pos, 2); ok {
+					v = int64(int16(binary.LittleEndian.Uint16(data)))
+				}
+			case enc == 0xD0:
+				if data, ok = slice(b, pos, 4); ok {
+					v = int64(int32(binary.LittleEndian.Uint32(data)))
+				}
+			case enc == 0xE0:
+				if data, ok = slice(b, pos, 8); ok {
+					v = int64(binary.LittleEndian.Uint64(data))
+				}
+			case enc == 0xF0:
+				if data, ok = slice(b, pos, 3); ok {
+					v = int64(int32(uint32(data[0])<<8|uint32(data[1])<<16|uint32(data[2])<<24) >> 8)
+				}
+			case enc == 0xFE:
+				if data, ok = slice(b, pos, 1); ok {
+					v = int64(int8(data[0]))
+				}
+			case enc >= 0xF1 && enc <= 0xFD:
+				v = int64(enc&0x0f) - 1
+				ok = true
+			}
+			if !ok {
+				return nil, ErrInvalidEncoding
+			}
+			pos += len(data)
+			res = append(res, intBytes(v))
+			continue
+		}
+		if data, ok = slice(b, pos, l); !ok {
+			return nil, ErrInvalidEncoding
+		}
+		res = append(res, data)
+		pos += l
+	}
+}
+
+// parseListpack returns the elements of a listpack, integers being converted
+// to their decimal representation.
+func parseListpack(b []byte) ([][]byte, error) {
+	// total-bytes(4) num-elements(2) entries... end(1)
+	if len(b) < 7 {
+		return nil, ErrInvalidEncoding
+	}
+	var res [][]byte
+	pos := 6
+	for {
+		if pos >= len(b) {
+			return nil, ErrInvalidEncoding
+		}
+		c := b[pos]
+		if c == 0xFF {
+			return res, nil
+		}
+		var (
+			size int
+			elem []byte
+			data []byte
+			ok   bool
+		)
+		switch {
+		case c&0x80 == 0:
+			elem, size, ok = intBytes(int64(c&0x7f)), 1, true
+		case c&0xC0 == 0x80:
+			l := int(c & 0x3f)
+			elem, ok = slice(b, pos+1, l)
+			size = 1 + l
+		case c&0xE0 == 0xC0:
+			if data, ok = slice(b, pos+1, 1); ok {
+				v := int64(c&0x1f)<<8 | int64(data[0])
+				if v >= 1<<12 {
+					v -= 1 << 13
+				}
+				elem, size = intBytes(v), 2
+			}
+		case c&0xF0 == 0xE0:
+			if data, ok = slice(b, pos+1, 1); ok {
+				l := int(c&0x0f)<<8 | int(data[0])
+				elem, ok = slice(b, pos+2, l)
+				size = 2 + l
+			}
+		case c == 0xF0:
+			if data, ok = slice(b, pos+1, 4); ok {
+				l := int(binary.LittleEndian.Uint32(data))
+				elem, ok = slice(b, pos+5, l)
+				size = 5 + l
+			}
+		case c == 0xF1:
+			if data, ok = slice(b, pos+1, 2); ok {
+				elem, size = intBytes(int64(int16(binary.LittleEndian.Uint16(data)))), 3
+			}
+		case c == 0xF2:
+			if data, ok = slice(b, pos+1, 3); ok {
+				v := int32(uint32(data[0])<<8|uint32(data[1])<<16|uint32(data[2])<<24) >> 8
+				elem, size = intBytes(int64(v)), 4
+			}
+		case c == 0xF3:
+			if data, ok = slice(b, pos+1, 4); ok {
+				elem, size = intBytes(int64(int32(binary.LittleEndian.Uint32(data)))), 5
+			}
+		case c == 0xF4:
+			if data, ok = slice(b, pos+1, 8); ok {
+				elem, size = intBytes(int64(binary.LittleEndian.Uint64(data))), 9
+			}
+		}
+		if !ok {
+			return nil, ErrInvalidEncoding
+		}
+		res = append(res, elem)
+		pos += size + listpackBacklenSize(size)
+	}
+}
+
+// listpackBacklenSize returns the number of bytes used to store the length
+// of an entry at its end.
+func listpackBacklenSize(l int) int {
+	switch {
+	case l <= 127:
+		return 1
+	case l < 16383:
+		return 2
+	case l < 2097151:
+		return 3
+	case l < 268435455:
+		return 4
+	}
+	return 5
+}
+
+// parseIntset returns the members of an intset as decimal strings.
+func parseIntset(b []byte) ([][]byte, error) {
+	if len(b) < 8 {
+		return nil, ErrInvalidEncoding
+	}
+	enc := int(binary.LittleEndian.Uint32(b[:4]))
+	n := int(binary.LittleEndian.Uint32(b[4:8]))
+	if enc != 2 && enc != 4 && enc != 8 {
+		return nil, ErrInvalidEncoding
+	}
+	if n < 0 || len(b)-8 != n*enc {
+		return nil, ErrInvalidEncoding
+	}
+	res := make([][]byte, 0, n)
+	for i := 0; i < n; i++ {
+		data := b[8+i*enc : 8+(i+1)*enc]
+		var v int64
+		switch enc {
+		case 2:
+			v = int64(int16(binary.LittleEndian.Uint16(data)))
+		case 4:
+			v = int64(int32(binary.LittleEndian.Uint32(data)))
+		case 8:
+			v = int64(binary.LittleEndian.Uint64(data))
+		}
+		res = append(res, intBytes(v))
+	}
+	return res, nil
+}
+
+// parseZipmap returns the fields and values of a legacy zipmap encoded hash,
+// alternated.
+func parseZipmap(b []byte) ([][]byte, error) {
+	if len(b) < 2 {
+		return nil, ErrInvalidEncoding
+	}
+	var res [][]byte
+	pos := 1
+	readLen := func() (int, bool) {
+		if pos >= len(b) {
+			return 0, false
+		}
+		switch {
+		case b[pos] < 254:
+			pos++
+			return int(b[pos-1]), true
+		case b[pos] == 254:
+			data, ok := slice(b, pos+1, 4)
+			if !ok {
+				return 0, false
+			}
+			pos += 5
+			return int(binary.LittleEndian.Uint32(data)), true
+		}
+		return 0, false
+	}
+	for {
+		if pos >= len(b) {
+			return nil, ErrInvalidEncoding
+		}
+		if b[pos] == 0xFF {
+			return res, nil
+		}
+		l, ok := readLen()
+		if !ok {
+			return nil, ErrInvalidEncoding
+		}
+		field, ok := slice(b, pos, l)
+		if !ok {
+			return nil, ErrInvalidEncoding
+		}
+		pos += l
+		if l, ok = readLen(); !ok {
+			return nil, ErrInvalidEncoding
+		}
+		free, ok := slice(b, pos, 1)
+		if !ok {
+			return nil, ErrInvalidEncoding
+		}
+		pos++
+		value, ok := slice(b, pos, l)
+		if !ok {
+			return nil, ErrInvalidEncoding
+		}
+		pos += l + int(free[0])
+		res = append(res, field, value)
+	}
+}
+
+// lzfDecompress decompresses LZF data into a buffer of outLen bytes.
+func lzfDecompress(in []byte, outLen int) ([]byte, error) {
+	out := make([]byte, 0, outLen)
+	for i := 0; i < len(in); {
+		ctrl := int(in[i])
+		i++
+		if ctrl < 32 {
+			// literal run of ctrl+1 bytes
+			l := ctrl + 1
+			if i+l > len(in) || len(out)+l > outLen {
+				return nil, ErrLzfCorrupted
+			}
+			out = append(out, in[i:i+l]...)
+			i += l
+			continue
+		}
+		// back reference
+		l := ctrl >> 5
+		if l == 7 {
+			if i >= len(in) {
+				return nil, ErrLzfCorrupted
+			}
+			l += int(in[i])
+			i++
+		}
+		if i >= len(in) {
+			return nil, ErrLzfCorrupted
+		}
+		ref := len(out) - ((ctrl & 0x1f) << 8) - int(in[i]) - 1
+		i++
+		l += 2
+		if ref < 0 || len(out)+l > outLen {
+			return nil, ErrLzfCorrupted
+		}
+		// copy byte by byte as the reference may overlap the output
+		for j := 0; j < l; j++ {
+			out = append(out, out[ref+j])
+		}
+	}
+	if len(out) != outLen {
+		return nil, ErrLzfCorrupted
+	}
+	return out, nil
+}
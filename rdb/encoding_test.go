@@ -0,0 +1,201 @@
+package rdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// listpack builds a listpack holding strings of less than 64 bytes and
+// integers.
+func listpack(elems ...interface{}) []byte {
+	var body []byte
+	for _, e := range elems {
+		var entry []byte
+		switch v := e.(type) {
+		case string:
+			entry = append([]byte{0x80 | byte(len(v))}, v...)
+		case int:
+			if v >= 0 && v <= 127 {
+				entry = []byte{byte(v)}
+			} else {
+				entry = []byte{0xF1, 0, 0}
+				binary.LittleEndian.PutUint16(entry[1:], uint16(int16(v)))
+			}
+		}
+		body = append(body, entry...)
+		body = append(body, byte(len(entry)))
+	}
+	b := make([]byte, 6, 6+len(body)+1)
+	binary.LittleEndian.PutUint32(b, uint32(6+len(body)+1))
+	binary.LittleEndian.PutUint16(b[4:], uint16(len(elems)))
+	b = append(b, body...)
+	return append(b, 0xFF)
+}
+
+// ziplist builds a ziplist holding strings of less than 64 bytes and small
+// integers.
+func ziplist(elems ...interface{}) []byte {
+	var body []byte
+	prevlen := 0
+	for _, e := range elems {
+		entry := []byte{byte(prevlen)}
+		switch v := e.(type) {
+		case string:
+			entry = append(entry, byte(len(v)))
+			entry = append(entry, v...)
+		case int:
+			if v >= 0 && v <= 12 {
+				entry = append(entry, 0xF1+byte(v))
+			} else {
+				entry = append(entry, 0xFE, byte(int8(v)))
+			}
+		}
+		body = append(body, entry...)
+		prevlen = len(entry)
+	}
+	b := make([]byte, 10, 10+len(body)+1)
+	binary.LittleEndian.PutUint32(b, uint32(10+len(body)+1))
+	binary.LittleEndian.PutUint16(b[8:], uint16(len(elems)))
+	b = append(b, body...)
+	return append(b, 0xFF)
+}
+
+func intset(enc int, vals ...int64) []byte {
+	b := make([]byte, 8+enc*len(vals))
+	binary.LittleEndian.PutUint32(b, uint32(enc))
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(vals)))
+	for i, v := range vals {
+		switch enc {
+		case 2:
+			binary.LittleEndian.PutUint16(b[8+i*2:], uint16(v))
+		case 4:
+			binary.LittleEndian.PutUint32(b[8+i*4:], uint32(v))
+		case 8:
+			binary.LittleEndian.PutUint64(b[8+i*8:], uint64(v))
+		}
+	}
+	return b
+}
+
+func checkElems(t *testing.T, got [][]byte, want ...string) {
+	if len(got) != len(want) {
+		t.Errorf("got %d elements, should be %d: %q", len(got), len(want), got)
+		return
+	}
+	for i := range want {
+		if !bytes.Equal(got[i], []byte(want[i])) {
+			t.Errorf("element %d is %q, should be %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseZiplist(t *testing.T) {
+	elems, err := parseZiplist(ziplist("foo", 3, -5, "", "bar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkElems(t, elems, "foo", "3", "-5", "", "bar")
+
+	// int16, int24, int32 and int64 encodings
+	zl := ziplist()
+	zl = zl[:len(zl)-1]
+	zl = append(zl, 0, 0xC0, 0x18, 0xFC)             // -1000
+	zl = append(zl, 4, 0xF0, 0xA0, 0x86, 0x01)       // 100000
+	zl = append(zl, 5, 0xD0, 0x00, 0x94, 0x35, 0x77) // 2000000000
+	zl = append(zl, 6, 0xE0, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF)
+	elems, err = parseZiplist(zl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkElems(t, elems, "-1000", "100000", "2000000000", "-1")
+
+	// truncated data
+	zl = ziplist("foo", "bar")
+	if _, err = parseZiplist(zl[:len(zl)-3]); err != ErrInvalidEncoding {
+		t.Errorf("should return ErrInvalidEncoding, not: %v", err)
+	}
+}
+
+func TestParseListpack(t *testing.T) {
+	elems, err := parseListpack(listpack("foo", 7, 300, -2, "bar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkElems(t, elems, "foo", "7", "300", "-2", "bar")
+
+	// 13 bit integer and 12 bit string encodings
+	long := bytes.Repeat([]byte("x"), 100)
+	lp := listpack()
+	lp = lp[:len(lp)-1]
+	lp = append(lp, 0xDF, 0xFF, 2) // -1
+	lp = append(lp, 0xC1, 0x00, 2) // 256
+	lp = append(lp, 0xE0, byte(len(long)))
+	lp = append(lp, long...)
+	lp = append(lp, byte(len(long)+2))
+	lp = append(lp, 0xFF)
+	elems, err = parseListpack(lp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkElems(t, elems, "-1", "256", string(long))
+
+	lp = listpack("foo")
+	if _, err = parseListpack(lp[:len(lp)-2]); err != ErrInvalidEncoding {
+		t.Errorf("should return ErrInvalidEncoding, not: %v", err)
+	}
+}
+
+func TestParseIntset(t *testing.T) {
+	elems, err := parseIntset(intset(2, -3, 1, 500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkElems(t, elems, "-3", "1", "500")
+
+	elems, err = parseIntset(intset(8, 1<<40))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkElems(t, elems, "1099511627776")
+
+	if _, err = parseIntset(intset(4, 1, 2)[:10]); err != ErrInvalidEncoding {
+		t.Errorf("should return ErrInvalidEncoding, not: %v", err)
+	}
+}
+
+func TestParseZipmap(t *testing.T) {
+	zm := []byte{2, 3, 'f', 'o', 'o', 3, 1, 'b', 'a', 'r', 'x', 1, 'a', 1, 0, 'b', 0xFF}
+	elems, err := parseZipmap(zm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkElems(t, elems, "foo", "bar", "a", "b")
+}
+
+func TestLzfDecompress(t *testing.T) {
+	// literal "a" followed by a back reference of 9 bytes at offset 1
+	out, err := lzfDecompress([]byte{0x00, 'a', 0xE0, 0x00, 0x00}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "aaaaaaaaaa" {
+		t.Errorf("error decompressed data: %q", out)
+	}
+
+	// literal "abc" followed by a back reference of 3 bytes at offset 3
+	out, err = lzfDecompress([]byte{0x02, 'a', 'b', 'c', 0x20, 0x02}, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "abcabc" {
+		t.Errorf("error decompressed data: %q", out)
+	}
+
+	if _, err = lzfDecompress([]byte{0x00, 'a', 0x20, 0x05}, 4); err != ErrLzfCorrupted {
+		t.Errorf("should return ErrLzfCorrupted, not: %v", err)
+	}
+	if _, err = lzfDecompress([]byte{0x00, 'a'}, 4); err != ErrLzfCorrupted {
+		t.Errorf("should return ErrLzfCorrupted, not: %v", err)
+	}
+}
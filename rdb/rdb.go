@@ -0,0 +1,570 @@
+// Package rdb parses Redis RDB snapshot files and converts the keys they
+// contain into the RESP commands that restore them.
+package rdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+)
+
+const (
+	opSlotInfo     = 0xF4
+	opFunction2    = 0xF5
+	opFunctionPre  = 0xF6
+	opModuleAux    = 0xF7
+	opIdle         = 0xF8
+	opFreq         = 0xF9
+	opAux          = 0xFA
+	opResizeDB     = 0xFB
+	opExpireTimeMs = 0xFC
+	opExpireTime   = 0xFD
+	opSelectDB     = 0xFE
+	opEOF          = 0xFF
+)
+
+const (
+	encInt8  = 0
+	encInt16 = 1
+	encInt32 = 2
+	encLZF   = 3
+
+	quicklistNodePlain  = 1
+	quicklistNodePacked = 2
+
+	maxSupportedVersion = 12
+	maxStringLen        = 512 << 20
+)
+
+// Value types as stored in the RDB file before each key.
+const (
+	TypeString           = 0
+	TypeList             = 1
+	TypeSet              = 2
+	TypeZSet             = 3
+	TypeHash             = 4
+	TypeZSet2            = 5
+	TypeModule           = 6
+	TypeModule2          = 7
+	TypeHashZipmap       = 9
+	TypeListZiplist      = 10
+	TypeSetIntset        = 11
+	TypeZSetZiplist      = 12
+	TypeHashZiplist      = 13
+	TypeListQuicklist    = 14
+	TypeStreamListpacks  = 15
+	TypeHashListpack     = 16
+	TypeZSetListpack     = 17
+	TypeListQuicklist2   = 18
+	TypeStreamListpacks2 = 19
+	TypeSetListpack      = 20
+	TypeStreamListpacks3 = 21
+)
+
+// Kind is the logical redis data type of an entry, independent of the
+// encoding used to store it.
+type Kind int
+
+const (
+	KindString Kind = iota
+	KindList
+	KindSet
+	KindZSet
+	KindHash
+	KindStream
+)
+
+var (
+	// ErrInvalidMagic is returned when the data does not start with "REDIS".
+	ErrInvalidMagic = errors.New("rdb: invalid magic string")
+
+	// ErrUnsupportedVersion is returned for RDB versions newer than the
+	// parser knows about.
+	ErrUnsupportedVersion = errors.New("rdb: unsupported version")
+
+	// ErrUnsupportedType is returned for value types or opcodes which can not
+	// be converted, such as module values.
+	ErrUnsupportedType = errors.New("rdb: unsupported value type")
+
+	// ErrInvalidEncoding is returned when a length, string or compact
+	// encoding (ziplist, listpack, intset...) is malformed.
+	ErrInvalidEncoding = errors.New("rdb: invalid encoding")
+
+	// ErrLzfCorrupted is returned when LZF compressed data can not be
+	// decompressed to the announced length.
+	ErrLzfCorrupted = errors.New("rdb: corrupted lzf data")
+)
+
+// ZMember is a sorted set member with its score.
+type ZMember struct {
+	Member []byte
+	Score  float64
+}
+
+// HashField is a single field/value pair of a hash.
+type HashField struct {
+	Field []byte
+	Value []byte
+}
+
+// Entry is a key read from the RDB file along with its value. Only the field
+// matching Kind is set.
+type Entry struct {
+	DB     int
+	Key    []byte
+	Kind   Kind
+	Type   byte
+	Expiry int64 // unix time in milliseconds, 0 if the key does not expire
+	String []byte
+	Values [][]byte // list elements or set members
+	ZSet   []ZMember
+	Hash   []HashField
+	Stream *Stream
+}
+
+// Parser reads entries one by one from an RDB file.
+type Parser struct {
+	r       *bufio.Reader
+	Version int
+	// Aux holds the auxiliary fields (redis-ver, ctime, used-mem...) seen so
+	// far.
+	Aux map[string]string
+	// Functions holds the function libraries seen so far, as loaded by
+	// FUNCTION LOAD.
+	Functions [][]byte
+
+	db         int
+	headerRead bool
+	done       bool
+}
+
+// NewParser creates a Parser reading the RDB file from r.
+func NewParser(r io.Reader) *Parser {
+	return &Parser{
+		r:   bufio.NewReader(r),
+		Aux: make(map[string]string),
+	}
+}
+
+// Next returns the next key of the file. It returns io.EOF once the end of
+// file opcode is reached.
+func (p *Parser) Next() (*Entry, error) {
+	if p.done {
+		return nil, io.EOF
+	}
+	if !p.headerRead {
+		if err := p.readHeader(); err != nil {
+			return nil, err
+		}
+	}
+
+	var expiry int64
+	for {
+		op, err := p.r.ReadByte()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		switch op {
+		case opEOF:
+			p.done = true
+			// The 8 bytes checksum follows since RDB version 5, it is not
+			// verified.
+			if p.Version >= 5 {
+				if _, err = p.readFull(8); err != nil && err != io.ErrUnexpectedEOF {
+					return nil, err
+				}
+			}
+			return nil, io.EOF
+		case opSelectDB:
+			db, _, err := p.readLength()
+			if err != nil {
+				return nil, err
+			}
+			p.db = int(db)
+		case opResizeDB:
+			if _, _, err = p.readLength(); err != nil {
+				return nil, err
+			}
+			if _, _, err = p.readLength(); err != nil {
+				return nil, err
+			}
+		case opSlotInfo:
+			for i := 0; i < 3; i++ {
+				if _, _, err = p.readLength(); err != nil {
+					return nil, err
+				}
+			}
+		case opAux:
+			key, err := p.readString()
+			if err != nil {
+				return nil, err
+			}
+			val, err := p.readString()
+			if err != nil {
+				return nil, err
+			}
+			p.Aux[string(key)] = string(val)
+		case opExpireTime:
+			b, err := p.readFull(4)
+			if err != nil {
+				return nil, err
+			}
+			expiry = int64(binary.LittleEndian.Uint32(b)) * 1000
+		case opExpireTimeMs:
+			b, err := p.readFull(8)
+			if err != nil {
+				return nil, err
+			}
+			expiry = int64(binary.LittleEndian.Uint64(b))
+		case opFreq:
+			if _, err = p.r.ReadByte(); err != nil {
+				return nil, unexpectedEOF(err)
+			}
+		case opIdle:
+			if _, _, err = p.readLength(); err != nil {
+				return nil, err
+			}
+		case opFunction2:
+			code, err := p.readString()
+			if err != nil {
+				return nil, err
+			}
+			p.Functions = append(p.Functions, code)
+		case opFunctionPre, opModuleAux:
+			return nil, ErrUnsupportedType
+		default:
+			e := &Entry{DB: p.db, Type: op, Expiry: expiry}
+			if e.Key, err = p.readString(); err != nil {
+				return nil, err
+			}
+			if err = p.readValue(e); err != nil {
+				return nil, err
+			}
+			return e, nil
+		}
+	}
+}
+
+func (p *Parser) readHeader() error {
+	b, err := p.readFull(9)
+	if err != nil {
+		return err
+	}
+	if string(b[:5]) != "REDIS" {
+		return ErrInvalidMagic
+	}
+	version, err := strconv.Atoi(string(b[5:]))
+	if err != nil {
+		return ErrInvalidMagic
+	}
+	if version < 1 || version > maxSupportedVersion {
+		return ErrUnsupportedVersion
+	}
+	p.Version = version
+	p.headerRead = true
+	return nil
+}
+
+func (p *Parser) readValue(e *Entry) error {
+	var err error
+	switch e.Type {
+	case TypeString:
+		e.Kind = KindString
+		e.String, err = p.readString()
+	case TypeList, TypeSet:
+		e.Kind = KindList
+		if e.Type == TypeSet {
+			e.Kind = KindSet
+		}
+		e.Values, err = p.readStrings(1)
+	case TypeZSet, TypeZSet2:
+		e.Kind = KindZSet
+		var n uint64
+		if n, _, err = p.readLength(); err != nil {
+			return err
+		}
+		for i := uint64(0); i < n; i++ {
+			var m ZMember
+			if m.Member, err = p.readString(); err != nil {
+				return err
+			}
+			if e.Type == TypeZSet {
+				m.Score, err = p.readDouble()
+			} else {
+				m.Score, err = p.readBinaryDouble()
+			}
+			if err != nil {
+				return err
+			}
+			e.ZSet = append(e.ZSet, m)
+		}
+	case TypeHash:
+		e.Kind = KindHash
+		var fields [][]byte
+		if fields, err = p.readStrings(2); err != nil {
+			return err
+		}
+		e.Hash = pairsToHash(fields)
+	case TypeHashZipmap, TypeHashZiplist, TypeHashListpack:
+		e.Kind = KindHash
+		var fields [][]byte
+		if fields, err = p.readEncoded(e.Type); err != nil {
+			return err
+		}
+		if len(fields)%2 != 0 {
+			return ErrInvalidEncoding
+		}
+		e.Hash = pairsToHash(fields)
+	case TypeListZiplist:
+		e.Kind = KindList
+		e.Values, err = p.readEncoded(e.Type)
+	case TypeSetIntset, TypeSetListpack:
+		e.Kind = KindSet
+		e.Values, err = p.readEncoded(e.Type)
+	case TypeZSetZiplist, TypeZSetListpack:
+		e.Kind = KindZSet
+		var elems [][]byte
+		if elems, err = p.readEncoded(e.Type); err != nil {
+			return err
+		}
+		if len(elems)%2 != 0 {
+			return ErrInvalidEncoding
+		}
+		for i := 0; i < len(elems); i += 2 {
+			score, err := strconv.ParseFloat(string(elems[i+1]), 64)
+			if err != nil {
+				return ErrInvalidEncoding
+			}
+			e.ZSet = append(e.ZSet, ZMember{Member: elems[i], Score: score})
+		}
+	case TypeListQuicklist, TypeListQuicklist2:
+		e.Kind = KindList
+		e.Values, err = p.readQuicklist(e.Type)
+	case TypeStreamListpacks, TypeStreamListpacks2, TypeStreamListpacks3:
+		e.Kind = KindStream
+		e.Stream, err = p.readStream(e.Type)
+	default:
+		return ErrUnsupportedType
+	}
+	return err
+}
+
+// readStrings reads a length prefixed sequence of strings, the length
+// counting groups of size strings.
+func (p *Parser) readStrings(size int) ([][]byte, error) {
+	n, _, err := p.readLength()
+	if err != nil {
+		return nil, err
+	}
+	var res [][]byte
+	for i := uint64(0); i < n*uint64(size); i++ {
+		s, err := p.readString()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+// readEncoded reads a string holding one of the compact encodings and
+// returns its elements.
+func (p *Parser) readEncoded(t byte) ([][]byte, error) {
+	b, err := p.readString()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case TypeHashZipmap:
+		return parseZipmap(b)
+	case TypeSetIntset:
+		return parseIntset(b)
+	case TypeHashListpack, TypeZSetListpack, TypeSetListpack:
+		return parseListpack(b)
+	default:
+		return parseZiplist(b)
+	}
+}
+
+func (p *Parser) readQuicklist(t byte) ([][]byte, error) {
+	n, _, err := p.readLength()
+	if err != nil {
+		return nil, err
+	}
+	var res [][]byte
+	for i := uint64(0); i < n; i++ {
+		container := uint64(quicklistNodePacked)
+		if t == TypeListQuicklist2 {
+			if container, _, err = p.readLength(); err != nil {
+				return nil, err
+			}
+		}
+		b, err := p.readString()
+		if err != nil {
+			return nil, err
+		}
+		var elems [][]byte
+		switch {
+		case container == quicklistNodePlain:
+			elems = [][]byte{b}
+		case t == TypeListQuicklist2 && container == quicklistNodePacked:
+			elems, err = parseListpack(b)
+		case t == TypeListQuicklist:
+			elems, err = parseZiplist(b)
+		default:
+			err = ErrInvalidEncoding
+		}
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, elems...)
+	}
+	return res, nil
+}
+
+func (p *Parser) readFull(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(p.r, b); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return b, nil
+}
+
+// readLength reads a length encoded value. When encoded is true the value
+// is one of the special string encodings instead of a length.
+func (p *Parser) readLength() (length uint64, encoded bool, err error) {
+	b, err := p.r.ReadByte()
+	if err != nil {
+		return 0, false, unexpectedEOF(err)
+	}
+	switch b >> 6 {
+	case 0:
+		return uint64(b & 0x3f), false, nil
+	case 1:
+		next, err := p.r.ReadByte()
+		if err != nil {
+			return 0, false, unexpectedEOF(err)
+		}
+		return uint64(b&0x3f)<<8 | uint64(next), false, nil
+	case 2:
+		switch b {
+		case 0x80:
+			buf, err := p.readFull(4)
+			if err != nil {
+				return 0, false, err
+			}
+			return uint64(binary.BigEndian.Uint32(buf)), false, nil
+		case 0x81:
+			buf, err := p.readFull(8)
+			if err != nil {
+				return 0, false, err
+			}
+			return binary.BigEndian.Uint64(buf), false, nil
+		}
+		return 0, false, ErrInvalidEncoding
+	}
+	return uint64(b & 0x3f), true, nil
+}
+
+func (p *Parser) readString() ([]byte, error) {
+	n, encoded, err := p.readLength()
+	if err != nil {
+		return nil, err
+	}
+	if !encoded {
+		if n > maxStringLen {
+			return nil, ErrInvalidEncoding
+		}
+		return p.readFull(int(n))
+	}
+	switch n {
+	case encInt8:
+		b, err := p.readFull(1)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strconv.FormatInt(int64(int8(b[0])), 10)), nil
+	case encInt16:
+		b, err := p.readFull(2)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strconv.FormatInt(int64(int16(binary.LittleEndian.Uint16(b))), 10)), nil
+	case encInt32:
+		b, err := p.readFull(4)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strconv.FormatInt(int64(int32(binary.LittleEndian.Uint32(b))), 10)), nil
+	case encLZF:
+		clen, _, err := p.readLength()
+		if err != nil {
+			return nil, err
+		}
+		ulen, _, err := p.readLength()
+		if err != nil {
+			return nil, err
+		}
+		if clen > maxStringLen || ulen > maxStringLen {
+			return nil, ErrInvalidEncoding
+		}
+		data, err := p.readFull(int(clen))
+		if err != nil {
+			return nil, err
+		}
+		return lzfDecompress(data, int(ulen))
+	}
+	return nil, ErrInvalidEncoding
+}
+
+// readDouble reads a score of the legacy zset encoding, stored as a string
+// prefixed by its length with special lengths for NaN and infinities.
+func (p *Parser) readDouble() (float64, error) {
+	l, err := p.r.ReadByte()
+	if err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	switch l {
+	case 253:
+		return math.NaN(), nil
+	case 254:
+		return math.Inf(1), nil
+	case 255:
+		return math.Inf(-1), nil
+	}
+	b, err := p.readFull(int(l))
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(string(b), 64)
+	if err != nil {
+		return 0, ErrInvalidEncoding
+	}
+	return f, nil
+}
+
+func (p *Parser) readBinaryDouble() (float64, error) {
+	b, err := p.readFull(8)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+}
+
+func pairsToHash(elems [][]byte) []HashField {
+	hash := make([]HashField, 0, len(elems)/2)
+	for i := 0; i+1 < len(elems); i += 2 {
+		hash = append(hash, HashField{Field: elems[i], Value: elems[i+1]})
+	}
+	return hash
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,261 @@
+package rdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+)
+
+// rdbWriter builds RDB files for tests.
+type rdbWriter struct {
+	bytes.Buffer
+}
+
+func newRDB(version string) *rdbWriter {
+	w := &rdbWriter{}
+	w.WriteString("REDIS" + version)
+	return w
+}
+
+func (w *rdbWriter) length(n uint64) *rdbWriter {
+	switch {
+	case n < 1<<6:
+		w.WriteByte(byte(n))
+	case n < 1<<14:
+		w.WriteByte(0x40 | byte(n>>8))
+		w.WriteByte(byte(n))
+	default:
+		w.WriteByte(0x80)
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, uint32(n))
+		w.Write(b)
+	}
+	return w
+}
+
+func (w *rdbWriter) str(s string) *rdbWriter {
+	w.length(uint64(len(s)))
+	w.WriteString(s)
+	return w
+}
+
+func (w *rdbWriter) raw(b ...byte) *rdbWriter {
+	w.Write(b)
+	return w
+}
+
+func (w *rdbWriter) u64(v uint64) *rdbWriter {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, v)
+	w.Write(b)
+	return w
+}
+
+func (w *rdbWriter) id(ms, seq uint64) *rdbWriter {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b, ms)
+	binary.BigEndian.PutUint64(b[8:], seq)
+	w.str(string(b))
+	return w
+}
+
+func (w *rdbWriter) end() []byte {
+	w.WriteByte(opEOF)
+	w.u64(0)
+	return w.Bytes()
+}
+
+func parseAll(t *testing.T, data []byte) (*Parser, []*Entry) {
+	p := NewParser(bytes.NewReader(data))
+	var entries []*Entry
+	for {
+		e, err := p.Next()
+		if err == io.EOF {
+			return p, entries
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+}
+
+func TestParseStrings(t *testing.T) {
+	w := newRDB("0009")
+	w.raw(opAux).str("redis-ver").str("5.0.7")
+	w.raw(opSelectDB).length(0)
+	w.raw(opResizeDB).length(4).length(1)
+	w.raw(TypeString).str("plain").str("value")
+	w.raw(opExpireTimeMs).u64(1700000000123)
+	w.raw(TypeString).str("int8").raw(0xC0, 0x85)
+	w.raw(opExpireTime).raw(0x00, 0xF1, 0x53, 0x65)
+	w.raw(TypeString).str("int16").raw(0xC1, 0x39, 0x30)
+	w.raw(opSelectDB).length(3)
+	w.raw(TypeString).str("int32").raw(0xC2, 0x00, 0x94, 0x35, 0x77)
+	w.raw(opFreq, 5)
+	w.raw(TypeString).str("lzf").raw(0xC3).length(5).length(10).raw(0x00, 'a', 0xE0, 0x00, 0x00)
+	p, entries := parseAll(t, w.end())
+
+	if p.Version != 9 {
+		t.Errorf("error version: %d", p.Version)
+	}
+	if p.Aux["redis-ver"] != "5.0.7" {
+		t.Errorf("error aux fields: %v", p.Aux)
+	}
+	if len(entries) != 5 {
+		t.Fatalf("should contains five entries, not %d", len(entries))
+	}
+	expected := []struct {
+		db     int
+		key    string
+		value  string
+		expiry int64
+	}{
+		{0, "plain", "value", 0},
+		{0, "int8", "-123", 1700000000123},
+		{0, "int16", "12345", 1700000000000},
+		{3, "int32", "2000000000", 0},
+		{3, "lzf", "aaaaaaaaaa", 0},
+	}
+	for i, e := range expected {
+		entry := entries[i]
+		if entry.Kind != KindString || entry.DB != e.db || string(entry.Key) != e.key ||
+			string(entry.String) != e.value || entry.Expiry != e.expiry {
+			t.Errorf("error entry %d: %+v", i, entry)
+		}
+	}
+}
+
+func TestParseCollections(t *testing.T) {
+	w := newRDB("0011")
+	w.raw(TypeList).str("list").length(2).str("a").str("b")
+	w.raw(TypeSet).str("set").length(1).str("m")
+	w.raw(TypeZSet).str("zset").length(2).str("one").raw(1, '1').str("inf").raw(254)
+	w.raw(TypeZSet2).str("zset2").length(1).str("pi").u64(math.Float64bits(3.25))
+	w.raw(TypeHash).str("hash").length(1).str("f").str("v")
+	w.raw(TypeListZiplist).str("ziplist").str(string(ziplist("x", 5)))
+	w.raw(TypeSetIntset).str("intset").str(string(intset(2, 1, 2)))
+	w.raw(TypeHashZiplist).str("hashzl").str(string(ziplist("f", "v")))
+	w.raw(TypeZSetZiplist).str("zsetzl").str(string(ziplist("m", "1.5")))
+	w.raw(TypeListQuicklist).str("quicklist").length(2).
+		str(string(ziplist("a"))).str(string(ziplist("b", "c")))
+	w.raw(TypeHashListpack).str("hashlp").str(string(listpack("f", 1)))
+	w.raw(TypeZSetListpack).str("zsetlp").str(string(listpack("m", 2)))
+	w.raw(TypeListQuicklist2).str("quicklist2").length(2).
+		length(quicklistNodePacked).str(string(listpack("a", "b"))).
+		length(quicklistNodePlain).str("plain")
+	w.raw(TypeSetListpack).str("setlp").str(string(listpack("x", "y")))
+	w.raw(TypeHashZipmap).str("zipmap").str(string([]byte{1, 1, 'k', 1, 0, 'v', 0xFF}))
+	_, entries := parseAll(t, w.end())
+
+	if len(entries) != 15 {
+		t.Fatalf("should contains 15 entries, not %d", len(entries))
+	}
+	byKey := make(map[string]*Entry)
+	for _, e := range entries {
+		byKey[string(e.Key)] = e
+	}
+
+	checkElems(t, byKey["list"].Values, "a", "b")
+	checkElems(t, byKey["set"].Values, "m")
+	checkElems(t, byKey["ziplist"].Values, "x", "5")
+	checkElems(t, byKey["intset"].Values, "1", "2")
+	checkElems(t, byKey["quicklist"].Values, "a", "b", "c")
+	checkElems(t, byKey["quicklist2"].Values, "a", "b", "plain")
+	checkElems(t, byKey["setlp"].Values, "x", "y")
+	if byKey["set"].Kind != KindSet || byKey["intset"].Kind != KindSet || byKey["quicklist"].Kind != KindList {
+		t.Error("error entry kind")
+	}
+
+	zset := byKey["zset"].ZSet
+	if len(zset) != 2 || string(zset[0].Member) != "one" || zset[0].Score != 1 || !math.IsInf(zset[1].Score, 1) {
+		t.Errorf("error zset: %+v", zset)
+	}
+	for key, score := range map[string]float64{"zset2": 3.25, "zsetzl": 1.5, "zsetlp": 2} {
+		z := byKey[key].ZSet
+		if len(z) != 1 || z[0].Score != score {
+			t.Errorf("error %s: %+v", key, z)
+		}
+	}
+
+	for key, value := range map[string]string{"hash": "v", "hashzl": "v", "hashlp": "1", "zipmap": "v"} {
+		h := byKey[key].Hash
+		if byKey[key].Kind != KindHash || len(h) != 1 || string(h[0].Value) != value {
+			t.Errorf("error %s: %+v", key, h)
+		}
+	}
+}
+
+func TestParseStream(t *testing.T) {
+	node := listpack(
+		// master entry: count, deleted, fields and terminator
+		2, 1, 2, "f1", "f2", 0,
+		// entry with the master fields
+		streamItemFlagSameFields, 0, 0, "v1", "v2", 6,
+		// deleted entry
+		streamItemFlagSameFields|streamItemFlagDeleted, 1, 0, "x", "y", 6,
+		// entry with its own fields
+		0, 5, 1, 1, "other", "v", 7,
+	)
+	w := newRDB("0011")
+	w.raw(TypeStreamListpacks2).str("stream")
+	w.length(1).id(1000, 0).str(string(node))
+	w.length(2).length(1005).length(1)                        // length, last ID
+	w.length(1000).length(0)                                  // first ID
+	w.length(1001).length(0)                                  // max deleted ID
+	w.length(3)                                               // entries added
+	w.length(1).str("group").length(1000).length(0).length(1) // group
+	w.length(1).raw(make([]byte, 16)...)                      // PEL
+	w.u64(1700000000000).length(2)
+	w.length(2)
+	w.str("alice").u64(1700000000001).length(1).raw(make([]byte, 16)...)
+	w.str("bob").u64(1700000000002).length(0)
+	_, entries := parseAll(t, w.end())
+
+	if len(entries) != 1 || entries[0].Kind != KindStream {
+		t.Fatalf("should contains a stream: %+v", entries)
+	}
+	s := entries[0].Stream
+	if len(s.Entries) != 2 {
+		t.Fatalf("should contains two entries, not %d", len(s.Entries))
+	}
+	if s.Entries[0].ID != (StreamID{1000, 0}) || s.Entries[1].ID != (StreamID{1005, 1}) {
+		t.Errorf("error entry IDs: %v %v", s.Entries[0].ID, s.Entries[1].ID)
+	}
+	checkElems(t, s.Entries[0].Fields, "f1", "v1", "f2", "v2")
+	checkElems(t, s.Entries[1].Fields, "other", "v")
+	if s.Length != 2 || s.LastID.String() != "1005-1" || !s.HasMeta || s.EntriesAdded != 3 {
+		t.Errorf("error stream metadata: %+v", s)
+	}
+	if len(s.Groups) != 1 {
+		t.Fatalf("should contains one group")
+	}
+	g := s.Groups[0]
+	if string(g.Name) != "group" || g.EntriesRead != 1 || len(g.Pending) != 1 ||
+		g.Pending[0].DeliveryCount != 2 || len(g.Consumers) != 2 {
+		t.Errorf("error group: %+v", g)
+	}
+	if string(g.Consumers[0].Name) != "alice" || len(g.Consumers[0].Pending) != 1 {
+		t.Errorf("error consumer: %+v", g.Consumers[0])
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := NewParser(bytes.NewReader([]byte("RIDES0009"))).Next(); err != ErrInvalidMagic {
+		t.Errorf("should return ErrInvalidMagic, not: %v", err)
+	}
+	if _, err := NewParser(bytes.NewReader([]byte("REDIS0099"))).Next(); err != ErrUnsupportedVersion {
+		t.Errorf("should return ErrUnsupportedVersion, not: %v", err)
+	}
+
+	data := newRDB("0009").raw(TypeString).str("key").str("value").end()
+	if _, err := NewParser(bytes.NewReader(data[:15])).Next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("should return io.ErrUnexpectedEOF, not: %v", err)
+	}
+
+	data = newRDB("0009").raw(TypeModule2).str("key").end()
+	if _, err := NewParser(bytes.NewReader(data)).Next(); err != ErrUnsupportedType {
+		t.Errorf("should return ErrUnsupportedType, not: %v", err)
+	}
+}
@@ -0,0 +1,302 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"strconv"
+)
+
+const (
+	streamItemFlagDeleted    = 1
+	streamItemFlagSameFields = 2
+)
+
+// StreamID is the ID of a stream entry.
+type StreamID struct {
+	Ms  uint64
+	Seq uint64
+}
+
+// String returns the ID in the ms-seq form used by redis commands.
+func (id StreamID) String() string {
+	return strconv.FormatUint(id.Ms, 10) + "-" + strconv.FormatUint(id.Seq, 10)
+}
+
+// StreamEntry is a single entry of a stream, with fields and values
+// alternated.
+type StreamEntry struct {
+	ID     StreamID
+	Fields [][]byte
+}
+
+// StreamPending is an entry of the pending entries list of a consumer group.
+type StreamPending struct {
+	ID            StreamID
+	DeliveryTime  int64 // unix time in milliseconds
+	DeliveryCount uint64
+}
+
+// StreamConsumer is a consumer of a consumer group.
+type StreamConsumer struct {
+	Name       []byte
+	SeenTime   int64 // unix time in milliseconds
+	ActiveTime int64 // unix time in milliseconds, only set since RDB 12
+	Pending    []StreamID
+}
+
+// StreamGroup is a consumer group of a stream.
+type StreamGroup struct {
+	Name        []byte
+	LastID      StreamID
+	EntriesRead int64 // -1 when unknown
+	Pending     []StreamPending
+	Consumers   []StreamConsumer
+}
+
+// Stream is the value of a stream key.
+type Stream struct {
+	Entries []StreamEntry
+	Length  uint64
+	LastID  StreamID
+	// The following fields are only stored since RDB 10, HasMeta reports
+	// whether they were read.
+	HasMeta      bool
+	FirstID      StreamID
+	MaxDeletedID StreamID
+	EntriesAdded uint64
+	Groups       []StreamGroup
+}
+
+func (p *Parser) readStreamID() (StreamID, error) {
+	var id StreamID
+	var err error
+	if id.Ms, _, err = p.readLength(); err != nil {
+		return id, err
+	}
+	id.Seq, _, err = p.readLength()
+	return id, err
+}
+
+func (p *Parser) readRawStreamID() (StreamID, error) {
+	b, err := p.readFull(16)
+	if err != nil {
+		return StreamID{}, err
+	}
+	return rawStreamID(b), nil
+}
+
+func rawStreamID(b []byte) StreamID {
+	return StreamID{
+		Ms:  binary.BigEndian.Uint64(b[:8]),
+		Seq: binary.BigEndian.Uint64(b[8:16]),
+	}
+}
+
+func (p *Parser) readMillisecondTime() (int64, error) {
+	b, err := p.readFull(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint64(b)), nil
+}
+
+func (p *Parser) readStream(t byte) (*Stream, error) {
+	s := &Stream{}
+	nodes, _, err := p.readLength()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < nodes; i++ {
+		key, err := p.readString()
+		if err != nil {
+			return nil, err
+		}
+		if len(key) != 16 {
+			return nil, ErrInvalidEncoding
+		}
+		lp, err := p.readString()
+		if err != nil {
+			return nil, err
+		}
+		elems, err := parseListpack(lp)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := parseStreamListpack(rawStreamID(key), elems)
+		if err != nil {
+			return nil, err
+		}
+		s.Entries = append(s.Entries, entries...)
+	}
+
+	if s.Length, _, err = p.readLength(); err != nil {
+		return nil, err
+	}
+	if s.LastID, err = p.readStreamID(); err != nil {
+		return nil, err
+	}
+	if t >= TypeStreamListpacks2 {
+		s.HasMeta = true
+		if s.FirstID, err = p.readStreamID(); err != nil {
+			return nil, err
+		}
+		if s.MaxDeletedID, err = p.readStreamID(); err != nil {
+			return nil, err
+		}
+		if s.EntriesAdded, _, err = p.readLength(); err != nil {
+			return nil, err
+		}
+	}
+
+	ngroups, _, err := p.readLength()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < ngroups; i++ {
+		g, err := p.readStreamGroup(t)
+		if err != nil {
+			return nil, err
+		}
+		s.Groups = append(s.Groups, g)
+	}
+	return s, nil
+}
+
+func (p *Parser) readStreamGroup(t byte) (StreamGroup, error) {
+	g := StreamGroup{EntriesRead: -1}
+	var err error
+	if g.Name, err = p.readString(); err != nil {
+		return g, err
+	}
+	if g.LastID, err = p.readStreamID(); err != nil {
+		return g, err
+	}
+	if t >= TypeStreamListpacks2 {
+		n, _, err := p.readLength()
+		if err != nil {
+			return g, err
+		}
+		g.EntriesRead = int64(n)
+	}
+
+	npel, _, err := p.readLength()
+	if err != nil {
+		return g, err
+	}
+	for i := uint64(0); i < npel; i++ {
+		var pe StreamPending
+		if pe.ID, err = p.readRawStreamID(); err != nil {
+			return g, err
+		}
+		if pe.DeliveryTime, err = p.readMillisecondTime(); err != nil {
+			return g, err
+		}
+		if pe.DeliveryCount, _, err = p.readLength(); err != nil {
+			return g, err
+		}
+		g.Pending = append(g.Pending, pe)
+	}
+
+	nconsumers, _, err := p.readLength()
+	if err != nil {
+		return g, err
+	}
+	for i := uint64(0); i < nconsumers; i++ {
+		var c StreamConsumer
+		if c.Name, err = p.readString(); err != nil {
+			return g, err
+		}
+		if c.SeenTime, err = p.readMillisecondTime(); err != nil {
+			return g, err
+		}
+		if t >= TypeStreamListpacks3 {
+			if c.ActiveTime, err = p.readMillisecondTime(); err != nil {
+				return g, err
+			}
+		}
+		n, _, err := p.readLength()
+		if err != nil {
+			return g, err
+		}
+		for j := uint64(0); j < n; j++ {
+			id, err := p.readRawStreamID()
+			if err != nil {
+				return g, err
+			}
+			c.Pending = append(c.Pending, id)
+		}
+		g.Consumers = append(g.Consumers, c)
+	}
+	return g, nil
+}
+
+// listpackIter walks the elements of a stream listpack, remembering the
+// first error met.
+type listpackIter struct {
+	elems [][]byte
+	pos   int
+	err   error
+}
+
+func (it *listpackIter) next(n int) [][]byte {
+	if it.err != nil {
+		return nil
+	}
+	if n < 0 || it.pos+n > len(it.elems) {
+		it.err = ErrInvalidEncoding
+		return nil
+	}
+	res := it.elems[it.pos : it.pos+n]
+	it.pos += n
+	return res
+}
+
+func (it *listpackIter) int() int64 {
+	elems := it.next(1)
+	if elems == nil {
+		return 0
+	}
+	v, err := strconv.ParseInt(string(elems[0]), 10, 64)
+	if err != nil {
+		it.err = ErrInvalidEncoding
+	}
+	return v
+}
+
+// parseStreamListpack decodes the entries of a stream listpack node. The
+// node starts with a master entry holding the fields shared by entries
+// flagged with SAMEFIELDS, each entry IDs being stored as a delta from the
+// master ID.
+func parseStreamListpack(master StreamID, elems [][]byte) ([]StreamEntry, error) {
+	it := &listpackIter{elems: elems}
+	it.int() // count
+	it.int() // deleted
+	masterFields := it.next(int(it.int()))
+	it.int() // master entry terminator
+
+	var entries []StreamEntry
+	for it.err == nil && it.pos < len(it.elems) {
+		flags := it.int()
+		e := StreamEntry{
+			ID: StreamID{
+				Ms:  master.Ms + uint64(it.int()),
+				Seq: master.Seq + uint64(it.int()),
+			},
+		}
+		if flags&streamItemFlagSameFields != 0 {
+			values := it.next(len(masterFields))
+			for i := range values {
+				e.Fields = append(e.Fields, masterFields[i], values[i])
+			}
+		} else {
+			e.Fields = it.next(int(it.int()) * 2)
+		}
+		it.int() // lp-count
+		if flags&streamItemFlagDeleted == 0 {
+			entries = append(entries, e)
+		}
+	}
+	if it.err != nil {
+		return nil, it.err
+	}
+	return entries, nil
+}
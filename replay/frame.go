@@ -0,0 +1,149 @@
+// Package replay records both directions of RESP connections to a file and
+// replays them, either the client side against a server or the server side
+// to a client, reporting where the conversation diverges from the recording.
+//
+// A recording is itself a RESP stream: a header followed by one frame per
+// message, each frame being an array of the direction, the time in
+// nanoseconds since the Unix epoch and the message:
+//
+//	*2\r\n$7\r\nRESPREC\r\n:1\r\n
+//	*3\r\n$1\r\n>\r\n:1500000000000000000\r\n*1\r\n$4\r\nPING\r\n
+//	*3\r\n$1\r\n<\r\n:1500000000000250000\r\n+PONG\r\n
+package replay
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+const (
+	magic   = "RESPREC"
+	version = 1
+)
+
+var (
+	// ErrInvalidHeader is returned when a file does not start with the
+	// header of a recording
+	ErrInvalidHeader = errors.New("replay: invalid recording header")
+
+	// ErrUnsupportedVersion is returned when a recording was written by a
+	// newer version of the package
+	ErrUnsupportedVersion = errors.New("replay: unsupported recording version")
+
+	// ErrInvalidFrame is returned when a message of a recording is not a
+	// frame
+	ErrInvalidFrame = errors.New("replay: invalid frame")
+)
+
+// Direction is the direction of a message.
+type Direction byte
+
+const (
+	// Request is a message sent by the client to the server
+	Request Direction = '>'
+	// Reply is a message sent by the server to the client
+	Reply Direction = '<'
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Request:
+		return "request"
+	case Reply:
+		return "reply"
+	}
+	return "unknown"
+}
+
+// Frame is a recorded message.
+type Frame struct {
+	Time time.Time
+	Dir  Direction
+	Msg  *resp.Message
+}
+
+// message returns the frame as a RESP message.
+func (f *Frame) message() *resp.Message {
+	return resp.Array(
+		resp.Bulk([]byte{byte(f.Dir)}),
+		resp.Int(f.Time.UnixNano()),
+		f.Msg,
+	)
+}
+
+func parseFrame(m *resp.Message) (*Frame, error) {
+	if m.Type != resp.ArrayHeader || len(m.Array) != 3 {
+		return nil, ErrInvalidFrame
+	}
+	dir, ts, msg := m.Array[0], m.Array[1], m.Array[2]
+	if dir.Type != resp.BulkHeader || len(dir.Bytes) != 1 || ts.Type != resp.IntegerHeader {
+		return nil, ErrInvalidFrame
+	}
+	f := &Frame{
+		Time: time.Unix(0, ts.Integer),
+		Dir:  Direction(dir.Bytes[0]),
+		Msg:  msg,
+	}
+	if f.Dir != Request && f.Dir != Reply {
+		return nil, ErrInvalidFrame
+	}
+	return f, nil
+}
+
+func header() *resp.Message {
+	return resp.Array(resp.Bulk([]byte(magic)), resp.Int(version))
+}
+
+// Reader reads the frames of a recording.
+type Reader struct {
+	r      *resp.Reader
+	header bool
+}
+
+// NewReader returns a Reader reading a recording from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: resp.NewReader(r)}
+}
+
+// Next returns the next frame of the recording, or io.EOF at its end.
+func (r *Reader) Next() (*Frame, error) {
+	if !r.header {
+		m, err := r.r.ReadMessage()
+		if err == io.EOF {
+			return nil, ErrInvalidHeader
+		} else if err != nil {
+			return nil, err
+		}
+		if m.Type != resp.ArrayHeader || len(m.Array) != 2 ||
+			string(m.Array[0].Bytes) != magic || m.Array[1].Type != resp.IntegerHeader {
+			return nil, ErrInvalidHeader
+		}
+		if m.Array[1].Integer > version {
+			return nil, ErrUnsupportedVersion
+		}
+		r.header = true
+	}
+	m, err := r.r.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	return parseFrame(m)
+}
+
+// ReadAll reads every frame of a recording.
+func ReadAll(r io.Reader) ([]*Frame, error) {
+	fr := NewReader(r)
+	var frames []*Frame
+	for {
+		f, err := fr.Next()
+		if err == io.EOF {
+			return frames, nil
+		} else if err != nil {
+			return frames, err
+		}
+		frames = append(frames, f)
+	}
+}
@@ -0,0 +1,63 @@
+package replay
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+func TestRecordRead(t *testing.T) {
+	var buf bytes.Buffer
+	rec, err := NewRecorder(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1500000000, 0)
+	rec.now = func() time.Time { return now }
+	rec.Record(Request, resp.Cmd("PING"))
+	now = now.Add(250 * time.Microsecond)
+	rec.Record(Reply, resp.Str("PONG"))
+
+	encoded := "*2\r\n$7\r\nRESPREC\r\n:1\r\n" +
+		"*3\r\n$1\r\n>\r\n:1500000000000000000\r\n*1\r\n$4\r\nPING\r\n" +
+		"*3\r\n$1\r\n<\r\n:1500000000000250000\r\n+PONG\r\n"
+	if buf.String() != encoded {
+		t.Errorf("error recording: %q", buf.String())
+	}
+
+	frames, err := ReadAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 2 {
+		t.Fatalf("error frames: %v", frames)
+	}
+	if frames[0].Dir != Request || !frames[0].Time.Equal(time.Unix(1500000000, 0)) ||
+		!resp.Equal(frames[0].Msg, resp.Cmd("PING")) {
+		t.Errorf("error first frame: %v", frames[0])
+	}
+	if frames[1].Dir != Reply || frames[1].Time.Sub(frames[0].Time) != 250*time.Microsecond ||
+		frames[1].Msg.Status != "PONG" {
+		t.Errorf("error second frame: %v", frames[1])
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	if _, err := ReadAll(strings.NewReader("")); err != ErrInvalidHeader {
+		t.Errorf("error empty recording error: %v", err)
+	}
+	if _, err := ReadAll(strings.NewReader("+OK\r\n")); err != ErrInvalidHeader {
+		t.Errorf("error invalid header error: %v", err)
+	}
+	if _, err := ReadAll(strings.NewReader("*2\r\n$7\r\nRESPREC\r\n:2\r\n")); err != ErrUnsupportedVersion {
+		t.Errorf("error version error: %v", err)
+	}
+	frames, err := ReadAll(strings.NewReader("*2\r\n$7\r\nRESPREC\r\n:1\r\n" +
+		"*3\r\n$1\r\n<\r\n:0\r\n+OK\r\n*3\r\n$1\r\n?\r\n:0\r\n+OK\r\n"))
+	if err != ErrInvalidFrame || len(frames) != 1 {
+		t.Errorf("error invalid frame error: %v", err)
+	}
+}
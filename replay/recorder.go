@@ -0,0 +1,162 @@
+package replay
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// Recorder writes frames to a recording. Its methods are safe for concurrent
+// use, but a recording should hold a single connection, as frames do not
+// tell connections apart.
+type Recorder struct {
+	mu   sync.Mutex
+	w    io.Writer
+	wbuf bytes.Buffer
+	enc  *resp.Encoder
+	err  error
+	now  func() time.Time
+}
+
+// NewRecorder writes the header of a recording to w and returns a Recorder
+// writing the frames that follow.
+func NewRecorder(w io.Writer) (*Recorder, error) {
+	r := &Recorder{w: w, now: time.Now}
+	r.enc = resp.NewEncoder(&r.wbuf)
+	if err := r.write(header()); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// write encodes m to wbuf, then writes it to the underlying writer in one
+// call, so that a frame failing to encode is not written at all.
+func (r *Recorder) write(m *resp.Message) error {
+	r.wbuf.Reset()
+	if err := r.enc.Encode(m); err != nil {
+		return err
+	}
+	_, err := r.w.Write(r.wbuf.Bytes())
+	return err
+}
+
+// Record writes a frame holding msg, timestamped with the current time.
+func (r *Recorder) Record(dir Direction, msg *resp.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	f := &Frame{Time: r.now(), Dir: dir, Msg: msg}
+	if err := r.write(f.message()); err != nil {
+		r.err = err
+	}
+	return r.err
+}
+
+// Err returns the first error which stopped the recording: a write error, or
+// a decode error of the data of a wrapped connection.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *Recorder) fail(err error) {
+	r.mu.Lock()
+	if r.err == nil {
+		r.err = err
+	}
+	r.mu.Unlock()
+}
+
+// WrapClient returns a connection recording the data exchanged on conn, a
+// connection of a client to a server: the data written is recorded as
+// requests and the data read as replies.
+func (r *Recorder) WrapClient(conn net.Conn) net.Conn {
+	return &recordedConn{
+		Conn: conn,
+		in:   &stream{rec: r, dir: Reply},
+		out:  &stream{rec: r, dir: Request},
+	}
+}
+
+// WrapServer returns a connection recording the data exchanged on conn, a
+// connection accepted by a server: the data read is recorded as requests and
+// the data written as replies.
+func (r *Recorder) WrapServer(conn net.Conn) net.Conn {
+	return &recordedConn{
+		Conn: conn,
+		in:   &stream{rec: r, dir: Request},
+		out:  &stream{rec: r, dir: Reply},
+	}
+}
+
+type recordedConn struct {
+	net.Conn
+	in, out *stream
+}
+
+func (c *recordedConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.in.feed(b[:n])
+	return n, err
+}
+
+func (c *recordedConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.out.feed(b[:n])
+	return n, err
+}
+
+// stream decodes the data of one direction of a connection and records the
+// messages, buffering incomplete ones until the rest of their data arrives.
+type stream struct {
+	mu  sync.Mutex
+	rec *Recorder
+	dir Direction
+	// buf holds the data of an incomplete message, scanned by framer
+	buf    []byte
+	framer resp.Framer
+	failed bool
+}
+
+func (s *stream) feed(b []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failed || len(b) == 0 {
+		return
+	}
+	s.buf = append(s.buf, b...)
+	// the data is only decoded once it holds whole messages, or data which
+	// is not valid
+	end, ok := s.framer.Scan(s.buf)
+	if ok && end == 0 {
+		return
+	}
+	data := s.buf[:end]
+	if !ok {
+		data = s.buf
+	}
+	msgQ, pos, err := resp.Decode(data)
+	for _, msg := range msgQ {
+		s.rec.Record(s.dir, msg)
+	}
+	if err != nil && !resp.MaybeSegmentError(err) {
+		s.failed = true
+		s.buf = nil
+		s.rec.fail(err)
+		return
+	}
+	// the messages are encoded by Record, the buffer can be reused
+	s.buf = append(s.buf[:0], s.buf[pos:]...)
+	if ok {
+		s.framer.Discard(pos)
+	} else {
+		s.framer.Reset()
+	}
+}
@@ -0,0 +1,137 @@
+package replay
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/amyangfei/resp-go/resp"
+	"github.com/amyangfei/resp-go/resptest"
+)
+
+func TestWrapClient(t *testing.T) {
+	s := resptest.NewServer()
+	defer s.Close()
+	conn, err := net.Dial("tcp", s.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	rec, err := NewRecorder(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := resp.NewConn(rec.WrapClient(conn))
+	defer c.Close()
+
+	c.Send(resp.Cmd("SET", "a", "1"))
+	c.Send(resp.Cmd("GET", "a"))
+	c.Flush()
+	c.Receive()
+	c.Receive()
+
+	frames, err := ReadAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range frames {
+		got = append(got, f.Dir.String()+" "+f.Msg.String())
+	}
+	expected := []string{
+		"request 1) \"SET\"\n2) \"a\"\n3) \"1\"",
+		"request 1) \"GET\"\n2) \"a\"",
+		"reply OK",
+		"reply \"1\"",
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("error frames: %q", got)
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Errorf("error frame %d: %q", i, got[i])
+		}
+	}
+}
+
+func TestWrapServerSegments(t *testing.T) {
+	client, server := net.Pipe()
+	var buf bytes.Buffer
+	rec, _ := NewRecorder(&buf)
+	conn := rec.WrapServer(server)
+
+	go func() {
+		// a request split in several writes
+		client.Write([]byte("*1\r\n$4\r"))
+		client.Write([]byte("\nPI"))
+		client.Write([]byte("NG\r\n?x\r\n"))
+		client.Close()
+	}()
+	b := make([]byte, 16)
+	for {
+		if _, err := conn.Read(b); err != nil {
+			break
+		}
+	}
+	frames, err := ReadAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 1 || frames[0].Dir != Request || !resp.Equal(frames[0].Msg, resp.Cmd("PING")) {
+		t.Errorf("error frames: %v", frames)
+	}
+	if rec.Err() == nil {
+		t.Error("the invalid data should stop the recording")
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(b []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestRecorderWriteError(t *testing.T) {
+	if _, err := NewRecorder(failingWriter{}); err == nil {
+		t.Error("error expected")
+	}
+}
+
+func TestStreamSmallReads(t *testing.T) {
+	var buf bytes.Buffer
+	rec, _ := NewRecorder(&buf)
+	s := &stream{rec: rec, dir: Reply}
+	big := bytes.Repeat([]byte("v"), 100000)
+	var data bytes.Buffer
+	enc := resp.NewEncoder(&data)
+	msgs := []*resp.Message{resp.Bulk(big), resp.Array(resp.Int(1), resp.Str("OK")), resp.Nil()}
+	for _, m := range msgs {
+		enc.Encode(m)
+	}
+	// a large reply arriving in many small reads
+	b := data.Bytes()
+	for len(b) > 0 {
+		n := 7
+		if n > len(b) {
+			n = len(b)
+		}
+		s.feed(b[:n])
+		b = b[n:]
+	}
+	if len(s.buf) != 0 || rec.Err() != nil {
+		t.Errorf("error stream state: %d bytes left, %v", len(s.buf), rec.Err())
+	}
+	frames, err := ReadAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != len(msgs) {
+		t.Fatalf("error frames: %d", len(frames))
+	}
+	for i, f := range frames {
+		if !resp.Equal(f.Msg, msgs[i]) {
+			t.Errorf("error frame %d: %v", i, f.Msg)
+		}
+	}
+}
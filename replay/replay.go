@@ -0,0 +1,215 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// Exchange is a request and the replies it received.
+type Exchange struct {
+	// Request is nil for the replies recorded before the first request
+	Request *Frame
+	Replies []*Frame
+}
+
+// Exchanges pairs the requests of a recording with their replies. Replies
+// are paired in order with the requests waiting for one, as pipelined
+// requests are replied in order, and the replies received while no request
+// is waiting, such as the extra replies of SUBSCRIBE or published messages,
+// are added to the last request.
+func Exchanges(frames []*Frame) []*Exchange {
+	var exchanges []*Exchange
+	waiting := 0
+	for _, f := range frames {
+		switch f.Dir {
+		case Request:
+			exchanges = append(exchanges, &Exchange{Request: f})
+			waiting++
+		case Reply:
+			if len(exchanges) == 0 {
+				exchanges = append(exchanges, &Exchange{})
+			}
+			e := exchanges[len(exchanges)-1]
+			if waiting > 0 {
+				e = exchanges[len(exchanges)-waiting]
+				waiting--
+			}
+			e.Replies = append(e.Replies, f)
+		}
+	}
+	return exchanges
+}
+
+// Options are the options of a replay.
+type Options struct {
+	// Speed scales the recorded delays: between requests when replaying the
+	// client side and before replies when replaying the server side. The
+	// replay runs as fast as possible when 0, at the recorded pace when 1.
+	Speed float64
+	// Ignore returns true for the requests whose exchange must not be
+	// compared, such as commands replying with the current time.
+	Ignore func(req *resp.Message) bool
+}
+
+func (o *Options) ignored(req *resp.Message) bool {
+	return o != nil && o.Ignore != nil && o.Ignore(req)
+}
+
+func (o *Options) delay(ctx context.Context, d time.Duration) error {
+	if o == nil || o.Speed <= 0 || d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(time.Duration(float64(d) / o.Speed))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Divergence is a difference between a replayed message and the recording.
+type Divergence struct {
+	// Exchange is the index of the exchange in the recording
+	Exchange int
+	// Request is the recorded request
+	Request *resp.Message
+	// Expected is the recorded message, Actual the one received, nil if
+	// none was
+	Expected, Actual *resp.Message
+	// Diff describes the differences, as returned by resp.Diff
+	Diff []string
+}
+
+func (d *Divergence) String() string {
+	return fmt.Sprintf("#%d %s: %s", d.Exchange, commandLine(d.Request), strings.Join(d.Diff, "; "))
+}
+
+// Report is the result of a replay.
+type Report struct {
+	// Exchanges is the number of exchanges replayed
+	Exchanges int
+	// Divergences lists the messages which differ from the recording
+	Divergences []*Divergence
+}
+
+// Ok reports whether the replay matched the recording.
+func (r *Report) Ok() bool {
+	return len(r.Divergences) == 0
+}
+
+func (r *Report) compare(i int, req, expected, actual *resp.Message) {
+	if diff := resp.Diff(expected, actual); diff != nil {
+		r.Divergences = append(r.Divergences, &Divergence{
+			Exchange: i,
+			Request:  req,
+			Expected: expected,
+			Actual:   actual,
+			Diff:     diff,
+		})
+	}
+}
+
+// ReplayClient replays the client side of a recording: the requests are sent
+// one at a time to c, the replies received being compared with the recorded
+// ones. An error is returned if the connection fails, along with the report
+// of the exchanges replayed so far.
+func ReplayClient(ctx context.Context, frames []*Frame, c *resp.Conn, opts *Options) (*Report, error) {
+	report := &Report{}
+	var last time.Time
+	for i, e := range Exchanges(frames) {
+		if e.Request == nil {
+			continue
+		}
+		if !last.IsZero() {
+			if err := opts.delay(ctx, e.Request.Time.Sub(last)); err != nil {
+				return report, err
+			}
+		}
+		last = e.Request.Time
+
+		if err := c.Send(e.Request.Msg); err != nil {
+			return report, err
+		}
+		if err := c.FlushContext(ctx); err != nil {
+			return report, err
+		}
+		for _, expected := range e.Replies {
+			actual, err := c.ReceiveContext(ctx)
+			if err != nil {
+				return report, err
+			}
+			if !opts.ignored(e.Request.Msg) {
+				report.compare(i, e.Request.Msg, expected.Msg, actual)
+			}
+		}
+		report.Exchanges++
+	}
+	return report, nil
+}
+
+// ReplayServer replays the server side of a recording to the client
+// connected to rw: each request read is compared with the recorded one and
+// answered with the recorded replies, whatever the request. A client
+// closing the connection early is reported as a divergence, with the
+// missing request received as nil.
+func ReplayServer(ctx context.Context, frames []*Frame, rw io.ReadWriter, opts *Options) (*Report, error) {
+	report := &Report{}
+	r := resp.NewReader(rw)
+	var wbuf bytes.Buffer
+	enc := resp.NewEncoder(&wbuf)
+	for i, e := range Exchanges(frames) {
+		if e.Request != nil {
+			actual, err := r.ReadMessageContext(ctx)
+			if err == io.EOF {
+				report.compare(i, e.Request.Msg, e.Request.Msg, nil)
+				return report, nil
+			} else if err != nil {
+				return report, err
+			}
+			if !opts.ignored(e.Request.Msg) {
+				report.compare(i, e.Request.Msg, e.Request.Msg, actual)
+			}
+		}
+		last := time.Time{}
+		if e.Request != nil {
+			last = e.Request.Time
+		}
+		for _, reply := range e.Replies {
+			if !last.IsZero() {
+				if err := opts.delay(ctx, reply.Time.Sub(last)); err != nil {
+					return report, err
+				}
+			}
+			last = reply.Time
+			wbuf.Reset()
+			if err := enc.Encode(reply.Msg); err != nil {
+				return report, err
+			}
+			if _, err := rw.Write(wbuf.Bytes()); err != nil {
+				return report, err
+			}
+		}
+		report.Exchanges++
+	}
+	return report, nil
+}
+
+// commandLine returns the arguments of a command separated by spaces.
+func commandLine(cmd *resp.Message) string {
+	if cmd == nil {
+		return "(no request)"
+	}
+	args, err := cmd.Strings()
+	if err != nil {
+		return cmd.String()
+	}
+	return strings.Join(args, " ")
+}
@@ -0,0 +1,127 @@
+package replay
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+	"github.com/amyangfei/resp-go/resptest"
+)
+
+func frame(dir Direction, ms int, m *resp.Message) *Frame {
+	return &Frame{Time: time.Unix(0, int64(ms)*int64(time.Millisecond)), Dir: dir, Msg: m}
+}
+
+func TestExchanges(t *testing.T) {
+	frames := []*Frame{
+		frame(Reply, 0, resp.Str("hello")),
+		frame(Request, 1, resp.Cmd("SET", "a", "1")),
+		frame(Request, 2, resp.Cmd("GET", "a")),
+		frame(Reply, 3, resp.Str("OK")),
+		frame(Reply, 4, resp.Bulk([]byte("1"))),
+		frame(Request, 5, resp.Cmd("SUBSCRIBE", "a", "b")),
+		frame(Reply, 6, resp.Array(resp.Bulk([]byte("subscribe")), resp.Bulk([]byte("a")), resp.Int(1))),
+		frame(Reply, 7, resp.Array(resp.Bulk([]byte("subscribe")), resp.Bulk([]byte("b")), resp.Int(2))),
+	}
+	exchanges := Exchanges(frames)
+	if len(exchanges) != 4 {
+		t.Fatalf("error exchanges: %d", len(exchanges))
+	}
+	counts := []int{1, 1, 1, 2}
+	for i, e := range exchanges {
+		if len(e.Replies) != counts[i] {
+			t.Errorf("error replies of exchange %d: %d", i, len(e.Replies))
+		}
+	}
+	if exchanges[0].Request != nil || exchanges[2].Replies[0].Msg.Type != resp.BulkHeader {
+		t.Error("error pairing")
+	}
+}
+
+func TestReplayClient(t *testing.T) {
+	s := resptest.NewServer()
+	defer s.Close()
+	c, err := resp.Dial("tcp", s.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	frames := []*Frame{
+		frame(Request, 0, resp.Cmd("SET", "a", "1")),
+		frame(Reply, 1, resp.Str("OK")),
+		frame(Request, 2, resp.Cmd("INCR", "a")),
+		frame(Reply, 3, resp.Int(3)),
+		frame(Request, 4, resp.Cmd("TIME")),
+		frame(Reply, 5, resp.Array(resp.Bulk([]byte("0")), resp.Bulk([]byte("0")))),
+	}
+	opts := &Options{
+		Ignore: func(req *resp.Message) bool {
+			return strings.EqualFold(string(req.Array[0].Bytes), "TIME")
+		},
+	}
+	report, err := ReplayClient(context.Background(), frames, c, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Exchanges != 3 || len(report.Divergences) != 1 {
+		t.Fatalf("error report: %+v", report)
+	}
+	d := report.Divergences[0]
+	if d.Exchange != 1 || d.Actual.Integer != 2 {
+		t.Errorf("error divergence: %+v", d)
+	}
+	if d.String() != "#1 INCR a: $: integer 3 != integer 2" {
+		t.Errorf("error divergence string: %s", d)
+	}
+}
+
+func TestReplayServer(t *testing.T) {
+	client, server := net.Pipe()
+	frames := []*Frame{
+		frame(Request, 0, resp.Cmd("GET", "a")),
+		frame(Reply, 1, resp.Bulk([]byte("1"))),
+		frame(Request, 2, resp.Cmd("GET", "b")),
+		frame(Reply, 3, resp.Nil()),
+		frame(Request, 4, resp.Cmd("QUIT")),
+		frame(Reply, 5, resp.Str("OK")),
+	}
+	done := make(chan *Report, 1)
+	go func() {
+		defer server.Close()
+		report, err := ReplayServer(context.Background(), frames, server, &Options{Speed: 100})
+		if err != nil {
+			t.Error(err)
+		}
+		done <- report
+	}()
+
+	c := resp.NewConn(client)
+	reply, err := c.Do(resp.Cmd("GET", "a"))
+	if err != nil {
+		t.Fatal(err)
+	} else if string(reply.Bytes) != "1" {
+		t.Errorf("error reply: %v", reply)
+	}
+	reply, err = c.Do(resp.Cmd("GET", "c"))
+	if err != nil {
+		t.Fatal(err)
+	} else if !reply.IsNil {
+		t.Errorf("error reply: %v", reply)
+	}
+	c.Close()
+
+	report := <-done
+	if report.Exchanges != 2 || len(report.Divergences) != 2 {
+		t.Fatalf("error report: %+v", report)
+	}
+	if d := report.Divergences[0]; d.Exchange != 1 || d.Actual == nil {
+		t.Errorf("error divergence: %s", d)
+	}
+	if d := report.Divergences[1]; d.Exchange != 2 || d.Actual != nil {
+		t.Errorf("error divergence: %s", d)
+	}
+}
@@ -0,0 +1,55 @@
+package resp
+
+import (
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	cases := []struct {
+		line string
+		args []string
+	}{
+		{"", nil},
+		{"  set  key value ", []string{"set", "key", "value"}},
+		{`set "a b" 'c d'`, []string{"set", "a b", "c d"}},
+		{`"\x00\xc3\xa9\n\r\t\a\b\"\\\q"`, []string{"\x00é\n\r\t\a\b\"\\q"}},
+		{`'it\'s' "" ''`, []string{"it's", "", ""}},
+		{`"\x1"`, []string{"x1"}},
+	}
+	for _, c := range cases {
+		args, err := SplitArgs(c.line)
+		if err != nil {
+			t.Errorf("error splitting %q: %v", c.line, err)
+			continue
+		}
+		if len(args) != len(c.args) {
+			t.Errorf("error args of %q: %q", c.line, args)
+			continue
+		}
+		for i := range args {
+			if args[i] != c.args[i] {
+				t.Errorf("error arg %d of %q: %q", i, c.line, args[i])
+			}
+		}
+	}
+
+	for _, line := range []string{`"abc`, `'abc`, `"a"b`, `'a'b`} {
+		if _, err := SplitArgs(line); err != ErrUnbalancedQuotes {
+			t.Errorf("error splitting %q: %v", line, err)
+		}
+	}
+}
+
+func TestSplitArgsRepr(t *testing.T) {
+	b := make([]byte, 256)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	args, err := SplitArgs(repr(b) + " " + repr(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(args) != 2 || args[0] != string(b) || args[1] != "" {
+		t.Errorf("error splitting quoted bytes: %q", args)
+	}
+}
@@ -0,0 +1,120 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Bulk returns a bulk string message.
+func Bulk(b []byte) *Message {
+	m := &Message{}
+	m.SetBytes(b)
+	return m
+}
+
+// Str returns a simple string (status) message, such as OK.
+func Str(s string) *Message {
+	m := &Message{}
+	m.SetStatus(s)
+	return m
+}
+
+// Int returns an integer message.
+func Int(i int64) *Message {
+	m := &Message{}
+	m.SetInteger(i)
+	return m
+}
+
+// Err returns an error message.
+func Err(e error) *Message {
+	m := &Message{}
+	m.SetError(e)
+	return m
+}
+
+// Nil returns a null bulk string message, the usual nil reply.
+func Nil() *Message {
+	return &Message{Type: BulkHeader, IsNil: true}
+}
+
+// NilArray returns a null array message.
+func NilArray() *Message {
+	return &Message{Type: ArrayHeader, IsNil: true}
+}
+
+// Array returns an array message holding children.
+func Array(children ...*Message) *Message {
+	m := &Message{}
+	if children == nil {
+		children = []*Message{}
+	}
+	m.SetArray(children)
+	return m
+}
+
+// Cmd returns a command, an array of bulk strings, made of name followed by
+// args. See Arg for the supported argument types.
+//
+//	resp.Cmd("SET", key, val).Arg("EX", 10)
+func Cmd(name string, args ...interface{}) *Message {
+	return Array(Bulk([]byte(name))).Arg(args...)
+}
+
+// Arg appends args to the array message as bulk strings and returns the
+// message. Strings and byte slices are used as is, numbers and booleans are
+// formatted in decimal and other values with fmt.Sprint.
+//
+// A message which is not an array becomes an array holding the former
+// message as its first element, so that nothing is lost, the zero Message
+// becoming an empty array. A nil array becomes an empty one.
+func (m *Message) Arg(args ...interface{}) *Message {
+	if m.Type != ArrayHeader {
+		var first []*Message
+		if m.Type != 0 || m.IsNil || m.Error != nil || m.Integer != 0 || m.Bytes != nil ||
+			m.Status != "" || m.Array != nil {
+			elem := *m
+			first = []*Message{&elem}
+		}
+		*m = Message{}
+		m.SetArray(first)
+	}
+	m.IsNil = false
+	for _, arg := range args {
+		m.Array = append(m.Array, Bulk(argBytes(arg)))
+	}
+	return m
+}
+
+func argBytes(arg interface{}) []byte {
+	switch v := arg.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	case int:
+		return strconv.AppendInt(nil, int64(v), 10)
+	case int32:
+		return strconv.AppendInt(nil, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(nil, v, 10)
+	case uint:
+		return strconv.AppendUint(nil, uint64(v), 10)
+	case uint32:
+		return strconv.AppendUint(nil, uint64(v), 10)
+	case uint64:
+		return strconv.AppendUint(nil, v, 10)
+	case float32:
+		return strconv.AppendFloat(nil, float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.AppendFloat(nil, v, 'f', -1, 64)
+	case bool:
+		if v {
+			return []byte("1")
+		}
+		return []byte("0")
+	case nil:
+		return []byte{}
+	}
+	return []byte(fmt.Sprint(arg))
+}
@@ -0,0 +1,82 @@
+package resp
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func marshalString(t *testing.T, m *Message) string {
+	b, err := Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestConstructors(t *testing.T) {
+	if s := marshalString(t, Bulk([]byte("foo"))); s != "$3\r\nfoo\r\n" {
+		t.Errorf("error bulk: %q", s)
+	}
+	if s := marshalString(t, Str("OK")); s != "+OK\r\n" {
+		t.Errorf("error status: %q", s)
+	}
+	if s := marshalString(t, Int(-3)); s != ":-3\r\n" {
+		t.Errorf("error integer: %q", s)
+	}
+	if s := marshalString(t, Err(errors.New("ERR x"))); s != "-ERR x\r\n" {
+		t.Errorf("error error: %q", s)
+	}
+	if s := marshalString(t, Nil()); s != "$-1\r\n" {
+		t.Errorf("error nil: %q", s)
+	}
+	if s := marshalString(t, NilArray()); s != "*-1\r\n" {
+		t.Errorf("error nil array: %q", s)
+	}
+	if s := marshalString(t, Array()); s != "*0\r\n" {
+		t.Errorf("error empty array: %q", s)
+	}
+	if s := marshalString(t, Array(Int(1), Array(Str("a"), Nil()))); s != "*2\r\n:1\r\n*2\r\n+a\r\n$-1\r\n" {
+		t.Errorf("error nested array: %q", s)
+	}
+}
+
+func TestCmd(t *testing.T) {
+	m := Cmd("SET", "key", []byte("val")).Arg("EX", 10)
+	target := "*5\r\n$3\r\nSET\r\n$3\r\nkey\r\n$3\r\nval\r\n$2\r\nEX\r\n$2\r\n10\r\n"
+	if s := marshalString(t, m); s != target {
+		t.Errorf("error command: %q", s)
+	}
+
+	m = Cmd("ZADD", "z", 1.5, "m", int64(-2), uint(3), true)
+	var args []string
+	for _, arg := range m.Array {
+		args = append(args, string(arg.Bytes))
+	}
+	if s := strings.Join(args, " "); s != "ZADD z 1.5 m -2 3 1" {
+		t.Errorf("error command arguments: %q", s)
+	}
+
+	// the messages which are not arrays are kept as the first element
+	if s := marshalString(t, Bulk([]byte("GET")).Arg("k")); s != "*2\r\n$3\r\nGET\r\n$1\r\nk\r\n" {
+		t.Errorf("error arguments of a bulk string: %q", s)
+	}
+	if s := marshalString(t, Nil().Arg("k")); s != "*2\r\n$-1\r\n$1\r\nk\r\n" {
+		t.Errorf("error arguments of a nil message: %q", s)
+	}
+	if s := marshalString(t, (&Message{}).Arg("k")); s != "*1\r\n$1\r\nk\r\n" {
+		t.Errorf("error arguments of the zero message: %q", s)
+	}
+	if s := marshalString(t, NilArray().Arg("k")); s != "*1\r\n$1\r\nk\r\n" {
+		t.Errorf("error arguments of a nil array: %q", s)
+	}
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(Cmd("PING")); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "*1\r\n$4\r\nPING\r\n" {
+		t.Errorf("error encoded command: %q", buf.String())
+	}
+}
@@ -0,0 +1,179 @@
+package resp
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// Equal reports whether a and b are the same message: same type, nil-ness
+// and value, aggregates being compared element by element. The messages of
+// no or an unknown type are equal when all their fields are.
+func Equal(a, b *Message) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Type != b.Type || a.IsNil != b.IsNil {
+		return false
+	}
+	if a.IsNil {
+		return true
+	}
+	switch a.Type {
+	case StringHeader, DoubleHeader, BigNumberHeader:
+		return a.Status == b.Status
+	case ErrorHeader:
+		return errorString(a.Error) == errorString(b.Error)
+	case IntegerHeader, BooleanHeader:
+		return a.Integer == b.Integer
+	case BulkHeader, VerbatimHeader:
+		return bytes.Equal(a.Bytes, b.Bytes)
+	case ArrayHeader, MapHeader, SetHeader, PushHeader:
+		return equalArrays(a.Array, b.Array)
+	}
+	return a.Status == b.Status && errorString(a.Error) == errorString(b.Error) &&
+		a.Integer == b.Integer && bytes.Equal(a.Bytes, b.Bytes) && equalArrays(a.Array, b.Array)
+}
+
+func equalArrays(a, b []*Message) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Clone returns a deep copy of the message. Messages returned by Decode
+// share their byte slices with the decoded buffer, Clone allows to keep them
+// once the buffer is reused.
+func (m *Message) Clone() *Message {
+	if m == nil {
+		return nil
+	}
+	c := *m
+	if m.Bytes != nil {
+		c.Bytes = make([]byte, len(m.Bytes))
+		copy(c.Bytes, m.Bytes)
+	}
+	if m.Array != nil {
+		c.Array = make([]*Message, len(m.Array))
+		for i, elem := range m.Array {
+			c.Array[i] = elem.Clone()
+		}
+	}
+	return &c
+}
+
+// Diff returns the differences between a and b, one line per difference,
+// each prefixed by the path of the differing element such as `$[1][0]`. It
+// returns nil if the messages are equal.
+func Diff(a, b *Message) []string {
+	return diff(nil, "$", a, b)
+}
+
+func diff(diffs []string, path string, a, b *Message) []string {
+	// only non nil aggregates of the same type are walked element by element
+	if a == nil || b == nil || a.Type != b.Type || !isAggregate(a.Type) || a.IsNil || b.IsNil {
+		if !Equal(a, b) {
+			diffs = append(diffs, fmt.Sprintf("%s: %s != %s", path, describe(a), describe(b)))
+		}
+		return diffs
+	}
+
+	if len(a.Array) != len(b.Array) {
+		diffs = append(diffs, fmt.Sprintf("%s: %s length %d != %d", path, typeName(a.Type), len(a.Array), len(b.Array)))
+	}
+	for i := 0; i < len(a.Array) || i < len(b.Array); i++ {
+		p := path + "[" + strconv.Itoa(i) + "]"
+		switch {
+		case i >= len(b.Array):
+			diffs = append(diffs, fmt.Sprintf("%s: %s != (missing)", p, describe(a.Array[i])))
+		case i >= len(a.Array):
+			diffs = append(diffs, fmt.Sprintf("%s: (missing) != %s", p, describe(b.Array[i])))
+		default:
+			diffs = diff(diffs, p, a.Array[i], b.Array[i])
+		}
+	}
+	return diffs
+}
+
+func isAggregate(t byte) bool {
+	switch t {
+	case ArrayHeader, MapHeader, SetHeader, PushHeader:
+		return true
+	}
+	return false
+}
+
+// describe returns a one line description of the message with its type.
+func describe(m *Message) string {
+	if m == nil {
+		return "(nil message)"
+	}
+	name := typeName(m.Type)
+	if m.IsNil {
+		return name + " (nil)"
+	}
+	switch m.Type {
+	case StringHeader:
+		return name + " " + strconv.Quote(m.Status)
+	case ErrorHeader:
+		return name + " " + strconv.Quote(errorString(m.Error))
+	case IntegerHeader:
+		return name + " " + strconv.FormatInt(m.Integer, 10)
+	case BooleanHeader:
+		return name + " " + strconv.FormatBool(m.Integer != 0)
+	case DoubleHeader, BigNumberHeader:
+		return name + " " + m.Status
+	case BulkHeader, VerbatimHeader:
+		return name + " " + repr(m.Bytes)
+	case ArrayHeader, MapHeader, SetHeader, PushHeader:
+		return name + " of " + strconv.Itoa(len(m.Array)) + " elements"
+	}
+	return name
+}
+
+func typeName(t byte) string {
+	switch t {
+	case StringHeader:
+		return "status"
+	case ErrorHeader:
+		return "error"
+	case IntegerHeader:
+		return "integer"
+	case BulkHeader:
+		return "bulk"
+	case ArrayHeader:
+		return "array"
+	case MapHeader:
+		return "map"
+	case SetHeader:
+		return "set"
+	case PushHeader:
+		return "push"
+	case NullHeader:
+		return "null"
+	case BooleanHeader:
+		return "boolean"
+	case DoubleHeader:
+		return "double"
+	case BigNumberHeader:
+		return "big number"
+	case VerbatimHeader:
+		return "verbatim"
+	case 0:
+		return "untyped"
+	}
+	return "unknown type " + strconv.Quote(string(t))
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
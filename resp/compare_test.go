@@ -0,0 +1,132 @@
+package resp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	a, err := decodeToMsg([]byte("*4\r\n$3\r\nfoo\r\n:1\r\n-ERR x\r\n*1\r\n$-1\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := decodeToMsg([]byte("*4\r\n$3\r\nfoo\r\n:1\r\n-ERR x\r\n*1\r\n$-1\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(a, b) {
+		t.Error("messages should be equal")
+	}
+
+	b.Array[3].Array[0].SetBytes([]byte{})
+	b.Array[3].Array[0].IsNil = false
+	if Equal(a, b) {
+		t.Error("nil and empty bulk strings should differ")
+	}
+
+	status := &Message{}
+	status.SetStatus("foo")
+	if Equal(a.Array[0], status) {
+		t.Error("bulk and status strings should differ")
+	}
+
+	if !Equal(nil, nil) || Equal(a, nil) {
+		t.Error("error comparing nil messages")
+	}
+
+	if !Equal(&Message{}, &Message{}) || Equal(&Message{Status: "a"}, &Message{Status: "b"}) ||
+		Equal(&Message{Type: 'x', Integer: 1}, &Message{Type: 'x'}) {
+		t.Error("error comparing untyped messages")
+	}
+}
+
+func TestClone(t *testing.T) {
+	buf := []byte("*2\r\n$3\r\nfoo\r\n*1\r\n:1\r\n")
+	msg, err := decodeToMsg(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := msg.Clone()
+	if !Equal(msg, c) {
+		t.Fatal("clone should equal the original message")
+	}
+
+	// overwrite the decoded buffer, the clone must not change
+	copy(buf, []byte("*2\r\n$3\r\nbar"))
+	msg.Array[1].Array[0].SetInteger(2)
+	if string(c.Array[0].Bytes) != "foo" || c.Array[1].Array[0].Integer != 1 {
+		t.Errorf("clone shares data with the original message: %v", c)
+	}
+	if (*Message)(nil).Clone() != nil {
+		t.Error("clone of nil should be nil")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a, err := decodeToMsg([]byte("*3\r\n$3\r\nfoo\r\n*2\r\n:1\r\n:2\r\n+OK\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diffs := Diff(a, a.Clone()); diffs != nil {
+		t.Errorf("equal messages should have no diff: %v", diffs)
+	}
+
+	b, err := decodeToMsg([]byte("*2\r\n$3\r\nbar\r\n*3\r\n:1\r\n:3\r\n:4\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := []string{
+		"$: array length 3 != 2",
+		`$[0]: bulk "foo" != bulk "bar"`,
+		"$[1]: array length 2 != 3",
+		"$[1][1]: integer 2 != integer 3",
+		"$[1][2]: (missing) != integer 4",
+		`$[2]: status "OK" != (missing)`,
+	}
+	diffs := Diff(a, b)
+	if len(diffs) != len(target) {
+		t.Fatalf("error diff: %q", diffs)
+	}
+	for i := range target {
+		if diffs[i] != target[i] {
+			t.Errorf("diff %d is %q, should be %q", i, diffs[i], target[i])
+		}
+	}
+
+	e := &Message{}
+	e.SetError(errors.New("ERR x"))
+	diffs = Diff(a.Array[2], e)
+	if len(diffs) != 1 || diffs[0] != `$: status "OK" != error "ERR x"` {
+		t.Errorf("error diff: %q", diffs)
+	}
+}
+
+func TestDiffResp3(t *testing.T) {
+	a, err := decodeToMsg([]byte("%2\r\n+a\r\n:1\r\n+b\r\n~1\r\n:2\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := decodeToMsg([]byte("%2\r\n+a\r\n:3\r\n+b\r\n~2\r\n:2\r\n:4\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := []string{
+		"$[1]: integer 1 != integer 3",
+		"$[3]: set length 1 != 2",
+		"$[3][1]: (missing) != integer 4",
+	}
+	if diffs := Diff(a, b); strings.Join(diffs, "|") != strings.Join(target, "|") {
+		t.Errorf("error diff of maps: %q", diffs)
+	}
+
+	push, _ := decodeToMsg([]byte(">2\r\n+message\r\n+x\r\n"))
+	array, _ := decodeToMsg([]byte("*2\r\n+message\r\n+x\r\n"))
+	if diffs := Diff(push, array); len(diffs) != 1 || diffs[0] != "$: push of 2 elements != array of 2 elements" {
+		t.Errorf("error diff of different aggregates: %q", diffs)
+	}
+	other, _ := decodeToMsg([]byte(">2\r\n+message\r\n+y\r\n"))
+	if diffs := Diff(push, other); len(diffs) != 1 || diffs[0] != `$[1]: status "x" != status "y"` {
+		t.Errorf("error diff of pushes: %q", diffs)
+	}
+}
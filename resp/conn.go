@@ -0,0 +1,244 @@
+package resp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"time"
+)
+
+// Conn is a client connection to a RESP server. A Conn is not safe for
+// concurrent use.
+//
+// Blocking operations have a Context variant bounded by the context: its
+// deadline and cancellation are applied to the deadlines of the underlying
+// net.Conn. A connection whose I/O is interrupted is broken, as a reply may
+// have been partially read or may still be on its way, and Err reports it.
+// The Context variants own the deadlines of the net.Conn: a deadline set on
+// it directly is overwritten and cleared once the operation is done, so
+// bound operations with contexts rather than with deadlines.
+type Conn struct {
+	rwc     io.ReadWriteCloser
+	r       *Reader
+	wbuf    bytes.Buffer
+	enc     *Encoder
+	pending int
+	err     error
+
+	hooks   hookList
+	metrics Metrics
+	// sent holds the commands whose reply was not received yet, when hooks
+	// or metrics are set, and unflushed the number of those not flushed.
+	sent      []pendingCommand
+	unflushed int
+}
+
+type pendingCommand struct {
+	ctx   context.Context
+	cmd   *Message
+	start time.Time
+}
+
+// Dial connects to the RESP server at address.
+func Dial(network, address string) (*Conn, error) {
+	return DialContext(context.Background(), network, address)
+}
+
+// DialContext connects to the RESP server at address, the context bounding
+// the time spent connecting.
+func DialContext(ctx context.Context, network, address string) (*Conn, error) {
+	var d net.Dialer
+	c, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(c), nil
+}
+
+// NewConn returns a Conn sending commands to and reading replies from rwc.
+func NewConn(rwc io.ReadWriteCloser) *Conn {
+	c := &Conn{
+		rwc: rwc,
+		r:   NewReader(rwc),
+	}
+	c.enc = NewEncoder(&c.wbuf)
+	return c
+}
+
+// AddHook adds a hook observing the commands of the connection. Hooks are
+// called in the order they were added. Hooks must be added before sending
+// any command.
+func (c *Conn) AddHook(h Hook) {
+	c.hooks = append(c.hooks, h)
+}
+
+// SetMetrics sets the Metrics receiving the bytes written and read, the
+// replies decoded and the latency of the commands. Like hooks, it must be
+// set before sending any command.
+func (c *Conn) SetMetrics(m Metrics) {
+	c.metrics = m
+	c.r.SetMetrics(m)
+}
+
+// Do sends the command, usually built with Cmd, and returns its reply. Error
+// replies are returned as messages, the returned error only reports I/O and
+// protocol errors.
+func (c *Conn) Do(cmd *Message) (*Message, error) {
+	return c.DoContext(context.Background(), cmd)
+}
+
+// DoContext is Do bounded by ctx.
+func (c *Conn) DoContext(ctx context.Context, cmd *Message) (*Message, error) {
+	if err := c.send(ctx, cmd); err != nil {
+		return nil, err
+	}
+	if err := c.FlushContext(ctx); err != nil {
+		return nil, err
+	}
+	return c.ReceiveContext(ctx)
+}
+
+// Send buffers the command without waiting for its reply, allowing to
+// pipeline several commands before calling Flush and Receive. Send never
+// blocks.
+func (c *Conn) Send(cmd *Message) error {
+	return c.send(context.Background(), cmd)
+}
+
+func (c *Conn) send(ctx context.Context, cmd *Message) error {
+	if c.err != nil {
+		return c.err
+	}
+	ctx, err := c.hooks.before(ctx, cmd)
+	if err != nil {
+		c.hooks.after(ctx, cmd, nil, err)
+		return err
+	}
+	if err = c.enc.Encode(cmd); err != nil {
+		c.hooks.after(ctx, cmd, nil, err)
+		return err
+	}
+	c.pending++
+	if len(c.hooks) > 0 || c.metrics != nil {
+		c.sent = append(c.sent, pendingCommand{ctx: ctx, cmd: cmd})
+		c.unflushed++
+	}
+	return nil
+}
+
+// Flush writes the buffered commands to the connection.
+func (c *Conn) Flush() error {
+	return c.FlushContext(context.Background())
+}
+
+// FlushContext is Flush bounded by ctx. The buffered commands are kept if
+// ctx is done before anything is written.
+func (c *Conn) FlushContext(ctx context.Context) error {
+	if c.err != nil {
+		return c.err
+	}
+	if c.wbuf.Len() == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c.unflushed > 1 && len(c.hooks) > 0 {
+		unflushed := c.sent[len(c.sent)-c.unflushed:]
+		cmds := make([]*Message, len(unflushed))
+		for i, p := range unflushed {
+			cmds[i] = p.cmd
+		}
+		c.hooks.pipeline(ctx, cmds)
+	}
+	var n int64
+	err := withContext(ctx, writeDeadline(c.rwc), func() error {
+		var err error
+		n, err = c.wbuf.WriteTo(c.rwc)
+		return err
+	})
+	if c.metrics != nil {
+		c.metrics.BytesEncoded(int(n))
+	}
+	if err != nil {
+		c.fatal(err)
+		c.abort(err)
+		return err
+	}
+	now := time.Now()
+	for i := len(c.sent) - c.unflushed; i < len(c.sent); i++ {
+		c.sent[i].start = now
+	}
+	c.unflushed = 0
+	return nil
+}
+
+// Receive reads a single reply.
+func (c *Conn) Receive() (*Message, error) {
+	return c.ReceiveContext(context.Background())
+}
+
+// ReceiveContext is Receive bounded by ctx.
+func (c *Conn) ReceiveContext(ctx context.Context) (*Message, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	msg, err := c.r.ReadMessageContext(ctx)
+	if err != nil {
+		if c.r.isDecodeError(err) {
+			c.hooks.decodeError(err)
+		}
+		c.fatal(err)
+		c.abort(err)
+		return nil, err
+	}
+	if c.pending > 0 {
+		c.pending--
+	}
+	if len(c.sent) > c.unflushed {
+		p := c.sent[0]
+		c.sent = c.sent[1:]
+		if c.metrics != nil {
+			c.metrics.CommandDuration(commandName(p.cmd), time.Since(p.start))
+		}
+		c.hooks.after(p.ctx, p.cmd, msg, nil)
+	}
+	return msg, nil
+}
+
+// Pending returns the number of commands sent whose reply was not received
+// yet.
+func (c *Conn) Pending() int {
+	return c.pending
+}
+
+// Err returns the error which broke the connection, if any. A broken
+// connection should be closed.
+func (c *Conn) Err() error {
+	return c.err
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	c.fatal(ErrConnClosed)
+	c.abort(ErrConnClosed)
+	return c.rwc.Close()
+}
+
+// abort reports err to the hooks of the commands whose reply will never be
+// received.
+func (c *Conn) abort(err error) {
+	for _, p := range c.sent {
+		c.hooks.after(p.ctx, p.cmd, nil, err)
+	}
+	c.sent = nil
+	c.unflushed = 0
+}
+
+func (c *Conn) fatal(err error) error {
+	if c.err == nil {
+		c.err = err
+	}
+	return err
+}
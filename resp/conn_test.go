@@ -0,0 +1,157 @@
+package resp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// commandString joins the arguments of a command with spaces.
+func commandString(cmd *Message) string {
+	args, _ := cmd.Strings()
+	return strings.Join(args, " ")
+}
+
+// newTestConn returns a Conn connected to a server replying to each command
+// with handle.
+func newTestConn(t *testing.T, handle func(cmd *Message) *Message) *Conn {
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		r := NewReader(server)
+		enc := NewEncoder(server)
+		for {
+			cmd, err := r.ReadMessage()
+			if err != nil {
+				return
+			}
+			enc.Encode(handle(cmd))
+		}
+	}()
+	c := NewConn(client)
+	return c
+}
+
+func TestConnDo(t *testing.T) {
+	c := newTestConn(t, func(cmd *Message) *Message {
+		switch commandString(cmd) {
+		case "PING":
+			return Str("PONG")
+		case "GET a":
+			return Bulk([]byte("1"))
+		}
+		return Err(errors.New("ERR unknown command"))
+	})
+	defer c.Close()
+
+	reply, err := c.Do(Cmd("PING"))
+	if err != nil {
+		t.Fatal(err)
+	} else if reply.Status != "PONG" {
+		t.Errorf("error reply: %v", reply)
+	}
+
+	reply, err = c.Do(Cmd("FOO"))
+	if err != nil {
+		t.Fatal(err)
+	} else if reply.Type != ErrorHeader {
+		t.Errorf("error reply should be returned as message: %v", reply)
+	}
+}
+
+func TestConnPipeline(t *testing.T) {
+	c := newTestConn(t, func(cmd *Message) *Message {
+		return Bulk([]byte(commandString(cmd)))
+	})
+	defer c.Close()
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := c.Send(Cmd("GET", key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		reply, err := c.Receive()
+		if err != nil {
+			t.Fatal(err)
+		} else if string(reply.Bytes) != "GET "+key {
+			t.Errorf("error reply: %v", reply)
+		}
+	}
+}
+
+func TestConnBroken(t *testing.T) {
+	client, server := net.Pipe()
+	server.Close()
+	c := NewConn(client)
+	if _, err := c.Do(Cmd("PING")); err == nil {
+		t.Fatal(errErrorExpected)
+	}
+	if c.Err() == nil {
+		t.Error("connection should be broken")
+	}
+	if _, err := c.Do(Cmd("PING")); err != c.Err() {
+		t.Errorf("should return the broken connection error, not: %v", err)
+	}
+}
+
+func TestConnContextInterrupted(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		r := NewReader(server)
+		if _, err := r.ReadMessage(); err != nil {
+			return
+		}
+		// write a partial reply and never complete it
+		server.Write([]byte("$5\r\nhel"))
+	}()
+	c := NewConn(client)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.DoContext(ctx, Cmd("GET", "a")); err != context.DeadlineExceeded {
+		t.Fatalf("should return context.DeadlineExceeded, not: %v", err)
+	}
+	if c.Err() != context.DeadlineExceeded {
+		t.Errorf("connection should be broken, not: %v", c.Err())
+	}
+}
+
+func TestConnContextCanceled(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	c := NewConn(client)
+	defer c.Close()
+
+	// nothing is written with a context already done, the connection is
+	// still usable
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.DoContext(ctx, Cmd("PING")); err != context.Canceled {
+		t.Fatalf("should return context.Canceled, not: %v", err)
+	}
+	if c.Err() != nil {
+		t.Errorf("connection should not be broken: %v", c.Err())
+	}
+
+	// the server never reads, the cancellation interrupts the write
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	if err := c.FlushContext(ctx); err != context.Canceled {
+		t.Fatalf("should return context.Canceled, not: %v", err)
+	}
+	if c.Err() == nil {
+		t.Error("connection should be broken")
+	}
+}
@@ -0,0 +1,80 @@
+package resp
+
+import (
+	"context"
+	"time"
+)
+
+// aLongTimeAgo is a deadline in the past, used to interrupt blocked I/O.
+var aLongTimeAgo = time.Unix(1, 0)
+
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+type writeDeadliner interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+func readDeadline(v interface{}) func(time.Time) error {
+	if d, ok := v.(readDeadliner); ok {
+		return d.SetReadDeadline
+	}
+	return nil
+}
+
+func writeDeadline(v interface{}) func(time.Time) error {
+	if d, ok := v.(writeDeadliner); ok {
+		return d.SetWriteDeadline
+	}
+	return nil
+}
+
+// withContext runs the blocking I/O operation fn bounded by ctx: the context
+// deadline is set with setDeadline and a cancellation moves the deadline to
+// the past to interrupt fn. The context error is returned instead of the
+// timeout error when fn is interrupted. Without setDeadline only an already
+// done context is detected.
+//
+// A net.Conn does not tell its deadlines, so the deadline set by the caller
+// can not be restored: it is replaced while fn runs and cleared after it.
+// Contexts which can neither expire nor be canceled, such as the one of the
+// methods without a Context variant, leave the deadlines untouched.
+func withContext(ctx context.Context, setDeadline func(time.Time) error, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	deadline, hasDeadline := ctx.Deadline()
+	if setDeadline == nil || (!hasDeadline && ctx.Done() == nil) {
+		return fn()
+	}
+
+	if hasDeadline {
+		setDeadline(deadline)
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		select {
+		case <-ctx.Done():
+			setDeadline(aLongTimeAgo)
+		case <-stop:
+		}
+	}()
+
+	err := fn()
+	close(stop)
+	<-done
+	setDeadline(time.Time{})
+
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	// the deadline of the connection may expire just before the context is
+	// done
+	if hasDeadline && isTimeout(err) && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+	return err
+}
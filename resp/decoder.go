@@ -97,7 +97,7 @@ func (d *Decoder) next(bufmsg *Message) error {
 			d.appendNewMsg(msg)
 		}
 		return nil
-	case ArrayHeader:
+	case ArrayHeader, MapHeader, SetHeader, PushHeader:
 		var arrLen int
 		if arrLen, err = strconv.Atoi(string(line)); err != nil {
 			return err
@@ -106,13 +106,19 @@ func (d *Decoder) next(bufmsg *Message) error {
 		// to specify a Null value (usually the Null Bulk String is used, but
 		// for historical reasons we have two formats).
 		if arrLen < 0 {
-			msg.Type = ArrayHeader
+			msg.Type = lineType
 			msg.IsNil = true
 			d.updatePos(true, len(line))
-			d.appendNewMsg(msg)
+			if bufmsg == nil {
+				d.appendNewMsg(msg)
+			}
 			return nil
 		}
-		msg.Type = ArrayHeader
+		// maps hold a key and a value per entry
+		if lineType == MapHeader {
+			arrLen *= 2
+		}
+		msg.Type = lineType
 		msg.Array = make([]*Message, arrLen)
 		d.updatePos(true, len(line))
 		for i := 0; i < arrLen; i++ {
@@ -125,6 +131,84 @@ func (d *Decoder) next(bufmsg *Message) error {
 			d.appendNewMsg(msg)
 		}
 		return nil
+	case AttributeHeader:
+		// attributes are a map preceding the reply they describe, which is
+		// decoded in place of the attributes
+		var attrLen int
+		if attrLen, err = strconv.Atoi(string(line)); err != nil || attrLen < 0 {
+			return ErrRespData
+		}
+		d.updatePos(true, len(line))
+		for i := 0; i < attrLen*2; i++ {
+			if err = d.next(&Message{}); err != nil {
+				return err
+			}
+		}
+		return d.next(bufmsg)
+	case NullHeader:
+		msg.Type = NullHeader
+		msg.IsNil = true
+		d.updatePos(true, len(line))
+		if len(line) != 0 {
+			d.updateStartPos(d.pos)
+			return ErrRespData
+		}
+		if bufmsg == nil {
+			d.appendNewMsg(msg)
+		}
+		return nil
+	case BooleanHeader:
+		msg.Type = BooleanHeader
+		d.updatePos(true, len(line))
+		if len(line) != 1 || (line[0] != 't' && line[0] != 'f') {
+			d.updateStartPos(d.pos)
+			return ErrRespData
+		}
+		if line[0] == 't' {
+			msg.Integer = 1
+		}
+		if bufmsg == nil {
+			d.appendNewMsg(msg)
+		}
+		return nil
+	case DoubleHeader, BigNumberHeader:
+		msg.Type = lineType
+		msg.Status = string(line)
+		d.updatePos(true, len(line))
+		if bufmsg == nil {
+			d.appendNewMsg(msg)
+		}
+		return nil
+	case VerbatimHeader, BlobErrorHeader:
+		var msgLen int
+		if msgLen, err = strconv.Atoi(string(line)); err != nil || msgLen < 0 {
+			return ErrRespData
+		}
+		d.updatePos(true, len(line))
+		_, blob, err := parseLine(d.src[d.pos:], msgLen)
+		d.updatePos(false, len(blob))
+		if err != nil {
+			if !MaybeSegmentError(err) {
+				d.updateStartPos(d.pos)
+			}
+			return err
+		}
+		if lineType == BlobErrorHeader {
+			msg.Type = ErrorHeader
+			msg.Error = errors.New(string(blob))
+		} else {
+			// verbatim strings start with their format, such as "txt:"
+			if len(blob) < 4 || blob[3] != ':' {
+				d.updateStartPos(d.pos)
+				return ErrRespData
+			}
+			msg.Type = VerbatimHeader
+			msg.Bytes = blob[4:]
+		}
+		if bufmsg == nil {
+			d.appendNewMsg(msg)
+		}
+		return nil
 	}
 	return ErrInvalidHeader
 }
@@ -171,18 +255,29 @@ func parseLine(data []byte, readLen int) (lineType byte, line []byte, err error)
 	}
 }
 
+// Decode decodes the messages of data. It returns the messages decoded and
+// the position following them, or the length of data when it can not be
+// decoded. Incomplete messages are reported with ErrCrlfNotFound or
+// ErrBulkendNotFound.
 func Decode(data []byte) ([]*Message, int, error) {
+	msgQ, end, err := DecodePrefix(data)
+	if err != nil && !MaybeSegmentError(err) {
+		return msgQ, len(data), err
+	}
+	return msgQ, end, err
+}
+
+// DecodePrefix is Decode, except that the position returned is the end of
+// the last message decoded whatever the error, so that the data following
+// the messages can be examined when it can not be decoded.
+func DecodePrefix(data []byte) ([]*Message, int, error) {
 	d := NewDecoder(data)
+	end := 0
 	for d.msgStartPos < len(data) {
-		err := d.next(nil)
-		if err != nil {
-			switch err {
-			case ErrCrlfNotFound, ErrBulkendNotFound:
-				return d.msgQ, d.msgStartPos, err
-			default:
-				return d.msgQ, len(data), err
-			}
+		if err := d.next(nil); err != nil {
+			return d.msgQ, end, err
 		}
+		end = d.msgStartPos
 	}
-	return d.msgQ, d.msgStartPos, nil
+	return d.msgQ, end, nil
 }
@@ -318,6 +318,19 @@ func TestArrayDecode(t *testing.T) {
 		t.Error("error new consume pos")
 	}
 
+	// Null array nested in an array
+	encoded = []byte("*2\r\n*-1\r\n:1\r\n")
+	msgQ, pos, err = Decode(encoded)
+	if err != nil {
+		t.Error(err)
+	} else if len(msgQ) != 1 {
+		t.Error("should contains one message")
+	} else if len(msgQ[0].Array) != 2 || !msgQ[0].Array[0].IsNil {
+		t.Error("error array result")
+	} else if pos != len(encoded) {
+		t.Error("error new consume pos")
+	}
+
 	// Array with two bulk string
 	encoded = []byte("*2\r\n$3\r\nget\r\n$5\r\nhello\r\n")
 	msgQ, pos, err = Decode(encoded)
@@ -547,3 +560,82 @@ func TestInvalidData(t *testing.T) {
 		t.Error("error new consume pos")
 	}
 }
+
+func TestDecodePrefix(t *testing.T) {
+	// encodings which Encoder does not give back byte for byte
+	valid := ":+5\r\n*02\r\n+a\r\n+b\r\n|1\r\n+ttl\r\n:3\r\n=7\r\nmkd:abc\r\n"
+	encoded := []byte(valid + "*2\r\n:x\r\n")
+	msgQ, pos, err := DecodePrefix(encoded)
+	if err == nil || MaybeSegmentError(err) {
+		t.Errorf("should return a decode error, not: %v", err)
+	} else if len(msgQ) != 3 || pos != len(valid) {
+		t.Errorf("error prefix: %d messages, position %d", len(msgQ), pos)
+	}
+	if _, pos, _ = Decode(encoded); pos != len(encoded) {
+		t.Errorf("error position of Decode: %d", pos)
+	}
+
+	msgQ, pos, err = DecodePrefix([]byte("+OK\r\n$5\r\nhel"))
+	if err != ErrBulkendNotFound || len(msgQ) != 1 || pos != 5 {
+		t.Errorf("error incomplete prefix: %v %d %v", msgQ, pos, err)
+	}
+}
+
+func TestDecodeResp3(t *testing.T) {
+	encoded := []byte("%2\r\n+first\r\n:1\r\n$6\r\nsecond\r\n,3.14\r\n" +
+		"~2\r\n#t\r\n#f\r\n" +
+		"_\r\n" +
+		"(3492890328409238509324850943850943825024385\r\n" +
+		"=15\r\ntxt:Some string\r\n" +
+		"!21\r\nSYNTAX invalid syntax\r\n" +
+		"|1\r\n+key-popularity\r\n%1\r\n$1\r\na\r\n,0.1923\r\n*1\r\n:2039123\r\n" +
+		">2\r\n$7\r\nmessage\r\n,-inf\r\n")
+	msgQ, pos, err := Decode(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos != len(encoded) || len(msgQ) != 8 {
+		t.Fatalf("error decoding %d messages up to %d", len(msgQ), pos)
+	}
+	m := msgQ[0]
+	if m.Type != MapHeader || len(m.Array) != 4 || m.Array[0].Status != "first" ||
+		string(m.Array[2].Bytes) != "second" || m.Array[3].Type != DoubleHeader || m.Array[3].Status != "3.14" {
+		t.Errorf("error map: %v", m)
+	}
+	m = msgQ[1]
+	if m.Type != SetHeader || len(m.Array) != 2 || m.Array[0].Type != BooleanHeader ||
+		m.Array[0].Integer != 1 || m.Array[1].Integer != 0 {
+		t.Errorf("error set: %v", m)
+	}
+	if m = msgQ[2]; m.Type != NullHeader || !m.IsNil {
+		t.Errorf("error null: %v", m)
+	}
+	if m = msgQ[3]; m.Type != BigNumberHeader || m.Status != "3492890328409238509324850943850943825024385" {
+		t.Errorf("error big number: %v", m)
+	}
+	if m = msgQ[4]; m.Type != VerbatimHeader || string(m.Bytes) != "Some string" {
+		t.Errorf("error verbatim string: %v", m)
+	}
+	if m = msgQ[5]; m.Type != ErrorHeader || m.Error.Error() != "SYNTAX invalid syntax" {
+		t.Errorf("error blob error: %v", m)
+	}
+	// the attribute is skipped
+	if m = msgQ[6]; m.Type != ArrayHeader || len(m.Array) != 1 || m.Array[0].Integer != 2039123 {
+		t.Errorf("error reply with attribute: %v", m)
+	}
+	if m = msgQ[7]; m.Type != PushHeader || len(m.Array) != 2 || m.Array[1].Status != "-inf" {
+		t.Errorf("error push: %v", m)
+	}
+
+	// a map split across reads is resumed from its start
+	msgQ, pos, err = Decode(encoded[:20])
+	if err != ErrBulkendNotFound || len(msgQ) != 0 || pos != 0 {
+		t.Errorf("error decoding a partial map: %v %d", err, pos)
+	}
+
+	for _, data := range []string{"#x\r\n", "_x\r\n", "=3\r\ntxt\r\n", "=5\r\ntxt-a\r\n"} {
+		if _, _, err := Decode([]byte(data)); err != ErrRespData {
+			t.Errorf("error decoding %q: %v", data, err)
+		}
+	}
+}
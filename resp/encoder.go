@@ -18,14 +18,26 @@ func intToBytes(v int) []byte {
 
 	i := len(buf)
 
-	for v >= 10 {
+	// work on the absolute value as an unsigned integer so that the
+	// smallest int does not overflow
+	u := uint64(v)
+	if v < 0 {
+		u = -u
+	}
+
+	for u >= 10 {
 		i--
-		buf[i] = digits[v%10]
-		v = v / 10
+		buf[i] = digits[u%10]
+		u = u / 10
 	}
 
 	i--
-	buf[i] = digits[v%10]
+	buf[i] = digits[u%10]
+
+	if v < 0 {
+		i--
+		buf[i] = '-'
+	}
 
 	return buf[i:]
 }
@@ -164,10 +176,9 @@ func (e *Encoder) writeEncoded(w io.Writer, data interface{}) (err error) {
 		e.buf = append(e.buf, b...)
 
 		if w != nil {
-			e.mu.Lock()
-			w.Write(e.buf)
-			e.buf = []byte{}
-			e.mu.Unlock()
+			if err = e.flush(w); err != nil {
+				return err
+			}
 		}
 
 		for i := range v {
@@ -200,6 +211,50 @@ func (e *Encoder) writeEncoded(w io.Writer, data interface{}) (err error) {
 				return e.writeEncoded(w, nil)
 			}
 			return e.writeEncoded(w, v.Array)
+		case MapHeader, SetHeader, PushHeader:
+			n := len(v.Array)
+			if v.Type == MapHeader {
+				n /= 2
+			}
+			e.buf = append(e.buf, v.Type)
+			e.buf = append(e.buf, intToBytes(n)...)
+			e.buf = append(e.buf, endOfLine...)
+
+			if w != nil {
+				if err = e.flush(w); err != nil {
+					return err
+				}
+			}
+
+			for _, msg := range v.Array {
+				if err = e.writeEncoded(w, msg); err != nil {
+					return err
+				}
+			}
+			return nil
+		case NullHeader:
+			b = append(b, NullHeader)
+			b = append(b, endOfLine...)
+		case BooleanHeader:
+			b = append(b, BooleanHeader)
+			if v.Integer != 0 {
+				b = append(b, 't')
+			} else {
+				b = append(b, 'f')
+			}
+			b = append(b, endOfLine...)
+		case DoubleHeader, BigNumberHeader:
+			b = append(b, v.Type)
+			b = append(b, v.Status...)
+			b = append(b, endOfLine...)
+		case VerbatimHeader:
+			// the format is not kept by the decoder, plain text is assumed
+			b = append(b, VerbatimHeader)
+			b = append(b, intToBytes(len(v.Bytes)+4)...)
+			b = append(b, endOfLine...)
+			b = append(b, "txt:"...)
+			b = append(b, v.Bytes...)
+			b = append(b, endOfLine...)
 		default:
 			return ErrInvalidHeader
 		}
@@ -216,14 +271,15 @@ func (e *Encoder) writeEncoded(w io.Writer, data interface{}) (err error) {
 		b = []byte("")
 
 		if w != nil {
-			e.mu.Lock()
-			w.Write(e.buf)
-			e.buf = []byte{}
-			e.mu.Unlock()
+			if err = e.flush(w); err != nil {
+				return err
+			}
 		}
 
 		for _, msg := range v {
-			e.writeEncoded(w, msg)
+			if err = e.writeEncoded(w, msg); err != nil {
+				return err
+			}
 		}
 
 	case nil:
@@ -236,16 +292,24 @@ func (e *Encoder) writeEncoded(w io.Writer, data interface{}) (err error) {
 
 	e.buf = append(e.buf, b...)
 
-	if w != nil {
-		e.mu.Lock()
-		w.Write(e.buf)
-		e.buf = []byte{}
-		e.mu.Unlock()
+	// arrays of messages have already written everything, avoid issuing an
+	// empty write which blocks on synchronous writers such as net.Pipe
+	if w != nil && len(e.buf) > 0 {
+		return e.flush(w)
 	}
 
 	return nil
 }
 
+// flush writes the buffered encoding to w.
+func (e *Encoder) flush(w io.Writer) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err := w.Write(e.buf)
+	e.buf = []byte{}
+	return err
+}
+
 // Marshal returns the RESP encoding of v. At this moment, it only works with
 // string, int, []byte, nil and []interface{} types.
 func Marshal(v interface{}) ([]byte, error) {
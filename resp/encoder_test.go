@@ -50,6 +50,19 @@ func TestEncodeInteger(t *testing.T) {
 	}
 }
 
+func TestEncodeNegativeInteger(t *testing.T) {
+	var buf []byte
+	var err error
+
+	if buf, err = Marshal(-123); err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Equal(buf, []byte(":-123\r\n")) == false {
+		t.Fatal(errTestFailed)
+	}
+}
+
 func TestEncodeBulk(t *testing.T) {
 	var buf []byte
 	var err error
@@ -125,3 +138,66 @@ func TestEncodeNil(t *testing.T) {
 		t.Fatal(errTestFailed)
 	}
 }
+
+// writeRecorder records the length of each write.
+type writeRecorder struct {
+	writes []int
+}
+
+func (w *writeRecorder) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, len(p))
+	return len(p), nil
+}
+
+func TestEncodeNoEmptyWrite(t *testing.T) {
+	w := &writeRecorder{}
+	m := &Message{}
+	m.SetArray([]*Message{{Type: IntegerHeader, Integer: 1}})
+	if err := NewEncoder(w).Encode(m); err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range w.writes {
+		if n == 0 {
+			t.Fatalf("empty write issued: %v", w.writes)
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(b []byte) (int, error) {
+	return 0, errTestFailed
+}
+
+func TestEncodeWriteError(t *testing.T) {
+	m := &Message{}
+	m.SetArray([]*Message{{Type: IntegerHeader, Integer: 1}})
+	for _, v := range []interface{}{"OK", [][]byte{[]byte("GET")}, []string{"GET"}, m} {
+		if err := NewEncoder(failingWriter{}).Encode(v); err != errTestFailed {
+			t.Errorf("error encoding %v: should return the write error, not: %v", v, err)
+		}
+	}
+}
+
+func TestEncodeResp3(t *testing.T) {
+	encoded := "%1\r\n+first\r\n~2\r\n#t\r\n#f\r\n" +
+		"_\r\n" +
+		",3.14\r\n" +
+		"(3492890328409238509324850943850943825024385\r\n" +
+		"=15\r\ntxt:Some string\r\n" +
+		">1\r\n$7\r\nmessage\r\n"
+	msgQ, _, err := Decode([]byte(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	for _, m := range msgQ {
+		if err := e.Encode(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if buf.String() != encoded {
+		t.Errorf("error encoding RESP3 messages: %q", buf.String())
+	}
+}
@@ -2,6 +2,7 @@ package resp
 
 import (
 	"errors"
+	"strconv"
 )
 
 var (
@@ -29,8 +30,61 @@ var (
 var (
 	// ErrInvalidInput is returned after any error encoding a message
 	ErrInvalidInput = errors.New("invalid input for encoding")
+
+	// ErrInvalidJSON is returned when a JSON document does not describe a
+	// message
+	ErrInvalidJSON = errors.New("invalid json message")
+)
+
+var (
+	// ErrNil is returned when converting a nil bulk string or nil array to a
+	// Go value
+	ErrNil = errors.New("nil reply")
+)
+
+var (
+	// ErrConnClosed is returned when using a closed connection
+	ErrConnClosed = errors.New("connection closed")
+
+	// ErrPoolClosed is returned when getting a connection from a closed
+	// pool
+	ErrPoolClosed = errors.New("pool closed")
+
+	// ErrServerClosed is returned by Serve once the server is closed
+	ErrServerClosed = errors.New("server closed")
+)
+
+var (
+	// ErrUnbalancedQuotes is returned by SplitArgs when a quoted argument
+	// is not closed, or is followed by other characters than a space
+	ErrUnbalancedQuotes = errors.New("unbalanced quotes")
+)
+
+var (
+	// ErrLibraryName is returned when the code of a function library does
+	// not start with a `#!<engine> name=<library>` line
+	ErrLibraryName = errors.New("library code without name")
 )
 
+// ConversionError is returned when a message can not be converted to the
+// requested Go type, either because of its type or because its value can
+// not be parsed.
+type ConversionError struct {
+	// Type is the type of the message, one of the headers
+	Type byte
+	// Target is the Go type the message was converted to
+	Target string
+	// Value is the value which could not be parsed, if any
+	Value string
+}
+
+func (e *ConversionError) Error() string {
+	if e.Value != "" {
+		return "cannot convert " + typeName(e.Type) + " " + strconv.Quote(e.Value) + " to " + e.Target
+	}
+	return "cannot convert " + typeName(e.Type) + " to " + e.Target
+}
+
 func MaybeSegmentError(err error) bool {
 	switch err {
 	case ErrCrlfNotFound, ErrBulkendNotFound:
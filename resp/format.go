@@ -0,0 +1,221 @@
+package resp
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+)
+
+// FormatMode selects how Format renders a message.
+type FormatMode int
+
+const (
+	// FormatCLI renders messages the way redis-cli does in a terminal,
+	// e.g. `1) "GET"`, `(integer) 5` or `(nil)`.
+	FormatCLI FormatMode = iota
+	// FormatRaw renders messages like `redis-cli --raw`, with bulk strings
+	// unquoted and array elements separated by newlines.
+	FormatRaw
+	// FormatDebug renders the RESP encoding of the message with CR, LF and
+	// non printable characters escaped.
+	FormatDebug
+)
+
+// FormatOptions configures Format.
+type FormatOptions struct {
+	Mode FormatMode
+}
+
+// Format writes a human readable representation of m to w. The output ends
+// with a newline, as redis-cli prints it.
+func Format(w io.Writer, m *Message, opts FormatOptions) error {
+	var buf bytes.Buffer
+	switch opts.Mode {
+	case FormatRaw:
+		formatRaw(&buf, m)
+		buf.WriteByte('\n')
+	case FormatDebug:
+		if m == nil || (m.IsNil && m.Type == 0) {
+			// nil messages without type are encoded as null bulk strings
+			m = &Message{Type: BulkHeader, IsNil: true}
+		}
+		b, err := Marshal(m)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(repr(b))
+		buf.WriteByte('\n')
+	default:
+		formatCLI(&buf, m, "")
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// String returns the redis-cli representation of the message, without the
+// trailing newline.
+func (m *Message) String() string {
+	var buf bytes.Buffer
+	formatCLI(&buf, m, "")
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}))
+}
+
+func formatCLI(buf *bytes.Buffer, m *Message, prefix string) {
+	if m == nil || m.IsNil {
+		buf.WriteString("(nil)\n")
+		return
+	}
+	switch m.Type {
+	case ErrorHeader:
+		buf.WriteString("(error) ")
+		if m.Error != nil {
+			buf.WriteString(m.Error.Error())
+		}
+	case IntegerHeader:
+		buf.WriteString("(integer) ")
+		buf.WriteString(strconv.FormatInt(m.Integer, 10))
+	case BulkHeader:
+		buf.WriteString(repr(m.Bytes))
+	case StringHeader:
+		buf.WriteString(m.Status)
+	case BooleanHeader:
+		if m.Integer != 0 {
+			buf.WriteString("(true)")
+		} else {
+			buf.WriteString("(false)")
+		}
+	case DoubleHeader:
+		buf.WriteString("(double) ")
+		buf.WriteString(m.Status)
+	case BigNumberHeader:
+		buf.WriteString("(big number) ")
+		buf.WriteString(m.Status)
+	case VerbatimHeader:
+		buf.Write(m.Bytes)
+	case ArrayHeader, SetHeader, PushHeader, MapHeader:
+		elems := m.Array
+		if m.Type == MapHeader {
+			elems = elems[:len(elems)/2*2]
+		}
+		if len(elems) == 0 {
+			buf.WriteString(emptyAggregate[m.Type])
+			return
+		}
+		// elements are prefixed by their index, right aligned on the width
+		// of the largest one, and nested arrays are indented past it
+		count, step := len(elems), 1
+		if m.Type == MapHeader {
+			count, step = count/2, 2
+		}
+		idxlen := len(strconv.Itoa(count))
+		nested := prefix + string(bytes.Repeat([]byte{' '}, idxlen+2))
+		for i := 0; i < len(elems); i += step {
+			// the parent already wrote the prefix of the first element
+			if i > 0 {
+				buf.WriteString(prefix)
+			}
+			idx := strconv.Itoa(i/step + 1)
+			buf.Write(bytes.Repeat([]byte{' '}, idxlen-len(idx)))
+			buf.WriteString(idx)
+			buf.WriteString(indexSuffix[m.Type])
+			if m.Type != MapHeader {
+				formatCLI(buf, elems[i], nested)
+				continue
+			}
+			// map keys are followed by their value on the same line
+			var key bytes.Buffer
+			formatCLI(&key, elems[i], nested)
+			buf.Write(bytes.TrimSuffix(key.Bytes(), []byte{'\n'}))
+			buf.WriteString(" => ")
+			formatCLI(buf, elems[i+1], nested)
+		}
+		return
+	default:
+		buf.WriteString("(unknown)")
+	}
+	buf.WriteByte('\n')
+}
+
+// indexSuffix and emptyAggregate hold what redis-cli prints after the index
+// of the elements of an aggregate, and in place of an empty one.
+var (
+	indexSuffix = map[byte]string{
+		ArrayHeader: ") ",
+		PushHeader:  ") ",
+		SetHeader:   "~ ",
+		MapHeader:   "# ",
+	}
+	emptyAggregate = map[byte]string{
+		ArrayHeader: "(empty array)\n",
+		PushHeader:  "(empty push)\n",
+		SetHeader:   "(empty set)\n",
+		MapHeader:   "(empty hash)\n",
+	}
+)
+
+func formatRaw(buf *bytes.Buffer, m *Message) {
+	if m == nil || m.IsNil {
+		return
+	}
+	switch m.Type {
+	case ErrorHeader:
+		if m.Error != nil {
+			buf.WriteString(m.Error.Error())
+		}
+	case IntegerHeader:
+		buf.WriteString(strconv.FormatInt(m.Integer, 10))
+	case BulkHeader:
+		buf.Write(m.Bytes)
+	case StringHeader, DoubleHeader, BigNumberHeader:
+		buf.WriteString(m.Status)
+	case VerbatimHeader:
+		buf.Write(m.Bytes)
+	case BooleanHeader:
+		if m.Integer != 0 {
+			buf.WriteString("(true)")
+		} else {
+			buf.WriteString("(false)")
+		}
+	case ArrayHeader, SetHeader, PushHeader, MapHeader:
+		for i, elem := range m.Array {
+			if i > 0 {
+				buf.WriteByte('\n')
+			}
+			formatRaw(buf, elem)
+		}
+	}
+}
+
+// repr quotes b the way redis does (sdscatrepr), escaping quotes,
+// backslashes, control characters and non printable bytes.
+func repr(b []byte) string {
+	var buf bytes.Buffer
+	buf.WriteByte('"')
+	for _, c := range b {
+		switch c {
+		case '\\', '"':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\a':
+			buf.WriteString(`\a`)
+		case '\b':
+			buf.WriteString(`\b`)
+		default:
+			if c >= 0x20 && c <= 0x7e {
+				buf.WriteByte(c)
+			} else {
+				buf.WriteString(`\x`)
+				buf.WriteByte("0123456789abcdef"[c>>4])
+				buf.WriteByte("0123456789abcdef"[c&0xf])
+			}
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
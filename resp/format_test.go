@@ -0,0 +1,150 @@
+package resp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func formatString(m *Message, mode FormatMode) string {
+	var buf bytes.Buffer
+	Format(&buf, m, FormatOptions{Mode: mode})
+	return buf.String()
+}
+
+func TestFormatCLI(t *testing.T) {
+	msg, err := decodeToMsg([]byte("*3\r\n$3\r\nGET\r\n:5\r\n$-1\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := "1) \"GET\"\n2) (integer) 5\n3) (nil)\n"
+	if s := formatString(msg, FormatCLI); s != target {
+		t.Errorf("error formatted message: %q", s)
+	}
+
+	msg = &Message{}
+	msg.SetStatus("OK")
+	if s := formatString(msg, FormatCLI); s != "OK\n" {
+		t.Errorf("error formatted status: %q", s)
+	}
+
+	msg.SetError(errors.New("ERR unknown command"))
+	if s := formatString(msg, FormatCLI); s != "(error) ERR unknown command\n" {
+		t.Errorf("error formatted error: %q", s)
+	}
+
+	msg.SetBytes([]byte("a\"b\\\r\n\x00é"))
+	if s := formatString(msg, FormatCLI); s != "\"a\\\"b\\\\\\r\\n\\x00\\xc3\\xa9\"\n" {
+		t.Errorf("error formatted bulk: %q", s)
+	}
+
+	msg.SetArray([]*Message{})
+	if s := formatString(msg, FormatCLI); s != "(empty array)\n" {
+		t.Errorf("error formatted empty array: %q", s)
+	}
+}
+
+func TestFormatNested(t *testing.T) {
+	encoded := "*2\r\n*2\r\n$1\r\na\r\n*1\r\n$1\r\nb\r\n" +
+		"*10\r\n:1\r\n:2\r\n:3\r\n:4\r\n:5\r\n:6\r\n:7\r\n:8\r\n:9\r\n*0\r\n"
+	msg, err := decodeToMsg([]byte(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := "" +
+		"1) 1) \"a\"\n" +
+		"   2) 1) \"b\"\n" +
+		"2)  1) (integer) 1\n" +
+		"    2) (integer) 2\n" +
+		"    3) (integer) 3\n" +
+		"    4) (integer) 4\n" +
+		"    5) (integer) 5\n" +
+		"    6) (integer) 6\n" +
+		"    7) (integer) 7\n" +
+		"    8) (integer) 8\n" +
+		"    9) (integer) 9\n" +
+		"   10) (empty array)\n"
+	if s := formatString(msg, FormatCLI); s != target {
+		t.Errorf("error formatted nested array:\n%s", s)
+	}
+}
+
+func TestFormatRaw(t *testing.T) {
+	msg, err := decodeToMsg([]byte("*4\r\n$3\r\nfoo\r\n:-2\r\n+OK\r\n$-1\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := formatString(msg, FormatRaw); s != "foo\n-2\nOK\n\n" {
+		t.Errorf("error raw formatted message: %q", s)
+	}
+}
+
+func TestFormatDebug(t *testing.T) {
+	msg, err := decodeToMsg([]byte("*2\r\n$3\r\nGET\r\n:-1\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := formatString(msg, FormatDebug); s != "\"*2\\r\\n$3\\r\\nGET\\r\\n:-1\\r\\n\"\n" {
+		t.Errorf("error debug formatted message: %q", s)
+	}
+
+	msg = &Message{}
+	msg.SetNil()
+	if s := formatString(msg, FormatDebug); s != "\"$-1\\r\\n\"\n" {
+		t.Errorf("error debug formatted nil: %q", s)
+	}
+}
+
+func TestMessageString(t *testing.T) {
+	msg := &Message{}
+	msg.SetInteger(5)
+	if s := fmt.Sprintf("%v", msg); s != "(integer) 5" {
+		t.Errorf("error message string: %q", s)
+	}
+
+	msg.SetArray([]*Message{{Type: BulkHeader, Bytes: []byte("x")}})
+	if s := msg.String(); s != "1) \"x\"" {
+		t.Errorf("error message string: %q", s)
+	}
+}
+
+func TestFormatResp3(t *testing.T) {
+	msg, err := decodeToMsg([]byte("%3\r\n$5\r\nflags\r\n~2\r\n+on\r\n+bcast\r\n" +
+		"$8\r\nredirect\r\n:-1\r\n$8\r\nprefixes\r\n*0\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := "1# \"flags\" => 1~ on\n" +
+		"   2~ bcast\n" +
+		"2# \"redirect\" => (integer) -1\n" +
+		"3# \"prefixes\" => (empty array)\n"
+	if s := formatString(msg, FormatCLI); s != target {
+		t.Errorf("error formatted map:\n%s", s)
+	}
+
+	cases := []struct {
+		encoded, cli, raw string
+	}{
+		{"#t\r\n", "(true)\n", "(true)\n"},
+		{"_\r\n", "(nil)\n", "\n"},
+		{",1.5\r\n", "(double) 1.5\n", "1.5\n"},
+		{"(12345678901234567890\r\n", "(big number) 12345678901234567890\n", "12345678901234567890\n"},
+		{"=9\r\ntxt:a\nb c\r\n", "a\nb c\n", "a\nb c\n"},
+		{"%0\r\n", "(empty hash)\n", "\n"},
+		{"~0\r\n", "(empty set)\n", "\n"},
+		{">2\r\n$7\r\nmessage\r\n$1\r\nx\r\n", "1) \"message\"\n2) \"x\"\n", "message\nx\n"},
+	}
+	for _, c := range cases {
+		msg, err := decodeToMsg([]byte(c.encoded))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := formatString(msg, FormatCLI); s != c.cli {
+			t.Errorf("error formatted %q: %q", c.encoded, s)
+		}
+		if s := formatString(msg, FormatRaw); s != c.raw {
+			t.Errorf("error raw formatted %q: %q", c.encoded, s)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package resp
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// Framer finds the end of the whole messages of a buffer growing as the data
+// of a stream arrives, without decoding them. It keeps its position between
+// calls, so that each byte is scanned once however many reads a message
+// takes, and the buffer is only decoded once it holds whole messages.
+type Framer struct {
+	// pos is the offset of the next element to scan, end the end of the last
+	// whole message and searched the offset up to which the line of the next
+	// element was searched for its end
+	pos      int
+	end      int
+	searched int
+	// pending holds the number of elements left in the aggregates open at pos
+	pending []int
+}
+
+// Scan scans the data appended to buf since the last call, and returns the
+// offset following the last whole message of buf, 0 if there is none. It
+// returns false when buf does not hold valid RESP data, Decode reporting
+// the error.
+func (f *Framer) Scan(buf []byte) (int, bool) {
+	for f.pos < len(buf) {
+		if f.searched < f.pos {
+			f.searched = f.pos
+		}
+		i := bytes.IndexByte(buf[f.searched:], LF)
+		if i < 0 {
+			f.searched = len(buf)
+			break
+		}
+		lf := f.searched + i
+		if lf < f.pos+2 || buf[lf-1] != CR {
+			return f.end, false
+		}
+		header, line, next := buf[f.pos], buf[f.pos+1:lf-1], lf+1
+
+		switch header {
+		case StringHeader, ErrorHeader, IntegerHeader, NullHeader, BooleanHeader,
+			DoubleHeader, BigNumberHeader:
+		case BulkHeader, VerbatimHeader, BlobErrorHeader:
+			n, err := strconv.Atoi(string(line))
+			if err != nil || (n < 0 && header != BulkHeader) {
+				return f.end, false
+			}
+			if n >= 0 {
+				if n > len(buf)-next-2 {
+					return f.end, true
+				}
+				next += n + 2
+			}
+		case ArrayHeader, MapHeader, SetHeader, PushHeader, AttributeHeader:
+			n, err := strconv.Atoi(string(line))
+			if err != nil || (n < 0 && header == AttributeHeader) {
+				return f.end, false
+			}
+			if header == MapHeader {
+				n *= 2
+			} else if header == AttributeHeader {
+				// the attributes are followed by the reply they describe
+				n = n*2 + 1
+			}
+			if n > 0 {
+				f.pending = append(f.pending, n)
+				f.pos = next
+				continue
+			}
+		default:
+			return f.end, false
+		}
+
+		f.pos = next
+		f.complete()
+	}
+	return f.end, true
+}
+
+// complete accounts for an element ending at pos, which ends the aggregates
+// it is the last element of.
+func (f *Framer) complete() {
+	for len(f.pending) > 0 {
+		top := len(f.pending) - 1
+		if f.pending[top]--; f.pending[top] > 0 {
+			return
+		}
+		f.pending = f.pending[:top]
+	}
+	f.end = f.pos
+}
+
+// Discard tells f that the first n bytes of the buffer were removed, n not
+// being past the offset returned by Scan.
+func (f *Framer) Discard(n int) {
+	f.pos -= n
+	f.end -= n
+	f.searched -= n
+}
+
+// Reset forgets the data scanned, the next buffer given to Scan being a new
+// one.
+func (f *Framer) Reset() {
+	f.pos, f.end, f.searched = 0, 0, 0
+	f.pending = f.pending[:0]
+}
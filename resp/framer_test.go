@@ -0,0 +1,67 @@
+package resp
+
+import (
+	"testing"
+)
+
+func TestFramerSegmented(t *testing.T) {
+	encoded := "+OK\r\n*2\r\n$3\r\nGET\r\n%1\r\n+k\r\n~2\r\n:1\r\n_\r\n" +
+		"|1\r\n+ttl\r\n:3\r\n=7\r\ntxt:abc\r\n*0\r\n!3\r\nERR\r\n$-1\r\n"
+	ends := map[int]bool{5: true, 37: true, 64: true, 68: true, 77: true, 82: true}
+
+	var f Framer
+	last := 0
+	for i := 1; i <= len(encoded); i++ {
+		end, ok := f.Scan([]byte(encoded[:i]))
+		if !ok {
+			t.Fatalf("error scanning %q", encoded[:i])
+		}
+		if end != last && (end != i || !ends[end]) {
+			t.Errorf("error end %d of %q", end, encoded[:i])
+		}
+		last = end
+	}
+	if last != len(encoded) {
+		t.Errorf("error last end: %d", last)
+	}
+}
+
+func TestFramerDiscard(t *testing.T) {
+	var f Framer
+	buf := []byte("*2\r\n:1\r\n:2\r\n*1\r\n$5\r\nhel")
+	if end, ok := f.Scan(buf); !ok || end != 12 {
+		t.Fatalf("error end: %d %v", end, ok)
+	}
+	f.Discard(12)
+	buf = append(buf[12:], "lo\r\n"...)
+	if end, ok := f.Scan(buf); !ok || end != len(buf) {
+		t.Errorf("error end after discard: %d %v", end, ok)
+	}
+
+	f.Reset()
+	if end, ok := f.Scan([]byte("+OK\r\n")); !ok || end != 5 {
+		t.Errorf("error end after reset: %d %v", end, ok)
+	}
+}
+
+func TestFramerInvalid(t *testing.T) {
+	for _, data := range []string{
+		"?x\r\n",
+		"\r\n",
+		"+OK\n",
+		"$x\r\n",
+		"*-x\r\n",
+		"!-1\r\n",
+		"|-1\r\n",
+	} {
+		var f Framer
+		if _, ok := f.Scan([]byte(data)); ok {
+			t.Errorf("%q should not be valid", data)
+		}
+	}
+
+	var f Framer
+	if end, ok := f.Scan([]byte(":1\r\n:2\r\n?\r\n")); ok || end != 8 {
+		t.Errorf("error end before invalid data: %d %v", end, ok)
+	}
+}
@@ -0,0 +1,83 @@
+package resp
+
+import (
+	"bytes"
+	"context"
+)
+
+// Hook observes the commands processed by a Conn or a Server.
+type Hook interface {
+	// BeforeProcess is called before a command is sent by a client or
+	// handled by a server. The returned context is passed to AfterProcess,
+	// a non nil error aborts the command.
+	BeforeProcess(ctx context.Context, cmd *Message) (context.Context, error)
+	// AfterProcess is called once the reply of a command is received by a
+	// client or written by a server, err being the error which prevented
+	// it.
+	AfterProcess(ctx context.Context, cmd *Message, reply *Message, err error)
+	// OnDecodeError is called when the data read can not be decoded.
+	OnDecodeError(err error)
+	// OnPipeline is called when several commands are flushed at once by a
+	// client or read at once by a server.
+	OnPipeline(ctx context.Context, cmds []*Message)
+}
+
+// NopHook implements Hook doing nothing, it can be embedded by hooks which
+// only need some of the methods.
+type NopHook struct{}
+
+// BeforeProcess implements Hook.
+func (NopHook) BeforeProcess(ctx context.Context, cmd *Message) (context.Context, error) {
+	return ctx, nil
+}
+
+// AfterProcess implements Hook.
+func (NopHook) AfterProcess(ctx context.Context, cmd *Message, reply *Message, err error) {}
+
+// OnDecodeError implements Hook.
+func (NopHook) OnDecodeError(err error) {}
+
+// OnPipeline implements Hook.
+func (NopHook) OnPipeline(ctx context.Context, cmds []*Message) {}
+
+type hookList []Hook
+
+func (hooks hookList) before(ctx context.Context, cmd *Message) (context.Context, error) {
+	for _, h := range hooks {
+		var err error
+		if ctx, err = h.BeforeProcess(ctx, cmd); err != nil {
+			return ctx, err
+		}
+	}
+	return ctx, nil
+}
+
+func (hooks hookList) after(ctx context.Context, cmd *Message, reply *Message, err error) {
+	for _, h := range hooks {
+		h.AfterProcess(ctx, cmd, reply, err)
+	}
+}
+
+func (hooks hookList) decodeError(err error) {
+	for _, h := range hooks {
+		h.OnDecodeError(err)
+	}
+}
+
+func (hooks hookList) pipeline(ctx context.Context, cmds []*Message) {
+	for _, h := range hooks {
+		h.OnPipeline(ctx, cmds)
+	}
+}
+
+// commandName returns the upper cased name of a command.
+func commandName(cmd *Message) string {
+	if cmd == nil || cmd.Type != ArrayHeader || len(cmd.Array) == 0 || cmd.Array[0] == nil {
+		return ""
+	}
+	name := cmd.Array[0].Bytes
+	if cmd.Array[0].Type == StringHeader {
+		name = []byte(cmd.Array[0].Status)
+	}
+	return string(bytes.ToUpper(name))
+}
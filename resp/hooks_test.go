@@ -0,0 +1,146 @@
+package resp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+type ctxKey struct{}
+
+// recordHook records the calls made to a hook.
+type recordHook struct {
+	calls []string
+	err   error
+}
+
+func (h *recordHook) BeforeProcess(ctx context.Context, cmd *Message) (context.Context, error) {
+	h.calls = append(h.calls, "before "+commandString(cmd))
+	if h.err != nil {
+		return ctx, h.err
+	}
+	return context.WithValue(ctx, ctxKey{}, commandString(cmd)), nil
+}
+
+func (h *recordHook) AfterProcess(ctx context.Context, cmd *Message, reply *Message, err error) {
+	call := "after " + commandString(cmd)
+	if v, ok := ctx.Value(ctxKey{}).(string); !ok || v != commandString(cmd) {
+		call += " (no context)"
+	}
+	if reply != nil {
+		call += " " + reply.String()
+	}
+	if err != nil {
+		call += " " + err.Error()
+	}
+	h.calls = append(h.calls, call)
+}
+
+func (h *recordHook) OnDecodeError(err error) {
+	h.calls = append(h.calls, "decode "+err.Error())
+}
+
+func (h *recordHook) OnPipeline(ctx context.Context, cmds []*Message) {
+	args := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		args[i] = commandString(cmd)
+	}
+	h.calls = append(h.calls, "pipeline "+strings.Join(args, ", "))
+}
+
+func checkCalls(t *testing.T, calls []string, expected ...string) {
+	if strings.Join(calls, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("error hook calls:\n%s\nexpected:\n%s",
+			strings.Join(calls, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestConnHooks(t *testing.T) {
+	c := newTestConn(t, func(cmd *Message) *Message {
+		return Str("OK")
+	})
+	defer c.Close()
+	h := &recordHook{}
+	c.AddHook(h)
+
+	if _, err := c.Do(Cmd("SET", "a", "1")); err != nil {
+		t.Fatal(err)
+	}
+	c.Send(Cmd("GET", "a"))
+	c.Send(Cmd("GET", "b"))
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.Receive(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkCalls(t, h.calls,
+		"before SET a 1",
+		"after SET a 1 OK",
+		"before GET a",
+		"before GET b",
+		"pipeline GET a, GET b",
+		"after GET a OK",
+		"after GET b OK",
+	)
+}
+
+func TestConnHookAbort(t *testing.T) {
+	c := newTestConn(t, func(cmd *Message) *Message {
+		return Str("OK")
+	})
+	defer c.Close()
+	h := &recordHook{err: errors.New("denied")}
+	c.AddHook(h)
+
+	if _, err := c.Do(Cmd("FLUSHALL")); err != h.err {
+		t.Errorf("error error: %v", err)
+	}
+	if c.Pending() != 0 || c.Err() != nil {
+		t.Error(errTestFailed)
+	}
+	checkCalls(t, h.calls,
+		"before FLUSHALL",
+		"after FLUSHALL (no context) denied",
+	)
+}
+
+func TestConnHookClosed(t *testing.T) {
+	c := newTestConn(t, func(cmd *Message) *Message {
+		return Str("OK")
+	})
+	h := &recordHook{}
+	c.AddHook(h)
+
+	c.Send(Cmd("PING"))
+	c.Close()
+	checkCalls(t, h.calls,
+		"before PING",
+		"after PING connection closed",
+	)
+}
+
+func TestConnHookDecodeError(t *testing.T) {
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		NewReader(server).ReadMessage()
+		server.Write([]byte("?bad\r\n"))
+	}()
+	c := NewConn(client)
+	defer c.Close()
+	h := &recordHook{}
+	c.AddHook(h)
+
+	if _, err := c.Do(Cmd("PING")); err == nil {
+		t.Error(errErrorExpected)
+	}
+	if len(h.calls) != 3 || !strings.HasPrefix(h.calls[1], "decode ") ||
+		!strings.HasPrefix(h.calls[2], "after PING ") {
+		t.Errorf("error hook calls: %q", h.calls)
+	}
+}
@@ -0,0 +1,340 @@
+package resp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Type names used by the tagged JSON form of a message.
+const (
+	jsonTypeStatus  = "status"
+	jsonTypeError   = "error"
+	jsonTypeInteger = "integer"
+	jsonTypeBulk    = "bulk"
+	jsonTypeArray   = "array"
+	jsonTypeNil     = "nil"
+
+	// RESP3 types
+	jsonTypeMap       = "map"
+	jsonTypeSet       = "set"
+	jsonTypePush      = "push"
+	jsonTypeNull      = "null"
+	jsonTypeBoolean   = "boolean"
+	jsonTypeDouble    = "double"
+	jsonTypeBigNumber = "bignumber"
+	jsonTypeVerbatim  = "verbatim"
+
+	jsonEncodingBase64 = "base64"
+)
+
+// jsonMessage is the tagged JSON form of a message, e.g.
+// {"type":"bulk","value":"foo"}. Bulk strings which are not valid UTF-8 are
+// base64 encoded and flagged with "encoding":"base64", nil bulk strings and
+// aggregates have a null value. RESP3 maps hold their keys and values
+// alternated in an array, doubles and big numbers their text.
+type jsonMessage struct {
+	Type     string          `json:"type"`
+	Value    json.RawMessage `json:"value"`
+	Encoding string          `json:"encoding,omitempty"`
+}
+
+// MarshalJSON returns the tagged JSON form of the message, which keeps the
+// type of the message so that UnmarshalJSON gives back the same message.
+func (m *Message) MarshalJSON() ([]byte, error) {
+	var (
+		jm    jsonMessage
+		value interface{}
+	)
+	switch m.Type {
+	case StringHeader:
+		jm.Type, value = jsonTypeStatus, m.Status
+	case ErrorHeader:
+		jm.Type = jsonTypeError
+		if m.Error != nil {
+			value = m.Error.Error()
+		} else {
+			value = ""
+		}
+	case IntegerHeader:
+		jm.Type, value = jsonTypeInteger, m.Integer
+	case BulkHeader, VerbatimHeader:
+		jm.Type = jsonTypeBulk
+		if m.Type == VerbatimHeader {
+			jm.Type = jsonTypeVerbatim
+		}
+		if m.IsNil {
+			break
+		}
+		if utf8.Valid(m.Bytes) {
+			value = string(m.Bytes)
+		} else {
+			jm.Encoding = jsonEncodingBase64
+			value = base64.StdEncoding.EncodeToString(m.Bytes)
+		}
+	case ArrayHeader, MapHeader, SetHeader, PushHeader:
+		jm.Type = jsonAggregateTypes[m.Type]
+		if m.IsNil {
+			break
+		}
+		if m.Array == nil {
+			value = []*Message{}
+		} else {
+			value = m.Array
+		}
+	case NullHeader:
+		jm.Type = jsonTypeNull
+	case BooleanHeader:
+		jm.Type, value = jsonTypeBoolean, m.Integer != 0
+	case DoubleHeader:
+		jm.Type, value = jsonTypeDouble, m.Status
+	case BigNumberHeader:
+		jm.Type, value = jsonTypeBigNumber, m.Status
+	default:
+		if !m.IsNil {
+			return nil, ErrInvalidHeader
+		}
+		jm.Type = jsonTypeNil
+	}
+
+	var err error
+	if jm.Value, err = json.Marshal(value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(jm)
+}
+
+var jsonAggregateTypes = map[byte]string{
+	ArrayHeader: jsonTypeArray,
+	MapHeader:   jsonTypeMap,
+	SetHeader:   jsonTypeSet,
+	PushHeader:  jsonTypePush,
+}
+
+// UnmarshalJSON sets the message from its tagged JSON form. It returns
+// ErrInvalidJSON when the data is not the tagged form of a message.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var jm jsonMessage
+	if err := json.Unmarshal(data, &jm); err != nil {
+		return ErrInvalidJSON
+	}
+	*m = Message{}
+	if len(jm.Value) == 0 {
+		return ErrInvalidJSON
+	}
+	isNull := string(jm.Value) == "null"
+
+	switch jm.Type {
+	case jsonTypeStatus:
+		var s string
+		if err := json.Unmarshal(jm.Value, &s); err != nil || isNull {
+			return ErrInvalidJSON
+		}
+		m.SetStatus(s)
+	case jsonTypeError:
+		var s string
+		if err := json.Unmarshal(jm.Value, &s); err != nil || isNull {
+			return ErrInvalidJSON
+		}
+		m.SetError(errors.New(s))
+	case jsonTypeInteger:
+		var i int64
+		if err := json.Unmarshal(jm.Value, &i); err != nil || isNull {
+			return ErrInvalidJSON
+		}
+		m.SetInteger(i)
+	case jsonTypeBulk, jsonTypeVerbatim:
+		if isNull && jm.Type == jsonTypeVerbatim {
+			return ErrInvalidJSON
+		}
+		if isNull {
+			m.SetNil()
+			m.Type = BulkHeader
+			return nil
+		}
+		var s string
+		if err := json.Unmarshal(jm.Value, &s); err != nil {
+			return ErrInvalidJSON
+		}
+		b := []byte(s)
+		if jm.Encoding == jsonEncodingBase64 {
+			var err error
+			if b, err = base64.StdEncoding.DecodeString(s); err != nil {
+				return ErrInvalidJSON
+			}
+		} else if jm.Encoding != "" {
+			return ErrInvalidJSON
+		}
+		m.SetBytes(b)
+		if jm.Type == jsonTypeVerbatim {
+			m.Type = VerbatimHeader
+		}
+	case jsonTypeArray, jsonTypeMap, jsonTypeSet, jsonTypePush:
+		var a []*Message
+		if isNull {
+			m.SetNil()
+		} else if err := json.Unmarshal(jm.Value, &a); err != nil {
+			return ErrInvalidJSON
+		} else if jm.Type == jsonTypeMap && len(a)%2 != 0 {
+			return ErrInvalidJSON
+		} else {
+			m.SetArray(a)
+		}
+		for header, name := range jsonAggregateTypes {
+			if name == jm.Type {
+				m.Type = header
+			}
+		}
+	case jsonTypeNil:
+		m.SetNil()
+	case jsonTypeNull:
+		m.SetNil()
+		m.Type = NullHeader
+	case jsonTypeBoolean:
+		var b bool
+		if err := json.Unmarshal(jm.Value, &b); err != nil || isNull {
+			return ErrInvalidJSON
+		}
+		m.Type = BooleanHeader
+		if b {
+			m.Integer = 1
+		}
+	case jsonTypeDouble, jsonTypeBigNumber:
+		var s string
+		if err := json.Unmarshal(jm.Value, &s); err != nil || isNull {
+			return ErrInvalidJSON
+		}
+		m.Type = DoubleHeader
+		if jm.Type == jsonTypeBigNumber {
+			m.Type = BigNumberHeader
+		}
+		m.Status = s
+	default:
+		return ErrInvalidJSON
+	}
+	return nil
+}
+
+// MarshalNaturalJSON returns a lossy JSON form of the message, as a client
+// would expect it from an HTTP API: status and bulk strings become JSON
+// strings, integers numbers, nil values null, arrays JSON arrays and errors
+// an {"error": "..."} object. RESP3 maps become objects, their keys being
+// formatted as strings, sets arrays, booleans and finite doubles JSON
+// booleans and numbers, and other doubles and big numbers strings.
+func MarshalNaturalJSON(m *Message) ([]byte, error) {
+	return json.Marshal(naturalValue(m))
+}
+
+func naturalValue(m *Message) interface{} {
+	if m == nil || m.IsNil {
+		return nil
+	}
+	switch m.Type {
+	case StringHeader:
+		return m.Status
+	case ErrorHeader:
+		e := ""
+		if m.Error != nil {
+			e = m.Error.Error()
+		}
+		return map[string]string{"error": e}
+	case IntegerHeader:
+		return m.Integer
+	case BulkHeader, VerbatimHeader:
+		return string(m.Bytes)
+	case ArrayHeader, SetHeader, PushHeader:
+		a := make([]interface{}, len(m.Array))
+		for i, elem := range m.Array {
+			a[i] = naturalValue(elem)
+		}
+		return a
+	case MapHeader:
+		o := make(map[string]interface{}, len(m.Array)/2)
+		for i := 0; i+1 < len(m.Array); i += 2 {
+			o[naturalKey(m.Array[i])] = naturalValue(m.Array[i+1])
+		}
+		return o
+	case BooleanHeader:
+		return m.Integer != 0
+	case DoubleHeader:
+		if f, err := strconv.ParseFloat(m.Status, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+			return f
+		}
+		return m.Status
+	case BigNumberHeader:
+		return m.Status
+	}
+	return nil
+}
+
+// naturalKey returns the key of a map entry as a string: the text of
+// strings and numbers, and the redis-cli form of other messages.
+func naturalKey(m *Message) string {
+	if m != nil && !m.IsNil {
+		if s, ok := m.text(); ok {
+			return s
+		}
+		if m.Type == IntegerHeader {
+			return strconv.FormatInt(m.Integer, 10)
+		}
+	}
+	return m.String()
+}
+
+// UnmarshalNaturalJSON builds a message from its natural JSON form. Strings
+// become bulk strings, integral numbers integers, other numbers and booleans
+// bulk strings, null a nil bulk string and {"error": "..."} an error.
+func UnmarshalNaturalJSON(data []byte) (*Message, error) {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return naturalMessage(v)
+}
+
+func naturalMessage(v interface{}) (*Message, error) {
+	m := &Message{}
+	switch t := v.(type) {
+	case nil:
+		m.SetNil()
+		m.Type = BulkHeader
+	case string:
+		m.SetBytes([]byte(t))
+	case bool:
+		if t {
+			m.SetBytes([]byte("1"))
+		} else {
+			m.SetBytes([]byte("0"))
+		}
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			m.SetInteger(i)
+		} else {
+			m.SetBytes([]byte(t.String()))
+		}
+	case []interface{}:
+		a := make([]*Message, len(t))
+		for i := range t {
+			var err error
+			if a[i], err = naturalMessage(t[i]); err != nil {
+				return nil, err
+			}
+		}
+		m.SetArray(a)
+	case map[string]interface{}:
+		e, ok := t["error"].(string)
+		if !ok || len(t) != 1 {
+			return nil, ErrInvalidJSON
+		}
+		m.SetError(errors.New(e))
+	default:
+		return nil, ErrInvalidJSON
+	}
+	return m, nil
+}
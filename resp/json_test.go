@@ -0,0 +1,211 @@
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONTaggedForm(t *testing.T) {
+	msg, err := decodeToMsg([]byte("*5\r\n+OK\r\n:-7\r\n$3\r\nfoo\r\n$-1\r\n*-1\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := `{"type":"array","value":[` +
+		`{"type":"status","value":"OK"},` +
+		`{"type":"integer","value":-7},` +
+		`{"type":"bulk","value":"foo"},` +
+		`{"type":"bulk","value":null},` +
+		`{"type":"array","value":null}]}`
+	if string(b) != target {
+		t.Errorf("error json form: %s", b)
+	}
+
+	msg = &Message{}
+	msg.SetBytes([]byte{0xff, 0x00})
+	if b, err = json.Marshal(msg); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"type":"bulk","value":"/wA=","encoding":"base64"}` {
+		t.Errorf("error json form of binary bulk: %s", b)
+	}
+	var decoded Message
+	if err = json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	} else if decoded.Type != BulkHeader || !bytes.Equal(decoded.Bytes, []byte{0xff, 0x00}) {
+		t.Errorf("error decoded binary bulk: %v", decoded.Bytes)
+	}
+}
+
+func TestJSONLinesRoundTrip(t *testing.T) {
+	encoded := []byte("+OK\r\n-ERR failed\r\n:42\r\n$5\r\nhello\r\n$-1\r\n" +
+		"*3\r\n$3\r\nGET\r\n*0\r\n$2\r\n\xc3\x28\r\n*-1\r\n")
+	msgQ, _, err := Decode(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines bytes.Buffer
+	enc := json.NewEncoder(&lines)
+	for _, msg := range msgQ {
+		if err = enc.Encode(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	respEnc := NewEncoder(&out)
+	scanner := bufio.NewScanner(&lines)
+	for scanner.Scan() {
+		msg := &Message{}
+		if err = json.Unmarshal(scanner.Bytes(), msg); err != nil {
+			t.Fatal(err)
+		}
+		if err = respEnc.Encode(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(out.Bytes(), encoded) {
+		t.Errorf("error round trip: %q", out.Bytes())
+	}
+}
+
+func TestJSONInvalid(t *testing.T) {
+	var msg Message
+	for _, data := range []string{
+		`{"type":"unknown","value":1}`,
+		`{"type":"integer","value":"1"}`,
+		`{"type":"status","value":null}`,
+		`{"type":"bulk","value":"x","encoding":"hex"}`,
+		`{"type":"bulk"}`,
+		`{"type":"map"}`,
+		`{"type":"map","value":{}}`,
+		`{"type":"set","value":[{"type":"integer"}]}`,
+		`{"type":"array","value":[1]}`,
+		`[]`,
+	} {
+		if err := json.Unmarshal([]byte(data), &msg); err != ErrInvalidJSON {
+			t.Errorf("%s should return ErrInvalidJSON, not: %v", data, err)
+		}
+	}
+
+	msg = Message{}
+	if _, err := json.Marshal(&msg); err == nil {
+		t.Error(errErrorExpected)
+	}
+}
+
+func TestJSONNilAggregates(t *testing.T) {
+	for _, header := range []byte{ArrayHeader, MapHeader, SetHeader, PushHeader} {
+		msg := &Message{}
+		msg.SetNil()
+		msg.Type = header
+		b, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Message
+		if err = json.Unmarshal(b, &decoded); err != nil {
+			t.Errorf("error unmarshalling %s: %v", b, err)
+		} else if decoded.Type != header || !decoded.IsNil {
+			t.Errorf("error nil aggregate from %s: %v", b, decoded)
+		}
+	}
+}
+
+func TestNaturalJSON(t *testing.T) {
+	msg, err := decodeToMsg([]byte("*5\r\n+OK\r\n:3\r\n$3\r\nfoo\r\n$-1\r\n-ERR oops\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := MarshalNaturalJSON(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `["OK",3,"foo",null,{"error":"ERR oops"}]` {
+		t.Errorf("error natural json: %s", b)
+	}
+
+	if msg, err = UnmarshalNaturalJSON(b); err != nil {
+		t.Fatal(err)
+	}
+	if newbuf, _ := Marshal(msg); string(newbuf) != "*5\r\n$2\r\nOK\r\n:3\r\n$3\r\nfoo\r\n$-1\r\n-ERR oops\r\n" {
+		t.Errorf("error message from natural json: %q", newbuf)
+	}
+
+	if msg, err = UnmarshalNaturalJSON([]byte(`[1.5, true]`)); err != nil {
+		t.Fatal(err)
+	} else if string(msg.Array[0].Bytes) != "1.5" || string(msg.Array[1].Bytes) != "1" {
+		t.Errorf("error message from natural json: %v", msg)
+	}
+
+	if _, err = UnmarshalNaturalJSON([]byte(`{"foo":"bar"}`)); err != ErrInvalidJSON {
+		t.Errorf("should return ErrInvalidJSON, not: %v", err)
+	}
+}
+
+func TestJSONResp3(t *testing.T) {
+	encoded := []byte("%1\r\n+proto\r\n:3\r\n~1\r\n#t\r\n>1\r\n,inf\r\n_\r\n(123456789012345678901\r\n=7\r\ntxt:abc\r\n")
+	msgQ, _, err := Decode(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines bytes.Buffer
+	enc := json.NewEncoder(&lines)
+	for _, msg := range msgQ {
+		if err = enc.Encode(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	target := `{"type":"map","value":[{"type":"status","value":"proto"},{"type":"integer","value":3}]}` + "\n" +
+		`{"type":"set","value":[{"type":"boolean","value":true}]}` + "\n" +
+		`{"type":"push","value":[{"type":"double","value":"inf"}]}` + "\n" +
+		`{"type":"null","value":null}` + "\n" +
+		`{"type":"bignumber","value":"123456789012345678901"}` + "\n" +
+		`{"type":"verbatim","value":"abc"}` + "\n"
+	if lines.String() != target {
+		t.Errorf("error json lines: %s", lines.String())
+	}
+
+	var out bytes.Buffer
+	respEnc := NewEncoder(&out)
+	scanner := bufio.NewScanner(&lines)
+	for scanner.Scan() {
+		msg := &Message{}
+		if err = json.Unmarshal(scanner.Bytes(), msg); err != nil {
+			t.Fatal(err)
+		}
+		respEnc.Encode(msg)
+	}
+	if !bytes.Equal(out.Bytes(), encoded) {
+		t.Errorf("error round trip: %q", out.Bytes())
+	}
+
+	for _, data := range []string{
+		`{"type":"map","value":[{"type":"integer","value":1}]}`,
+		`{"type":"set","value":"a"}`,
+		`{"type":"boolean","value":1}`,
+		`{"type":"double","value":1.5}`,
+		`{"type":"verbatim","value":null}`,
+	} {
+		var msg Message
+		if err := json.Unmarshal([]byte(data), &msg); err != ErrInvalidJSON {
+			t.Errorf("%s should return ErrInvalidJSON, not: %v", data, err)
+		}
+	}
+
+	b, err := MarshalNaturalJSON(msgQ[0])
+	if err != nil || string(b) != `{"proto":3}` {
+		t.Errorf("error natural json of a map: %s %v", b, err)
+	}
+	msg, _ := decodeToMsg([]byte("*4\r\n#f\r\n,1.5\r\n,nan\r\n(12\r\n"))
+	b, err = MarshalNaturalJSON(msg)
+	if err != nil || string(b) != `[false,1.5,"nan","12"]` {
+		t.Errorf("error natural json of RESP3 scalars: %s %v", b, err)
+	}
+}
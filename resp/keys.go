@@ -0,0 +1,305 @@
+package resp
+
+import (
+	"strconv"
+	"strings"
+)
+
+// keySpec locates the keys in the arguments of a command, as the first,
+// last and step fields of the redis command table: a negative last counts
+// from the end of the arguments.
+type keySpec struct {
+	first, last, step int
+	// numKeys is the position of the argument counting the keys which
+	// follow it, for commands such as EVAL
+	numKeys int
+	// find returns the keys of the commands whose keys depend on their
+	// subcommand or options, instead of the fields above
+	find func(args []string) []string
+}
+
+var (
+	noKeys     = keySpec{}
+	firstKey   = keySpec{first: 1, last: 1, step: 1}
+	allKeys    = keySpec{first: 1, last: -1, step: 1}
+	twoKeys    = keySpec{first: 1, last: 2, step: 1}
+	blocking   = keySpec{first: 1, last: -2, step: 1}
+	keyValues  = keySpec{first: 1, last: -1, step: 2}
+	storeKeys  = keySpec{first: 1, last: 1, step: 1, numKeys: 2}
+	scriptKeys = keySpec{numKeys: 2}
+	// subKey is the spec of the commands whose key follows the subcommand,
+	// such as OBJECT ENCODING key
+	subKey = keySpec{find: subcommandKey}
+)
+
+var keySpecs = map[string]keySpec{
+	"PING": noKeys, "ECHO": noKeys, "SELECT": noKeys, "AUTH": noKeys,
+	"HELLO": noKeys, "QUIT": noKeys, "RESET": noKeys, "INFO": noKeys,
+	"CONFIG": noKeys, "CLIENT": noKeys, "CLUSTER": noKeys, "COMMAND": noKeys,
+	"DBSIZE": noKeys, "FLUSHDB": noKeys, "FLUSHALL": noKeys, "KEYS": noKeys,
+	"SCAN": noKeys, "RANDOMKEY": noKeys, "MULTI": noKeys, "EXEC": noKeys,
+	"DISCARD": noKeys, "UNWATCH": noKeys, "PUBLISH": noKeys,
+	"SUBSCRIBE": noKeys, "UNSUBSCRIBE": noKeys, "PSUBSCRIBE": noKeys,
+	"PUNSUBSCRIBE": noKeys, "SPUBLISH": noKeys, "SSUBSCRIBE": noKeys,
+	"SUNSUBSCRIBE": noKeys, "PUBSUB": noKeys, "SCRIPT": noKeys,
+	"FUNCTION": noKeys, "TIME": noKeys, "SLOWLOG": noKeys, "LATENCY": noKeys,
+	"DEBUG": noKeys, "SAVE": noKeys, "BGSAVE": noKeys, "BGREWRITEAOF": noKeys,
+	"LASTSAVE": noKeys, "REPLICAOF": noKeys, "SLAVEOF": noKeys, "ROLE": noKeys,
+	"WAIT": noKeys, "WAITAOF": noKeys, "READONLY": noKeys, "READWRITE": noKeys,
+	"ACL": noKeys, "SWAPDB": noKeys, "SHUTDOWN": noKeys, "MONITOR": noKeys,
+	"SYNC": noKeys, "PSYNC": noKeys, "REPLCONF": noKeys, "ASKING": noKeys,
+	"MODULE": noKeys, "FAILOVER": noKeys, "LOLWUT": noKeys,
+
+	"GET": firstKey, "SET": firstKey, "SETNX": firstKey, "SETEX": firstKey,
+	"PSETEX": firstKey, "GETSET": firstKey, "GETDEL": firstKey,
+	"GETEX": firstKey, "APPEND": firstKey, "STRLEN": firstKey,
+	"INCR": firstKey, "DECR": firstKey, "INCRBY": firstKey, "DECRBY": firstKey,
+	"INCRBYFLOAT": firstKey, "GETRANGE": firstKey, "SETRANGE": firstKey,
+	"SUBSTR": firstKey, "GETBIT": firstKey, "SETBIT": firstKey,
+	"BITCOUNT": firstKey, "BITPOS": firstKey, "BITFIELD": firstKey,
+	"BITFIELD_RO": firstKey, "EXPIRE": firstKey, "PEXPIRE": firstKey,
+	"EXPIREAT": firstKey, "PEXPIREAT": firstKey, "EXPIRETIME": firstKey,
+	"PEXPIRETIME": firstKey, "TTL": firstKey, "PTTL": firstKey,
+	"PERSIST": firstKey, "TYPE": firstKey, "DUMP": firstKey,
+	"RESTORE": firstKey, "PFADD": firstKey,
+	"LPUSH": firstKey, "RPUSH": firstKey, "LPUSHX": firstKey,
+	"RPUSHX": firstKey, "LPOP": firstKey, "RPOP": firstKey, "LLEN": firstKey,
+	"LRANGE": firstKey, "LINDEX": firstKey, "LSET": firstKey,
+	"LINSERT": firstKey, "LREM": firstKey, "LTRIM": firstKey, "LPOS": firstKey,
+	"SADD": firstKey, "SREM": firstKey, "SMEMBERS": firstKey,
+	"SISMEMBER": firstKey, "SMISMEMBER": firstKey, "SCARD": firstKey,
+	"SPOP": firstKey, "SRANDMEMBER": firstKey, "SSCAN": firstKey,
+	"ZADD": firstKey, "ZREM": firstKey, "ZCARD": firstKey, "ZCOUNT": firstKey,
+	"ZSCORE": firstKey, "ZMSCORE": firstKey, "ZINCRBY": firstKey,
+	"ZRANK": firstKey, "ZREVRANK": firstKey, "ZRANGE": firstKey,
+	"ZREVRANGE": firstKey, "ZRANGEBYSCORE": firstKey,
+	"ZREVRANGEBYSCORE": firstKey, "ZRANGEBYLEX": firstKey,
+	"ZREVRANGEBYLEX": firstKey, "ZLEXCOUNT": firstKey,
+	"ZREMRANGEBYRANK": firstKey, "ZREMRANGEBYSCORE": firstKey,
+	"ZREMRANGEBYLEX": firstKey, "ZPOPMIN": firstKey, "ZPOPMAX": firstKey,
+	"ZRANDMEMBER": firstKey, "ZSCAN": firstKey,
+	"HSET": firstKey, "HSETNX": firstKey, "HMSET": firstKey, "HGET": firstKey,
+	"HMGET": firstKey, "HDEL": firstKey, "HLEN": firstKey, "HEXISTS": firstKey,
+	"HGETALL": firstKey, "HKEYS": firstKey, "HVALS": firstKey,
+	"HINCRBY": firstKey, "HINCRBYFLOAT": firstKey, "HSTRLEN": firstKey,
+	"HSCAN": firstKey, "HRANDFIELD": firstKey, "HEXPIRE": firstKey,
+	"HPEXPIRE": firstKey, "HEXPIREAT": firstKey, "HPEXPIREAT": firstKey,
+	"HEXPIRETIME": firstKey, "HPEXPIRETIME": firstKey, "HTTL": firstKey,
+	"HPTTL": firstKey, "HPERSIST": firstKey,
+	"GEOADD": firstKey, "GEODIST": firstKey, "GEOHASH": firstKey,
+	"GEOPOS": firstKey, "GEOSEARCH": firstKey, "GEORADIUS_RO": firstKey,
+	"GEORADIUSBYMEMBER_RO": firstKey, "SORT_RO": {find: sortKeys},
+	"XADD": firstKey, "XLEN": firstKey, "XRANGE": firstKey,
+	"XREVRANGE": firstKey, "XDEL": firstKey, "XTRIM": firstKey,
+	"XACK": firstKey, "XPENDING": firstKey, "XCLAIM": firstKey,
+	"XAUTOCLAIM": firstKey, "XSETID": firstKey,
+
+	"DEL": allKeys, "UNLINK": allKeys, "EXISTS": allKeys, "TOUCH": allKeys,
+	"MGET": allKeys, "WATCH": allKeys, "SINTER": allKeys, "SUNION": allKeys,
+	"SDIFF": allKeys, "SINTERSTORE": allKeys, "SUNIONSTORE": allKeys,
+	"SDIFFSTORE": allKeys, "PFCOUNT": allKeys, "PFMERGE": allKeys,
+
+	"MSET": keyValues, "MSETNX": keyValues,
+
+	"RENAME": twoKeys, "RENAMENX": twoKeys, "SMOVE": twoKeys,
+	"RPOPLPUSH": twoKeys, "LMOVE": twoKeys, "COPY": twoKeys,
+	"BRPOPLPUSH": twoKeys, "BLMOVE": twoKeys, "ZRANGESTORE": twoKeys,
+	"GEOSEARCHSTORE": twoKeys, "LCS": twoKeys,
+
+	"BLPOP": blocking, "BRPOP": blocking, "BZPOPMIN": blocking,
+	"BZPOPMAX": blocking,
+
+	"ZUNIONSTORE": storeKeys, "ZINTERSTORE": storeKeys, "ZDIFFSTORE": storeKeys,
+
+	"EVAL": scriptKeys, "EVALSHA": scriptKeys, "EVAL_RO": scriptKeys,
+	"EVALSHA_RO": scriptKeys, "FCALL": scriptKeys, "FCALL_RO": scriptKeys,
+	"ZUNION": {numKeys: 1}, "ZINTER": {numKeys: 1}, "ZDIFF": {numKeys: 1},
+	"SINTERCARD": {numKeys: 1}, "ZINTERCARD": {numKeys: 1},
+	"LMPOP": {numKeys: 1}, "ZMPOP": {numKeys: 1},
+	"BLMPOP": {numKeys: 2}, "BZMPOP": {numKeys: 2},
+
+	"OBJECT": subKey, "XINFO": subKey, "XGROUP": subKey,
+	"MEMORY":            {find: memoryKeys},
+	"BITOP":             {first: 2, last: -1, step: 1},
+	"SORT":              {find: sortKeys},
+	"GEORADIUS":         {find: georadiusKeys(5)},
+	"GEORADIUSBYMEMBER": {find: georadiusKeys(4)},
+	"XREAD":             {find: streamKeys(1)},
+	"XREADGROUP":        {find: streamKeys(4)},
+	"MIGRATE":           {find: migrateKeys},
+}
+
+// subcommandKey returns the key following the subcommand, for the
+// subcommands taking one, the others such as HELP having none.
+func subcommandKey(args []string) []string {
+	if len(args) < 3 {
+		return nil
+	}
+	return args[2:3]
+}
+
+// memoryKeys returns the key of MEMORY USAGE key.
+func memoryKeys(args []string) []string {
+	if len(args) < 3 || !strings.EqualFold(args[1], "USAGE") {
+		return nil
+	}
+	return args[2:3]
+}
+
+// optionKeys returns the arguments following one of the options, such as
+// the destination of a STORE option, among args.
+func optionKeys(args []string, options ...string) []string {
+	var found []string
+	for i := 0; i+1 < len(args); i++ {
+		for _, option := range options {
+			if strings.EqualFold(args[i], option) {
+				found = append(found, args[i+1])
+				i++
+				break
+			}
+		}
+	}
+	return found
+}
+
+// sortKeys returns the key of SORT and the destination of its STORE option.
+// The BY and GET patterns are not keys, SortPatterns returns them.
+func sortKeys(args []string) []string {
+	keys := args[1:2:2]
+	for i := 2; i+1 < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "BY", "GET":
+			i++
+		case "LIMIT":
+			i += 2
+		case "STORE":
+			keys = append(keys, args[i+1])
+			i++
+		}
+	}
+	return keys
+}
+
+// SortPatterns returns the patterns of the BY and GET options of a SORT or
+// SORT_RO command, which read the keys they match, "nosort" and "#" not
+// reading any.
+func SortPatterns(args []string) []string {
+	var patterns []string
+	for i := 2; i+1 < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "BY", "GET":
+			if args[i+1] != "#" && !strings.EqualFold(args[i+1], "nosort") {
+				patterns = append(patterns, args[i+1])
+			}
+			i++
+		case "LIMIT":
+			i += 2
+		case "STORE":
+			i++
+		}
+	}
+	return patterns
+}
+
+// georadiusKeys returns the keys of GEORADIUS and GEORADIUSBYMEMBER: the
+// first argument and the destinations of the STORE and STOREDIST options,
+// which follow n arguments.
+func georadiusKeys(n int) func(args []string) []string {
+	return func(args []string) []string {
+		keys := args[1:2:2]
+		if len(args) <= n {
+			return keys
+		}
+		return append(keys, optionKeys(args[n+1:], "STORE", "STOREDIST")...)
+	}
+}
+
+// streamKeys returns the keys of XREAD and XREADGROUP: the first half of
+// the arguments following STREAMS, the options starting at position first.
+func streamKeys(first int) func(args []string) []string {
+	return func(args []string) []string {
+		for i := first; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "COUNT", "BLOCK", "CLAIM":
+				i++
+			case "STREAMS":
+				streams := args[i+1:]
+				return streams[:len(streams)/2]
+			}
+		}
+		return nil
+	}
+}
+
+// migrateKeys returns the key of MIGRATE, empty when several keys follow
+// the KEYS option.
+func migrateKeys(args []string) []string {
+	var found []string
+	if len(args) > 3 && args[3] != "" {
+		found = append(found, args[3])
+	}
+	for i := 6; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "AUTH":
+			i++
+		case "AUTH2":
+			i += 2
+		case "KEYS":
+			return append(found, args[i+1:]...)
+		}
+	}
+	return found
+}
+
+// CommandKeys returns the keys used by a command, given as its name followed
+// by its arguments, using a table of the redis commands. The first argument
+// is taken as the key of the commands which are not known.
+func CommandKeys(args []string) []string {
+	keys, ok := KnownCommandKeys(args)
+	if !ok && len(args) > 1 {
+		return args[1:2]
+	}
+	return keys
+}
+
+// KnownCommandKeys is CommandKeys for the commands of the table only: it
+// returns false for the commands which are not known, whose keys can not be
+// told.
+func KnownCommandKeys(args []string) ([]string, bool) {
+	if len(args) == 0 {
+		return nil, false
+	}
+	spec, ok := keySpecs[strings.ToUpper(args[0])]
+	if !ok {
+		return nil, false
+	}
+	if spec.find != nil {
+		if len(args) < 2 {
+			return nil, true
+		}
+		return spec.find(args), true
+	}
+	var found []string
+	if spec.first > 0 {
+		last := spec.last
+		if last < 0 {
+			last += len(args)
+		}
+		if last >= len(args) {
+			last = len(args) - 1
+		}
+		for i := spec.first; i <= last; i += spec.step {
+			found = append(found, args[i])
+		}
+	}
+	if spec.numKeys > 0 && spec.numKeys < len(args) {
+		n, err := strconv.Atoi(args[spec.numKeys])
+		if err != nil || n < 0 {
+			return found, true
+		}
+		for i := spec.numKeys + 1; i <= spec.numKeys+n && i < len(args); i++ {
+			found = append(found, args[i])
+		}
+	}
+	return found, true
+}
@@ -0,0 +1,78 @@
+package resp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommandKeys(t *testing.T) {
+	cases := []struct {
+		cmd, keys string
+	}{
+		{"GET a", "a"},
+		{"set a 1 EX 10", "a"},
+		{"PING", ""},
+		{"KEYS *", ""},
+		{"DEL a b c", "a b c"},
+		{"MSET a 1 b 2", "a b"},
+		{"RENAME a b", "a b"},
+		{"BLPOP a b 0", "a b"},
+		{"ZUNIONSTORE d 2 a b WEIGHTS 1 2", "d a b"},
+		{"EVAL script 2 a b arg", "a b"},
+		{"EVALSHA sha 3 a", "a"},
+		{"LMPOP 2 a b LEFT", "a b"},
+		{"BZMPOP 0 1 a MIN", "a"},
+		{"EVAL script x a", ""},
+		{"MEMORY USAGE a SAMPLES 5", "a"},
+		{"MEMORY STATS", ""},
+		{"OBJECT ENCODING a", "a"},
+		{"LCS a b IDX", "a b"},
+		{"BITOP AND d a b", "d a b"},
+		{"SORT a BY w_* LIMIT 0 10 GET store STORE d", "a d"},
+		{"SORT_RO a ALPHA", "a"},
+		{"GEORADIUS a 15 37 200 km STORE d STOREDIST e", "a d e"},
+		{"GEORADIUSBYMEMBER a m 200 km COUNT 5 STORE d", "a d"},
+		{"GEOSEARCHSTORE d a FROMMEMBER m BYRADIUS 1 km", "d a"},
+		{"XREAD COUNT 2 BLOCK 0 STREAMS a b 0 0", "a b"},
+		{"XREADGROUP GROUP g c COUNT 1 NOACK STREAMS a >", "a"},
+		{"MIGRATE host 6379 a 0 5000 COPY AUTH pw", "a"},
+		{"UNKNOWN a b", "a"},
+		{"get", ""},
+	}
+	for _, c := range cases {
+		got := strings.Join(CommandKeys(strings.Fields(c.cmd)), " ")
+		if got != c.keys {
+			t.Errorf("error keys of %q: %q", c.cmd, got)
+		}
+	}
+}
+
+func TestKnownCommandKeys(t *testing.T) {
+	if keys, ok := KnownCommandKeys([]string{"UNKNOWN", "a"}); ok || keys != nil {
+		t.Errorf("error keys of an unknown command: %q %v", keys, ok)
+	}
+	if keys, ok := KnownCommandKeys([]string{"sort"}); !ok || keys != nil {
+		t.Errorf("error keys of a command without arguments: %q %v", keys, ok)
+	}
+	args := []string{"MIGRATE", "host", "6379", "", "0", "5000", "AUTH2", "u", "pw", "KEYS", "a", "b"}
+	if keys, _ := KnownCommandKeys(args); strings.Join(keys, " ") != "a b" {
+		t.Errorf("error keys of MIGRATE: %q", keys)
+	}
+}
+
+func TestSortPatterns(t *testing.T) {
+	cases := []struct {
+		cmd, patterns string
+	}{
+		{"SORT a", ""},
+		{"SORT a BY nosort GET # STORE d", ""},
+		{"SORT a BY w_* GET o_*->f", "w_* o_*->f"},
+		{"sort_ro a limit 0 1 get by", "by"},
+	}
+	for _, c := range cases {
+		got := strings.Join(SortPatterns(strings.Fields(c.cmd)), " ")
+		if got != c.patterns {
+			t.Errorf("error patterns of %q: %q", c.cmd, got)
+		}
+	}
+}
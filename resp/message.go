@@ -1,5 +1,9 @@
 package resp
 
+import (
+	"strconv"
+)
+
 const (
 	// StringHeader is the header used to prefix simple strings (or status
 	// messages). String messages are not binary safe.
@@ -14,6 +18,39 @@ const (
 	ArrayHeader = '*'
 )
 
+// RESP3 headers, sent by servers once a connection switched to the version 3
+// of the protocol with HELLO 3.
+const (
+	// MapHeader is the header used to prefix a map, decoded as an array of
+	// keys and values alternated.
+	MapHeader = '%'
+	// SetHeader is the header used to prefix a set, decoded as an array.
+	SetHeader = '~'
+	// PushHeader is the header used to prefix out of band data, such as the
+	// messages of a subscription, decoded as an array.
+	PushHeader = '>'
+	// AttributeHeader is the header used to prefix auxiliary data preceding
+	// a reply. Attributes are skipped by the decoder.
+	AttributeHeader = '|'
+	// NullHeader is the header of the RESP3 null.
+	NullHeader = '_'
+	// BooleanHeader is the header used to prefix a boolean, decoded with
+	// Integer set to 1 for true and 0 for false.
+	BooleanHeader = '#'
+	// DoubleHeader is the header used to prefix a floating point number,
+	// decoded with its text, such as "3.14" or "inf", in Status.
+	DoubleHeader = ','
+	// BigNumberHeader is the header used to prefix an integer which may not
+	// fit in 64 bits, decoded with its text in Status.
+	BigNumberHeader = '('
+	// VerbatimHeader is the header used to prefix a verbatim string, decoded
+	// with its content, without the format, in Bytes.
+	VerbatimHeader = '='
+	// BlobErrorHeader is the header used to prefix a binary safe error,
+	// decoded as a message of type ErrorHeader.
+	BlobErrorHeader = '!'
+)
+
 // Message is a representation of a RESP message.
 type Message struct {
 	Error   error
@@ -72,8 +109,17 @@ func (m Message) Interface() interface{} {
 		return m.Bytes
 	case StringHeader:
 		return m.Status
-	case ArrayHeader:
+	case ArrayHeader, MapHeader, SetHeader, PushHeader:
 		return m.Array
+	case BooleanHeader:
+		return m.Integer != 0
+	case DoubleHeader:
+		f, _ := strconv.ParseFloat(m.Status, 64)
+		return f
+	case BigNumberHeader:
+		return m.Status
+	case VerbatimHeader:
+		return m.Bytes
 	}
 	return nil
 }
@@ -0,0 +1,145 @@
+package resp
+
+import (
+	"bytes"
+	"expvar"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Metrics receives the counters and timings of readers, clients and
+// servers. Implementations must be safe for concurrent use.
+type Metrics interface {
+	// BytesEncoded counts the bytes written.
+	BytesEncoded(n int)
+	// BytesDecoded counts the bytes consumed by decoded messages.
+	BytesDecoded(n int)
+	// MessageDecoded counts a decoded message by its type.
+	MessageDecoded(typ byte)
+	// CommandDuration records the latency of a command, from the time it
+	// is flushed to the time its reply is read by a client, or the time
+	// spent handling it by a server.
+	CommandDuration(name string, d time.Duration)
+	// SegmentRetry counts the times a message was incomplete, the decoder
+	// returning ErrCrlfNotFound or ErrBulkendNotFound, and more data had to
+	// be read.
+	SegmentRetry()
+}
+
+// latencyBounds are the upper bounds of the latency histogram buckets.
+var latencyBounds = []time.Duration{
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// latencyHistogram is an expvar.Var counting latencies in cumulative
+// buckets.
+type latencyHistogram struct {
+	mu      sync.Mutex
+	count   int64
+	total   time.Duration
+	buckets []int64
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{buckets: make([]int64, len(latencyBounds))}
+}
+
+func (h *latencyHistogram) observe(d time.Duration) {
+	h.mu.Lock()
+	h.count++
+	h.total += d
+	for i, bound := range latencyBounds {
+		if d <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.mu.Unlock()
+}
+
+// String returns the histogram as JSON, as required by expvar.Var.
+func (h *latencyHistogram) String() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var buf bytes.Buffer
+	buf.WriteString(`{"count":`)
+	buf.WriteString(strconv.FormatInt(h.count, 10))
+	buf.WriteString(`,"total_us":`)
+	buf.WriteString(strconv.FormatInt(int64(h.total/time.Microsecond), 10))
+	for i, bound := range latencyBounds {
+		buf.WriteString(`,"le_`)
+		buf.WriteString(bound.String())
+		buf.WriteString(`":`)
+		buf.WriteString(strconv.FormatInt(h.buckets[i], 10))
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// ExpvarMetrics implements Metrics publishing the counters with expvar, as
+// a map holding bytes_encoded, bytes_decoded, segment_retries, messages per
+// type and latency histograms per command.
+type ExpvarMetrics struct {
+	bytesEncoded   expvar.Int
+	bytesDecoded   expvar.Int
+	segmentRetries expvar.Int
+	messages       expvar.Map
+	commands       expvar.Map
+	mu             sync.Mutex
+}
+
+// NewExpvarMetrics creates an ExpvarMetrics published under name. Like
+// expvar.Publish, it panics if the name is already used.
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	m := newExpvarMetrics()
+	root := expvar.NewMap(name)
+	root.Set("bytes_encoded", &m.bytesEncoded)
+	root.Set("bytes_decoded", &m.bytesDecoded)
+	root.Set("segment_retries", &m.segmentRetries)
+	root.Set("messages", &m.messages)
+	root.Set("commands", &m.commands)
+	return m
+}
+
+func newExpvarMetrics() *ExpvarMetrics {
+	m := &ExpvarMetrics{}
+	m.messages.Init()
+	m.commands.Init()
+	return m
+}
+
+// BytesEncoded implements Metrics.
+func (m *ExpvarMetrics) BytesEncoded(n int) {
+	m.bytesEncoded.Add(int64(n))
+}
+
+// BytesDecoded implements Metrics.
+func (m *ExpvarMetrics) BytesDecoded(n int) {
+	m.bytesDecoded.Add(int64(n))
+}
+
+// MessageDecoded implements Metrics.
+func (m *ExpvarMetrics) MessageDecoded(typ byte) {
+	m.messages.Add(typeName(typ), 1)
+}
+
+// CommandDuration implements Metrics.
+func (m *ExpvarMetrics) CommandDuration(name string, d time.Duration) {
+	m.mu.Lock()
+	h, ok := m.commands.Get(name).(*latencyHistogram)
+	if !ok {
+		h = newLatencyHistogram()
+		m.commands.Set(name, h)
+	}
+	m.mu.Unlock()
+	h.observe(d)
+}
+
+// SegmentRetry implements Metrics.
+func (m *ExpvarMetrics) SegmentRetry() {
+	m.segmentRetries.Add(1)
+}
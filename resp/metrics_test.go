@@ -0,0 +1,105 @@
+package resp
+
+import (
+	"encoding/json"
+	"expvar"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestExpvarMetrics(t *testing.T) {
+	m := newExpvarMetrics()
+	m.BytesEncoded(10)
+	m.BytesEncoded(5)
+	m.MessageDecoded(BulkHeader)
+	m.MessageDecoded(BulkHeader)
+	m.MessageDecoded(ArrayHeader)
+	m.CommandDuration("GET", 50*time.Microsecond)
+	m.CommandDuration("GET", 5*time.Millisecond)
+	m.SegmentRetry()
+
+	if m.bytesEncoded.Value() != 15 || m.segmentRetries.Value() != 1 {
+		t.Error(errTestFailed)
+	}
+	if v := m.messages.Get("bulk"); v == nil || v.String() != "2" {
+		t.Errorf("error bulk messages: %v", v)
+	}
+	if v := m.messages.Get("array"); v == nil || v.String() != "1" {
+		t.Errorf("error array messages: %v", v)
+	}
+
+	var h map[string]int64
+	v := m.commands.Get("GET")
+	if v == nil {
+		t.Fatal(errTestFailed)
+	}
+	if err := json.Unmarshal([]byte(v.String()), &h); err != nil {
+		t.Fatal(err)
+	}
+	if h["count"] != 2 || h["total_us"] != 5050 || h["le_100µs"] != 1 ||
+		h["le_1ms"] != 1 || h["le_10ms"] != 2 || h["le_1s"] != 2 {
+		t.Errorf("error histogram: %s", v.String())
+	}
+}
+
+func TestNewExpvarMetrics(t *testing.T) {
+	m := NewExpvarMetrics("resp_test")
+	m.BytesDecoded(42)
+	v := expvar.Get("resp_test")
+	if v == nil {
+		t.Fatal(errTestFailed)
+	}
+	var root map[string]interface{}
+	if err := json.Unmarshal([]byte(v.String()), &root); err != nil {
+		t.Fatal(err)
+	}
+	if root["bytes_decoded"] != float64(42) {
+		t.Errorf("error published metrics: %s", v.String())
+	}
+}
+
+func TestReaderMetrics(t *testing.T) {
+	encoded := "+OK\r\n$5\r\nhello\r\n"
+	r := NewReader(iotest.OneByteReader(strings.NewReader(encoded)))
+	m := newExpvarMetrics()
+	r.SetMetrics(m)
+	for i := 0; i < 2; i++ {
+		if _, err := r.ReadMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if m.bytesDecoded.Value() != int64(len(encoded)) {
+		t.Errorf("error bytes decoded: %d", m.bytesDecoded.Value())
+	}
+	if v := m.messages.Get("status"); v == nil || v.String() != "1" {
+		t.Errorf("error status messages: %v", v)
+	}
+	// every byte but the last of each message is an incomplete segment
+	if m.segmentRetries.Value() != int64(len(encoded)-2) {
+		t.Errorf("error segment retries: %d", m.segmentRetries.Value())
+	}
+}
+
+func TestConnMetrics(t *testing.T) {
+	c := newTestConn(t, func(cmd *Message) *Message {
+		return Str("OK")
+	})
+	defer c.Close()
+	m := newExpvarMetrics()
+	c.SetMetrics(m)
+
+	if _, err := c.Do(Cmd("set", "a", "1")); err != nil {
+		t.Fatal(err)
+	}
+	if m.bytesEncoded.Value() != int64(len("*3\r\n$3\r\nset\r\n$1\r\na\r\n$1\r\n1\r\n")) {
+		t.Errorf("error bytes encoded: %d", m.bytesEncoded.Value())
+	}
+	if m.bytesDecoded.Value() != int64(len("+OK\r\n")) {
+		t.Errorf("error bytes decoded: %d", m.bytesDecoded.Value())
+	}
+	if h, ok := m.commands.Get("SET").(*latencyHistogram); !ok || h.count != 1 {
+		t.Error(errTestFailed)
+	}
+}
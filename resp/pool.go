@@ -0,0 +1,70 @@
+package resp
+
+import (
+	"context"
+	"sync"
+)
+
+// Pool keeps idle connections for reuse. Connections which are broken, or
+// still waiting for replies, are closed instead of being kept.
+type Pool struct {
+	// Dial creates a new connection when no idle connection is available
+	Dial func(ctx context.Context) (*Conn, error)
+	// MaxIdle is the maximum number of idle connections kept, 0 meaning
+	// none
+	MaxIdle int
+
+	mu     sync.Mutex
+	idle   []*Conn
+	closed bool
+}
+
+// Get returns an idle connection or dials a new one.
+func (p *Pool) Get(ctx context.Context) (*Conn, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrPoolClosed
+	}
+	if n := len(p.idle); n > 0 {
+		c := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return c, nil
+	}
+	p.mu.Unlock()
+	return p.Dial(ctx)
+}
+
+// Put returns a connection obtained with Get to the pool.
+func (p *Pool) Put(c *Conn) {
+	p.mu.Lock()
+	if p.closed || c.Err() != nil || c.Pending() != 0 || len(p.idle) >= p.MaxIdle {
+		p.mu.Unlock()
+		c.Close()
+		return
+	}
+	p.idle = append(p.idle, c)
+	p.mu.Unlock()
+}
+
+// Idle returns the number of idle connections.
+func (p *Pool) Idle() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.idle)
+}
+
+// Close closes the idle connections, connections put back afterwards are
+// closed as well.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.mu.Unlock()
+	for _, c := range idle {
+		c.Close()
+	}
+	return nil
+}
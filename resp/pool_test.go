@@ -0,0 +1,66 @@
+package resp
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func newTestPool(t *testing.T, maxIdle int, dialed *int) *Pool {
+	return &Pool{
+		MaxIdle: maxIdle,
+		Dial: func(ctx context.Context) (*Conn, error) {
+			*dialed++
+			return newTestConn(t, func(cmd *Message) *Message {
+				return Str("PONG")
+			}), nil
+		},
+	}
+}
+
+func TestPoolReuse(t *testing.T) {
+	dialed := 0
+	p := newTestPool(t, 1, &dialed)
+	defer p.Close()
+
+	c, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Do(Cmd("PING")); err != nil {
+		t.Fatal(err)
+	}
+	p.Put(c)
+	if p.Idle() != 1 {
+		t.Errorf("connection should be idle")
+	}
+	if c2, _ := p.Get(context.Background()); c2 != c || dialed != 1 {
+		t.Errorf("idle connection should be reused")
+	}
+}
+
+func TestPoolDiscard(t *testing.T) {
+	dialed := 0
+	p := newTestPool(t, 2, &dialed)
+
+	// connection waiting for a reply
+	c, _ := p.Get(context.Background())
+	c.Send(Cmd("PING"))
+	p.Put(c)
+
+	// broken connection
+	client, server := net.Pipe()
+	server.Close()
+	c = NewConn(client)
+	c.Do(Cmd("PING"))
+	p.Put(c)
+
+	if p.Idle() != 0 {
+		t.Errorf("connections should be discarded, %d idle", p.Idle())
+	}
+
+	p.Close()
+	if _, err := p.Get(context.Background()); err != ErrPoolClosed {
+		t.Errorf("should return ErrPoolClosed, not: %v", err)
+	}
+}
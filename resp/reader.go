@@ -0,0 +1,161 @@
+package resp
+
+import (
+	"context"
+	"io"
+	"net"
+)
+
+const readerBufSize = 4096
+
+// Reader decodes messages from a stream, such as a network connection. It
+// buffers the data read until a whole message is available, as found by a
+// Framer, so that a message arriving in many reads is decoded once.
+type Reader struct {
+	r         io.Reader
+	buf       []byte
+	framer    Framer
+	msgQ      []*Message
+	err       error
+	decodeErr bool
+	metrics   Metrics
+}
+
+// NewReader creates a Reader decoding messages read from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+// ReadMessage returns the next message of the stream. Messages share memory
+// with the read buffer, which is never written again, so they remain valid
+// after later reads.
+//
+// Read errors end the stream, except timeouts: the data read so far is kept
+// and ReadMessage can be called again.
+func (r *Reader) ReadMessage() (*Message, error) {
+	return r.ReadMessageContext(context.Background())
+}
+
+// ReadMessageContext is ReadMessage bounded by ctx. If the underlying reader
+// has a SetReadDeadline method, as net.Conn does, the context deadline and
+// cancellation interrupt the read. The data read so far is kept, so reading
+// can resume with another call. A read deadline set on the underlying reader
+// is overwritten, and cleared once the read is done.
+func (r *Reader) ReadMessageContext(ctx context.Context) (*Message, error) {
+	for len(r.msgQ) == 0 {
+		if r.err != nil {
+			return nil, r.err
+		}
+		if len(r.buf) > 0 {
+			r.decode()
+			if len(r.msgQ) > 0 || r.err != nil {
+				continue
+			}
+		}
+		err := withContext(ctx, readDeadline(r.r), r.fill)
+		if err == nil {
+			continue
+		}
+		if err == ctx.Err() || isTimeout(err) {
+			return nil, err
+		}
+		if err == io.EOF && len(r.buf) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		r.err = err
+	}
+	msg := r.msgQ[0]
+	r.msgQ = r.msgQ[1:]
+	return msg, nil
+}
+
+// decode decodes the whole messages buffered. When the data is not valid,
+// the whole buffer is decoded for Decode to report the error.
+func (r *Reader) decode() {
+	end, ok := r.framer.Scan(r.buf)
+	if ok && end == 0 {
+		if r.metrics != nil {
+			r.metrics.SegmentRetry()
+		}
+		return
+	}
+	data := r.buf[:end]
+	if !ok {
+		data = r.buf
+	}
+	msgQ, pos, err := Decode(data)
+	r.msgQ = msgQ
+	if r.metrics != nil {
+		r.record(msgQ, pos, err)
+	}
+	r.buf = r.buf[pos:]
+	if ok {
+		r.framer.Discard(pos)
+	} else {
+		r.framer.Reset()
+	}
+	if err != nil && !MaybeSegmentError(err) {
+		r.err = err
+		r.decodeErr = true
+	}
+}
+
+// SetMetrics sets the Metrics receiving the decoded bytes, messages and
+// segment retries.
+func (r *Reader) SetMetrics(m Metrics) {
+	r.metrics = m
+}
+
+func (r *Reader) record(msgQ []*Message, pos int, err error) {
+	if err != nil && !MaybeSegmentError(err) {
+		return
+	}
+	r.metrics.BytesDecoded(pos)
+	for _, msg := range msgQ {
+		r.metrics.MessageDecoded(msg.Type)
+	}
+	if len(msgQ) == 0 && err != nil {
+		r.metrics.SegmentRetry()
+	}
+}
+
+// isDecodeError reports whether err, returned by ReadMessage, is due to data
+// which can not be decoded rather than to the underlying reader.
+func (r *Reader) isDecodeError(err error) bool {
+	return r.decodeErr && err == r.err
+}
+
+// Buffered returns the number of decoded messages and bytes waiting to be
+// returned by ReadMessage.
+func (r *Reader) Buffered() (messages, bytes int) {
+	return len(r.msgQ), len(r.buf)
+}
+
+// fill reads more data at the end of the buffer. Decoded messages point into
+// the buffer, so its beginning is never overwritten: a new buffer is
+// allocated when more room is needed.
+func (r *Reader) fill() error {
+	if cap(r.buf)-len(r.buf) < readerBufSize/2 {
+		size := len(r.buf) * 2
+		if size < readerBufSize {
+			size = readerBufSize
+		}
+		buf := make([]byte, len(r.buf), size)
+		copy(buf, r.buf)
+		r.buf = buf
+	}
+	n, err := r.r.Read(r.buf[len(r.buf):cap(r.buf)])
+	r.buf = r.buf[:len(r.buf)+n]
+	if n > 0 {
+		return nil
+	}
+	if err == nil {
+		err = io.ErrNoProgress
+	}
+	return err
+}
+
+func isTimeout(err error) bool {
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
@@ -0,0 +1,92 @@
+package resp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestReaderSegmented(t *testing.T) {
+	encoded := "+OK\r\n*2\r\n$3\r\nGET\r\n$5\r\nhello\r\n:42\r\n$9\r\nhello\r\ngo\r\n"
+	r := NewReader(iotest.OneByteReader(bytes.NewReader([]byte(encoded))))
+
+	var msgQ []*Message
+	for {
+		msg, err := r.ReadMessage()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		msgQ = append(msgQ, msg)
+	}
+	if len(msgQ) != 4 {
+		t.Fatalf("should contains four messages, not %d", len(msgQ))
+	}
+	// messages read first must not be overwritten by later reads
+	if msgQ[0].Status != "OK" || string(msgQ[1].Array[1].Bytes) != "hello" ||
+		msgQ[2].Integer != 42 || string(msgQ[3].Bytes) != "hello\r\ngo" {
+		t.Errorf("error messages: %v", msgQ)
+	}
+}
+
+func TestReaderLargeMessage(t *testing.T) {
+	large := bytes.Repeat([]byte("x"), readerBufSize*3)
+	encoded, _ := Marshal(large)
+	encoded = append(encoded, ":1\r\n"...)
+	r := NewReader(bytes.NewReader(encoded))
+
+	msg, err := r.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(msg.Bytes, large) {
+		t.Error("error bulk result")
+	}
+	if msg, err = r.ReadMessage(); err != nil {
+		t.Fatal(err)
+	} else if msg.Integer != 1 {
+		t.Error("error integer result")
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte("$5\r\nhel")))
+	if _, err := r.ReadMessage(); err != io.ErrUnexpectedEOF {
+		t.Errorf("should return io.ErrUnexpectedEOF, not: %v", err)
+	}
+
+	r = NewReader(bytes.NewReader([]byte(":1\r\n@oops\r\n")))
+	if msg, err := r.ReadMessage(); err != nil || msg.Integer != 1 {
+		t.Errorf("error first message: %v %v", msg, err)
+	}
+	if _, err := r.ReadMessage(); err != ErrInvalidHeader {
+		t.Errorf("should return ErrInvalidHeader, not: %v", err)
+	}
+}
+
+func TestReaderContextResume(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	r := NewReader(client)
+
+	go server.Write([]byte("$5\r\nhel"))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := r.ReadMessageContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("should return context.DeadlineExceeded, not: %v", err)
+	}
+
+	// the partial data is kept, reading resumes once the rest arrives
+	go server.Write([]byte("lo\r\n"))
+	msg, err := r.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	} else if string(msg.Bytes) != "hello" {
+		t.Errorf("error bulk result: %q", msg.Bytes)
+	}
+}
@@ -0,0 +1,213 @@
+package resp
+
+import (
+	"strconv"
+)
+
+// ScoredMember is a sorted set member along with its score, as returned by
+// ZRANGE ... WITHSCORES.
+type ScoredMember struct {
+	Member string
+	Score  float64
+}
+
+// check returns the error to report before converting the message: ErrNil
+// for nil replies and the error itself for error replies.
+func (m *Message) check() error {
+	if m == nil || m.IsNil {
+		return ErrNil
+	}
+	if m.Type == ErrorHeader {
+		if m.Error == nil {
+			return &ConversionError{Type: ErrorHeader, Target: "error"}
+		}
+		return m.Error
+	}
+	return nil
+}
+
+// text returns the content of a bulk or status message, and of the RESP3
+// doubles, big numbers and verbatim strings.
+func (m *Message) text() (string, bool) {
+	switch m.Type {
+	case BulkHeader, VerbatimHeader:
+		return string(m.Bytes), true
+	case StringHeader, DoubleHeader, BigNumberHeader:
+		return m.Status, true
+	}
+	return "", false
+}
+
+// Str returns the content of a bulk or status message.
+func (m *Message) Str() (string, error) {
+	if err := m.check(); err != nil {
+		return "", err
+	}
+	if s, ok := m.text(); ok {
+		return s, nil
+	}
+	return "", &ConversionError{Type: m.Type, Target: "string"}
+}
+
+// Int64 returns the value of an integer message, or parses a bulk or status
+// message as a decimal integer.
+func (m *Message) Int64() (int64, error) {
+	if err := m.check(); err != nil {
+		return 0, err
+	}
+	if m.Type == IntegerHeader {
+		return m.Integer, nil
+	}
+	s, ok := m.text()
+	if !ok {
+		return 0, &ConversionError{Type: m.Type, Target: "int64"}
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, &ConversionError{Type: m.Type, Target: "int64", Value: s}
+	}
+	return i, nil
+}
+
+// Float64 parses a bulk or status message as a floating point number, as
+// returned by INCRBYFLOAT or ZSCORE. Integer messages are converted.
+func (m *Message) Float64() (float64, error) {
+	if err := m.check(); err != nil {
+		return 0, err
+	}
+	if m.Type == IntegerHeader {
+		return float64(m.Integer), nil
+	}
+	s, ok := m.text()
+	if !ok {
+		return 0, &ConversionError{Type: m.Type, Target: "float64"}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, &ConversionError{Type: m.Type, Target: "float64", Value: s}
+	}
+	return f, nil
+}
+
+// Bool returns true for non zero integer messages and RESP3 true booleans,
+// bulk and status messages being parsed with strconv.ParseBool.
+func (m *Message) Bool() (bool, error) {
+	if err := m.check(); err != nil {
+		return false, err
+	}
+	if m.Type == IntegerHeader || m.Type == BooleanHeader {
+		return m.Integer != 0, nil
+	}
+	s, ok := m.text()
+	if !ok {
+		return false, &ConversionError{Type: m.Type, Target: "bool"}
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, &ConversionError{Type: m.Type, Target: "bool", Value: s}
+	}
+	return b, nil
+}
+
+// array returns the elements of an array message.
+func (m *Message) array(target string) ([]*Message, error) {
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	switch m.Type {
+	case ArrayHeader, SetHeader, PushHeader, MapHeader:
+		return m.Array, nil
+	}
+	return nil, &ConversionError{Type: m.Type, Target: target}
+}
+
+// Strings converts an array of bulk or status messages, nil elements being
+// converted to empty strings.
+func (m *Message) Strings() ([]string, error) {
+	a, err := m.array("[]string")
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, len(a))
+	for i, elem := range a {
+		if elem == nil || elem.IsNil {
+			continue
+		}
+		if res[i], err = elem.Str(); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// Int64s converts an array of integers, nil elements being converted to 0.
+func (m *Message) Int64s() ([]int64, error) {
+	a, err := m.array("[]int64")
+	if err != nil {
+		return nil, err
+	}
+	res := make([]int64, len(a))
+	for i, elem := range a {
+		if elem == nil || elem.IsNil {
+			continue
+		}
+		if res[i], err = elem.Int64(); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// StringMap converts a flat array of alternated keys and values, as returned
+// by HGETALL or CONFIG GET, to a map.
+func (m *Message) StringMap() (map[string]string, error) {
+	a, err := m.Strings()
+	if err != nil {
+		if _, ok := err.(*ConversionError); ok {
+			return nil, &ConversionError{Type: m.Type, Target: "map[string]string"}
+		}
+		return nil, err
+	}
+	if len(a)%2 != 0 {
+		return nil, &ConversionError{Type: m.Type, Target: "map[string]string"}
+	}
+	res := make(map[string]string, len(a)/2)
+	for i := 0; i < len(a); i += 2 {
+		res[a[i]] = a[i+1]
+	}
+	return res, nil
+}
+
+// ScoredMembers converts the reply of a sorted set command called with
+// WITHSCORES. Both the flat [member, score, ...] form and the nested
+// [[member, score], ...] form are accepted.
+func (m *Message) ScoredMembers() ([]ScoredMember, error) {
+	a, err := m.array("[]ScoredMember")
+	if err != nil {
+		return nil, err
+	}
+	var pairs []*Message
+	if len(a) > 0 && a[0] != nil && a[0].Type == ArrayHeader {
+		for _, elem := range a {
+			if elem == nil || elem.Type != ArrayHeader || len(elem.Array) != 2 {
+				return nil, &ConversionError{Type: m.Type, Target: "[]ScoredMember"}
+			}
+			pairs = append(pairs, elem.Array...)
+		}
+	} else {
+		pairs = a
+	}
+	if len(pairs)%2 != 0 {
+		return nil, &ConversionError{Type: m.Type, Target: "[]ScoredMember"}
+	}
+	res := make([]ScoredMember, len(pairs)/2)
+	for i := range res {
+		if res[i].Member, err = pairs[i*2].Str(); err != nil {
+			return nil, err
+		}
+		if res[i].Score, err = pairs[i*2+1].Float64(); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
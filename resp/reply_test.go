@@ -0,0 +1,149 @@
+package resp
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestReplyScalars(t *testing.T) {
+	if s, err := Bulk([]byte("foo")).Str(); err != nil || s != "foo" {
+		t.Errorf("error string conversion: %v %v", s, err)
+	}
+	if s, err := Str("OK").Str(); err != nil || s != "OK" {
+		t.Errorf("error string conversion: %v %v", s, err)
+	}
+	if i, err := Int(12).Int64(); err != nil || i != 12 {
+		t.Errorf("error int64 conversion: %v %v", i, err)
+	}
+	if i, err := Bulk([]byte("-5")).Int64(); err != nil || i != -5 {
+		t.Errorf("error int64 conversion: %v %v", i, err)
+	}
+	if f, err := Bulk([]byte("3.5")).Float64(); err != nil || f != 3.5 {
+		t.Errorf("error float64 conversion: %v %v", f, err)
+	}
+	if f, err := Bulk([]byte("-inf")).Float64(); err != nil || !math.IsInf(f, -1) {
+		t.Errorf("error float64 conversion: %v %v", f, err)
+	}
+	if b, err := Int(1).Bool(); err != nil || !b {
+		t.Errorf("error bool conversion: %v %v", b, err)
+	}
+	if b, err := Bulk([]byte("0")).Bool(); err != nil || b {
+		t.Errorf("error bool conversion: %v %v", b, err)
+	}
+}
+
+func TestReplyErrors(t *testing.T) {
+	if _, err := Nil().Str(); err != ErrNil {
+		t.Errorf("should return ErrNil, not: %v", err)
+	}
+	if _, err := NilArray().Strings(); err != ErrNil {
+		t.Errorf("should return ErrNil, not: %v", err)
+	}
+
+	redisErr := errors.New("WRONGTYPE Operation against a key")
+	if _, err := Err(redisErr).Int64(); err != redisErr {
+		t.Errorf("should return the error reply, not: %v", err)
+	}
+
+	_, err := Int(1).Str()
+	if ce, ok := err.(*ConversionError); !ok || ce.Type != IntegerHeader || ce.Target != "string" {
+		t.Errorf("should return a conversion error, not: %v", err)
+	} else if err.Error() != "cannot convert integer to string" {
+		t.Errorf("error conversion error message: %v", err)
+	}
+
+	_, err = Bulk([]byte("abc")).Int64()
+	if ce, ok := err.(*ConversionError); !ok || ce.Value != "abc" {
+		t.Errorf("should return a conversion error, not: %v", err)
+	} else if err.Error() != `cannot convert bulk "abc" to int64` {
+		t.Errorf("error conversion error message: %v", err)
+	}
+
+	if _, err = Bulk([]byte("a")).Strings(); err == nil {
+		t.Error(errErrorExpected)
+	}
+}
+
+func TestReplyArrays(t *testing.T) {
+	msg, err := decodeToMsg([]byte("*3\r\n$1\r\na\r\n+b\r\n$-1\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	strs, err := msg.Strings()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(strs) != 3 || strs[0] != "a" || strs[1] != "b" || strs[2] != "" {
+		t.Errorf("error strings conversion: %q", strs)
+	}
+
+	ints, err := Array(Int(1), Bulk([]byte("2")), Nil()).Int64s()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(ints) != 3 || ints[0] != 1 || ints[1] != 2 || ints[2] != 0 {
+		t.Errorf("error int64s conversion: %v", ints)
+	}
+
+	msg, err = decodeToMsg([]byte("*4\r\n$4\r\nname\r\n$3\r\nfoo\r\n$3\r\nage\r\n$2\r\n10\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := msg.StringMap()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(m) != 2 || m["name"] != "foo" || m["age"] != "10" {
+		t.Errorf("error string map conversion: %v", m)
+	}
+	if _, err = Array(Bulk([]byte("a"))).StringMap(); err == nil {
+		t.Error(errErrorExpected)
+	}
+}
+
+func TestReplyScoredMembers(t *testing.T) {
+	msg, err := decodeToMsg([]byte("*4\r\n$1\r\na\r\n$1\r\n1\r\n$1\r\nb\r\n$3\r\n2.5\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	members, err := msg.ScoredMembers()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(members) != 2 || members[0] != (ScoredMember{"a", 1}) || members[1] != (ScoredMember{"b", 2.5}) {
+		t.Errorf("error scored members conversion: %v", members)
+	}
+
+	nested := Array(Array(Bulk([]byte("a")), Bulk([]byte("1"))))
+	if members, err = nested.ScoredMembers(); err != nil {
+		t.Fatal(err)
+	} else if len(members) != 1 || members[0] != (ScoredMember{"a", 1}) {
+		t.Errorf("error scored members conversion: %v", members)
+	}
+
+	if _, err = Array(Bulk([]byte("a"))).ScoredMembers(); err == nil {
+		t.Error(errErrorExpected)
+	}
+}
+
+func TestReplyResp3(t *testing.T) {
+	msgQ, _, err := Decode([]byte("#t\r\n,2.5\r\n(123\r\n=7\r\ntxt:abc\r\n~2\r\n$1\r\na\r\n+b\r\n%1\r\n$1\r\nk\r\n$1\r\nv\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := msgQ[0].Bool(); err != nil || !b {
+		t.Errorf("error boolean: %v %v", b, err)
+	}
+	if f, err := msgQ[1].Float64(); err != nil || f != 2.5 {
+		t.Errorf("error double: %v %v", f, err)
+	}
+	if i, err := msgQ[2].Int64(); err != nil || i != 123 {
+		t.Errorf("error big number: %v %v", i, err)
+	}
+	if s, err := msgQ[3].Str(); err != nil || s != "abc" {
+		t.Errorf("error verbatim string: %v %v", s, err)
+	}
+	if a, err := msgQ[4].Strings(); err != nil || len(a) != 2 || a[1] != "b" {
+		t.Errorf("error set: %v %v", a, err)
+	}
+	if m, err := msgQ[5].StringMap(); err != nil || m["k"] != "v" {
+		t.Errorf("error map: %v %v", m, err)
+	}
+}
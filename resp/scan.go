@@ -0,0 +1,184 @@
+package resp
+
+import (
+	"context"
+)
+
+// ScanOptions are the options of the SCAN family of commands.
+type ScanOptions struct {
+	// Match only returns elements matching the glob-style pattern
+	Match string
+	// Count hints the number of elements returned by each call
+	Count int
+	// Type only returns keys of the given type, for SCAN only
+	Type string
+}
+
+// ScanIterator iterates over the elements returned by SCAN, SSCAN, HSCAN or
+// ZSCAN, issuing a new command each time the current page is exhausted until
+// the server returns the cursor 0. Elements returned more than once by the
+// server are only reported once, which requires remembering every element
+// seen so far.
+//
+//	it := conn.Scan(resp.ScanOptions{Match: "user:*"})
+//	for it.Next(ctx) {
+//		fmt.Println(it.Val())
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type ScanIterator struct {
+	conn  *Conn
+	cmd   string
+	key   string
+	opts  ScanOptions
+	pairs bool
+
+	cursor string
+	done   bool
+	page   []*Message
+	seen   map[string]struct{}
+	val    string
+	value  string
+	err    error
+}
+
+// Scan iterates over the keys of the current database.
+func (c *Conn) Scan(opts ScanOptions) *ScanIterator {
+	return newScanIterator(c, "SCAN", "", opts, false)
+}
+
+// SScan iterates over the members of a set.
+func (c *Conn) SScan(key string, opts ScanOptions) *ScanIterator {
+	return newScanIterator(c, "SSCAN", key, opts, false)
+}
+
+// HScan iterates over the fields of a hash, Value returning the value of
+// each field.
+func (c *Conn) HScan(key string, opts ScanOptions) *ScanIterator {
+	return newScanIterator(c, "HSCAN", key, opts, true)
+}
+
+// ZScan iterates over the members of a sorted set, Value returning the score
+// of each member.
+func (c *Conn) ZScan(key string, opts ScanOptions) *ScanIterator {
+	return newScanIterator(c, "ZSCAN", key, opts, true)
+}
+
+func newScanIterator(c *Conn, cmd, key string, opts ScanOptions, pairs bool) *ScanIterator {
+	return &ScanIterator{
+		conn:   c,
+		cmd:    cmd,
+		key:    key,
+		opts:   opts,
+		pairs:  pairs,
+		cursor: "0",
+		seen:   make(map[string]struct{}),
+	}
+}
+
+// Next advances to the next element, fetching a new page if needed. It
+// returns false once the iteration is over or an error occurred.
+func (it *ScanIterator) Next(ctx context.Context) bool {
+	for it.err == nil {
+		for len(it.page) > 0 {
+			elem := it.page[0]
+			var value *Message
+			if it.pairs {
+				value = it.page[1]
+				it.page = it.page[2:]
+			} else {
+				it.page = it.page[1:]
+			}
+			val, err := elem.Str()
+			if err != nil {
+				it.err = err
+				return false
+			}
+			if _, ok := it.seen[val]; ok {
+				continue
+			}
+			it.seen[val] = struct{}{}
+			it.val, it.value = val, ""
+			if value != nil {
+				if it.value, err = value.Str(); err != nil {
+					it.err = err
+					return false
+				}
+			}
+			return true
+		}
+		if it.done {
+			return false
+		}
+		if err := ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+		it.fetch(ctx)
+	}
+	return false
+}
+
+// fetch issues the command for the current cursor and stores the page of
+// elements returned.
+func (it *ScanIterator) fetch(ctx context.Context) {
+	cmd := Cmd(it.cmd)
+	if it.key != "" {
+		cmd.Arg(it.key)
+	}
+	cmd.Arg(it.cursor)
+	if it.opts.Match != "" {
+		cmd.Arg("MATCH", it.opts.Match)
+	}
+	if it.opts.Count > 0 {
+		cmd.Arg("COUNT", it.opts.Count)
+	}
+	if it.opts.Type != "" {
+		cmd.Arg("TYPE", it.opts.Type)
+	}
+
+	reply, err := it.conn.DoContext(ctx, cmd)
+	if err != nil {
+		it.err = err
+		return
+	}
+	if reply.Type == ErrorHeader {
+		it.err = reply.Error
+		return
+	}
+	// the reply is [cursor, [elements...]]
+	if reply.Type != ArrayHeader || reply.IsNil || len(reply.Array) != 2 ||
+		reply.Array[1] == nil || reply.Array[1].Type != ArrayHeader {
+		it.err = &ConversionError{Type: reply.Type, Target: "scan reply"}
+		return
+	}
+	if it.cursor, err = reply.Array[0].Str(); err != nil {
+		it.err = err
+		return
+	}
+	it.page = reply.Array[1].Array
+	if it.pairs && len(it.page)%2 != 0 {
+		it.err = &ConversionError{Type: ArrayHeader, Target: "scan reply"}
+		return
+	}
+	it.done = it.cursor == "0"
+}
+
+// Val returns the current element: a key for SCAN, a member for SSCAN and
+// ZSCAN or a field for HSCAN.
+func (it *ScanIterator) Val() string {
+	return it.val
+}
+
+// Value returns the value paired with the current element: the value of the
+// field for HSCAN and the score of the member for ZSCAN. It is empty for
+// SCAN and SSCAN.
+func (it *ScanIterator) Value() string {
+	return it.value
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *ScanIterator) Err() error {
+	return it.err
+}
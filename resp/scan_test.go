@@ -0,0 +1,109 @@
+package resp
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func bulks(elems ...string) []*Message {
+	a := make([]*Message, len(elems))
+	for i, e := range elems {
+		a[i] = Bulk([]byte(e))
+	}
+	return a
+}
+
+func TestScan(t *testing.T) {
+	var cmds []string
+	c := newTestConn(t, func(cmd *Message) *Message {
+		cmds = append(cmds, commandString(cmd))
+		switch cmd.Array[1].Bytes[0] {
+		case '0':
+			return Array(Bulk([]byte("17")), Array(bulks("a", "b")...))
+		case '1':
+			return Array(Bulk([]byte("9")), Array())
+		default:
+			// "b" is returned again, as SCAN may do during a rehash
+			return Array(Bulk([]byte("0")), Array(bulks("b", "c")...))
+		}
+	})
+	defer c.Close()
+
+	it := c.Scan(ScanOptions{Match: "*", Count: 10, Type: "string"})
+	var keys []string
+	for it.Next(context.Background()) {
+		keys = append(keys, it.Val())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 || keys[0] != "a" || keys[1] != "b" || keys[2] != "c" {
+		t.Errorf("error scanned keys: %q", keys)
+	}
+	if len(cmds) != 3 || cmds[0] != "SCAN 0 MATCH * COUNT 10 TYPE string" || cmds[2] != "SCAN 9 MATCH * COUNT 10 TYPE string" {
+		t.Errorf("error scan commands: %q", cmds)
+	}
+}
+
+func TestHScanZScan(t *testing.T) {
+	var cmds []string
+	c := newTestConn(t, func(cmd *Message) *Message {
+		cmds = append(cmds, commandString(cmd))
+		return Array(Bulk([]byte("0")), Array(bulks("f1", "v1", "f2", "v2")...))
+	})
+	defer c.Close()
+
+	it := c.HScan("h", ScanOptions{})
+	var pairs []string
+	for it.Next(context.Background()) {
+		pairs = append(pairs, it.Val()+"="+it.Value())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) != 2 || pairs[0] != "f1=v1" || pairs[1] != "f2=v2" {
+		t.Errorf("error scanned pairs: %q", pairs)
+	}
+
+	it = c.ZScan("z", ScanOptions{Match: "f*"})
+	for it.Next(context.Background()) {
+	}
+	it = c.SScan("s", ScanOptions{})
+	for it.Next(context.Background()) {
+	}
+	if len(cmds) != 3 || cmds[0] != "HSCAN h 0" || cmds[1] != "ZSCAN z 0 MATCH f*" || cmds[2] != "SSCAN s 0" {
+		t.Errorf("error scan commands: %q", cmds)
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	replyErr := errors.New("WRONGTYPE Operation against a key")
+	c := newTestConn(t, func(cmd *Message) *Message {
+		if string(cmd.Array[0].Bytes) == "SSCAN" {
+			return Err(replyErr)
+		}
+		return Array(Bulk([]byte("0")))
+	})
+	defer c.Close()
+
+	it := c.SScan("s", ScanOptions{})
+	if it.Next(context.Background()) || it.Err().Error() != replyErr.Error() {
+		t.Errorf("should return the error reply, not: %v", it.Err())
+	}
+
+	it = c.Scan(ScanOptions{})
+	if _, ok := it.Err().(*ConversionError); it.Next(context.Background()) || ok {
+		t.Error("iteration should not start")
+	}
+	if _, ok := it.Err().(*ConversionError); !ok {
+		t.Errorf("should return a conversion error, not: %v", it.Err())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	it = c.Scan(ScanOptions{})
+	if it.Next(ctx) || it.Err() != context.Canceled {
+		t.Errorf("should return context.Canceled, not: %v", it.Err())
+	}
+}
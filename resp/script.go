@@ -0,0 +1,182 @@
+package resp
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+)
+
+// ScriptFallback selects what Script.Do does when the server replies to
+// EVALSHA with a NOSCRIPT error.
+type ScriptFallback int
+
+const (
+	// FallbackEval sends the whole script with EVAL, which also caches it
+	// on the server.
+	FallbackEval ScriptFallback = iota
+	// FallbackLoad loads the script with SCRIPT LOAD and retries EVALSHA.
+	FallbackLoad
+)
+
+// Script is a Lua script run with EVALSHA, sending its source only when the
+// server does not have it cached yet.
+type Script struct {
+	src  string
+	hash string
+	// Fallback is used when the server does not know the script
+	Fallback ScriptFallback
+}
+
+// NewScript creates a Script from its Lua source.
+func NewScript(src string) *Script {
+	h := sha1.Sum([]byte(src))
+	return &Script{
+		src:  src,
+		hash: hex.EncodeToString(h[:]),
+	}
+}
+
+// Hash returns the SHA1 digest of the script, as used by EVALSHA.
+func (s *Script) Hash() string {
+	return s.hash
+}
+
+// Load loads the script into the server script cache.
+func (s *Script) Load(c *Conn) error {
+	return s.LoadContext(context.Background(), c)
+}
+
+// LoadContext is Load bounded by ctx.
+func (s *Script) LoadContext(ctx context.Context, c *Conn) error {
+	reply, err := c.DoContext(ctx, Cmd("SCRIPT", "LOAD", s.src))
+	if err != nil {
+		return err
+	}
+	if reply.Type == ErrorHeader {
+		return reply.Error
+	}
+	return nil
+}
+
+// Do runs the script with EVALSHA and falls back according to Fallback if
+// the server does not know it. Error replies are returned as messages.
+func (s *Script) Do(c *Conn, keys []string, args ...interface{}) (*Message, error) {
+	return s.DoContext(context.Background(), c, keys, args...)
+}
+
+// DoContext is Do bounded by ctx, which also bounds the fallback.
+func (s *Script) DoContext(ctx context.Context, c *Conn, keys []string, args ...interface{}) (*Message, error) {
+	reply, err := c.DoContext(ctx, s.cmd("EVALSHA", s.hash, keys, args))
+	if err != nil || !hasErrorPrefix(reply, "NOSCRIPT") {
+		return reply, err
+	}
+	if s.Fallback == FallbackLoad {
+		if err = s.LoadContext(ctx, c); err != nil {
+			return nil, err
+		}
+		return c.DoContext(ctx, s.cmd("EVALSHA", s.hash, keys, args))
+	}
+	return c.DoContext(ctx, s.cmd("EVAL", s.src, keys, args))
+}
+
+func (s *Script) cmd(name, script string, keys []string, args []interface{}) *Message {
+	cmd := Cmd(name, script, len(keys))
+	for _, key := range keys {
+		cmd.Arg(key)
+	}
+	return cmd.Arg(args...)
+}
+
+// Library is a Redis 7 function library, whose functions are called with
+// FCALL.
+type Library struct {
+	name string
+	code string
+}
+
+// NewLibrary creates a Library from its code, which must start with a
+// `#!lua name=<library>` line.
+func NewLibrary(code string) (*Library, error) {
+	line := code
+	if i := strings.IndexByte(code, '\n'); i >= 0 {
+		line = code[:i]
+	}
+	if !strings.HasPrefix(line, "#!") {
+		return nil, ErrLibraryName
+	}
+	for _, field := range strings.Fields(line)[1:] {
+		if strings.HasPrefix(field, "name=") && len(field) > len("name=") {
+			return &Library{name: field[len("name="):], code: code}, nil
+		}
+	}
+	return nil, ErrLibraryName
+}
+
+// Name returns the name of the library.
+func (l *Library) Name() string {
+	return l.name
+}
+
+// Load loads the library with FUNCTION LOAD REPLACE.
+func (l *Library) Load(c *Conn) error {
+	return l.LoadContext(context.Background(), c)
+}
+
+// LoadContext is Load bounded by ctx.
+func (l *Library) LoadContext(ctx context.Context, c *Conn) error {
+	reply, err := c.DoContext(ctx, Cmd("FUNCTION", "LOAD", "REPLACE", l.code))
+	if err != nil {
+		return err
+	}
+	if reply.Type == ErrorHeader {
+		return reply.Error
+	}
+	return nil
+}
+
+// Call runs a function of the library with FCALL, loading the library and
+// retrying if the server does not know the function.
+func (l *Library) Call(c *Conn, function string, keys []string, args ...interface{}) (*Message, error) {
+	return l.call(context.Background(), c, "FCALL", function, keys, args)
+}
+
+// CallContext is Call bounded by ctx, which also bounds the loading of the
+// library.
+func (l *Library) CallContext(ctx context.Context, c *Conn, function string, keys []string, args ...interface{}) (*Message, error) {
+	return l.call(ctx, c, "FCALL", function, keys, args)
+}
+
+// CallRO runs a read-only function of the library with FCALL_RO.
+func (l *Library) CallRO(c *Conn, function string, keys []string, args ...interface{}) (*Message, error) {
+	return l.call(context.Background(), c, "FCALL_RO", function, keys, args)
+}
+
+// CallROContext is CallRO bounded by ctx.
+func (l *Library) CallROContext(ctx context.Context, c *Conn, function string, keys []string, args ...interface{}) (*Message, error) {
+	return l.call(ctx, c, "FCALL_RO", function, keys, args)
+}
+
+func (l *Library) call(ctx context.Context, c *Conn, name, function string, keys []string, args []interface{}) (*Message, error) {
+	cmd := Cmd(name, function, len(keys))
+	for _, key := range keys {
+		cmd.Arg(key)
+	}
+	cmd.Arg(args...)
+
+	reply, err := c.DoContext(ctx, cmd)
+	if err != nil || !hasErrorPrefix(reply, "ERR Function not found") {
+		return reply, err
+	}
+	if err = l.LoadContext(ctx, c); err != nil {
+		return nil, err
+	}
+	return c.DoContext(ctx, cmd)
+}
+
+// hasErrorPrefix reports whether the message is an error reply starting
+// with prefix.
+func hasErrorPrefix(m *Message, prefix string) bool {
+	return m != nil && m.Type == ErrorHeader && m.Error != nil &&
+		strings.HasPrefix(m.Error.Error(), prefix)
+}
@@ -0,0 +1,142 @@
+package resp
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// scriptServer emulates the script cache and function registry of a server.
+func scriptServer(t *testing.T, cmds *[]string) *Conn {
+	scripts := make(map[string]bool)
+	functions := make(map[string]bool)
+	return newTestConn(t, func(cmd *Message) *Message {
+		args, _ := cmd.Strings()
+		*cmds = append(*cmds, args[0])
+		switch args[0] {
+		case "EVALSHA":
+			if !scripts[args[1]] {
+				return Err(errors.New("NOSCRIPT No matching script. Please use EVAL."))
+			}
+			return Array(bulks(args[3:]...)...)
+		case "EVAL":
+			scripts[NewScript(args[1]).Hash()] = true
+			return Array(bulks(args[3:]...)...)
+		case "SCRIPT":
+			scripts[NewScript(args[2]).Hash()] = true
+			return Bulk([]byte(NewScript(args[2]).Hash()))
+		case "FUNCTION":
+			functions["myfunc"] = true
+			return Str("mylib")
+		case "FCALL", "FCALL_RO":
+			if !functions[args[1]] {
+				return Err(errors.New("ERR Function not found"))
+			}
+			return Array(bulks(args[3:]...)...)
+		}
+		return Err(errors.New("ERR unknown command"))
+	})
+}
+
+func TestScriptHash(t *testing.T) {
+	s := NewScript("return 1")
+	if s.Hash() != "e0e1f9fabfc9d4800c877a703b823ac0578ff8db" {
+		t.Errorf("error script hash: %s", s.Hash())
+	}
+}
+
+func TestScriptEvalFallback(t *testing.T) {
+	var cmds []string
+	c := scriptServer(t, &cmds)
+	defer c.Close()
+
+	s := NewScript("return {KEYS[1], ARGV[1]}")
+	reply, err := s.Do(c, []string{"key"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args, _ := reply.Strings(); len(args) != 2 || args[0] != "key" || args[1] != "1" {
+		t.Errorf("error script reply: %v", reply)
+	}
+	if _, err = s.Do(c, []string{"key"}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(cmds) != 3 || cmds[0] != "EVALSHA" || cmds[1] != "EVAL" || cmds[2] != "EVALSHA" {
+		t.Errorf("error commands: %q", cmds)
+	}
+}
+
+func TestScriptLoadFallback(t *testing.T) {
+	var cmds []string
+	c := scriptServer(t, &cmds)
+	defer c.Close()
+
+	s := NewScript("return ARGV[1]")
+	s.Fallback = FallbackLoad
+	if _, err := s.Do(c, nil, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if len(cmds) != 3 || cmds[0] != "EVALSHA" || cmds[1] != "SCRIPT" || cmds[2] != "EVALSHA" {
+		t.Errorf("error commands: %q", cmds)
+	}
+}
+
+func TestLibrary(t *testing.T) {
+	var cmds []string
+	c := scriptServer(t, &cmds)
+	defer c.Close()
+
+	l, err := NewLibrary("#!lua name=mylib\nredis.register_function('myfunc', function(keys, args) return args[1] end)")
+	if err != nil {
+		t.Fatal(err)
+	} else if l.Name() != "mylib" {
+		t.Errorf("error library name: %s", l.Name())
+	}
+
+	reply, err := l.Call(c, "myfunc", []string{"k"}, "v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args, _ := reply.Strings(); len(args) != 2 || args[1] != "v" {
+		t.Errorf("error function reply: %v", reply)
+	}
+	if _, err = l.CallRO(c, "myfunc", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(cmds) != 4 || cmds[0] != "FCALL" || cmds[1] != "FUNCTION" || cmds[2] != "FCALL" || cmds[3] != "FCALL_RO" {
+		t.Errorf("error commands: %q", cmds)
+	}
+
+	if _, err = NewLibrary("return 1"); err != ErrLibraryName {
+		t.Errorf("should return ErrLibraryName, not: %v", err)
+	}
+}
+
+func TestScriptContext(t *testing.T) {
+	var cmds []string
+	c := scriptServer(t, &cmds)
+	defer c.Close()
+
+	s := NewScript("return 1")
+	l, _ := NewLibrary("#!lua name=mylib")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.DoContext(ctx, c, nil); err != context.Canceled {
+		t.Errorf("should return context.Canceled, not: %v", err)
+	}
+	if err := s.LoadContext(ctx, c); err != context.Canceled {
+		t.Errorf("should return context.Canceled, not: %v", err)
+	}
+	if err := l.LoadContext(ctx, c); err != context.Canceled {
+		t.Errorf("should return context.Canceled, not: %v", err)
+	}
+	if _, err := l.CallContext(ctx, c, "myfunc", nil); err != context.Canceled {
+		t.Errorf("should return context.Canceled, not: %v", err)
+	}
+	if _, err := l.CallROContext(ctx, c, "myfunc", nil); err != context.Canceled {
+		t.Errorf("should return context.Canceled, not: %v", err)
+	}
+	if len(cmds) != 0 {
+		t.Errorf("no command should be sent: %q", cmds)
+	}
+}
@@ -0,0 +1,296 @@
+package resp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Handler handles the commands received by a Server, writing their replies
+// to the connection.
+type Handler interface {
+	ServeRESP(c *ServerConn, cmd *Message)
+}
+
+// HandlerFunc adapts a function to the Handler interface.
+type HandlerFunc func(c *ServerConn, cmd *Message)
+
+// ServeRESP calls f(c, cmd).
+func (f HandlerFunc) ServeRESP(c *ServerConn, cmd *Message) {
+	f(c, cmd)
+}
+
+// Server serves RESP connections, reading commands and calling the handler
+// for each of them. The commands of a connection are handled one at a time,
+// in order, the replies written while handling a batch of pipelined commands
+// being flushed at once.
+type Server struct {
+	// Handler handles the commands
+	Handler Handler
+	// Hooks observe the commands handled. An error returned by BeforeProcess
+	// is written as the reply instead of calling the handler.
+	Hooks []Hook
+	// Metrics receives the bytes written and read, the commands decoded and
+	// the time spent handling them, if not nil
+	Metrics Metrics
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[*ServerConn]struct{}
+	closed    bool
+}
+
+// Serve accepts connections on l and serves each of them in a new goroutine.
+// It returns ErrServerClosed once the server is closed.
+func (s *Server) Serve(l net.Listener) error {
+	if !s.track(l, true) {
+		return ErrServerClosed
+	}
+	defer s.track(l, false)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			if isTimeout(err) {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		go s.ServeConn(conn)
+	}
+}
+
+// ServeConn serves a single connection until it is closed by the client,
+// the handler or the server, and closes it. Decode errors are replied with
+// a protocol error before closing the connection.
+func (s *Server) ServeConn(conn net.Conn) error {
+	c := newServerConn(conn, s.Metrics)
+	defer c.Close()
+	if !s.trackConn(c, true) {
+		return ErrServerClosed
+	}
+	defer s.trackConn(c, false)
+
+	hooks := hookList(s.Hooks)
+	for {
+		cmd, err := c.r.ReadMessage()
+		if err != nil {
+			if c.r.isDecodeError(err) {
+				hooks.decodeError(err)
+				c.WriteMessage(Err(errors.New("ERR Protocol error: " + err.Error())))
+				c.Flush()
+				return err
+			}
+			if err == io.EOF || c.isClosed() {
+				return nil
+			}
+			return err
+		}
+		// the commands already read are handled as a pipeline
+		batch := []*Message{cmd}
+		for n, _ := c.r.Buffered(); n > 0; n-- {
+			cmd, _ := c.r.ReadMessage()
+			batch = append(batch, cmd)
+		}
+		if len(batch) > 1 {
+			hooks.pipeline(c.ctx, batch)
+		}
+		for _, cmd := range batch {
+			s.handle(c, hooks, cmd)
+		}
+		if err := c.Flush(); err != nil {
+			if c.isClosed() {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(c *ServerConn, hooks hookList, cmd *Message) {
+	ctx, err := hooks.before(c.ctx, cmd)
+	if err != nil {
+		reply := Err(err)
+		c.WriteMessage(reply)
+		hooks.after(ctx, cmd, reply, err)
+		return
+	}
+
+	c.mu.Lock()
+	c.handling, c.reply = true, nil
+	c.mu.Unlock()
+	start := time.Now()
+	s.Handler.ServeRESP(c, cmd)
+	d := time.Since(start)
+	c.mu.Lock()
+	reply := c.reply
+	c.handling, c.reply = false, nil
+	c.mu.Unlock()
+
+	if s.Metrics != nil {
+		s.Metrics.CommandDuration(commandName(cmd), d)
+	}
+	hooks.after(ctx, cmd, reply, nil)
+}
+
+// Close closes the listeners and the connections of the server.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	var err error
+	for l := range s.listeners {
+		if cerr := l.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	conns := make([]*ServerConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+	for _, c := range conns {
+		c.Close()
+	}
+	return err
+}
+
+func (s *Server) track(l net.Listener, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !add {
+		delete(s.listeners, l)
+		return true
+	}
+	if s.closed {
+		return false
+	}
+	if s.listeners == nil {
+		s.listeners = make(map[net.Listener]struct{})
+	}
+	s.listeners[l] = struct{}{}
+	return true
+}
+
+func (s *Server) trackConn(c *ServerConn, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !add {
+		delete(s.conns, c)
+		return true
+	}
+	if s.closed {
+		return false
+	}
+	if s.conns == nil {
+		s.conns = make(map[*ServerConn]struct{})
+	}
+	s.conns[c] = struct{}{}
+	return true
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// ServerConn is a connection served by a Server. Its methods are safe for
+// concurrent use, so that messages can be pushed to the client outside of
+// the handler.
+type ServerConn struct {
+	conn    net.Conn
+	r       *Reader
+	ctx     context.Context
+	cancel  context.CancelFunc
+	metrics Metrics
+
+	mu       sync.Mutex
+	wbuf     bytes.Buffer
+	enc      *Encoder
+	handling bool
+	reply    *Message
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func newServerConn(conn net.Conn, m Metrics) *ServerConn {
+	c := &ServerConn{
+		conn:    conn,
+		r:       NewReader(conn),
+		metrics: m,
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.enc = NewEncoder(&c.wbuf)
+	if m != nil {
+		c.r.SetMetrics(m)
+	}
+	return c
+}
+
+// WriteMessage buffers a message, written to the client once the commands
+// read so far are handled or when Flush is called. The first message written
+// while handling a command is reported as its reply to the hooks. A message
+// which fails to encode is not written at all.
+func (c *ServerConn) WriteMessage(m *Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.isClosed() {
+		return ErrConnClosed
+	}
+	n := c.wbuf.Len()
+	if err := c.enc.Encode(m); err != nil {
+		// drop the elements of the message already encoded
+		c.wbuf.Truncate(n)
+		return err
+	}
+	if c.handling && c.reply == nil {
+		c.reply = m
+	}
+	return nil
+}
+
+// Flush writes the buffered messages to the client.
+func (c *ServerConn) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.isClosed() {
+		return ErrConnClosed
+	}
+	n, err := c.wbuf.WriteTo(c.conn)
+	if c.metrics != nil {
+		c.metrics.BytesEncoded(int(n))
+	}
+	return err
+}
+
+// Context returns the context of the connection, passed to the hooks and
+// canceled once the connection is closed.
+func (c *ServerConn) Context() context.Context {
+	return c.ctx
+}
+
+// RemoteAddr returns the address of the client.
+func (c *ServerConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// Close closes the connection, discarding the messages not flushed.
+func (c *ServerConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.cancel()
+		c.closeErr = c.conn.Close()
+	})
+	return c.closeErr
+}
+
+func (c *ServerConn) isClosed() bool {
+	return c.ctx.Err() != nil
+}
@@ -0,0 +1,172 @@
+package resp
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+var echoHandler = HandlerFunc(func(c *ServerConn, cmd *Message) {
+	switch strings.ToUpper(commandString(cmd)) {
+	case "PING":
+		c.WriteMessage(Str("PONG"))
+	case "QUIT":
+		c.WriteMessage(Str("OK"))
+		c.Flush()
+		c.Close()
+	default:
+		args, _ := cmd.Strings()
+		c.WriteMessage(Bulk([]byte(strings.Join(args[1:], " "))))
+	}
+})
+
+// newTestServer returns a client connected through a pipe to a connection
+// served by s, and a channel receiving the result of ServeConn.
+func newTestServer(t *testing.T, s *Server) (*Conn, <-chan error) {
+	client, server := net.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ServeConn(server)
+	}()
+	return NewConn(client), done
+}
+
+func TestServer(t *testing.T) {
+	s := &Server{Handler: echoHandler}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Serve(l)
+	}()
+
+	c, err := Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	reply, err := c.Do(Cmd("PING"))
+	if err != nil {
+		t.Fatal(err)
+	} else if reply.Status != "PONG" {
+		t.Errorf("error reply: %v", reply)
+	}
+	reply, err = c.Do(Cmd("ECHO", "hello", "world"))
+	if err != nil {
+		t.Fatal(err)
+	} else if string(reply.Bytes) != "hello world" {
+		t.Errorf("error reply: %v", reply)
+	}
+
+	s.Close()
+	select {
+	case err := <-done:
+		if err != ErrServerClosed {
+			t.Errorf("error serve error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Serve does not return once closed")
+	}
+	if _, err := c.Do(Cmd("PING")); err == nil {
+		t.Error(errErrorExpected)
+	}
+}
+
+func TestServerPipeline(t *testing.T) {
+	h := &recordHook{}
+	s := &Server{Handler: echoHandler, Hooks: []Hook{h}}
+	c, done := newTestServer(t, s)
+	defer c.Close()
+
+	c.Send(Cmd("ECHO", "a"))
+	c.Send(Cmd("ECHO", "b"))
+	c.Send(Cmd("QUIT"))
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	var replies []string
+	for {
+		reply, err := c.Receive()
+		if err != nil {
+			break
+		}
+		replies = append(replies, reply.String())
+	}
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+	if strings.Join(replies, ",") != `"a","b",OK` {
+		t.Errorf("error replies: %q", replies)
+	}
+	checkCalls(t, h.calls,
+		"pipeline ECHO a, ECHO b, QUIT",
+		"before ECHO a",
+		`after ECHO a "a"`,
+		"before ECHO b",
+		`after ECHO b "b"`,
+		"before QUIT",
+		"after QUIT OK",
+	)
+}
+
+func TestServerHookAbort(t *testing.T) {
+	h := &recordHook{err: errors.New("NOPERM denied")}
+	m := newExpvarMetrics()
+	s := &Server{Handler: echoHandler, Hooks: []Hook{h}, Metrics: m}
+	c, _ := newTestServer(t, s)
+	defer c.Close()
+
+	reply, err := c.Do(Cmd("PING"))
+	if err != nil {
+		t.Fatal(err)
+	} else if reply.Type != ErrorHeader || reply.Error.Error() != "NOPERM denied" {
+		t.Errorf("error reply: %v", reply)
+	}
+	if m.messages.Get("array") == nil || m.commands.Get("PING") != nil {
+		t.Error(errTestFailed)
+	}
+}
+
+func TestServerProtocolError(t *testing.T) {
+	h := &recordHook{}
+	s := &Server{Handler: echoHandler, Hooks: []Hook{h}}
+	c, done := newTestServer(t, s)
+	go c.rwc.Write([]byte("?bad\r\n"))
+
+	r := NewReader(c.rwc)
+	reply, err := r.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	} else if reply.Type != ErrorHeader || !strings.HasPrefix(reply.Error.Error(), "ERR Protocol error") {
+		t.Errorf("error reply: %v", reply)
+	}
+	if err := <-done; err == nil {
+		t.Error(errErrorExpected)
+	}
+	if len(h.calls) != 1 || !strings.HasPrefix(h.calls[0], "decode ") {
+		t.Errorf("error hook calls: %q", h.calls)
+	}
+}
+
+func TestServerWriteMessageError(t *testing.T) {
+	var writeErr error
+	s := &Server{Handler: HandlerFunc(func(c *ServerConn, cmd *Message) {
+		writeErr = c.WriteMessage(Array(Int(1), Array(Str("a"), &Message{Type: 'x'})))
+		c.WriteMessage(Str("OK"))
+	})}
+	c, _ := newTestServer(t, s)
+	defer c.Close()
+	reply, err := c.Do(Cmd("PING"))
+	if err != nil {
+		t.Fatal(err)
+	} else if reply.Type != StringHeader || reply.Status != "OK" {
+		t.Errorf("error reply after a failed write: %v", reply)
+	}
+	if writeErr == nil {
+		t.Error(errErrorExpected)
+	}
+}
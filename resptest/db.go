@@ -0,0 +1,238 @@
+package resptest
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// Types of the values, as returned by the TYPE command.
+const (
+	typeString = "string"
+	typeHash   = "hash"
+	typeList   = "list"
+	typeSet    = "set"
+	typeZSet   = "zset"
+)
+
+// value is the value of a key.
+type value struct {
+	kind   string
+	str    []byte
+	hash   map[string][]byte
+	list   [][]byte
+	set    map[string]struct{}
+	zset   map[string]float64
+	expire time.Time
+}
+
+func newValue(kind string) *value {
+	v := &value{kind: kind}
+	switch kind {
+	case typeHash:
+		v.hash = make(map[string][]byte)
+	case typeSet:
+		v.set = make(map[string]struct{})
+	case typeZSet:
+		v.zset = make(map[string]float64)
+	}
+	return v
+}
+
+// empty reports whether an aggregate value has no element left, in which
+// case its key is removed as redis does.
+func (v *value) empty() bool {
+	switch v.kind {
+	case typeHash:
+		return len(v.hash) == 0
+	case typeList:
+		return len(v.list) == 0
+	case typeSet:
+		return len(v.set) == 0
+	case typeZSet:
+		return len(v.zset) == 0
+	}
+	return false
+}
+
+type db struct {
+	keys map[string]*value
+}
+
+func newDB() *db {
+	return &db{keys: make(map[string]*value)}
+}
+
+// watchKey identifies a key watched by a client.
+type watchKey struct {
+	db  int
+	key string
+}
+
+// get returns the value of a key of the client database, removing it if it
+// has expired.
+func (s *Server) get(c *client, key []byte) *value {
+	return s.getDB(c.db, string(key))
+}
+
+func (s *Server) getDB(i int, key string) *value {
+	v, ok := s.dbs[i].keys[key]
+	if !ok {
+		return nil
+	}
+	if !v.expire.IsZero() && !s.clock().Before(v.expire) {
+		delete(s.dbs[i].keys, key)
+		s.touchDB(i, key)
+		return nil
+	}
+	return v
+}
+
+// lookup returns the value of a key which must be of the given kind, or a
+// WRONGTYPE error reply. The value is nil if the key does not exist.
+func (s *Server) lookup(c *client, key []byte, kind string) (*value, *resp.Message) {
+	v := s.get(c, key)
+	if v != nil && v.kind != kind {
+		return nil, replyError(errWrongType)
+	}
+	return v, nil
+}
+
+// lookupOrCreate is lookup creating an empty value when the key does not
+// exist.
+func (s *Server) lookupOrCreate(c *client, key []byte, kind string) (*value, *resp.Message) {
+	v, errReply := s.lookup(c, key, kind)
+	if errReply != nil || v != nil {
+		return v, errReply
+	}
+	v = newValue(kind)
+	s.dbs[c.db].keys[string(key)] = v
+	return v, nil
+}
+
+// set stores the value of a key, replacing the previous value and its
+// expiry.
+func (s *Server) set(c *client, key []byte, v *value) {
+	s.dbs[c.db].keys[string(key)] = v
+	s.touch(c, key)
+}
+
+// del removes a key, reporting whether it existed.
+func (s *Server) del(c *client, key []byte) bool {
+	if s.get(c, key) == nil {
+		return false
+	}
+	delete(s.dbs[c.db].keys, string(key))
+	s.touch(c, key)
+	return true
+}
+
+// modified must be called once an aggregate value was changed, to remove
+// its key once empty and to notify the clients watching it.
+func (s *Server) modified(c *client, key []byte, v *value) {
+	if v.empty() {
+		delete(s.dbs[c.db].keys, string(key))
+	}
+	s.touch(c, key)
+}
+
+func (s *Server) touch(c *client, key []byte) {
+	s.touchDB(c.db, string(key))
+}
+
+func (s *Server) touchDB(i int, key string) {
+	for w := range s.watchers[watchKey{i, key}] {
+		w.dirty = true
+	}
+}
+
+func (s *Server) flushDB(i int) {
+	for k, clients := range s.watchers {
+		if k.db != i {
+			continue
+		}
+		if _, ok := s.dbs[i].keys[k.key]; ok {
+			for w := range clients {
+				w.dirty = true
+			}
+		}
+	}
+	s.dbs[i] = newDB()
+}
+
+// keys returns the keys of a database which have not expired.
+func (s *Server) keys(i int) []string {
+	var keys []string
+	for key := range s.dbs[i].keys {
+		if s.getDB(i, key) != nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func parseInt(b []byte) (int64, bool) {
+	i, err := strconv.ParseInt(string(b), 10, 64)
+	return i, err == nil
+}
+
+// parseFloat parses a float as redis does, accepting inf and -inf but not
+// NaN.
+func parseFloat(b []byte) (float64, bool) {
+	switch strings.ToLower(string(b)) {
+	case "inf", "+inf", "infinity", "+infinity":
+		return math.Inf(1), true
+	case "-inf", "-infinity":
+		return math.Inf(-1), true
+	}
+	f, err := strconv.ParseFloat(string(b), 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
+func formatInt(i int64) []byte {
+	return []byte(strconv.FormatInt(i, 10))
+}
+
+// formatFloat formats a float with the shortest representation, as redis 7
+// does.
+func formatFloat(f float64) []byte {
+	switch {
+	case math.IsInf(f, 1):
+		return []byte("inf")
+	case math.IsInf(f, -1):
+		return []byte("-inf")
+	}
+	return []byte(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+// listIndex converts a possibly negative index of a sequence of length n to
+// a positive one.
+func listIndex(i int64, n int) int64 {
+	if i < 0 {
+		i += int64(n)
+	}
+	return i
+}
+
+// listRange converts the inclusive, possibly negative, start and stop
+// indexes of a sequence of length n to a slice range, empty when start is
+// after stop.
+func listRange(start, stop int64, n int) (int, int) {
+	start, stop = listIndex(start, n), listIndex(stop, n)
+	if start < 0 {
+		start = 0
+	}
+	if stop >= int64(n) {
+		stop = int64(n) - 1
+	}
+	if start > stop || start >= int64(n) {
+		return 0, 0
+	}
+	return int(start), int(stop) + 1
+}
@@ -0,0 +1,271 @@
+package resptest
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// Fields of hashes are returned in lexicographic order.
+var hashCommands = map[string]command{
+	"HSET":         {fn: (*Server).cmdHSet, arity: -4},
+	"HMSET":        {fn: (*Server).cmdHSet, arity: -4},
+	"HSETNX":       {fn: (*Server).cmdHSetNX, arity: 4},
+	"HGET":         {fn: (*Server).cmdHGet, arity: 3},
+	"HMGET":        {fn: (*Server).cmdHMGet, arity: -3},
+	"HGETALL":      {fn: (*Server).cmdHGetAll, arity: 2},
+	"HDEL":         {fn: (*Server).cmdHDel, arity: -3},
+	"HEXISTS":      {fn: (*Server).cmdHExists, arity: 3},
+	"HLEN":         {fn: (*Server).cmdHLen, arity: 2},
+	"HSTRLEN":      {fn: (*Server).cmdHStrlen, arity: 3},
+	"HKEYS":        {fn: (*Server).cmdHKeys, arity: 2},
+	"HVALS":        {fn: (*Server).cmdHKeys, arity: 2},
+	"HINCRBY":      {fn: (*Server).cmdHIncrBy, arity: 4},
+	"HINCRBYFLOAT": {fn: (*Server).cmdHIncrByFloat, arity: 4},
+	"HSCAN":        {fn: (*Server).cmdHScan, arity: -3},
+}
+
+func (s *Server) cmdHSet(c *client, args [][]byte) *resp.Message {
+	name := strings.ToLower(string(args[0]))
+	if len(args)%2 != 0 {
+		return replyError("ERR wrong number of arguments for '" + name + "' command")
+	}
+	v, errReply := s.lookupOrCreate(c, args[1], typeHash)
+	if errReply != nil {
+		return errReply
+	}
+	var added int64
+	for i := 2; i < len(args); i += 2 {
+		if _, ok := v.hash[string(args[i])]; !ok {
+			added++
+		}
+		v.hash[string(args[i])] = args[i+1]
+	}
+	s.modified(c, args[1], v)
+	if name == "hmset" {
+		return okReply()
+	}
+	return resp.Int(added)
+}
+
+func (s *Server) cmdHSetNX(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookupOrCreate(c, args[1], typeHash)
+	if errReply != nil {
+		return errReply
+	}
+	if _, ok := v.hash[string(args[2])]; ok {
+		return resp.Int(0)
+	}
+	v.hash[string(args[2])] = args[3]
+	s.modified(c, args[1], v)
+	return resp.Int(1)
+}
+
+func (s *Server) cmdHGet(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeHash)
+	if errReply != nil {
+		return errReply
+	}
+	if v == nil {
+		return resp.Nil()
+	}
+	b, ok := v.hash[string(args[2])]
+	if !ok {
+		return resp.Nil()
+	}
+	return resp.Bulk(b)
+}
+
+func (s *Server) cmdHMGet(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeHash)
+	if errReply != nil {
+		return errReply
+	}
+	values := make([][]byte, len(args)-2)
+	if v != nil {
+		for i, field := range args[2:] {
+			values[i] = v.hash[string(field)]
+		}
+	}
+	return bulks(values)
+}
+
+func (s *Server) cmdHGetAll(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeHash)
+	if errReply != nil {
+		return errReply
+	}
+	var items [][]byte
+	if v != nil {
+		for _, field := range sortedKeys(v.hash) {
+			items = append(items, []byte(field), v.hash[field])
+		}
+	}
+	return bulks(items)
+}
+
+func (s *Server) cmdHDel(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeHash)
+	if errReply != nil || v == nil {
+		return intOrError(0, errReply)
+	}
+	var n int64
+	for _, field := range args[2:] {
+		if _, ok := v.hash[string(field)]; ok {
+			delete(v.hash, string(field))
+			n++
+		}
+	}
+	if n > 0 {
+		s.modified(c, args[1], v)
+	}
+	return resp.Int(n)
+}
+
+func (s *Server) cmdHExists(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeHash)
+	if errReply != nil || v == nil {
+		return intOrError(0, errReply)
+	}
+	if _, ok := v.hash[string(args[2])]; ok {
+		return resp.Int(1)
+	}
+	return resp.Int(0)
+}
+
+func (s *Server) cmdHLen(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeHash)
+	if errReply != nil || v == nil {
+		return intOrError(0, errReply)
+	}
+	return resp.Int(int64(len(v.hash)))
+}
+
+func (s *Server) cmdHStrlen(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeHash)
+	if errReply != nil || v == nil {
+		return intOrError(0, errReply)
+	}
+	return resp.Int(int64(len(v.hash[string(args[2])])))
+}
+
+// cmdHKeys implements HKEYS and HVALS.
+func (s *Server) cmdHKeys(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeHash)
+	if errReply != nil {
+		return errReply
+	}
+	vals := strings.ToUpper(string(args[0])) == "HVALS"
+	var items [][]byte
+	if v != nil {
+		for _, field := range sortedKeys(v.hash) {
+			if vals {
+				items = append(items, v.hash[field])
+			} else {
+				items = append(items, []byte(field))
+			}
+		}
+	}
+	return bulks(items)
+}
+
+func (s *Server) cmdHIncrBy(c *client, args [][]byte) *resp.Message {
+	delta, ok := parseInt(args[3])
+	if !ok {
+		return replyError(errNotInteger)
+	}
+	v, errReply := s.lookupOrCreate(c, args[1], typeHash)
+	if errReply != nil {
+		return errReply
+	}
+	var n int64
+	if b, exists := v.hash[string(args[2])]; exists {
+		if n, ok = parseInt(b); !ok {
+			s.modified(c, args[1], v)
+			return replyError("ERR hash value is not an integer")
+		}
+	}
+	if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+		s.modified(c, args[1], v)
+		return replyError(errOverflow)
+	}
+	n += delta
+	v.hash[string(args[2])] = formatInt(n)
+	s.modified(c, args[1], v)
+	return resp.Int(n)
+}
+
+func (s *Server) cmdHIncrByFloat(c *client, args [][]byte) *resp.Message {
+	delta, ok := parseFloat(args[3])
+	if !ok {
+		return replyError(errNotFloat)
+	}
+	v, errReply := s.lookupOrCreate(c, args[1], typeHash)
+	if errReply != nil {
+		return errReply
+	}
+	var f float64
+	if b, exists := v.hash[string(args[2])]; exists {
+		if f, ok = parseFloat(b); !ok {
+			s.modified(c, args[1], v)
+			return replyError("ERR hash value is not a float")
+		}
+	}
+	f += delta
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		s.modified(c, args[1], v)
+		return replyError("ERR increment would produce NaN or Infinity")
+	}
+	b := []byte(strconv.FormatFloat(f, 'f', -1, 64))
+	v.hash[string(args[2])] = b
+	s.modified(c, args[1], v)
+	return resp.Bulk(b)
+}
+
+func (s *Server) cmdHScan(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeHash)
+	if errReply != nil {
+		return errReply
+	}
+	var fields []string
+	if v != nil {
+		fields = sortedKeys(v.hash)
+	}
+	return scanElements(args[2:], fields, func(field string) [][]byte {
+		return [][]byte{[]byte(field), v.hash[field]}
+	})
+}
+
+// scanElements implements the cursor and options of HSCAN, SSCAN and ZSCAN
+// over the sorted elements, items returning the items replied for each
+// element.
+func scanElements(args [][]byte, elements []string, items func(string) [][]byte) *resp.Message {
+	cursor, ok := parseInt(args[0])
+	if !ok || cursor < 0 {
+		return replyError("ERR invalid cursor")
+	}
+	opts, errReply := parseScanOptions(args[1:], false)
+	if errReply != nil {
+		return errReply
+	}
+	var page [][]byte
+	i := int(cursor)
+	for ; i < len(elements) && i < int(cursor)+opts.count; i++ {
+		if opts.match == "" || resp.MatchPattern(opts.match, elements[i]) {
+			page = append(page, items(elements[i])...)
+		}
+	}
+	if i >= len(elements) {
+		i = 0
+	}
+	return resp.Array(resp.Bulk(formatInt(int64(i))), bulks(page))
+}
+
+func intOrError(n int64, errReply *resp.Message) *resp.Message {
+	if errReply != nil {
+		return errReply
+	}
+	return resp.Int(n)
+}
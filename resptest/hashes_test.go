@@ -0,0 +1,34 @@
+package resptest
+
+import (
+	"testing"
+)
+
+func TestHashes(t *testing.T) {
+	s, c := newTestClient(t)
+	defer s.Close()
+	defer c.Close()
+
+	expect(t, c, "(integer) 2", "HSET", "h", "b", "2", "a", "1")
+	expect(t, c, "(integer) 0", "HSET", "h", "a", "one")
+	expect(t, c, "OK", "HMSET", "h", "c", "3")
+	expect(t, c, "(integer) 0", "HSETNX", "h", "a", "x")
+	expect(t, c, `"one"`, "HGET", "h", "a")
+	expect(t, c, "(nil)", "HGET", "h", "nope")
+	expect(t, c, lines(`1) "one"`, `2) (nil)`), "HMGET", "h", "a", "nope")
+	expect(t, c, lines(`1) "a"`, `2) "one"`, `3) "b"`, `4) "2"`, `5) "c"`, `6) "3"`), "HGETALL", "h")
+	expect(t, c, lines(`1) "a"`, `2) "b"`, `3) "c"`), "HKEYS", "h")
+	expect(t, c, lines(`1) "one"`, `2) "2"`, `3) "3"`), "HVALS", "h")
+	expect(t, c, "(integer) 3", "HLEN", "h")
+	expect(t, c, "(integer) 3", "HSTRLEN", "h", "a")
+	expect(t, c, "(integer) 1", "HEXISTS", "h", "a")
+	expect(t, c, "(integer) 12", "HINCRBY", "h", "b", 10)
+	expect(t, c, `"3.5"`, "HINCRBYFLOAT", "h", "c", "0.5")
+	expect(t, c, "(error) ERR hash value is not an integer", "HINCRBY", "h", "a", 1)
+	expect(t, c, lines(`1) "0"`, `2) 1) "b"`, `   2) "12"`), "HSCAN", "h", 0, "MATCH", "b")
+
+	expect(t, c, "(integer) 3", "HDEL", "h", "a", "b", "c", "nope")
+	expect(t, c, "(integer) 0", "EXISTS", "h")
+	expect(t, c, "(empty array)", "HGETALL", "h")
+	expect(t, c, "(error) ERR wrong number of arguments for 'hset' command", "HSET", "h", "a")
+}
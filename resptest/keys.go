@@ -0,0 +1,295 @@
+package resptest
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+var keyCommands = map[string]command{
+	"DEL":       {fn: (*Server).cmdDel, arity: -2},
+	"UNLINK":    {fn: (*Server).cmdDel, arity: -2},
+	"EXISTS":    {fn: (*Server).cmdExists, arity: -2},
+	"TYPE":      {fn: (*Server).cmdType, arity: 2},
+	"EXPIRE":    {fn: (*Server).cmdExpire, arity: -3},
+	"PEXPIRE":   {fn: (*Server).cmdExpire, arity: -3},
+	"EXPIREAT":  {fn: (*Server).cmdExpire, arity: -3},
+	"PEXPIREAT": {fn: (*Server).cmdExpire, arity: -3},
+	"TTL":       {fn: (*Server).cmdTTL, arity: 2},
+	"PTTL":      {fn: (*Server).cmdTTL, arity: 2},
+	"PERSIST":   {fn: (*Server).cmdPersist, arity: 2},
+	"KEYS":      {fn: (*Server).cmdKeys, arity: 2},
+	"SCAN":      {fn: (*Server).cmdScan, arity: -2},
+	"RENAME":    {fn: (*Server).cmdRename, arity: 3},
+	"RENAMENX":  {fn: (*Server).cmdRename, arity: 3},
+	"DBSIZE":    {fn: (*Server).cmdDBSize, arity: 1},
+	"FLUSHDB":   {fn: (*Server).cmdFlushDB, arity: -1},
+	"FLUSHALL":  {fn: (*Server).cmdFlushAll, arity: -1},
+}
+
+func (s *Server) cmdDel(c *client, args [][]byte) *resp.Message {
+	var n int64
+	for _, key := range args[1:] {
+		if s.del(c, key) {
+			n++
+		}
+	}
+	return resp.Int(n)
+}
+
+func (s *Server) cmdExists(c *client, args [][]byte) *resp.Message {
+	var n int64
+	for _, key := range args[1:] {
+		if s.get(c, key) != nil {
+			n++
+		}
+	}
+	return resp.Int(n)
+}
+
+func (s *Server) cmdType(c *client, args [][]byte) *resp.Message {
+	v := s.get(c, args[1])
+	if v == nil {
+		return resp.Str("none")
+	}
+	return resp.Str(v.kind)
+}
+
+// cmdExpire implements EXPIRE, PEXPIRE, EXPIREAT and PEXPIREAT with their
+// NX, XX, GT and LT options.
+func (s *Server) cmdExpire(c *client, args [][]byte) *resp.Message {
+	name := strings.ToUpper(string(args[0]))
+	n, ok := parseInt(args[2])
+	if !ok {
+		return replyError(errNotInteger)
+	}
+	var nx, xx, gt, lt bool
+	for _, opt := range args[3:] {
+		switch strings.ToUpper(string(opt)) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "GT":
+			gt = true
+		case "LT":
+			lt = true
+		default:
+			return replyError("ERR Unsupported option " + string(opt))
+		}
+	}
+	if nx && (xx || gt || lt) {
+		return replyError("ERR NX and XX, GT or LT options at the same time are not compatible")
+	}
+	if gt && lt {
+		return replyError("ERR GT and LT options at the same time are not compatible")
+	}
+
+	unit := time.Second
+	if name[0] == 'P' {
+		unit = time.Millisecond
+	}
+	if n > math.MaxInt64/int64(unit) || n < math.MinInt64/int64(unit) {
+		return replyError(fmt.Sprintf(errInvalidTime, strings.ToLower(name)))
+	}
+	d := time.Duration(n) * unit
+	at := s.clock().Add(d)
+	if strings.HasSuffix(name, "AT") {
+		at = time.Unix(0, int64(d))
+	}
+
+	v := s.get(c, args[1])
+	if v == nil {
+		return resp.Int(0)
+	}
+	// a key without expiry has an infinite TTL for GT and LT
+	switch {
+	case nx && !v.expire.IsZero(),
+		xx && v.expire.IsZero(),
+		gt && (v.expire.IsZero() || !at.After(v.expire)),
+		lt && !v.expire.IsZero() && !at.Before(v.expire):
+		return resp.Int(0)
+	}
+	if !at.After(s.clock()) {
+		s.del(c, args[1])
+		return resp.Int(1)
+	}
+	v.expire = at
+	s.touch(c, args[1])
+	return resp.Int(1)
+}
+
+func (s *Server) cmdTTL(c *client, args [][]byte) *resp.Message {
+	v := s.get(c, args[1])
+	if v == nil {
+		return resp.Int(-2)
+	}
+	if v.expire.IsZero() {
+		return resp.Int(-1)
+	}
+	ttl := v.expire.Sub(s.clock())
+	if strings.ToUpper(string(args[0])) == "PTTL" {
+		return resp.Int(int64((ttl + time.Millisecond/2) / time.Millisecond))
+	}
+	return resp.Int(int64((ttl + time.Second/2) / time.Second))
+}
+
+func (s *Server) cmdPersist(c *client, args [][]byte) *resp.Message {
+	v := s.get(c, args[1])
+	if v == nil || v.expire.IsZero() {
+		return resp.Int(0)
+	}
+	v.expire = time.Time{}
+	s.touch(c, args[1])
+	return resp.Int(1)
+}
+
+func (s *Server) cmdKeys(c *client, args [][]byte) *resp.Message {
+	var keys [][]byte
+	keyList := s.keys(c.db)
+	sort.Strings(keyList)
+	for _, key := range keyList {
+		if resp.MatchPattern(string(args[1]), key) {
+			keys = append(keys, []byte(key))
+		}
+	}
+	return bulks(keys)
+}
+
+// cmdScan implements SCAN, the cursor being the index of the next key in
+// lexicographic order. Unlike redis, keys added during the iteration before
+// the cursor are not returned.
+func (s *Server) cmdScan(c *client, args [][]byte) *resp.Message {
+	cursor, ok := parseInt(args[1])
+	if !ok || cursor < 0 {
+		return replyError("ERR invalid cursor")
+	}
+	opts, errReply := parseScanOptions(args[2:], true)
+	if errReply != nil {
+		return errReply
+	}
+	keyList := s.keys(c.db)
+	sort.Strings(keyList)
+
+	var keys [][]byte
+	i := int(cursor)
+	for ; i < len(keyList) && i < int(cursor)+opts.count; i++ {
+		key := keyList[i]
+		if opts.match != "" && !resp.MatchPattern(opts.match, key) {
+			continue
+		}
+		if opts.kind != "" && s.getDB(c.db, key).kind != opts.kind {
+			continue
+		}
+		keys = append(keys, []byte(key))
+	}
+	if i >= len(keyList) {
+		i = 0
+	}
+	return resp.Array(resp.Bulk([]byte(strconv.Itoa(i))), bulks(keys))
+}
+
+type scanOptions struct {
+	match string
+	count int
+	kind  string
+}
+
+// parseScanOptions parses the MATCH, COUNT and, for SCAN only, TYPE options
+// of the SCAN family of commands.
+func parseScanOptions(args [][]byte, withType bool) (scanOptions, *resp.Message) {
+	opts := scanOptions{count: 10}
+	for i := 0; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return opts, replyError(errSyntax)
+		}
+		switch strings.ToUpper(string(args[i])) {
+		case "MATCH":
+			opts.match = string(args[i+1])
+		case "COUNT":
+			n, ok := parseInt(args[i+1])
+			if !ok {
+				return opts, replyError(errNotInteger)
+			}
+			if n < 1 {
+				return opts, replyError(errSyntax)
+			}
+			opts.count = int(n)
+		case "TYPE":
+			if !withType {
+				return opts, replyError(errSyntax)
+			}
+			opts.kind = strings.ToLower(string(args[i+1]))
+		default:
+			return opts, replyError(errSyntax)
+		}
+	}
+	return opts, nil
+}
+
+func (s *Server) cmdRename(c *client, args [][]byte) *resp.Message {
+	v := s.get(c, args[1])
+	if v == nil {
+		return replyError(errNoSuchKey)
+	}
+	nx := strings.ToUpper(string(args[0])) == "RENAMENX"
+	if bytes.Equal(args[1], args[2]) {
+		if nx {
+			return resp.Int(0)
+		}
+		return okReply()
+	}
+	if nx && s.get(c, args[2]) != nil {
+		return resp.Int(0)
+	}
+	s.del(c, args[1])
+	s.set(c, args[2], v)
+	if nx {
+		return resp.Int(1)
+	}
+	return okReply()
+}
+
+func (s *Server) cmdDBSize(c *client, args [][]byte) *resp.Message {
+	return resp.Int(int64(len(s.keys(c.db))))
+}
+
+func (s *Server) cmdFlushDB(c *client, args [][]byte) *resp.Message {
+	if errReply := checkFlushMode(args); errReply != nil {
+		return errReply
+	}
+	s.flushDB(c.db)
+	return okReply()
+}
+
+func (s *Server) cmdFlushAll(c *client, args [][]byte) *resp.Message {
+	if errReply := checkFlushMode(args); errReply != nil {
+		return errReply
+	}
+	for i := range s.dbs {
+		s.flushDB(i)
+	}
+	return okReply()
+}
+
+// checkFlushMode checks the ASYNC or SYNC option of FLUSHDB and FLUSHALL,
+// both flushing synchronously here.
+func checkFlushMode(args [][]byte) *resp.Message {
+	if len(args) > 2 {
+		return replyError(errSyntax)
+	}
+	if len(args) == 2 {
+		switch strings.ToUpper(string(args[1])) {
+		case "ASYNC", "SYNC":
+		default:
+			return replyError(errSyntax)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package resptest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeys(t *testing.T) {
+	s, c := newTestClient(t)
+	defer s.Close()
+	defer c.Close()
+
+	expect(t, c, "OK", "MSET", "user:1", "a", "user:2", "b", "other", "c")
+	expect(t, c, "(integer) 2", "EXISTS", "user:1", "user:2", "nope")
+	expect(t, c, "string", "TYPE", "user:1")
+	expect(t, c, "none", "TYPE", "nope")
+	expect(t, c, lines(`1) "user:1"`, `2) "user:2"`), "KEYS", "user:*")
+	expect(t, c, "(integer) 3", "DBSIZE")
+
+	expect(t, c, "OK", "RENAME", "other", "another")
+	expect(t, c, "(error) ERR no such key", "RENAME", "other", "x")
+	expect(t, c, "(integer) 0", "RENAMENX", "another", "user:1")
+	expect(t, c, "(integer) 2", "DEL", "another", "user:1", "nope")
+	expect(t, c, lines(`1) "0"`, `2) 1) "user:2"`), "SCAN", 0)
+
+	expect(t, c, "OK", "FLUSHDB")
+	expect(t, c, "(empty array)", "KEYS", "*")
+}
+
+func TestExpire(t *testing.T) {
+	s, c := newTestClient(t)
+	defer s.Close()
+	defer c.Close()
+	now := time.Unix(1500000000, 0)
+	s.SetTime(now)
+
+	expect(t, c, "OK", "SET", "a", "1")
+	expect(t, c, "(integer) -1", "TTL", "a")
+	expect(t, c, "(integer) 0", "EXPIRE", "nope", 10)
+	expect(t, c, "(integer) 1", "EXPIRE", "a", 10)
+	expect(t, c, "(integer) 0", "EXPIRE", "a", 20, "NX")
+	expect(t, c, "(integer) 0", "EXPIRE", "a", 5, "GT")
+	expect(t, c, "(integer) 1", "EXPIRE", "a", 5, "LT")
+	expect(t, c, "(integer) 5000", "PTTL", "a")
+	expect(t, c, "(integer) 1", "PEXPIREAT", "a", 1500000002000)
+	expect(t, c, "(integer) 2", "TTL", "a")
+	expect(t, c, "(integer) 1", "PERSIST", "a")
+	expect(t, c, "(integer) -1", "TTL", "a")
+	expect(t, c, "(integer) 1", "EXPIREAT", "a", 1500000001)
+	s.FastForward(time.Second)
+	expect(t, c, "(integer) 0", "EXISTS", "a")
+
+	expect(t, c, "OK", "SET", "b", "1")
+	expect(t, c, "(integer) 1", "EXPIRE", "b", -1)
+	expect(t, c, "(integer) 0", "EXISTS", "b")
+	expect(t, c, "(error) ERR Unsupported option YY", "EXPIRE", "b", 1, "YY")
+}
@@ -0,0 +1,322 @@
+package resptest
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+var listCommands = map[string]command{
+	"LPUSH":   {fn: (*Server).cmdPush, arity: -3},
+	"RPUSH":   {fn: (*Server).cmdPush, arity: -3},
+	"LPUSHX":  {fn: (*Server).cmdPush, arity: -3},
+	"RPUSHX":  {fn: (*Server).cmdPush, arity: -3},
+	"LPOP":    {fn: (*Server).cmdPop, arity: -2},
+	"RPOP":    {fn: (*Server).cmdPop, arity: -2},
+	"LLEN":    {fn: (*Server).cmdLLen, arity: 2},
+	"LRANGE":  {fn: (*Server).cmdLRange, arity: 4},
+	"LINDEX":  {fn: (*Server).cmdLIndex, arity: 3},
+	"LSET":    {fn: (*Server).cmdLSet, arity: 4},
+	"LREM":    {fn: (*Server).cmdLRem, arity: 4},
+	"LTRIM":   {fn: (*Server).cmdLTrim, arity: 4},
+	"LINSERT": {fn: (*Server).cmdLInsert, arity: 5},
+	"LPOS":    {fn: (*Server).cmdLPos, arity: -3},
+}
+
+// cmdPush implements LPUSH, RPUSH, LPUSHX and RPUSHX.
+func (s *Server) cmdPush(c *client, args [][]byte) *resp.Message {
+	name := strings.ToUpper(string(args[0]))
+	var v *value
+	var errReply *resp.Message
+	if strings.HasSuffix(name, "X") {
+		if v, errReply = s.lookup(c, args[1], typeList); errReply != nil || v == nil {
+			return intOrError(0, errReply)
+		}
+	} else if v, errReply = s.lookupOrCreate(c, args[1], typeList); errReply != nil {
+		return errReply
+	}
+	for _, elem := range args[2:] {
+		if name[0] == 'L' {
+			v.list = append([][]byte{elem}, v.list...)
+		} else {
+			v.list = append(v.list, elem)
+		}
+	}
+	s.modified(c, args[1], v)
+	return resp.Int(int64(len(v.list)))
+}
+
+// cmdPop implements LPOP and RPOP, with an optional count.
+func (s *Server) cmdPop(c *client, args [][]byte) *resp.Message {
+	if len(args) > 3 {
+		return replyError(errSyntax)
+	}
+	count := int64(-1)
+	if len(args) == 3 {
+		var ok bool
+		if count, ok = parseInt(args[2]); !ok || count < 0 {
+			return replyError("ERR value is out of range, must be positive")
+		}
+	}
+	v, errReply := s.lookup(c, args[1], typeList)
+	if errReply != nil {
+		return errReply
+	}
+	if v == nil {
+		if count >= 0 {
+			return resp.NilArray()
+		}
+		return resp.Nil()
+	}
+	n := 1
+	if count >= 0 {
+		n = len(v.list)
+		if count < int64(n) {
+			n = int(count)
+		}
+	}
+	left := strings.ToUpper(string(args[0])) == "LPOP"
+	var popped [][]byte
+	for i := 0; i < n; i++ {
+		if left {
+			popped = append(popped, v.list[0])
+			v.list = v.list[1:]
+		} else {
+			popped = append(popped, v.list[len(v.list)-1])
+			v.list = v.list[:len(v.list)-1]
+		}
+	}
+	if n > 0 {
+		s.modified(c, args[1], v)
+	}
+	if count < 0 {
+		return resp.Bulk(popped[0])
+	}
+	return bulks(popped)
+}
+
+func (s *Server) cmdLLen(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeList)
+	if errReply != nil || v == nil {
+		return intOrError(0, errReply)
+	}
+	return resp.Int(int64(len(v.list)))
+}
+
+func (s *Server) cmdLRange(c *client, args [][]byte) *resp.Message {
+	start, ok1 := parseInt(args[2])
+	stop, ok2 := parseInt(args[3])
+	if !ok1 || !ok2 {
+		return replyError(errNotInteger)
+	}
+	v, errReply := s.lookup(c, args[1], typeList)
+	if errReply != nil {
+		return errReply
+	}
+	if v == nil {
+		return bulks(nil)
+	}
+	from, to := listRange(start, stop, len(v.list))
+	return bulks(v.list[from:to])
+}
+
+func (s *Server) cmdLIndex(c *client, args [][]byte) *resp.Message {
+	index, ok := parseInt(args[2])
+	if !ok {
+		return replyError(errNotInteger)
+	}
+	v, errReply := s.lookup(c, args[1], typeList)
+	if errReply != nil {
+		return errReply
+	}
+	if v == nil {
+		return resp.Nil()
+	}
+	index = listIndex(index, len(v.list))
+	if index < 0 || index >= int64(len(v.list)) {
+		return resp.Nil()
+	}
+	return resp.Bulk(v.list[index])
+}
+
+func (s *Server) cmdLSet(c *client, args [][]byte) *resp.Message {
+	index, ok := parseInt(args[2])
+	if !ok {
+		return replyError(errNotInteger)
+	}
+	v, errReply := s.lookup(c, args[1], typeList)
+	if errReply != nil {
+		return errReply
+	}
+	if v == nil {
+		return replyError(errNoSuchKey)
+	}
+	index = listIndex(index, len(v.list))
+	if index < 0 || index >= int64(len(v.list)) {
+		return replyError(errOutOfRange)
+	}
+	v.list[index] = args[3]
+	s.modified(c, args[1], v)
+	return okReply()
+}
+
+// cmdLRem removes count occurrences of an element, from the head when count
+// is positive, from the tail when negative and all of them when 0.
+func (s *Server) cmdLRem(c *client, args [][]byte) *resp.Message {
+	count, ok := parseInt(args[2])
+	if !ok {
+		return replyError(errNotInteger)
+	}
+	v, errReply := s.lookup(c, args[1], typeList)
+	if errReply != nil || v == nil {
+		return intOrError(0, errReply)
+	}
+	n := len(v.list)
+	removed := make([]bool, n)
+	var total int64
+	for i := 0; i < n; i++ {
+		j := i
+		if count < 0 {
+			j = n - 1 - i
+		}
+		if bytes.Equal(v.list[j], args[3]) {
+			removed[j] = true
+			total++
+			if count != 0 && (total == count || total == -count) {
+				break
+			}
+		}
+	}
+	list := v.list[:0:0]
+	for i, elem := range v.list {
+		if !removed[i] {
+			list = append(list, elem)
+		}
+	}
+	v.list = list
+	if total > 0 {
+		s.modified(c, args[1], v)
+	}
+	return resp.Int(total)
+}
+
+func (s *Server) cmdLTrim(c *client, args [][]byte) *resp.Message {
+	start, ok1 := parseInt(args[2])
+	stop, ok2 := parseInt(args[3])
+	if !ok1 || !ok2 {
+		return replyError(errNotInteger)
+	}
+	v, errReply := s.lookup(c, args[1], typeList)
+	if errReply != nil {
+		return errReply
+	}
+	if v == nil {
+		return okReply()
+	}
+	from, to := listRange(start, stop, len(v.list))
+	v.list = v.list[from:to]
+	s.modified(c, args[1], v)
+	return okReply()
+}
+
+func (s *Server) cmdLInsert(c *client, args [][]byte) *resp.Message {
+	var after bool
+	switch strings.ToUpper(string(args[2])) {
+	case "BEFORE":
+	case "AFTER":
+		after = true
+	default:
+		return replyError(errSyntax)
+	}
+	v, errReply := s.lookup(c, args[1], typeList)
+	if errReply != nil || v == nil {
+		return intOrError(0, errReply)
+	}
+	for i, elem := range v.list {
+		if !bytes.Equal(elem, args[3]) {
+			continue
+		}
+		if after {
+			i++
+		}
+		list := make([][]byte, 0, len(v.list)+1)
+		list = append(list, v.list[:i]...)
+		list = append(list, args[4])
+		v.list = append(list, v.list[i:]...)
+		s.modified(c, args[1], v)
+		return resp.Int(int64(len(v.list)))
+	}
+	return resp.Int(-1)
+}
+
+// cmdLPos implements LPOS with its RANK, COUNT and MAXLEN options.
+func (s *Server) cmdLPos(c *client, args [][]byte) *resp.Message {
+	rank, count, maxLen := int64(1), int64(-1), int64(0)
+	for i := 3; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return replyError(errSyntax)
+		}
+		n, ok := parseInt(args[i+1])
+		if !ok {
+			return replyError(errNotInteger)
+		}
+		switch strings.ToUpper(string(args[i])) {
+		case "RANK":
+			if n == 0 {
+				return replyError("ERR RANK can't be zero: use 1 to start from the first match, " +
+					"2 from the second ... or use negative to start from the end of the list")
+			}
+			rank = n
+		case "COUNT":
+			if n < 0 {
+				return replyError("ERR COUNT can't be negative")
+			}
+			count = n
+		case "MAXLEN":
+			if n < 0 {
+				return replyError("ERR MAXLEN can't be negative")
+			}
+			maxLen = n
+		default:
+			return replyError(errSyntax)
+		}
+	}
+	v, errReply := s.lookup(c, args[1], typeList)
+	if errReply != nil {
+		return errReply
+	}
+
+	var matches []*resp.Message
+	if v != nil {
+		skip := rank - 1
+		if rank < 0 {
+			skip = -rank - 1
+		}
+		n := len(v.list)
+		for i := 0; i < n && (maxLen == 0 || int64(i) < maxLen); i++ {
+			j := i
+			if rank < 0 {
+				j = n - 1 - i
+			}
+			if !bytes.Equal(v.list[j], args[2]) {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			matches = append(matches, resp.Int(int64(j)))
+			if count < 0 || (count > 0 && int64(len(matches)) == count) {
+				break
+			}
+		}
+	}
+	if count < 0 {
+		if len(matches) == 0 {
+			return resp.Nil()
+		}
+		return matches[0]
+	}
+	return resp.Array(matches...)
+}
@@ -0,0 +1,35 @@
+package resptest
+
+import (
+	"testing"
+)
+
+func TestLists(t *testing.T) {
+	s, c := newTestClient(t)
+	defer s.Close()
+	defer c.Close()
+
+	expect(t, c, "(integer) 0", "LPUSHX", "l", "a")
+	expect(t, c, "(integer) 2", "RPUSH", "l", "b", "c")
+	expect(t, c, "(integer) 4", "LPUSH", "l", "x", "a")
+	expect(t, c, lines(`1) "a"`, `2) "x"`, `3) "b"`, `4) "c"`), "LRANGE", "l", 0, -1)
+	expect(t, c, lines(`1) "b"`, `2) "c"`), "LRANGE", "l", -2, 100)
+	expect(t, c, "(empty array)", "LRANGE", "l", 3, 1)
+	expect(t, c, "(integer) 4", "LLEN", "l")
+	expect(t, c, `"c"`, "LINDEX", "l", -1)
+	expect(t, c, "(nil)", "LINDEX", "l", 10)
+	expect(t, c, "OK", "LSET", "l", 1, "b")
+	expect(t, c, "(error) ERR index out of range", "LSET", "l", 10, "b")
+	expect(t, c, "(integer) 3", "LPOS", "l", "c")
+	expect(t, c, lines(`1) (integer) 1`, `2) (integer) 2`), "LPOS", "l", "b", "COUNT", 0)
+	expect(t, c, "(integer) 5", "LINSERT", "l", "AFTER", "c", "d")
+	expect(t, c, "(integer) -1", "LINSERT", "l", "BEFORE", "nope", "d")
+	expect(t, c, "(integer) 2", "LREM", "l", 0, "b")
+	expect(t, c, lines(`1) "a"`, `2) "c"`, `3) "d"`), "LRANGE", "l", 0, -1)
+	expect(t, c, "OK", "LTRIM", "l", 1, -1)
+
+	expect(t, c, `"c"`, "LPOP", "l")
+	expect(t, c, lines(`1) "d"`), "RPOP", "l", 5)
+	expect(t, c, "(integer) 0", "EXISTS", "l")
+	expect(t, c, "(nil)", "LPOP", "l")
+}
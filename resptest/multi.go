@@ -0,0 +1,86 @@
+package resptest
+
+import (
+	"github.com/amyangfei/resp-go/resp"
+)
+
+var txCommands = map[string]command{
+	"MULTI":   {fn: (*Server).cmdMulti, arity: 1, flags: flagTx},
+	"EXEC":    {fn: (*Server).cmdExec, arity: 1, flags: flagTx},
+	"DISCARD": {fn: (*Server).cmdDiscard, arity: 1, flags: flagTx},
+	"WATCH":   {fn: (*Server).cmdWatch, arity: -2, flags: flagTx},
+	"UNWATCH": {fn: (*Server).cmdUnwatch, arity: 1},
+}
+
+func (s *Server) cmdMulti(c *client, args [][]byte) *resp.Message {
+	if c.multi {
+		return replyError("ERR MULTI calls can not be nested")
+	}
+	c.multi = true
+	return okReply()
+}
+
+// cmdExec runs the queued commands, unless an error occurred while queuing
+// them or a watched key was modified.
+func (s *Server) cmdExec(c *client, args [][]byte) *resp.Message {
+	if !c.multi {
+		return replyError("ERR EXEC without MULTI")
+	}
+	queue, multiErr, dirty := c.queue, c.multiErr, c.dirty
+	c.multi, c.multiErr, c.queue = false, false, nil
+	s.unwatch(c)
+	if multiErr {
+		return replyError("EXECABORT Transaction discarded because of previous errors.")
+	}
+	if dirty {
+		return resp.NilArray()
+	}
+	replies := resp.Array()
+	for _, args := range queue {
+		replies.Array = append(replies.Array, s.process(c, args))
+	}
+	return replies
+}
+
+func (s *Server) cmdDiscard(c *client, args [][]byte) *resp.Message {
+	if !c.multi {
+		return replyError("ERR DISCARD without MULTI")
+	}
+	c.multi, c.multiErr, c.queue = false, false, nil
+	s.unwatch(c)
+	return okReply()
+}
+
+func (s *Server) cmdWatch(c *client, args [][]byte) *resp.Message {
+	if c.multi {
+		return replyError("ERR WATCH inside MULTI is not allowed")
+	}
+	for _, key := range args[1:] {
+		k := watchKey{c.db, string(key)}
+		clients := s.watchers[k]
+		if clients == nil {
+			clients = make(map[*client]struct{})
+			s.watchers[k] = clients
+		}
+		if _, ok := clients[c]; !ok {
+			clients[c] = struct{}{}
+			c.watched = append(c.watched, k)
+		}
+	}
+	return okReply()
+}
+
+func (s *Server) cmdUnwatch(c *client, args [][]byte) *resp.Message {
+	s.unwatch(c)
+	return okReply()
+}
+
+func (s *Server) unwatch(c *client) {
+	for _, k := range c.watched {
+		delete(s.watchers[k], c)
+		if len(s.watchers[k]) == 0 {
+			delete(s.watchers, k)
+		}
+	}
+	c.watched, c.dirty = nil, false
+}
@@ -0,0 +1,66 @@
+package resptest
+
+import (
+	"testing"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+func TestMulti(t *testing.T) {
+	s, c := newTestClient(t)
+	defer s.Close()
+	defer c.Close()
+
+	expect(t, c, "(error) ERR EXEC without MULTI", "EXEC")
+	expect(t, c, "(error) ERR DISCARD without MULTI", "DISCARD")
+
+	expect(t, c, "OK", "MULTI")
+	expect(t, c, "(error) ERR MULTI calls can not be nested", "MULTI")
+	expect(t, c, "QUEUED", "SET", "a", "1")
+	expect(t, c, "QUEUED", "INCR", "a")
+	expect(t, c, "QUEUED", "LPUSH", "a", "x")
+	expect(t, c, lines("1) OK", "2) (integer) 2",
+		"3) (error) WRONGTYPE Operation against a key holding the wrong kind of value"), "EXEC")
+
+	expect(t, c, "OK", "MULTI")
+	expect(t, c, "QUEUED", "INCR", "a")
+	expect(t, c, "OK", "DISCARD")
+	expect(t, c, `"2"`, "GET", "a")
+
+	expect(t, c, "OK", "MULTI")
+	expect(t, c, "QUEUED", "INCR", "a")
+	expect(t, c, "(error) ERR wrong number of arguments for 'get' command", "GET")
+	expect(t, c, "(error) EXECABORT Transaction discarded because of previous errors.", "EXEC")
+	expect(t, c, `"2"`, "GET", "a")
+}
+
+func TestWatch(t *testing.T) {
+	s, c := newTestClient(t)
+	defer s.Close()
+	defer c.Close()
+	other, err := resp.Dial("tcp", s.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	expect(t, c, "OK", "WATCH", "a")
+	expect(t, c, "OK", "MULTI")
+	expect(t, c, "(error) ERR WATCH inside MULTI is not allowed", "WATCH", "b")
+	expect(t, c, "QUEUED", "SET", "a", "1")
+	expect(t, c, lines("1) OK"), "EXEC")
+
+	expect(t, c, "OK", "WATCH", "a")
+	expect(t, other, "OK", "SET", "a", "2")
+	expect(t, c, "OK", "MULTI")
+	expect(t, c, "QUEUED", "SET", "a", "1")
+	expect(t, c, "(nil)", "EXEC")
+	expect(t, c, `"2"`, "GET", "a")
+
+	expect(t, c, "OK", "WATCH", "a")
+	expect(t, c, "OK", "UNWATCH")
+	expect(t, other, "OK", "SET", "a", "3")
+	expect(t, c, "OK", "MULTI")
+	expect(t, c, "QUEUED", "GET", "a")
+	expect(t, c, lines(`1) "3"`), "EXEC")
+}
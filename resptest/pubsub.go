@@ -0,0 +1,149 @@
+package resptest
+
+import (
+	"strings"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+var pubsubCommands = map[string]command{
+	"SUBSCRIBE":    {fn: (*Server).cmdSubscribe, arity: -2, flags: flagPubSub | flagNoMulti},
+	"PSUBSCRIBE":   {fn: (*Server).cmdSubscribe, arity: -2, flags: flagPubSub | flagNoMulti},
+	"UNSUBSCRIBE":  {fn: (*Server).cmdUnsubscribe, arity: -1, flags: flagPubSub | flagNoMulti},
+	"PUNSUBSCRIBE": {fn: (*Server).cmdUnsubscribe, arity: -1, flags: flagPubSub | flagNoMulti},
+	"PUBLISH":      {fn: (*Server).cmdPublish, arity: 3},
+}
+
+// Publish sends a message to the clients subscribed to the channel and
+// returns the number of clients which received it, as the PUBLISH command.
+func (s *Server) Publish(channel string, message []byte) int {
+	s.mu.Lock()
+	n := s.publish(channel, message)
+	flush := s.takeFlush()
+	s.mu.Unlock()
+	for _, conn := range flush {
+		conn.Flush()
+	}
+	return n
+}
+
+func (s *Server) publish(channel string, message []byte) int {
+	var n int
+	for c := range s.channels[channel] {
+		s.push(c, resp.Array(
+			resp.Bulk([]byte("message")),
+			resp.Bulk([]byte(channel)),
+			resp.Bulk(message),
+		))
+		n++
+	}
+	for pattern, clients := range s.patterns {
+		if !resp.MatchPattern(pattern, channel) {
+			continue
+		}
+		for c := range clients {
+			s.push(c, resp.Array(
+				resp.Bulk([]byte("pmessage")),
+				resp.Bulk([]byte(pattern)),
+				resp.Bulk([]byte(channel)),
+				resp.Bulk(message),
+			))
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Server) cmdPublish(c *client, args [][]byte) *resp.Message {
+	return resp.Int(int64(s.publish(string(args[1]), args[2])))
+}
+
+// subscriptions returns the channels or patterns of a client and of the
+// server.
+func (s *Server) subscriptions(c *client, pattern bool) (map[string]struct{}, map[string]map[*client]struct{}) {
+	if pattern {
+		if c.patterns == nil {
+			c.patterns = make(map[string]struct{})
+		}
+		return c.patterns, s.patterns
+	}
+	if c.channels == nil {
+		c.channels = make(map[string]struct{})
+	}
+	return c.channels, s.channels
+}
+
+// cmdSubscribe implements SUBSCRIBE and PSUBSCRIBE, replying once for each
+// channel.
+func (s *Server) cmdSubscribe(c *client, args [][]byte) *resp.Message {
+	kind := strings.ToLower(string(args[0]))
+	own, all := s.subscriptions(c, kind == "psubscribe")
+	for _, arg := range args[1:] {
+		name := string(arg)
+		if _, ok := own[name]; !ok {
+			own[name] = struct{}{}
+			if all[name] == nil {
+				all[name] = make(map[*client]struct{})
+			}
+			all[name][c] = struct{}{}
+		}
+		c.replies = append(c.replies, subscriptionReply(kind, arg, c))
+	}
+	return nil
+}
+
+// cmdUnsubscribe implements UNSUBSCRIBE and PUNSUBSCRIBE, from every channel
+// when none is given.
+func (s *Server) cmdUnsubscribe(c *client, args [][]byte) *resp.Message {
+	kind := strings.ToLower(string(args[0]))
+	own, all := s.subscriptions(c, kind == "punsubscribe")
+	names := args[1:]
+	if len(names) == 0 {
+		for _, name := range sortedKeys(own) {
+			names = append(names, []byte(name))
+		}
+	}
+	if len(names) == 0 {
+		return resp.Array(resp.Bulk([]byte(kind)), resp.Nil(), resp.Int(int64(c.subscriptionCount())))
+	}
+	for _, arg := range names {
+		name := string(arg)
+		if _, ok := own[name]; ok {
+			delete(own, name)
+			delete(all[name], c)
+			if len(all[name]) == 0 {
+				delete(all, name)
+			}
+		}
+		c.replies = append(c.replies, subscriptionReply(kind, arg, c))
+	}
+	return nil
+}
+
+func (s *Server) unsubscribeAll(c *client) {
+	for channel := range c.channels {
+		delete(s.channels[channel], c)
+		if len(s.channels[channel]) == 0 {
+			delete(s.channels, channel)
+		}
+	}
+	for pattern := range c.patterns {
+		delete(s.patterns[pattern], c)
+		if len(s.patterns[pattern]) == 0 {
+			delete(s.patterns, pattern)
+		}
+	}
+	c.channels, c.patterns = nil, nil
+}
+
+func (c *client) subscriptionCount() int {
+	return len(c.channels) + len(c.patterns)
+}
+
+func subscriptionReply(kind string, name []byte, c *client) *resp.Message {
+	return resp.Array(
+		resp.Bulk([]byte(kind)),
+		resp.Bulk(name),
+		resp.Int(int64(c.subscriptionCount())),
+	)
+}
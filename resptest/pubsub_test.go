@@ -0,0 +1,71 @@
+package resptest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// receive checks the next message received by a subscribed client.
+func receive(t *testing.T, c *resp.Conn, expected string) {
+	msg, err := c.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.String() != expected {
+		t.Errorf("error message:\n%s\nexpected:\n%s", msg, expected)
+	}
+}
+
+func TestPubSub(t *testing.T) {
+	s, sub := newTestClient(t)
+	defer s.Close()
+	defer sub.Close()
+	pub, err := resp.Dial("tcp", s.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pub.Close()
+
+	sub.Send(resp.Cmd("SUBSCRIBE", "news", "sport"))
+	sub.Flush()
+	receive(t, sub, lines(`1) "subscribe"`, `2) "news"`, `3) (integer) 1`))
+	receive(t, sub, lines(`1) "subscribe"`, `2) "sport"`, `3) (integer) 2`))
+	expect(t, sub, lines(`1) "psubscribe"`, `2) "n*"`, `3) (integer) 3`), "PSUBSCRIBE", "n*")
+
+	expect(t, pub, "(integer) 2", "PUBLISH", "news", "hello")
+	receive(t, sub, lines(`1) "message"`, `2) "news"`, `3) "hello"`))
+	receive(t, sub, lines(`1) "pmessage"`, `2) "n*"`, `3) "news"`, `4) "hello"`))
+	if n := s.Publish("sport", []byte("goal")); n != 1 {
+		t.Errorf("error Publish result: %d", n)
+	}
+	receive(t, sub, lines(`1) "message"`, `2) "sport"`, `3) "goal"`))
+
+	expect(t, sub, lines(`1) "pong"`, `2) ""`), "PING")
+	expect(t, sub, "(error) ERR Can't execute 'get': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / "+
+		"PING / QUIT / RESET are allowed in this context", "GET", "a")
+
+	sub.Send(resp.Cmd("UNSUBSCRIBE"))
+	sub.Flush()
+	receive(t, sub, lines(`1) "unsubscribe"`, `2) "news"`, `3) (integer) 2`))
+	receive(t, sub, lines(`1) "unsubscribe"`, `2) "sport"`, `3) (integer) 1`))
+	expect(t, sub, lines(`1) "punsubscribe"`, `2) "n*"`, `3) (integer) 0`), "PUNSUBSCRIBE", "n*")
+	expect(t, sub, "PONG", "PING")
+	expect(t, pub, "(integer) 0", "PUBLISH", "news", "hello")
+}
+
+func TestPubSubDisconnect(t *testing.T) {
+	s, sub := newTestClient(t)
+	defer s.Close()
+
+	expect(t, sub, lines(`1) "subscribe"`, `2) "news"`, `3) (integer) 1`), "SUBSCRIBE", "news")
+	sub.Close()
+	// the subscription is removed once the server notices the disconnection
+	for i := 0; i < 1000 && s.Publish("news", []byte("hello")) != 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if n := s.Publish("news", []byte("hello")); n != 0 {
+		t.Errorf("error Publish result: %d", n)
+	}
+}
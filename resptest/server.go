@@ -0,0 +1,420 @@
+// Package resptest provides an in-memory server speaking RESP and
+// implementing a subset of the redis commands, so that client code can be
+// tested without a real redis server.
+//
+//	s := resptest.NewServer()
+//	defer s.Close()
+//	conn, err := resp.Dial("tcp", s.Addr())
+//
+// Strings, hashes, lists, sets, sorted sets, key expiry, MULTI/EXEC with
+// WATCH and pub/sub are supported, with the same replies as redis. Time is
+// read from a clock which can be set and moved forward, to test expiry
+// without sleeping. Blocking commands, streams, scripting and persistence
+// are not supported.
+package resptest
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+const numDatabases = 16
+
+const (
+	errWrongType   = "WRONGTYPE Operation against a key holding the wrong kind of value"
+	errNotInteger  = "ERR value is not an integer or out of range"
+	errNotFloat    = "ERR value is not a valid float"
+	errSyntax      = "ERR syntax error"
+	errNoSuchKey   = "ERR no such key"
+	errOutOfRange  = "ERR index out of range"
+	errOverflow    = "ERR increment or decrement would overflow"
+	errInvalidDB   = "ERR DB index is out of range"
+	errInvalidTime = "ERR invalid expire time in '%s' command"
+)
+
+// Server is an in-memory RESP server listening on a loopback address.
+type Server struct {
+	l   net.Listener
+	srv *resp.Server
+
+	mu       sync.Mutex
+	dbs      [numDatabases]*db
+	clients  map[*resp.ServerConn]*client
+	watchers map[watchKey]map[*client]struct{}
+	channels map[string]map[*client]struct{}
+	patterns map[string]map[*client]struct{}
+	now      time.Time
+	// flush holds the connections which were pushed messages while handling
+	// a command
+	flush map[*resp.ServerConn]struct{}
+}
+
+// NewServer starts a server on a loopback address chosen by the system. It
+// panics if no address is available.
+func NewServer() *Server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		if l, err = net.Listen("tcp6", "[::1]:0"); err != nil {
+			panic(fmt.Sprintf("resptest: failed to listen on a port: %v", err))
+		}
+	}
+	s := &Server{
+		l:        l,
+		clients:  make(map[*resp.ServerConn]*client),
+		watchers: make(map[watchKey]map[*client]struct{}),
+		channels: make(map[string]map[*client]struct{}),
+		patterns: make(map[string]map[*client]struct{}),
+		flush:    make(map[*resp.ServerConn]struct{}),
+	}
+	for i := range s.dbs {
+		s.dbs[i] = newDB()
+	}
+	s.srv = &resp.Server{Handler: s}
+	go s.srv.Serve(l)
+	return s
+}
+
+// Addr returns the address the server listens on, in the form host:port.
+func (s *Server) Addr() string {
+	return s.l.Addr().String()
+}
+
+// Close stops the server and closes the connections of its clients.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Now returns the time of the server clock, the current time unless it was
+// set with SetTime.
+func (s *Server) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clock()
+}
+
+// SetTime stops the server clock at t. Keys expire once the clock reaches
+// their expiry time.
+func (s *Server) SetTime(t time.Time) {
+	s.mu.Lock()
+	s.now = t
+	s.mu.Unlock()
+}
+
+// FastForward moves the server clock forward by d, stopping it first if it
+// was following the current time.
+func (s *Server) FastForward(d time.Duration) {
+	s.mu.Lock()
+	s.now = s.clock().Add(d)
+	s.mu.Unlock()
+}
+
+// FlushAll removes the keys of every database.
+func (s *Server) FlushAll() {
+	s.mu.Lock()
+	for i := range s.dbs {
+		s.flushDB(i)
+	}
+	s.mu.Unlock()
+}
+
+func (s *Server) clock() time.Time {
+	if s.now.IsZero() {
+		return time.Now()
+	}
+	return s.now
+}
+
+// command is an entry of the command table.
+type command struct {
+	fn func(s *Server, c *client, args [][]byte) *resp.Message
+	// arity is the number of arguments including the command name, or minus
+	// the minimum number of arguments when negative
+	arity int
+	flags int
+}
+
+const (
+	// flagPubSub allows the command in the pub/sub mode
+	flagPubSub = 1 << iota
+	// flagNoMulti forbids queuing the command in a transaction
+	flagNoMulti
+	// flagTx marks the commands handled while queuing a transaction
+	flagTx
+)
+
+var commands map[string]command
+
+func init() {
+	commands = make(map[string]command)
+	for _, table := range []map[string]command{
+		connectionCommands,
+		keyCommands,
+		stringCommands,
+		hashCommands,
+		listCommands,
+		setCommands,
+		zsetCommands,
+		pubsubCommands,
+		txCommands,
+	} {
+		for name, cmd := range table {
+			commands[name] = cmd
+		}
+	}
+}
+
+// ServeRESP implements resp.Handler.
+func (s *Server) ServeRESP(conn *resp.ServerConn, cmd *resp.Message) {
+	args, ok := arguments(cmd)
+	if !ok {
+		conn.WriteMessage(replyError("ERR Protocol error: expected an array of bulk strings"))
+		conn.Flush()
+		conn.Close()
+		return
+	}
+	if len(args) == 0 {
+		return
+	}
+
+	// replies are written holding the lock so that messages published to
+	// the client are not written before them
+	s.mu.Lock()
+	c := s.client(conn)
+	reply := s.process(c, args)
+	for _, m := range c.replies {
+		conn.WriteMessage(m)
+	}
+	c.replies = c.replies[:0]
+	if reply != nil {
+		conn.WriteMessage(reply)
+	}
+	flush := s.takeFlush()
+	s.mu.Unlock()
+
+	for _, conn := range flush {
+		conn.Flush()
+	}
+	if c.quit {
+		conn.Flush()
+		conn.Close()
+	}
+}
+
+// process runs a command, or queues it when in a transaction.
+func (s *Server) process(c *client, args [][]byte) *resp.Message {
+	name := strings.ToUpper(string(args[0]))
+	cmd, ok := commands[name]
+	if !ok {
+		c.multiErr = c.multiErr || c.multi
+		var buf []string
+		for _, arg := range args[1:] {
+			buf = append(buf, "'"+string(arg)+"' ")
+		}
+		return replyError(fmt.Sprintf("ERR unknown command '%s', with args beginning with: %s",
+			args[0], strings.Join(buf, "")))
+	}
+	if (cmd.arity > 0 && len(args) != cmd.arity) || (cmd.arity < 0 && len(args) < -cmd.arity) {
+		c.multiErr = c.multiErr || c.multi
+		return replyError(fmt.Sprintf("ERR wrong number of arguments for '%s' command",
+			strings.ToLower(name)))
+	}
+	if c.subscribed() && cmd.flags&flagPubSub == 0 {
+		return replyError(fmt.Sprintf("ERR Can't execute '%s': only (P|S)SUBSCRIBE / "+
+			"(P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context",
+			strings.ToLower(name)))
+	}
+	if c.multi && cmd.flags&flagTx == 0 {
+		if cmd.flags&flagNoMulti != 0 {
+			c.multiErr = true
+			return replyError("ERR Command not allowed inside a transaction")
+		}
+		c.queue = append(c.queue, args)
+		return resp.Str("QUEUED")
+	}
+	return cmd.fn(s, c, args)
+}
+
+func (s *Server) client(conn *resp.ServerConn) *client {
+	c, ok := s.clients[conn]
+	if ok {
+		return c
+	}
+	c = &client{conn: conn}
+	s.clients[conn] = c
+	go func() {
+		<-conn.Context().Done()
+		s.mu.Lock()
+		s.unwatch(c)
+		s.unsubscribeAll(c)
+		delete(s.clients, conn)
+		s.mu.Unlock()
+	}()
+	return c
+}
+
+// push writes a message to a client outside of the reply to its commands.
+func (s *Server) push(c *client, m *resp.Message) {
+	c.conn.WriteMessage(m)
+	s.flush[c.conn] = struct{}{}
+}
+
+// takeFlush returns the connections pushed messages since the last call,
+// to be flushed once the lock is released.
+func (s *Server) takeFlush() []*resp.ServerConn {
+	flush := make([]*resp.ServerConn, 0, len(s.flush))
+	for conn := range s.flush {
+		flush = append(flush, conn)
+		delete(s.flush, conn)
+	}
+	return flush
+}
+
+// client is the state of a connection.
+type client struct {
+	conn *resp.ServerConn
+	db   int
+	quit bool
+	// replies are the replies written before the one returned by the
+	// command
+	replies []*resp.Message
+
+	multi    bool
+	multiErr bool
+	queue    [][][]byte
+	watched  []watchKey
+	dirty    bool
+
+	channels map[string]struct{}
+	patterns map[string]struct{}
+}
+
+func (c *client) subscribed() bool {
+	return len(c.channels) > 0 || len(c.patterns) > 0
+}
+
+// arguments returns the arguments of a command, which must be an array of
+// bulk strings. They are copied, as the bytes of the command point into the
+// read buffer of the connection, which stored values must not keep alive.
+func arguments(cmd *resp.Message) ([][]byte, bool) {
+	if cmd.Type != resp.ArrayHeader || cmd.IsNil {
+		return nil, false
+	}
+	size := 0
+	for _, arg := range cmd.Array {
+		if arg == nil || arg.Type != resp.BulkHeader || arg.IsNil {
+			return nil, false
+		}
+		size += len(arg.Bytes)
+	}
+	buf := make([]byte, 0, size)
+	args := make([][]byte, len(cmd.Array))
+	for i, arg := range cmd.Array {
+		start := len(buf)
+		buf = append(buf, arg.Bytes...)
+		args[i] = buf[start:len(buf):len(buf)]
+	}
+	return args, true
+}
+
+var connectionCommands = map[string]command{
+	"PING":   {fn: (*Server).cmdPing, arity: -1, flags: flagPubSub},
+	"ECHO":   {fn: (*Server).cmdEcho, arity: 2},
+	"SELECT": {fn: (*Server).cmdSelect, arity: 2},
+	"QUIT":   {fn: (*Server).cmdQuit, arity: -1, flags: flagPubSub | flagTx},
+	"TIME":   {fn: (*Server).cmdTime, arity: 1},
+}
+
+func (s *Server) cmdPing(c *client, args [][]byte) *resp.Message {
+	if len(args) > 2 {
+		return replyError("ERR wrong number of arguments for 'ping' command")
+	}
+	if c.subscribed() {
+		payload := []byte{}
+		if len(args) == 2 {
+			payload = args[1]
+		}
+		return resp.Array(resp.Bulk([]byte("pong")), resp.Bulk(payload))
+	}
+	if len(args) == 2 {
+		return resp.Bulk(args[1])
+	}
+	return resp.Str("PONG")
+}
+
+func (s *Server) cmdEcho(c *client, args [][]byte) *resp.Message {
+	return resp.Bulk(args[1])
+}
+
+func (s *Server) cmdSelect(c *client, args [][]byte) *resp.Message {
+	i, ok := parseInt(args[1])
+	if !ok {
+		return replyError(errNotInteger)
+	}
+	if i < 0 || i >= numDatabases {
+		return replyError(errInvalidDB)
+	}
+	c.db = int(i)
+	return okReply()
+}
+
+func (s *Server) cmdQuit(c *client, args [][]byte) *resp.Message {
+	c.quit = true
+	return okReply()
+}
+
+func (s *Server) cmdTime(c *client, args [][]byte) *resp.Message {
+	now := s.clock()
+	return resp.Array(
+		resp.Bulk(formatInt(now.Unix())),
+		resp.Bulk(formatInt(int64(now.Nanosecond()/1000))),
+	)
+}
+
+func okReply() *resp.Message {
+	return resp.Str("OK")
+}
+
+func replyError(msg string) *resp.Message {
+	return resp.Err(errors.New(msg))
+}
+
+func bulks(items [][]byte) *resp.Message {
+	m := resp.Array()
+	for _, item := range items {
+		if item == nil {
+			m.Array = append(m.Array, resp.Nil())
+		} else {
+			m.Array = append(m.Array, resp.Bulk(item))
+		}
+	}
+	return m
+}
+
+// sortedKeys returns the keys of a set or hash in lexicographic order, redis
+// not guaranteeing any order.
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]struct{}:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string][]byte:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]float64:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,128 @@
+package resptest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// newTestClient starts a server and returns a client connected to it.
+func newTestClient(t *testing.T) (*Server, *resp.Conn) {
+	s := NewServer()
+	c, err := resp.Dial("tcp", s.Addr())
+	if err != nil {
+		s.Close()
+		t.Fatal(err)
+	}
+	return s, c
+}
+
+// expect sends a command and checks its reply, formatted as redis-cli does.
+func expect(t *testing.T, c *resp.Conn, expected string, name string, args ...interface{}) {
+	cmd := resp.Cmd(name, args...)
+	cmdline, _ := cmd.Strings()
+	reply, err := c.Do(cmd)
+	if err != nil {
+		t.Fatalf("error sending %q: %v", cmdline, err)
+	}
+	if reply.String() != expected {
+		t.Errorf("error reply to %q:\n%s\nexpected:\n%s", cmdline, reply, expected)
+	}
+}
+
+// lines joins the lines of a reply to an array.
+func lines(l ...string) string {
+	return strings.Join(l, "\n")
+}
+
+func TestServerConnection(t *testing.T) {
+	s, c := newTestClient(t)
+	defer s.Close()
+	defer c.Close()
+
+	expect(t, c, "PONG", "PING")
+	expect(t, c, `"hello"`, "PING", "hello")
+	expect(t, c, `"hello"`, "ECHO", "hello")
+	expect(t, c, "(error) ERR unknown command 'FOO', with args beginning with: 'a' 'b' ", "FOO", "a", "b")
+	expect(t, c, "(error) ERR wrong number of arguments for 'get' command", "get")
+	expect(t, c, "(error) ERR value is not an integer or out of range", "SELECT", "a")
+	expect(t, c, "(error) ERR DB index is out of range", "SELECT", 16)
+
+	expect(t, c, "OK", "SET", "a", "0")
+	expect(t, c, "OK", "SELECT", 1)
+	expect(t, c, "(nil)", "GET", "a")
+	expect(t, c, "OK", "SELECT", 0)
+	expect(t, c, `"0"`, "GET", "a")
+
+	expect(t, c, "OK", "QUIT")
+	if _, err := c.Do(resp.Cmd("PING")); err == nil {
+		t.Error("connection should be closed after QUIT")
+	}
+}
+
+func TestServerClock(t *testing.T) {
+	s, c := newTestClient(t)
+	defer s.Close()
+	defer c.Close()
+
+	now := time.Unix(1500000000, 250000000)
+	s.SetTime(now)
+	if !s.Now().Equal(now) {
+		t.Errorf("error server time: %v", s.Now())
+	}
+	expect(t, c, lines(`1) "1500000000"`, `2) "250000"`), "TIME")
+
+	expect(t, c, "OK", "SET", "a", "1", "EX", 10)
+	s.FastForward(9 * time.Second)
+	expect(t, c, "(integer) 1", "TTL", "a")
+	s.FastForward(time.Second)
+	expect(t, c, "(nil)", "GET", "a")
+	expect(t, c, "(integer) -2", "TTL", "a")
+}
+
+func TestServerFlushAll(t *testing.T) {
+	s, c := newTestClient(t)
+	defer s.Close()
+	defer c.Close()
+
+	expect(t, c, "OK", "SET", "a", "1")
+	s.FlushAll()
+	expect(t, c, "(integer) 0", "DBSIZE")
+}
+
+func TestServerClose(t *testing.T) {
+	s, c := newTestClient(t)
+	defer c.Close()
+
+	expect(t, c, "PONG", "PING")
+	s.Close()
+	if _, err := c.Do(resp.Cmd("PING")); err == nil {
+		t.Error("connection should be closed with the server")
+	}
+	if _, err := resp.Dial("tcp", s.Addr()); err == nil {
+		t.Error("server should not accept connections once closed")
+	}
+}
+
+func TestServerArgumentsCopied(t *testing.T) {
+	data := []byte("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$5\r\nvalue\r\n")
+	msgQ, _, err := resp.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args, ok := arguments(msgQ[0])
+	if !ok {
+		t.Fatal("error arguments")
+	}
+	for i := range data {
+		data[i] = 'x'
+	}
+	if string(args[0]) != "SET" || string(args[1]) != "k" || string(args[2]) != "value" {
+		t.Errorf("error arguments: %q", args)
+	}
+	if args[1] = append(args[1], '!'); string(args[2]) != "value" {
+		t.Errorf("arguments should not share their capacity: %q", args)
+	}
+}
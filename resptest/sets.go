@@ -0,0 +1,282 @@
+package resptest
+
+import (
+	"math/rand"
+	"strings"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// Members of sets are returned in lexicographic order, except by SPOP and
+// SRANDMEMBER which pick them at random.
+var setCommands = map[string]command{
+	"SADD":        {fn: (*Server).cmdSAdd, arity: -3},
+	"SREM":        {fn: (*Server).cmdSRem, arity: -3},
+	"SMEMBERS":    {fn: (*Server).cmdSMembers, arity: 2},
+	"SISMEMBER":   {fn: (*Server).cmdSIsMember, arity: 3},
+	"SMISMEMBER":  {fn: (*Server).cmdSMIsMember, arity: -3},
+	"SCARD":       {fn: (*Server).cmdSCard, arity: 2},
+	"SPOP":        {fn: (*Server).cmdSRandom, arity: -2},
+	"SRANDMEMBER": {fn: (*Server).cmdSRandom, arity: -2},
+	"SMOVE":       {fn: (*Server).cmdSMove, arity: 4},
+	"SINTER":      {fn: (*Server).cmdSetOp, arity: -2},
+	"SUNION":      {fn: (*Server).cmdSetOp, arity: -2},
+	"SDIFF":       {fn: (*Server).cmdSetOp, arity: -2},
+	"SINTERSTORE": {fn: (*Server).cmdSetOpStore, arity: -3},
+	"SUNIONSTORE": {fn: (*Server).cmdSetOpStore, arity: -3},
+	"SDIFFSTORE":  {fn: (*Server).cmdSetOpStore, arity: -3},
+	"SSCAN":       {fn: (*Server).cmdSScan, arity: -3},
+}
+
+func (s *Server) cmdSAdd(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookupOrCreate(c, args[1], typeSet)
+	if errReply != nil {
+		return errReply
+	}
+	var n int64
+	for _, member := range args[2:] {
+		if _, ok := v.set[string(member)]; !ok {
+			v.set[string(member)] = struct{}{}
+			n++
+		}
+	}
+	s.modified(c, args[1], v)
+	return resp.Int(n)
+}
+
+func (s *Server) cmdSRem(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeSet)
+	if errReply != nil || v == nil {
+		return intOrError(0, errReply)
+	}
+	var n int64
+	for _, member := range args[2:] {
+		if _, ok := v.set[string(member)]; ok {
+			delete(v.set, string(member))
+			n++
+		}
+	}
+	if n > 0 {
+		s.modified(c, args[1], v)
+	}
+	return resp.Int(n)
+}
+
+func (s *Server) cmdSMembers(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeSet)
+	if errReply != nil {
+		return errReply
+	}
+	if v == nil {
+		return bulks(nil)
+	}
+	return stringBulks(sortedKeys(v.set))
+}
+
+func (s *Server) cmdSIsMember(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeSet)
+	if errReply != nil || v == nil {
+		return intOrError(0, errReply)
+	}
+	if _, ok := v.set[string(args[2])]; ok {
+		return resp.Int(1)
+	}
+	return resp.Int(0)
+}
+
+func (s *Server) cmdSMIsMember(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeSet)
+	if errReply != nil {
+		return errReply
+	}
+	m := resp.Array()
+	for _, member := range args[2:] {
+		var n int64
+		if v != nil {
+			if _, ok := v.set[string(member)]; ok {
+				n = 1
+			}
+		}
+		m.Array = append(m.Array, resp.Int(n))
+	}
+	return m
+}
+
+func (s *Server) cmdSCard(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeSet)
+	if errReply != nil || v == nil {
+		return intOrError(0, errReply)
+	}
+	return resp.Int(int64(len(v.set)))
+}
+
+// cmdSRandom implements SPOP and SRANDMEMBER. A negative count given to
+// SRANDMEMBER allows returning the same member several times.
+func (s *Server) cmdSRandom(c *client, args [][]byte) *resp.Message {
+	pop := strings.ToUpper(string(args[0])) == "SPOP"
+	if len(args) > 3 {
+		return replyError(errSyntax)
+	}
+	count, hasCount := int64(1), len(args) == 3
+	if hasCount {
+		var ok bool
+		if count, ok = parseInt(args[2]); !ok || (pop && count < 0) {
+			return replyError("ERR value is out of range, must be positive")
+		}
+	}
+	v, errReply := s.lookup(c, args[1], typeSet)
+	if errReply != nil {
+		return errReply
+	}
+	if v == nil {
+		if hasCount {
+			return bulks(nil)
+		}
+		return resp.Nil()
+	}
+
+	members := sortedKeys(v.set)
+	var picked []string
+	if count < 0 {
+		for i := int64(0); i < -count; i++ {
+			picked = append(picked, members[rand.Intn(len(members))])
+		}
+	} else {
+		for _, i := range rand.Perm(len(members)) {
+			if int64(len(picked)) == count {
+				break
+			}
+			picked = append(picked, members[i])
+		}
+	}
+	if pop {
+		for _, member := range picked {
+			delete(v.set, member)
+		}
+		s.modified(c, args[1], v)
+	}
+	if !hasCount {
+		return resp.Bulk([]byte(picked[0]))
+	}
+	return stringBulks(picked)
+}
+
+func (s *Server) cmdSMove(c *client, args [][]byte) *resp.Message {
+	src, errReply := s.lookup(c, args[1], typeSet)
+	if errReply != nil {
+		return errReply
+	}
+	dst, errReply := s.lookup(c, args[2], typeSet)
+	if errReply != nil {
+		return errReply
+	}
+	member := string(args[3])
+	if src == nil {
+		return resp.Int(0)
+	}
+	if _, ok := src.set[member]; !ok {
+		return resp.Int(0)
+	}
+	if src == dst {
+		return resp.Int(1)
+	}
+	delete(src.set, member)
+	s.modified(c, args[1], src)
+	if dst == nil {
+		dst, _ = s.lookupOrCreate(c, args[2], typeSet)
+	}
+	dst.set[member] = struct{}{}
+	s.modified(c, args[2], dst)
+	return resp.Int(1)
+}
+
+// setOp computes the intersection, union or difference of the sets stored
+// at keys, missing keys being empty sets.
+func (s *Server) setOp(c *client, op string, keys [][]byte) (map[string]struct{}, *resp.Message) {
+	sets := make([]*value, len(keys))
+	for i, key := range keys {
+		v, errReply := s.lookup(c, key, typeSet)
+		if errReply != nil {
+			return nil, errReply
+		}
+		sets[i] = v
+	}
+	result := make(map[string]struct{})
+	if sets[0] != nil {
+		for member := range sets[0].set {
+			result[member] = struct{}{}
+		}
+	}
+	for _, v := range sets[1:] {
+		switch op {
+		case "INTER":
+			for member := range result {
+				if v == nil {
+					delete(result, member)
+				} else if _, ok := v.set[member]; !ok {
+					delete(result, member)
+				}
+			}
+		case "UNION":
+			if v != nil {
+				for member := range v.set {
+					result[member] = struct{}{}
+				}
+			}
+		case "DIFF":
+			if v != nil {
+				for member := range v.set {
+					delete(result, member)
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// cmdSetOp implements SINTER, SUNION and SDIFF.
+func (s *Server) cmdSetOp(c *client, args [][]byte) *resp.Message {
+	op := strings.ToUpper(string(args[0]))[1:]
+	result, errReply := s.setOp(c, op, args[1:])
+	if errReply != nil {
+		return errReply
+	}
+	return stringBulks(sortedKeys(result))
+}
+
+// cmdSetOpStore implements SINTERSTORE, SUNIONSTORE and SDIFFSTORE.
+func (s *Server) cmdSetOpStore(c *client, args [][]byte) *resp.Message {
+	name := strings.ToUpper(string(args[0]))
+	op := strings.TrimSuffix(name[1:], "STORE")
+	result, errReply := s.setOp(c, op, args[2:])
+	if errReply != nil {
+		return errReply
+	}
+	s.del(c, args[1])
+	if len(result) > 0 {
+		s.set(c, args[1], &value{kind: typeSet, set: result})
+	}
+	return resp.Int(int64(len(result)))
+}
+
+func (s *Server) cmdSScan(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeSet)
+	if errReply != nil {
+		return errReply
+	}
+	var members []string
+	if v != nil {
+		members = sortedKeys(v.set)
+	}
+	return scanElements(args[2:], members, func(member string) [][]byte {
+		return [][]byte{[]byte(member)}
+	})
+}
+
+func stringBulks(items []string) *resp.Message {
+	m := resp.Array()
+	for _, item := range items {
+		m.Array = append(m.Array, resp.Bulk([]byte(item)))
+	}
+	return m
+}
@@ -0,0 +1,43 @@
+package resptest
+
+import (
+	"testing"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+func TestSets(t *testing.T) {
+	s, c := newTestClient(t)
+	defer s.Close()
+	defer c.Close()
+
+	expect(t, c, "(integer) 3", "SADD", "s1", "c", "a", "b", "a")
+	expect(t, c, "(integer) 2", "SADD", "s2", "b", "d")
+	expect(t, c, lines(`1) "a"`, `2) "b"`, `3) "c"`), "SMEMBERS", "s1")
+	expect(t, c, "(integer) 3", "SCARD", "s1")
+	expect(t, c, "(integer) 1", "SISMEMBER", "s1", "a")
+	expect(t, c, lines(`1) (integer) 1`, `2) (integer) 0`), "SMISMEMBER", "s1", "a", "d")
+	expect(t, c, lines(`1) "b"`), "SINTER", "s1", "s2")
+	expect(t, c, lines(`1) "a"`, `2) "b"`, `3) "c"`, `4) "d"`), "SUNION", "s1", "s2")
+	expect(t, c, lines(`1) "a"`, `2) "c"`), "SDIFF", "s1", "s2")
+	expect(t, c, "(integer) 2", "SDIFFSTORE", "s3", "s1", "s2")
+	expect(t, c, "(integer) 1", "SMOVE", "s3", "s2", "a")
+	expect(t, c, lines(`1) "a"`, `2) "b"`, `3) "d"`), "SMEMBERS", "s2")
+	expect(t, c, "(integer) 1", "SREM", "s3", "c")
+	expect(t, c, "(integer) 0", "EXISTS", "s3")
+	expect(t, c, lines(`1) "0"`, `2) 1) "a"`, `   2) "b"`, `   3) "c"`), "SSCAN", "s1", 0)
+
+	reply, err := c.Do(resp.Cmd("SRANDMEMBER", "s1", -5))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(reply.Array) != 5 {
+		t.Errorf("error SRANDMEMBER reply: %v", reply)
+	}
+	reply, err = c.Do(resp.Cmd("SPOP", "s1", 2))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(reply.Array) != 2 {
+		t.Errorf("error SPOP reply: %v", reply)
+	}
+	expect(t, c, "(integer) 1", "SCARD", "s1")
+}
@@ -0,0 +1,327 @@
+package resptest
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+var stringCommands = map[string]command{
+	"GET":         {fn: (*Server).cmdGet, arity: 2},
+	"SET":         {fn: (*Server).cmdSet, arity: -3},
+	"SETNX":       {fn: (*Server).cmdSetNX, arity: 3},
+	"SETEX":       {fn: (*Server).cmdSetEX, arity: 4},
+	"PSETEX":      {fn: (*Server).cmdSetEX, arity: 4},
+	"GETSET":      {fn: (*Server).cmdGetSet, arity: 3},
+	"GETDEL":      {fn: (*Server).cmdGetDel, arity: 2},
+	"MGET":        {fn: (*Server).cmdMGet, arity: -2},
+	"MSET":        {fn: (*Server).cmdMSet, arity: -3},
+	"MSETNX":      {fn: (*Server).cmdMSet, arity: -3},
+	"INCR":        {fn: (*Server).cmdIncr, arity: 2},
+	"DECR":        {fn: (*Server).cmdIncr, arity: 2},
+	"INCRBY":      {fn: (*Server).cmdIncr, arity: 3},
+	"DECRBY":      {fn: (*Server).cmdIncr, arity: 3},
+	"INCRBYFLOAT": {fn: (*Server).cmdIncrByFloat, arity: 3},
+	"APPEND":      {fn: (*Server).cmdAppend, arity: 3},
+	"STRLEN":      {fn: (*Server).cmdStrlen, arity: 2},
+	"GETRANGE":    {fn: (*Server).cmdGetRange, arity: 4},
+	"SETRANGE":    {fn: (*Server).cmdSetRange, arity: 4},
+}
+
+func (s *Server) cmdGet(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeString)
+	if errReply != nil {
+		return errReply
+	}
+	if v == nil {
+		return resp.Nil()
+	}
+	return resp.Bulk(v.str)
+}
+
+// cmdSet implements SET with its NX, XX, GET, KEEPTTL, EX, PX, EXAT and PXAT
+// options.
+func (s *Server) cmdSet(c *client, args [][]byte) *resp.Message {
+	var nx, xx, get, keepTTL bool
+	var expire time.Time
+	for i := 3; i < len(args); i++ {
+		opt := strings.ToUpper(string(args[i]))
+		switch opt {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "GET":
+			get = true
+		case "KEEPTTL":
+			keepTTL = true
+		case "EX", "PX", "EXAT", "PXAT":
+			if i+1 >= len(args) || !expire.IsZero() {
+				return replyError(errSyntax)
+			}
+			i++
+			n, ok := parseInt(args[i])
+			if !ok {
+				return replyError(errNotInteger)
+			}
+			unit := time.Second
+			if opt[0] == 'P' {
+				unit = time.Millisecond
+			}
+			if n <= 0 || n > math.MaxInt64/int64(unit) {
+				return replyError("ERR invalid expire time in 'set' command")
+			}
+			d := time.Duration(n) * unit
+			expire = s.clock().Add(d)
+			if strings.HasSuffix(opt, "AT") {
+				expire = time.Unix(0, int64(d))
+			}
+		default:
+			return replyError(errSyntax)
+		}
+	}
+	if (nx && xx) || (keepTTL && !expire.IsZero()) {
+		return replyError(errSyntax)
+	}
+
+	old := s.get(c, args[1])
+	reply := okReply()
+	if get {
+		if old != nil && old.kind != typeString {
+			return replyError(errWrongType)
+		}
+		reply = resp.Nil()
+		if old != nil {
+			reply = resp.Bulk(old.str)
+		}
+	}
+	if (nx && old != nil) || (xx && old == nil) {
+		if get {
+			return reply
+		}
+		return resp.Nil()
+	}
+	v := &value{kind: typeString, str: args[2], expire: expire}
+	if keepTTL && old != nil {
+		v.expire = old.expire
+	}
+	s.set(c, args[1], v)
+	return reply
+}
+
+func (s *Server) cmdSetNX(c *client, args [][]byte) *resp.Message {
+	if s.get(c, args[1]) != nil {
+		return resp.Int(0)
+	}
+	s.set(c, args[1], &value{kind: typeString, str: args[2]})
+	return resp.Int(1)
+}
+
+func (s *Server) cmdSetEX(c *client, args [][]byte) *resp.Message {
+	name := strings.ToLower(string(args[0]))
+	n, ok := parseInt(args[2])
+	if !ok {
+		return replyError(errNotInteger)
+	}
+	unit := time.Second
+	if name == "psetex" {
+		unit = time.Millisecond
+	}
+	if n <= 0 || n > math.MaxInt64/int64(unit) {
+		return replyError("ERR invalid expire time in '" + name + "' command")
+	}
+	v := &value{kind: typeString, str: args[3], expire: s.clock().Add(time.Duration(n) * unit)}
+	s.set(c, args[1], v)
+	return okReply()
+}
+
+func (s *Server) cmdGetSet(c *client, args [][]byte) *resp.Message {
+	reply := s.cmdGet(c, args[:2])
+	if reply.Type != resp.ErrorHeader {
+		s.set(c, args[1], &value{kind: typeString, str: args[2]})
+	}
+	return reply
+}
+
+func (s *Server) cmdGetDel(c *client, args [][]byte) *resp.Message {
+	reply := s.cmdGet(c, args)
+	if reply.Type == resp.BulkHeader && !reply.IsNil {
+		s.del(c, args[1])
+	}
+	return reply
+}
+
+func (s *Server) cmdMGet(c *client, args [][]byte) *resp.Message {
+	values := make([][]byte, len(args)-1)
+	for i, key := range args[1:] {
+		if v := s.get(c, key); v != nil && v.kind == typeString {
+			values[i] = v.str
+		}
+	}
+	return bulks(values)
+}
+
+func (s *Server) cmdMSet(c *client, args [][]byte) *resp.Message {
+	name := strings.ToLower(string(args[0]))
+	if len(args)%2 != 1 {
+		return replyError("ERR wrong number of arguments for '" + name + "' command")
+	}
+	nx := name == "msetnx"
+	if nx {
+		for i := 1; i < len(args); i += 2 {
+			if s.get(c, args[i]) != nil {
+				return resp.Int(0)
+			}
+		}
+	}
+	for i := 1; i < len(args); i += 2 {
+		s.set(c, args[i], &value{kind: typeString, str: args[i+1]})
+	}
+	if nx {
+		return resp.Int(1)
+	}
+	return okReply()
+}
+
+// cmdIncr implements INCR, DECR, INCRBY and DECRBY.
+func (s *Server) cmdIncr(c *client, args [][]byte) *resp.Message {
+	name := strings.ToUpper(string(args[0]))
+	delta := int64(1)
+	if len(args) == 3 {
+		var ok bool
+		if delta, ok = parseInt(args[2]); !ok {
+			return replyError(errNotInteger)
+		}
+	}
+	if name[0] == 'D' {
+		if delta == math.MinInt64 {
+			return replyError("ERR decrement would overflow")
+		}
+		delta = -delta
+	}
+
+	v, errReply := s.lookup(c, args[1], typeString)
+	if errReply != nil {
+		return errReply
+	}
+	var n int64
+	if v != nil {
+		var ok bool
+		if n, ok = parseInt(v.str); !ok {
+			return replyError(errNotInteger)
+		}
+	}
+	if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+		return replyError(errOverflow)
+	}
+	n += delta
+	s.setString(c, args[1], v, formatInt(n))
+	return resp.Int(n)
+}
+
+func (s *Server) cmdIncrByFloat(c *client, args [][]byte) *resp.Message {
+	delta, ok := parseFloat(args[2])
+	if !ok {
+		return replyError(errNotFloat)
+	}
+	v, errReply := s.lookup(c, args[1], typeString)
+	if errReply != nil {
+		return errReply
+	}
+	var f float64
+	if v != nil {
+		if f, ok = parseFloat(v.str); !ok {
+			return replyError(errNotFloat)
+		}
+	}
+	f += delta
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return replyError("ERR increment would produce NaN or Infinity")
+	}
+	b := []byte(strconv.FormatFloat(f, 'f', -1, 64))
+	s.setString(c, args[1], v, b)
+	return resp.Bulk(b)
+}
+
+// setString changes the value of a string key, v being its current value,
+// keeping its expiry.
+func (s *Server) setString(c *client, key []byte, v *value, b []byte) {
+	if v == nil {
+		v = &value{kind: typeString}
+		s.dbs[c.db].keys[string(key)] = v
+	}
+	v.str = b
+	s.touch(c, key)
+}
+
+func (s *Server) cmdAppend(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeString)
+	if errReply != nil {
+		return errReply
+	}
+	var b []byte
+	if v != nil {
+		b = append(b, v.str...)
+	}
+	b = append(b, args[2]...)
+	s.setString(c, args[1], v, b)
+	return resp.Int(int64(len(b)))
+}
+
+func (s *Server) cmdStrlen(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeString)
+	if errReply != nil {
+		return errReply
+	}
+	if v == nil {
+		return resp.Int(0)
+	}
+	return resp.Int(int64(len(v.str)))
+}
+
+func (s *Server) cmdGetRange(c *client, args [][]byte) *resp.Message {
+	start, ok1 := parseInt(args[2])
+	stop, ok2 := parseInt(args[3])
+	if !ok1 || !ok2 {
+		return replyError(errNotInteger)
+	}
+	v, errReply := s.lookup(c, args[1], typeString)
+	if errReply != nil {
+		return errReply
+	}
+	if v == nil {
+		return resp.Bulk([]byte{})
+	}
+	from, to := listRange(start, stop, len(v.str))
+	return resp.Bulk(v.str[from:to])
+}
+
+func (s *Server) cmdSetRange(c *client, args [][]byte) *resp.Message {
+	offset, ok := parseInt(args[2])
+	if !ok {
+		return replyError(errNotInteger)
+	}
+	if offset < 0 || offset+int64(len(args[3])) > 512*1024*1024 {
+		return replyError("ERR offset is out of range")
+	}
+	v, errReply := s.lookup(c, args[1], typeString)
+	if errReply != nil {
+		return errReply
+	}
+	var b []byte
+	if v != nil {
+		b = append(b, v.str...)
+	}
+	if len(args[3]) == 0 {
+		return resp.Int(int64(len(b)))
+	}
+	if end := int(offset) + len(args[3]); end > len(b) {
+		b = append(b, make([]byte, end-len(b))...)
+	}
+	copy(b[offset:], args[3])
+	s.setString(c, args[1], v, b)
+	return resp.Int(int64(len(b)))
+}
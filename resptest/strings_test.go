@@ -0,0 +1,55 @@
+package resptest
+
+import (
+	"testing"
+)
+
+func TestStrings(t *testing.T) {
+	s, c := newTestClient(t)
+	defer s.Close()
+	defer c.Close()
+
+	expect(t, c, "(nil)", "GET", "a")
+	expect(t, c, "OK", "SET", "a", "hello")
+	expect(t, c, `"hello"`, "GET", "a")
+	expect(t, c, "(nil)", "SET", "a", "x", "NX")
+	expect(t, c, "(nil)", "SET", "b", "x", "XX")
+	expect(t, c, `"hello"`, "SET", "a", "world", "GET")
+	expect(t, c, "(integer) 0", "SETNX", "a", "x")
+	expect(t, c, "(integer) 10", "APPEND", "a", "!!!!!")
+	expect(t, c, "(integer) 10", "STRLEN", "a")
+	expect(t, c, `"world"`, "GETRANGE", "a", 0, 4)
+	expect(t, c, `"!!"`, "GETRANGE", "a", -2, -1)
+	expect(t, c, "(integer) 10", "SETRANGE", "a", 5, "?")
+	expect(t, c, `"world?!!!!"`, "GETSET", "a", "x")
+	expect(t, c, `"x"`, "GETDEL", "a")
+	expect(t, c, "(nil)", "GET", "a")
+	expect(t, c, "(error) ERR syntax error", "SET", "a", "x", "NX", "XX")
+	expect(t, c, "(error) ERR invalid expire time in 'set' command", "SET", "a", "x", "EX", 0)
+
+	expect(t, c, "OK", "MSET", "a", "1", "b", "2")
+	expect(t, c, lines(`1) "1"`, `2) (nil)`, `3) "2"`), "MGET", "a", "nope", "b")
+	expect(t, c, "(integer) 0", "MSETNX", "b", "3", "c", "3")
+	expect(t, c, "(error) ERR wrong number of arguments for 'mset' command", "MSET", "a", "1", "b")
+}
+
+func TestCounters(t *testing.T) {
+	s, c := newTestClient(t)
+	defer s.Close()
+	defer c.Close()
+
+	expect(t, c, "(integer) 1", "INCR", "n")
+	expect(t, c, "(integer) 11", "INCRBY", "n", 10)
+	expect(t, c, "(integer) 10", "DECR", "n")
+	expect(t, c, "(integer) -5", "DECRBY", "n", 15)
+	expect(t, c, `"-4.5"`, "INCRBYFLOAT", "n", "0.5")
+	expect(t, c, "(error) ERR value is not an integer or out of range", "INCR", "n")
+	expect(t, c, "(error) ERR value is not a valid float", "INCRBYFLOAT", "n", "abc")
+
+	expect(t, c, "OK", "SET", "max", "9223372036854775807")
+	expect(t, c, "(error) ERR increment or decrement would overflow", "INCR", "max")
+
+	expect(t, c, "(integer) 1", "RPUSH", "list", "a")
+	expect(t, c, "(error) WRONGTYPE Operation against a key holding the wrong kind of value", "INCR", "list")
+	expect(t, c, "(error) WRONGTYPE Operation against a key holding the wrong kind of value", "GET", "list")
+}
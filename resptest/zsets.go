@@ -0,0 +1,562 @@
+package resptest
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+var zsetCommands = map[string]command{
+	"ZADD":             {fn: (*Server).cmdZAdd, arity: -4},
+	"ZINCRBY":          {fn: (*Server).cmdZIncrBy, arity: 4},
+	"ZSCORE":           {fn: (*Server).cmdZScore, arity: 3},
+	"ZMSCORE":          {fn: (*Server).cmdZMScore, arity: -3},
+	"ZREM":             {fn: (*Server).cmdZRem, arity: -3},
+	"ZCARD":            {fn: (*Server).cmdZCard, arity: 2},
+	"ZCOUNT":           {fn: (*Server).cmdZCount, arity: 4},
+	"ZRANK":            {fn: (*Server).cmdZRank, arity: 3},
+	"ZREVRANK":         {fn: (*Server).cmdZRank, arity: 3},
+	"ZRANGE":           {fn: (*Server).cmdZRange, arity: -4},
+	"ZREVRANGE":        {fn: (*Server).cmdZRange, arity: -4},
+	"ZRANGEBYSCORE":    {fn: (*Server).cmdZRange, arity: -4},
+	"ZREVRANGEBYSCORE": {fn: (*Server).cmdZRange, arity: -4},
+	"ZRANGEBYLEX":      {fn: (*Server).cmdZRange, arity: -4},
+	"ZREVRANGEBYLEX":   {fn: (*Server).cmdZRange, arity: -4},
+	"ZPOPMIN":          {fn: (*Server).cmdZPop, arity: -2},
+	"ZPOPMAX":          {fn: (*Server).cmdZPop, arity: -2},
+	"ZREMRANGEBYRANK":  {fn: (*Server).cmdZRemRange, arity: 4},
+	"ZREMRANGEBYSCORE": {fn: (*Server).cmdZRemRange, arity: 4},
+	"ZREMRANGEBYLEX":   {fn: (*Server).cmdZRemRange, arity: 4},
+	"ZSCAN":            {fn: (*Server).cmdZScan, arity: -3},
+}
+
+type zmember struct {
+	member string
+	score  float64
+}
+
+// sortedMembers returns the members of a sorted set ordered by score, then
+// lexicographically.
+func sortedMembers(v *value) []zmember {
+	if v == nil {
+		return nil
+	}
+	members := make([]zmember, 0, len(v.zset))
+	for member, score := range v.zset {
+		members = append(members, zmember{member, score})
+	}
+	sort.Sort(byScore(members))
+	return members
+}
+
+type byScore []zmember
+
+func (s byScore) Len() int      { return len(s) }
+func (s byScore) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byScore) Less(i, j int) bool {
+	if s[i].score != s[j].score {
+		return s[i].score < s[j].score
+	}
+	return s[i].member < s[j].member
+}
+
+func (s *Server) cmdZAdd(c *client, args [][]byte) *resp.Message {
+	var nx, xx, gt, lt, ch, incr bool
+	i := 2
+options:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(string(args[i])) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "GT":
+			gt = true
+		case "LT":
+			lt = true
+		case "CH":
+			ch = true
+		case "INCR":
+			incr = true
+		default:
+			break options
+		}
+	}
+	pairs := args[i:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return replyError(errSyntax)
+	}
+	if nx && xx {
+		return replyError("ERR XX and NX options at the same time are not compatible")
+	}
+	if (gt && lt) || (gt && nx) || (lt && nx) {
+		return replyError("ERR GT, LT, and/or NX options at the same time are not compatible")
+	}
+	if incr && len(pairs) > 2 {
+		return replyError("ERR INCR option supports a single increment-element pair")
+	}
+	scores := make([]float64, len(pairs)/2)
+	for j := range scores {
+		var ok bool
+		if scores[j], ok = parseFloat(pairs[2*j]); !ok {
+			return replyError(errNotFloat)
+		}
+	}
+
+	v, errReply := s.lookup(c, args[1], typeZSet)
+	if errReply != nil {
+		return errReply
+	}
+	if v == nil {
+		if xx {
+			if incr {
+				return resp.Nil()
+			}
+			return resp.Int(0)
+		}
+		v, _ = s.lookupOrCreate(c, args[1], typeZSet)
+	}
+
+	var added, changed int64
+	var result *resp.Message
+	for j, score := range scores {
+		member := string(pairs[2*j+1])
+		old, exists := v.zset[member]
+		if (nx && exists) || (xx && !exists) {
+			result = resp.Nil()
+			continue
+		}
+		if incr && exists {
+			score += old
+			if math.IsNaN(score) {
+				s.modified(c, args[1], v)
+				return replyError("ERR resulting score is not a number (NaN)")
+			}
+		}
+		if exists && ((gt && score <= old) || (lt && score >= old)) {
+			result = resp.Nil()
+			continue
+		}
+		v.zset[member] = score
+		result = resp.Bulk(formatFloat(score))
+		if !exists {
+			added++
+		} else if score != old {
+			changed++
+		}
+	}
+	s.modified(c, args[1], v)
+	if incr {
+		return result
+	}
+	if ch {
+		return resp.Int(added + changed)
+	}
+	return resp.Int(added)
+}
+
+func (s *Server) cmdZIncrBy(c *client, args [][]byte) *resp.Message {
+	return s.cmdZAdd(c, [][]byte{args[0], args[1], []byte("INCR"), args[2], args[3]})
+}
+
+func (s *Server) cmdZScore(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeZSet)
+	if errReply != nil {
+		return errReply
+	}
+	if v == nil {
+		return resp.Nil()
+	}
+	score, ok := v.zset[string(args[2])]
+	if !ok {
+		return resp.Nil()
+	}
+	return resp.Bulk(formatFloat(score))
+}
+
+func (s *Server) cmdZMScore(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeZSet)
+	if errReply != nil {
+		return errReply
+	}
+	scores := make([][]byte, len(args)-2)
+	if v != nil {
+		for i, member := range args[2:] {
+			if score, ok := v.zset[string(member)]; ok {
+				scores[i] = formatFloat(score)
+			}
+		}
+	}
+	return bulks(scores)
+}
+
+func (s *Server) cmdZRem(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeZSet)
+	if errReply != nil || v == nil {
+		return intOrError(0, errReply)
+	}
+	var n int64
+	for _, member := range args[2:] {
+		if _, ok := v.zset[string(member)]; ok {
+			delete(v.zset, string(member))
+			n++
+		}
+	}
+	if n > 0 {
+		s.modified(c, args[1], v)
+	}
+	return resp.Int(n)
+}
+
+func (s *Server) cmdZCard(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeZSet)
+	if errReply != nil || v == nil {
+		return intOrError(0, errReply)
+	}
+	return resp.Int(int64(len(v.zset)))
+}
+
+func (s *Server) cmdZCount(c *client, args [][]byte) *resp.Message {
+	min, max, errReply := parseScoreRange(args[2], args[3])
+	if errReply != nil {
+		return errReply
+	}
+	v, errReply := s.lookup(c, args[1], typeZSet)
+	if errReply != nil || v == nil {
+		return intOrError(0, errReply)
+	}
+	var n int64
+	for _, score := range v.zset {
+		if min.below(score) && max.above(score) {
+			n++
+		}
+	}
+	return resp.Int(n)
+}
+
+// cmdZRank implements ZRANK and ZREVRANK.
+func (s *Server) cmdZRank(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeZSet)
+	if errReply != nil {
+		return errReply
+	}
+	members := sortedMembers(v)
+	for i, m := range members {
+		if m.member != string(args[2]) {
+			continue
+		}
+		if strings.ToUpper(string(args[0])) == "ZREVRANK" {
+			i = len(members) - 1 - i
+		}
+		return resp.Int(int64(i))
+	}
+	return resp.Nil()
+}
+
+// zrangeSpec describes a range of a sorted set.
+type zrangeSpec struct {
+	by         string // "", "SCORE" or "LEX"
+	rev        bool
+	withScores bool
+	offset     int64
+	count      int64
+}
+
+// cmdZRange implements ZRANGE, ZREVRANGE, ZRANGEBYSCORE, ZREVRANGEBYSCORE,
+// ZRANGEBYLEX and ZREVRANGEBYLEX.
+func (s *Server) cmdZRange(c *client, args [][]byte) *resp.Message {
+	name := strings.ToUpper(string(args[0]))
+	spec := zrangeSpec{count: -1}
+	switch name {
+	case "ZREVRANGE":
+		spec.rev = true
+	case "ZRANGEBYSCORE":
+		spec.by = "SCORE"
+	case "ZREVRANGEBYSCORE":
+		spec.by, spec.rev = "SCORE", true
+	case "ZRANGEBYLEX":
+		spec.by = "LEX"
+	case "ZREVRANGEBYLEX":
+		spec.by, spec.rev = "LEX", true
+	}
+	hasLimit := false
+	for i := 4; i < len(args); i++ {
+		switch opt := strings.ToUpper(string(args[i])); {
+		case opt == "WITHSCORES" && !strings.HasSuffix(name, "LEX"):
+			spec.withScores = true
+		case opt == "LIMIT" && name != "ZREVRANGE":
+			if i+2 >= len(args) {
+				return replyError(errSyntax)
+			}
+			var ok1, ok2 bool
+			spec.offset, ok1 = parseInt(args[i+1])
+			spec.count, ok2 = parseInt(args[i+2])
+			if !ok1 || !ok2 {
+				return replyError(errNotInteger)
+			}
+			hasLimit = true
+			i += 2
+		case (opt == "BYSCORE" || opt == "BYLEX") && name == "ZRANGE" && spec.by == "":
+			spec.by = opt[2:]
+		case opt == "REV" && name == "ZRANGE":
+			spec.rev = true
+		default:
+			return replyError(errSyntax)
+		}
+	}
+	if hasLimit && spec.by == "" {
+		return replyError("ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+	}
+	if spec.withScores && spec.by == "LEX" {
+		return replyError("ERR syntax error, WITHSCORES not supported in combination with BYLEX")
+	}
+
+	start, stop := args[2], args[3]
+	if spec.rev && spec.by != "" {
+		// reversed ranges by score or lex take the max first
+		start, stop = stop, start
+	}
+	members, errReply := s.zrange(c, args[1], start, stop, spec)
+	if errReply != nil {
+		return errReply
+	}
+	return zmembersReply(members, spec.withScores)
+}
+
+// zrange returns the members of the range of a sorted set. For ranges by
+// score or by lex, start and stop are the min and the max.
+func (s *Server) zrange(c *client, key, start, stop []byte, spec zrangeSpec) ([]zmember, *resp.Message) {
+	var selected []zmember
+	switch spec.by {
+	case "":
+		from, ok1 := parseInt(start)
+		to, ok2 := parseInt(stop)
+		if !ok1 || !ok2 {
+			return nil, replyError(errNotInteger)
+		}
+		v, errReply := s.lookup(c, key, typeZSet)
+		if errReply != nil {
+			return nil, errReply
+		}
+		members := sortedMembers(v)
+		if spec.rev {
+			reverse(members)
+		}
+		i, j := listRange(from, to, len(members))
+		return members[i:j], nil
+	case "SCORE":
+		min, max, errReply := parseScoreRange(start, stop)
+		if errReply != nil {
+			return nil, errReply
+		}
+		v, errReply := s.lookup(c, key, typeZSet)
+		if errReply != nil {
+			return nil, errReply
+		}
+		for _, m := range sortedMembers(v) {
+			if min.below(m.score) && max.above(m.score) {
+				selected = append(selected, m)
+			}
+		}
+	case "LEX":
+		min, max, errReply := parseLexRange(start, stop)
+		if errReply != nil {
+			return nil, errReply
+		}
+		v, errReply := s.lookup(c, key, typeZSet)
+		if errReply != nil {
+			return nil, errReply
+		}
+		for _, m := range sortedMembers(v) {
+			if min.below(m.member) && max.above(m.member) {
+				selected = append(selected, m)
+			}
+		}
+	}
+	if spec.rev {
+		reverse(selected)
+	}
+	if spec.offset < 0 || spec.offset >= int64(len(selected)) {
+		return nil, nil
+	}
+	selected = selected[spec.offset:]
+	if spec.count >= 0 && spec.count < int64(len(selected)) {
+		selected = selected[:spec.count]
+	}
+	return selected, nil
+}
+
+// cmdZPop implements ZPOPMIN and ZPOPMAX.
+func (s *Server) cmdZPop(c *client, args [][]byte) *resp.Message {
+	if len(args) > 3 {
+		return replyError(errSyntax)
+	}
+	count := int64(1)
+	if len(args) == 3 {
+		var ok bool
+		if count, ok = parseInt(args[2]); !ok || count < 0 {
+			return replyError("ERR value is out of range, must be positive")
+		}
+	}
+	v, errReply := s.lookup(c, args[1], typeZSet)
+	if errReply != nil {
+		return errReply
+	}
+	members := sortedMembers(v)
+	if strings.ToUpper(string(args[0])) == "ZPOPMAX" {
+		reverse(members)
+	}
+	if count < int64(len(members)) {
+		members = members[:count]
+	}
+	for _, m := range members {
+		delete(v.zset, m.member)
+	}
+	if len(members) > 0 {
+		s.modified(c, args[1], v)
+	}
+	return zmembersReply(members, true)
+}
+
+// cmdZRemRange implements ZREMRANGEBYRANK, ZREMRANGEBYSCORE and
+// ZREMRANGEBYLEX.
+func (s *Server) cmdZRemRange(c *client, args [][]byte) *resp.Message {
+	spec := zrangeSpec{count: -1}
+	switch strings.ToUpper(string(args[0])) {
+	case "ZREMRANGEBYSCORE":
+		spec.by = "SCORE"
+	case "ZREMRANGEBYLEX":
+		spec.by = "LEX"
+	}
+	members, errReply := s.zrange(c, args[1], args[2], args[3], spec)
+	if errReply != nil {
+		return errReply
+	}
+	if len(members) == 0 {
+		return resp.Int(0)
+	}
+	v := s.get(c, args[1])
+	for _, m := range members {
+		delete(v.zset, m.member)
+	}
+	s.modified(c, args[1], v)
+	return resp.Int(int64(len(members)))
+}
+
+func (s *Server) cmdZScan(c *client, args [][]byte) *resp.Message {
+	v, errReply := s.lookup(c, args[1], typeZSet)
+	if errReply != nil {
+		return errReply
+	}
+	var members []string
+	if v != nil {
+		members = sortedKeys(v.zset)
+	}
+	return scanElements(args[2:], members, func(member string) [][]byte {
+		return [][]byte{[]byte(member), formatFloat(v.zset[member])}
+	})
+}
+
+func zmembersReply(members []zmember, withScores bool) *resp.Message {
+	var items [][]byte
+	for _, m := range members {
+		items = append(items, []byte(m.member))
+		if withScores {
+			items = append(items, formatFloat(m.score))
+		}
+	}
+	return bulks(items)
+}
+
+func reverse(members []zmember) {
+	for i, j := 0, len(members)-1; i < j; i, j = i+1, j-1 {
+		members[i], members[j] = members[j], members[i]
+	}
+}
+
+// scoreBound is a bound of a range of scores, "(" making it exclusive.
+type scoreBound struct {
+	score     float64
+	exclusive bool
+}
+
+func (b scoreBound) below(score float64) bool {
+	return b.score < score || (!b.exclusive && b.score == score)
+}
+
+func (b scoreBound) above(score float64) bool {
+	return b.score > score || (!b.exclusive && b.score == score)
+}
+
+func parseScoreRange(min, max []byte) (scoreBound, scoreBound, *resp.Message) {
+	lo, ok1 := parseScoreBound(min)
+	hi, ok2 := parseScoreBound(max)
+	if !ok1 || !ok2 {
+		return lo, hi, replyError("ERR min or max is not a float")
+	}
+	return lo, hi, nil
+}
+
+func parseScoreBound(b []byte) (scoreBound, bool) {
+	var bound scoreBound
+	if len(b) > 0 && b[0] == '(' {
+		bound.exclusive = true
+		b = b[1:]
+	}
+	var ok bool
+	bound.score, ok = parseFloat(b)
+	return bound, ok
+}
+
+// lexBound is a bound of a range of members: "-" and "+" are the lowest and
+// highest strings, "[" and "(" prefix inclusive and exclusive bounds.
+type lexBound struct {
+	member    string
+	exclusive bool
+	inf       int
+}
+
+func (b lexBound) below(member string) bool {
+	switch b.inf {
+	case -1:
+		return true
+	case 1:
+		return false
+	}
+	return b.member < member || (!b.exclusive && b.member == member)
+}
+
+func (b lexBound) above(member string) bool {
+	switch b.inf {
+	case -1:
+		return false
+	case 1:
+		return true
+	}
+	return b.member > member || (!b.exclusive && b.member == member)
+}
+
+func parseLexRange(min, max []byte) (lexBound, lexBound, *resp.Message) {
+	lo, ok1 := parseLexBound(min)
+	hi, ok2 := parseLexBound(max)
+	if !ok1 || !ok2 {
+		return lo, hi, replyError("ERR min or max not valid string range item")
+	}
+	return lo, hi, nil
+}
+
+func parseLexBound(b []byte) (lexBound, bool) {
+	switch {
+	case len(b) == 1 && b[0] == '-':
+		return lexBound{inf: -1}, true
+	case len(b) == 1 && b[0] == '+':
+		return lexBound{inf: 1}, true
+	case len(b) > 0 && b[0] == '[':
+		return lexBound{member: string(b[1:])}, true
+	case len(b) > 0 && b[0] == '(':
+		return lexBound{member: string(b[1:]), exclusive: true}, true
+	}
+	return lexBound{}, false
+}
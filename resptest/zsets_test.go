@@ -0,0 +1,49 @@
+package resptest
+
+import (
+	"testing"
+)
+
+func TestSortedSets(t *testing.T) {
+	s, c := newTestClient(t)
+	defer s.Close()
+	defer c.Close()
+
+	expect(t, c, "(integer) 3", "ZADD", "z", 1, "a", 2, "b", 3, "c")
+	expect(t, c, "(integer) 1", "ZADD", "z", "CH", 1.5, "a", 3, "c")
+	expect(t, c, "(integer) 0", "ZADD", "z", "GT", 1, "a")
+	expect(t, c, "(nil)", "ZADD", "z", "NX", "INCR", 1, "a")
+	expect(t, c, `"2.5"`, "ZINCRBY", "z", 1, "a")
+	expect(t, c, `"2.5"`, "ZSCORE", "z", "a")
+	expect(t, c, lines(`1) "2.5"`, `2) (nil)`), "ZMSCORE", "z", "a", "nope")
+	expect(t, c, "(integer) 3", "ZCARD", "z")
+	expect(t, c, "(integer) 2", "ZCOUNT", "z", "(2", "+inf")
+	expect(t, c, "(integer) 0", "ZRANK", "z", "b")
+	expect(t, c, "(integer) 2", "ZREVRANK", "z", "b")
+	expect(t, c, "(nil)", "ZRANK", "z", "nope")
+
+	expect(t, c, lines(`1) "b"`, `2) "a"`, `3) "c"`), "ZRANGE", "z", 0, -1)
+	expect(t, c, lines(`1) "c"`, `2) "3"`, `3) "a"`, `4) "2.5"`), "ZREVRANGE", "z", 0, 1, "WITHSCORES")
+	expect(t, c, lines(`1) "a"`, `2) "c"`), "ZRANGEBYSCORE", "z", "(2", 3)
+	expect(t, c, lines(`1) "c"`), "ZREVRANGEBYSCORE", "z", "+inf", "-inf", "LIMIT", 0, 1)
+	expect(t, c, lines(`1) "c"`, `2) "a"`), "ZRANGE", "z", 3, 2.5, "BYSCORE", "REV")
+	expect(t, c, "(error) ERR min or max is not a float", "ZRANGEBYSCORE", "z", "x", 1)
+	expect(t, c, "(error) ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX",
+		"ZRANGE", "z", 0, 1, "LIMIT", 0, 1)
+
+	expect(t, c, "(integer) 3", "ZADD", "lex", 0, "a", 0, "b", 0, "c")
+	expect(t, c, lines(`1) "a"`, `2) "b"`), "ZRANGEBYLEX", "lex", "-", "(c")
+	expect(t, c, lines(`1) "c"`, `2) "b"`), "ZREVRANGEBYLEX", "lex", "+", "[b")
+	expect(t, c, "(error) ERR min or max not valid string range item", "ZRANGEBYLEX", "lex", "a", "+")
+	expect(t, c, "(integer) 2", "ZREMRANGEBYLEX", "lex", "[b", "+")
+
+	expect(t, c, lines(`1) "b"`, `2) "2"`), "ZPOPMIN", "z")
+	expect(t, c, lines(`1) "c"`, `2) "3"`), "ZPOPMAX", "z")
+	expect(t, c, lines(`1) "0"`, `2) 1) "a"`, `   2) "2.5"`), "ZSCAN", "z", 0)
+	expect(t, c, "(integer) 1", "ZREMRANGEBYSCORE", "z", "-inf", "+inf")
+	expect(t, c, "(integer) 0", "EXISTS", "z")
+
+	expect(t, c, "(error) ERR value is not a valid float", "ZADD", "z", "x", "a")
+	expect(t, c, "(error) ERR syntax error", "ZADD", "z", 1, "a", 2)
+	expect(t, c, "(error) ERR XX and NX options at the same time are not compatible", "ZADD", "z", "NX", "XX", 1, "a")
+}
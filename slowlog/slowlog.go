@@ -0,0 +1,130 @@
+// Package slowlog parses the reply of the redis SLOWLOG GET command, an
+// array of entries describing the commands which took longer than the
+// slowlog-log-slower-than threshold.
+package slowlog
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+var (
+	// ErrInvalidReply is returned when the reply does not have the layout
+	// of a SLOWLOG GET reply
+	ErrInvalidReply = errors.New("slowlog: invalid reply")
+)
+
+// Entry is an entry of the slow log.
+type Entry struct {
+	ID   int64
+	Time time.Time
+	// Duration is the execution time of the command, reported in
+	// microseconds
+	Duration time.Duration
+	// Args holds the command and its arguments. Redis keeps at most 32 of
+	// them, the last one then being "... (N more arguments)", and truncates
+	// the ones longer than 128 bytes, appending "... (N more bytes)".
+	Args []string
+	// ClientAddr and ClientName are reported since redis 4.0
+	ClientAddr string
+	ClientName string
+}
+
+// Command returns the command name of the entry, "" if it has no arguments.
+func (e *Entry) Command() string {
+	if len(e.Args) == 0 {
+		return ""
+	}
+	return e.Args[0]
+}
+
+var moreArgs = regexp.MustCompile(`^\.\.\. \((\d+) more arguments\)$`)
+
+// ArgCount returns the number of arguments of the command, including the
+// ones redis dropped from the entry.
+func (e *Entry) ArgCount() int {
+	if len(e.Args) == 0 {
+		return 0
+	}
+	if m := moreArgs.FindStringSubmatch(e.Args[len(e.Args)-1]); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return len(e.Args) - 1 + n
+	}
+	return len(e.Args)
+}
+
+// Parse parses the reply to SLOWLOG GET. The fields following the ones
+// this package knows about are ignored. An error reply is returned as the
+// error.
+func Parse(m *resp.Message) ([]*Entry, error) {
+	if m == nil || m.IsNil {
+		return nil, ErrInvalidReply
+	}
+	if m.Type == resp.ErrorHeader {
+		return nil, m.Error
+	}
+	if m.Type != resp.ArrayHeader {
+		return nil, ErrInvalidReply
+	}
+	entries := make([]*Entry, 0, len(m.Array))
+	for _, item := range m.Array {
+		e, err := parseEntry(item)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func parseEntry(m *resp.Message) (*Entry, error) {
+	if m.Type != resp.ArrayHeader || len(m.Array) < 4 {
+		return nil, ErrInvalidReply
+	}
+	for _, f := range m.Array[:3] {
+		if f.Type != resp.IntegerHeader {
+			return nil, ErrInvalidReply
+		}
+	}
+	e := &Entry{
+		ID:       m.Array[0].Integer,
+		Time:     time.Unix(m.Array[1].Integer, 0),
+		Duration: time.Duration(m.Array[2].Integer) * time.Microsecond,
+	}
+	args := m.Array[3]
+	if args.Type != resp.ArrayHeader {
+		return nil, ErrInvalidReply
+	}
+	e.Args = make([]string, len(args.Array))
+	for i, arg := range args.Array {
+		s, ok := text(arg)
+		if !ok {
+			return nil, ErrInvalidReply
+		}
+		e.Args[i] = s
+	}
+	if len(m.Array) > 5 {
+		var ok1, ok2 bool
+		e.ClientAddr, ok1 = text(m.Array[4])
+		e.ClientName, ok2 = text(m.Array[5])
+		if !ok1 || !ok2 {
+			return nil, ErrInvalidReply
+		}
+	}
+	return e, nil
+}
+
+// text returns the content of a bulk or status message.
+func text(m *resp.Message) (string, bool) {
+	switch m.Type {
+	case resp.BulkHeader:
+		return string(m.Bytes), true
+	case resp.StringHeader:
+		return m.Status, true
+	}
+	return "", false
+}
@@ -0,0 +1,119 @@
+package slowlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func loadFixture(t *testing.T, name string) *resp.Message {
+	data, err := ioutil.ReadFile("testdata/" + name + ".resp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, _, err := resp.Decode(data)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("error decoding %s: %v", name, err)
+	}
+	return msgs[0]
+}
+
+func TestParseGolden(t *testing.T) {
+	for _, name := range []string{"slowlog-2.6", "slowlog-7.2"} {
+		entries, err := Parse(loadFixture(t, name))
+		if err != nil {
+			t.Fatalf("error parsing %s: %v", name, err)
+		}
+		// the times are compared in UTC, whatever the local time zone
+		for _, e := range entries {
+			e.Time = e.Time.UTC()
+		}
+		got, err := json.MarshalIndent(entries, "", "\t")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, '\n')
+		golden := "testdata/" + name + ".golden"
+		if *update {
+			if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("error parsing %s, got:\n%s", name, got)
+		}
+	}
+}
+
+func TestEntry(t *testing.T) {
+	entries, err := Parse(loadFixture(t, "slowlog-7.2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := entries[0]
+	if e.ID != 7 || e.Time.Unix() != 1700000300 || e.Duration != 21034*time.Microsecond {
+		t.Errorf("error entry: %+v", e)
+	}
+	if e.Command() != "EVAL" || e.ArgCount() != 4 {
+		t.Errorf("error command %s with %d arguments", e.Command(), e.ArgCount())
+	}
+	if e.ClientAddr != "10.0.1.20:42312" || e.ClientName != "api-7f9c" {
+		t.Errorf("error client %s %s", e.ClientAddr, e.ClientName)
+	}
+	if n := entries[1].ArgCount(); n != 42 {
+		t.Errorf("error truncated arguments: %d", n)
+	}
+	if (&Entry{}).Command() != "" || (&Entry{}).ArgCount() != 0 {
+		t.Error("error entry without arguments")
+	}
+}
+
+func TestParseNewerFields(t *testing.T) {
+	reply := resp.Array(resp.Array(resp.Int(1), resp.Int(1700000000), resp.Int(10),
+		resp.Cmd("GET", "k"), resp.Bulk([]byte("127.0.0.1:50188")), resp.Bulk([]byte("")),
+		resp.Int(42), resp.Array()))
+	entries, err := Parse(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].ClientAddr != "127.0.0.1:50188" || len(entries[0].Args) != 2 {
+		t.Error("error parsing an entry with newer fields")
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	replies := []*resp.Message{
+		resp.Nil(),
+		resp.Str("OK"),
+		resp.Array(resp.Int(1)),
+		resp.Array(resp.Array(resp.Int(1), resp.Int(2), resp.Int(3))),
+		resp.Array(resp.Array(resp.Int(1), resp.Bulk([]byte("2")), resp.Int(3), resp.Cmd("GET"))),
+		resp.Array(resp.Array(resp.Int(1), resp.Int(2), resp.Int(3), resp.Bulk([]byte("GET")))),
+		resp.Array(resp.Array(resp.Int(1), resp.Int(2), resp.Int(3), resp.Array(resp.Int(1)))),
+		resp.Array(resp.Array(resp.Int(1), resp.Int(2), resp.Int(3), resp.Cmd("GET"), resp.Int(1), resp.Bulk(nil))),
+	}
+	for i, reply := range replies {
+		if _, err := Parse(reply); err != ErrInvalidReply {
+			t.Errorf("error parsing reply %d: %v", i, err)
+		}
+	}
+	if _, err := Parse(resp.Err(errors.New("ERR unknown"))); err == nil || err.Error() != "ERR unknown" {
+		t.Errorf("error parsing an error reply: %v", err)
+	}
+	entries, err := Parse(resp.Array())
+	if err != nil || len(entries) != 0 {
+		t.Errorf("error parsing an empty slow log: %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package stream
+
+import (
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// StreamEntry is an entry of a stream.
+type StreamEntry struct {
+	ID XMessageID
+	// Fields holds the field names and values alternated, in the order they
+	// were added. It is nil for an entry which was deleted while pending,
+	// as XREADGROUP and XCLAIM report it.
+	Fields []string
+}
+
+// Get returns the value of a field.
+func (e *StreamEntry) Get(name string) (string, bool) {
+	for i := 0; i+1 < len(e.Fields); i += 2 {
+		if e.Fields[i] == name {
+			return e.Fields[i+1], true
+		}
+	}
+	return "", false
+}
+
+// Map returns the fields as a map, the last value winning when a field is
+// repeated.
+func (e *StreamEntry) Map() map[string]string {
+	m := make(map[string]string, len(e.Fields)/2)
+	for i := 0; i+1 < len(e.Fields); i += 2 {
+		m[e.Fields[i]] = e.Fields[i+1]
+	}
+	return m
+}
+
+// Stream is the entries read from a stream by XREAD or XREADGROUP.
+type Stream struct {
+	Key     string
+	Entries []StreamEntry
+}
+
+// ParseEntries parses a list of entries, as replied to XRANGE, XREVRANGE
+// and XCLAIM. An error reply is returned as the error.
+func ParseEntries(m *resp.Message) ([]StreamEntry, error) {
+	if err := checkArray(m); err != nil {
+		return nil, err
+	}
+	entries := make([]StreamEntry, len(m.Array))
+	for i, item := range m.Array {
+		e, err := parseEntry(item)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = e
+	}
+	return entries, nil
+}
+
+// ParseRead parses the reply to XREAD or XREADGROUP, an array of streams
+// with their entries, or with RESP3 a map of the streams to their entries.
+// The nil reply of a read which timed out is returned as no stream.
+func ParseRead(m *resp.Message) ([]Stream, error) {
+	if m != nil && m.IsNil && (m.Type == resp.ArrayHeader || m.Type == resp.NullHeader) {
+		return nil, nil
+	}
+	if m != nil && m.Type == resp.MapHeader {
+		if len(m.Array)%2 != 0 {
+			return nil, ErrInvalidReply
+		}
+		streams := make([]Stream, len(m.Array)/2)
+		for i := range streams {
+			s, err := parseStream(m.Array[2*i], m.Array[2*i+1])
+			if err != nil {
+				return nil, err
+			}
+			streams[i] = s
+		}
+		return streams, nil
+	}
+	if err := checkArray(m); err != nil {
+		return nil, err
+	}
+	streams := make([]Stream, len(m.Array))
+	for i, item := range m.Array {
+		if item.Type != resp.ArrayHeader || len(item.Array) != 2 {
+			return nil, ErrInvalidReply
+		}
+		s, err := parseStream(item.Array[0], item.Array[1])
+		if err != nil {
+			return nil, err
+		}
+		streams[i] = s
+	}
+	return streams, nil
+}
+
+// parseStream parses the key of a stream read and its entries.
+func parseStream(key, entries *resp.Message) (Stream, error) {
+	k, ok := text(key)
+	if !ok {
+		return Stream{}, ErrInvalidReply
+	}
+	e, err := ParseEntries(entries)
+	if err != nil {
+		return Stream{}, err
+	}
+	return Stream{Key: k, Entries: e}, nil
+}
+
+// parseEntry parses an entry, an array of its ID and of its fields.
+func parseEntry(m *resp.Message) (StreamEntry, error) {
+	var e StreamEntry
+	if m.Type != resp.ArrayHeader || m.IsNil || len(m.Array) != 2 {
+		return e, ErrInvalidReply
+	}
+	var err error
+	if e.ID, err = parseIDMessage(m.Array[0]); err != nil {
+		return e, err
+	}
+	fields := m.Array[1]
+	if fields.IsNil {
+		return e, nil
+	}
+	if fields.Type != resp.ArrayHeader || len(fields.Array)%2 != 0 {
+		return e, ErrInvalidReply
+	}
+	e.Fields = make([]string, len(fields.Array))
+	for i, f := range fields.Array {
+		s, ok := text(f)
+		if !ok {
+			return e, ErrInvalidReply
+		}
+		e.Fields[i] = s
+	}
+	return e, nil
+}
+
+func parseIDMessage(m *resp.Message) (XMessageID, error) {
+	s, ok := text(m)
+	if !ok {
+		return XMessageID{}, ErrInvalidReply
+	}
+	return ParseID(s)
+}
+
+// checkArray returns the error of an error reply, and ErrInvalidReply for
+// other messages than arrays.
+func checkArray(m *resp.Message) error {
+	if m == nil || m.IsNil {
+		return ErrInvalidReply
+	}
+	if m.Type == resp.ErrorHeader {
+		return m.Error
+	}
+	if m.Type != resp.ArrayHeader {
+		return ErrInvalidReply
+	}
+	return nil
+}
+
+// text returns the content of a bulk or status message.
+func text(m *resp.Message) (string, bool) {
+	if m == nil || m.IsNil {
+		return "", false
+	}
+	switch m.Type {
+	case resp.BulkHeader:
+		return string(m.Bytes), true
+	case resp.StringHeader:
+		return m.Status, true
+	}
+	return "", false
+}
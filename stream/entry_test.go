@@ -0,0 +1,139 @@
+package stream
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+func b(s string) *resp.Message {
+	return resp.Bulk([]byte(s))
+}
+
+func entry(id string, fields ...string) *resp.Message {
+	values := resp.Array()
+	for _, f := range fields {
+		values.Array = append(values.Array, b(f))
+	}
+	return resp.Array(b(id), values)
+}
+
+func TestParseEntries(t *testing.T) {
+	reply := resp.Array(
+		entry("1526985054069-0", "duration", "72", "event-id", "5", "duration", "73"),
+		entry("1526985069902-0", "duration", "18"),
+		resp.Array(b("1526985070000-1"), resp.NilArray()),
+	)
+	entries, err := ParseEntries(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("error entries: %d", len(entries))
+	}
+	e := entries[0]
+	if e.ID != (XMessageID{1526985054069, 0}) || len(e.Fields) != 6 {
+		t.Errorf("error entry: %+v", e)
+	}
+	if v, ok := e.Get("duration"); !ok || v != "72" {
+		t.Errorf("error first duration: %s", v)
+	}
+	if _, ok := e.Get("user"); ok {
+		t.Error("error missing field")
+	}
+	if m := e.Map(); len(m) != 2 || m["duration"] != "73" || m["event-id"] != "5" {
+		t.Errorf("error map: %v", m)
+	}
+	if entries[2].Fields != nil || entries[2].ID.Seq != 1 {
+		t.Errorf("error deleted entry: %+v", entries[2])
+	}
+
+	entries, err = ParseEntries(resp.Array())
+	if err != nil || len(entries) != 0 {
+		t.Errorf("error parsing an empty range: %v", err)
+	}
+}
+
+func TestParseRead(t *testing.T) {
+	reply := resp.Array(
+		resp.Array(b("mystream"), resp.Array(entry("1-1", "a", "1"), entry("1-2", "b", "2"))),
+		resp.Array(b("other"), resp.Array()),
+	)
+	streams, err := ParseRead(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(streams) != 2 || streams[0].Key != "mystream" || len(streams[0].Entries) != 2 {
+		t.Fatalf("error streams: %+v", streams)
+	}
+	if streams[0].Entries[1].ID.String() != "1-2" || streams[1].Key != "other" || len(streams[1].Entries) != 0 {
+		t.Errorf("error streams: %+v", streams)
+	}
+
+	streams, err = ParseRead(resp.NilArray())
+	if err != nil || streams != nil {
+		t.Errorf("error parsing a timeout: %v", err)
+	}
+}
+
+func TestParseReadResp3(t *testing.T) {
+	msgQ, _, err := resp.Decode([]byte("%2\r\n$8\r\nmystream\r\n*1\r\n*2\r\n$3\r\n1-1\r\n*2\r\n$1\r\na\r\n$1\r\n1\r\n" +
+		"$5\r\nother\r\n*0\r\n_\r\n"))
+	if err != nil || len(msgQ) != 2 {
+		t.Fatalf("error decoding: %v %v", msgQ, err)
+	}
+	streams, err := ParseRead(msgQ[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(streams) != 2 || streams[0].Key != "mystream" || len(streams[0].Entries) != 1 ||
+		streams[0].Entries[0].Fields[1] != "1" || streams[1].Key != "other" || len(streams[1].Entries) != 0 {
+		t.Errorf("error streams from a map: %+v", streams)
+	}
+	// the RESP3 null of a read which timed out
+	if streams, err = ParseRead(msgQ[1]); err != nil || streams != nil {
+		t.Errorf("error parsing a timeout: %v %v", streams, err)
+	}
+	odd := &resp.Message{Type: resp.MapHeader, Array: []*resp.Message{b("s")}}
+	if _, err := ParseRead(odd); err != ErrInvalidReply {
+		t.Errorf("should return ErrInvalidReply, not: %v", err)
+	}
+}
+
+func TestParseInvalidEntries(t *testing.T) {
+	replies := []*resp.Message{
+		resp.Nil(),
+		resp.Int(1),
+		resp.Array(b("1-1")),
+		resp.Array(resp.Array(b("1-1"))),
+		resp.Array(resp.Array(resp.Int(1), resp.Array())),
+		resp.Array(resp.Array(b("1-1"), resp.Array(b("a")))),
+		resp.Array(resp.Array(b("1-1"), resp.Array(b("a"), resp.Int(1)))),
+		resp.Array(resp.Array(b("1-1"), b("a"))),
+	}
+	for i, reply := range replies {
+		if _, err := ParseEntries(reply); err != ErrInvalidReply {
+			t.Errorf("error parsing reply %d: %v", i, err)
+		}
+	}
+	if _, err := ParseEntries(resp.Array(entry("x-1", "a", "1"))); err != ErrInvalidID {
+		t.Errorf("error parsing an invalid ID: %v", err)
+	}
+
+	reads := []*resp.Message{
+		resp.Nil(),
+		resp.Array(resp.Array(b("s"))),
+		resp.Array(resp.Array(resp.Int(1), resp.Array())),
+		resp.Array(resp.Array(b("s"), b("1-1"))),
+	}
+	for i, reply := range reads {
+		if _, err := ParseRead(reply); err != ErrInvalidReply {
+			t.Errorf("error parsing read %d: %v", i, err)
+		}
+	}
+	_, err := ParseRead(resp.Err(errors.New("NOGROUP No such key")))
+	if err == nil || err.Error() != "NOGROUP No such key" {
+		t.Errorf("error parsing an error reply: %v", err)
+	}
+}
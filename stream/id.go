@@ -0,0 +1,91 @@
+// Package stream decodes the replies of the redis stream commands, XRANGE,
+// XREAD, XREADGROUP, XINFO STREAM and XPENDING, into typed values, and reads
+// the streams of a consumer group.
+package stream
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidID is returned when a stream entry ID is not of the ms-seq
+	// form
+	ErrInvalidID = errors.New("stream: invalid entry ID")
+
+	// ErrInvalidReply is returned when a reply does not have the layout of
+	// the command it was parsed for
+	ErrInvalidReply = errors.New("stream: invalid reply")
+)
+
+// XMessageID is the ID of a stream entry: the unix time in milliseconds at
+// which it was added and a sequence number among the entries of the same
+// millisecond.
+type XMessageID struct {
+	Ms  uint64
+	Seq uint64
+}
+
+// ParseID parses an ID of the ms-seq form. The sequence number may be
+// omitted, as redis allows it in XRANGE, and is then 0.
+func ParseID(s string) (XMessageID, error) {
+	var id XMessageID
+	ms, seq := s, ""
+	if dash := strings.IndexByte(s, '-'); dash >= 0 {
+		ms, seq = s[:dash], s[dash+1:]
+		if seq == "" {
+			return id, ErrInvalidID
+		}
+	}
+	var err error
+	if id.Ms, err = strconv.ParseUint(ms, 10, 64); err != nil {
+		return id, ErrInvalidID
+	}
+	if seq != "" {
+		if id.Seq, err = strconv.ParseUint(seq, 10, 64); err != nil {
+			return id, ErrInvalidID
+		}
+	}
+	return id, nil
+}
+
+// String returns the ID in the ms-seq form used by redis commands.
+func (id XMessageID) String() string {
+	return strconv.FormatUint(id.Ms, 10) + "-" + strconv.FormatUint(id.Seq, 10)
+}
+
+// IsZero reports whether the ID is 0-0, which no entry has.
+func (id XMessageID) IsZero() bool {
+	return id.Ms == 0 && id.Seq == 0
+}
+
+// Compare returns -1, 0 or 1 whether the ID is lower than, equal to or
+// greater than other.
+func (id XMessageID) Compare(other XMessageID) int {
+	switch {
+	case id.Ms < other.Ms:
+		return -1
+	case id.Ms > other.Ms:
+		return 1
+	case id.Seq < other.Seq:
+		return -1
+	case id.Seq > other.Seq:
+		return 1
+	}
+	return 0
+}
+
+// Less reports whether the ID is lower than other.
+func (id XMessageID) Less(other XMessageID) bool {
+	return id.Compare(other) < 0
+}
+
+// Next returns the smallest ID greater than id, to resume a range after the
+// last entry read.
+func (id XMessageID) Next() XMessageID {
+	if id.Seq == ^uint64(0) {
+		return XMessageID{Ms: id.Ms + 1}
+	}
+	return XMessageID{Ms: id.Ms, Seq: id.Seq + 1}
+}
@@ -0,0 +1,62 @@
+package stream
+
+import (
+	"testing"
+)
+
+func TestParseID(t *testing.T) {
+	cases := []struct {
+		s    string
+		id   XMessageID
+		text string
+	}{
+		{"1526919030474-55", XMessageID{1526919030474, 55}, "1526919030474-55"},
+		{"0-1", XMessageID{0, 1}, "0-1"},
+		{"1526919030474", XMessageID{1526919030474, 0}, "1526919030474-0"},
+		{"18446744073709551615-18446744073709551615", XMessageID{^uint64(0), ^uint64(0)}, "18446744073709551615-18446744073709551615"},
+	}
+	for _, c := range cases {
+		id, err := ParseID(c.s)
+		if err != nil {
+			t.Errorf("error parsing %s: %v", c.s, err)
+			continue
+		}
+		if id != c.id || id.String() != c.text {
+			t.Errorf("error parsing %s: %s", c.s, id)
+		}
+	}
+	for _, s := range []string{"", "-", "1-", "-1", "x-1", "1-x", "1-2-3", "-1-1", "18446744073709551616-0"} {
+		if _, err := ParseID(s); err != ErrInvalidID {
+			t.Errorf("error parsing %q: %v", s, err)
+		}
+	}
+}
+
+func TestCompareID(t *testing.T) {
+	ids := []XMessageID{{0, 0}, {0, 1}, {1, 0}, {1, 5}, {2, 0}}
+	for i, a := range ids {
+		for j, b := range ids {
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if a.Compare(b) != want || a.Less(b) != (want < 0) {
+				t.Errorf("error comparing %s and %s", a, b)
+			}
+		}
+	}
+	if !ids[0].IsZero() || ids[1].IsZero() {
+		t.Error("error zero ID")
+	}
+}
+
+func TestNextID(t *testing.T) {
+	if id := (XMessageID{5, 1}).Next(); id != (XMessageID{5, 2}) {
+		t.Errorf("error next ID: %s", id)
+	}
+	if id := (XMessageID{5, ^uint64(0)}).Next(); id != (XMessageID{6, 0}) {
+		t.Errorf("error next ID of the last sequence: %s", id)
+	}
+}
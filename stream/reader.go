@@ -0,0 +1,128 @@
+package stream
+
+import (
+	"context"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// DefaultBlock is the time a GroupReader waits for new entries when its
+// Block field is zero.
+const DefaultBlock = time.Second
+
+// GroupReader reads the streams of a consumer group as one of its
+// consumers. It first reads the entries which were delivered to the
+// consumer but not acknowledged, as left by a previous run which stopped
+// early, then the new entries.
+type GroupReader struct {
+	Conn     *resp.Conn
+	Group    string
+	Consumer string
+	Streams  []string
+	// Count is the maximum number of entries read at once, unlimited when
+	// zero
+	Count int
+	// Block is the time a read waits for new entries, DefaultBlock when
+	// zero
+	Block time.Duration
+}
+
+// Run reads entries and calls fn for each of them, acknowledging the entry
+// with XACK once fn returns nil. It returns when ctx is done, the
+// connection fails or fn returns an error, which is returned, the entry
+// then being left pending. Pending entries which were deleted from the
+// stream are acknowledged without calling fn.
+func (r *GroupReader) Run(ctx context.Context, fn func(stream string, e StreamEntry) error) error {
+	// ids holds the ID to read each stream from: the last pending entry
+	// read, then ">" for the new entries
+	ids := make(map[string]string, len(r.Streams))
+	for _, s := range r.Streams {
+		ids[s] = "0"
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		reply, err := r.Conn.DoContext(ctx, r.command(ids))
+		if err != nil {
+			return err
+		}
+		streams, err := ParseRead(reply)
+		if err != nil {
+			return err
+		}
+		for _, s := range streams {
+			if ids[s.Key] != ">" {
+				if len(s.Entries) == 0 {
+					ids[s.Key] = ">"
+					continue
+				}
+				ids[s.Key] = s.Entries[len(s.Entries)-1].ID.String()
+			}
+			if err := r.handle(ctx, s, fn); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// command returns the XREADGROUP command reading the streams from ids. It
+// only blocks once every pending entry was read, as redis ignores BLOCK
+// otherwise.
+func (r *GroupReader) command(ids map[string]string) *resp.Message {
+	cmd := resp.Cmd("XREADGROUP", "GROUP", r.Group, r.Consumer)
+	if r.Count > 0 {
+		cmd.Arg("COUNT", r.Count)
+	}
+	pending := false
+	for _, s := range r.Streams {
+		if ids[s] != ">" {
+			pending = true
+		}
+	}
+	if !pending {
+		block := r.Block
+		if block <= 0 {
+			block = DefaultBlock
+		}
+		cmd.Arg("BLOCK", int64(block/time.Millisecond))
+	}
+	cmd.Arg("STREAMS")
+	for _, s := range r.Streams {
+		cmd.Arg(s)
+	}
+	for _, s := range r.Streams {
+		cmd.Arg(ids[s])
+	}
+	return cmd
+}
+
+// handle calls fn for the entries of a stream, and acknowledges those it
+// handled, even when it fails for one of them.
+func (r *GroupReader) handle(ctx context.Context, s Stream, fn func(string, StreamEntry) error) error {
+	var handled []string
+	var fnErr error
+	for _, e := range s.Entries {
+		if e.Fields != nil {
+			if fnErr = fn(s.Key, e); fnErr != nil {
+				break
+			}
+		}
+		handled = append(handled, e.ID.String())
+	}
+	if len(handled) > 0 {
+		cmd := resp.Cmd("XACK", s.Key, r.Group)
+		for _, id := range handled {
+			cmd.Arg(id)
+		}
+		reply, err := r.Conn.DoContext(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		if reply.Type == resp.ErrorHeader {
+			return reply.Error
+		}
+	}
+	return fnErr
+}
@@ -0,0 +1,140 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// groupServer serves XREADGROUP and XACK for a stream "s" holding two
+// pending entries, the second one deleted, and two new entries.
+type groupServer struct {
+	mu       sync.Mutex
+	reads    []string
+	acks     []string
+	timeouts int
+	cancel   func()
+}
+
+func (g *groupServer) ServeRESP(c *resp.ServerConn, cmd *resp.Message) {
+	args, _ := cmd.Strings()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch strings.ToUpper(args[0]) {
+	case "XREADGROUP":
+		g.reads = append(g.reads, strings.Join(args, " "))
+		switch args[len(args)-1] {
+		case "0":
+			c.WriteMessage(resp.Array(resp.Array(b("s"), resp.Array(
+				entry("1-0", "n", "1"),
+				resp.Array(b("2-0"), resp.NilArray())))))
+		case "2-0":
+			c.WriteMessage(resp.Array(resp.Array(b("s"), resp.Array())))
+		case ">":
+			if len(g.reads) == 3 {
+				c.WriteMessage(resp.Array(resp.Array(b("s"), resp.Array(
+					entry("3-0", "n", "3"),
+					entry("4-0", "n", "4")))))
+				return
+			}
+			g.timeouts++
+			if g.timeouts == 2 && g.cancel != nil {
+				g.cancel()
+			}
+			c.WriteMessage(resp.NilArray())
+		}
+	case "XACK":
+		g.acks = append(g.acks, strings.Join(args[1:], " "))
+		c.WriteMessage(resp.Int(int64(len(args) - 3)))
+	}
+}
+
+func TestGroupReader(t *testing.T) {
+	g := &groupServer{}
+	client, server := net.Pipe()
+	go (&resp.Server{Handler: g}).ServeConn(server)
+	c := resp.NewConn(client)
+	defer c.Close()
+
+	r := &GroupReader{
+		Conn:     c,
+		Group:    "g",
+		Consumer: "c1",
+		Streams:  []string{"s"},
+		Count:    10,
+		Block:    100 * time.Millisecond,
+	}
+	var read []string
+	stop := errors.New("stop")
+	err := r.Run(context.Background(), func(stream string, e StreamEntry) error {
+		if e.ID.String() == "4-0" {
+			return stop
+		}
+		read = append(read, stream+" "+e.ID.String())
+		return nil
+	})
+	if err != stop {
+		t.Errorf("error running: %v", err)
+	}
+	if len(read) != 2 || read[0] != "s 1-0" || read[1] != "s 3-0" {
+		t.Errorf("error entries read: %q", read)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	wantReads := []string{
+		"XREADGROUP GROUP g c1 COUNT 10 STREAMS s 0",
+		"XREADGROUP GROUP g c1 COUNT 10 STREAMS s 2-0",
+		"XREADGROUP GROUP g c1 COUNT 10 BLOCK 100 STREAMS s >",
+	}
+	if len(g.reads) != len(wantReads) {
+		t.Fatalf("error reads: %q", g.reads)
+	}
+	for i, want := range wantReads {
+		if g.reads[i] != want {
+			t.Errorf("error read %d: %s", i, g.reads[i])
+		}
+	}
+	wantAcks := []string{"s g 1-0 2-0", "s g 3-0"}
+	if len(g.acks) != len(wantAcks) || g.acks[0] != wantAcks[0] || g.acks[1] != wantAcks[1] {
+		t.Errorf("error acknowledgments: %q", g.acks)
+	}
+}
+
+func TestGroupReaderCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g := &groupServer{cancel: cancel}
+	client, server := net.Pipe()
+	go (&resp.Server{Handler: g}).ServeConn(server)
+	c := resp.NewConn(client)
+	defer c.Close()
+
+	r := &GroupReader{Conn: c, Group: "g", Consumer: "c1", Streams: []string{"s"}}
+	n := 0
+	err := r.Run(ctx, func(stream string, e StreamEntry) error {
+		n++
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("error running: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("error entries read: %d", n)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.timeouts != 2 || !strings.Contains(g.reads[3], "BLOCK 1000 ") {
+		t.Errorf("error reads: %q", g.reads)
+	}
+	if len(g.acks) != 2 || g.acks[1] != "s g 3-0 4-0" {
+		t.Errorf("error acknowledgments: %q", g.acks)
+	}
+}
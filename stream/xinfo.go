@@ -0,0 +1,89 @@
+package stream
+
+import (
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// XInfoStream is the reply to XINFO STREAM. The fields added by redis 7.0
+// are left empty for earlier versions.
+type XInfoStream struct {
+	Length          int64
+	RadixTreeKeys   int64
+	RadixTreeNodes  int64
+	Groups          int64
+	LastGeneratedID XMessageID
+	// MaxDeletedEntryID, EntriesAdded and RecordedFirstEntryID are reported
+	// since redis 7.0
+	MaxDeletedEntryID    XMessageID
+	EntriesAdded         int64
+	RecordedFirstEntryID XMessageID
+	// FirstEntry and LastEntry are nil for an empty stream
+	FirstEntry *StreamEntry
+	LastEntry  *StreamEntry
+}
+
+// ParseXInfoStream parses the reply to XINFO STREAM, without the FULL
+// option. Unknown fields are ignored. An error reply is returned as the
+// error.
+func ParseXInfoStream(m *resp.Message) (*XInfoStream, error) {
+	if err := checkArray(m); err != nil {
+		return nil, err
+	}
+	if len(m.Array)%2 != 0 {
+		return nil, ErrInvalidReply
+	}
+	info := &XInfoStream{}
+	for i := 0; i < len(m.Array); i += 2 {
+		name, ok := text(m.Array[i])
+		if !ok {
+			return nil, ErrInvalidReply
+		}
+		v := m.Array[i+1]
+		var err error
+		switch name {
+		case "length":
+			info.Length, err = integer(v)
+		case "radix-tree-keys":
+			info.RadixTreeKeys, err = integer(v)
+		case "radix-tree-nodes":
+			info.RadixTreeNodes, err = integer(v)
+		case "groups":
+			info.Groups, err = integer(v)
+		case "entries-added":
+			info.EntriesAdded, err = integer(v)
+		case "last-generated-id":
+			info.LastGeneratedID, err = parseIDMessage(v)
+		case "max-deleted-entry-id":
+			info.MaxDeletedEntryID, err = parseIDMessage(v)
+		case "recorded-first-entry-id":
+			info.RecordedFirstEntryID, err = parseIDMessage(v)
+		case "first-entry":
+			info.FirstEntry, err = optionalEntry(v)
+		case "last-entry":
+			info.LastEntry, err = optionalEntry(v)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+func optionalEntry(m *resp.Message) (*StreamEntry, error) {
+	if m.IsNil {
+		return nil, nil
+	}
+	e, err := parseEntry(m)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// integer returns the value of an integer message.
+func integer(m *resp.Message) (int64, error) {
+	if m.Type != resp.IntegerHeader {
+		return 0, ErrInvalidReply
+	}
+	return m.Integer, nil
+}
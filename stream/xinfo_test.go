@@ -0,0 +1,75 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+func TestParseXInfoStream(t *testing.T) {
+	reply := resp.Array(
+		b("length"), resp.Int(2),
+		b("radix-tree-keys"), resp.Int(1),
+		b("radix-tree-nodes"), resp.Int(2),
+		b("last-generated-id"), b("1638125141232-0"),
+		b("max-deleted-entry-id"), b("1638125133432-0"),
+		b("entries-added"), resp.Int(3),
+		b("recorded-first-entry-id"), b("1638125136000-0"),
+		b("groups"), resp.Int(1),
+		b("first-entry"), entry("1638125136000-0", "message", "banana"),
+		b("last-entry"), entry("1638125141232-0", "message", "mango"),
+		b("entries-read-lag"), resp.Int(0),
+	)
+	info, err := ParseXInfoStream(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Length != 2 || info.RadixTreeKeys != 1 || info.RadixTreeNodes != 2 || info.Groups != 1 || info.EntriesAdded != 3 {
+		t.Errorf("error counters: %+v", info)
+	}
+	if info.LastGeneratedID.String() != "1638125141232-0" || info.MaxDeletedEntryID.String() != "1638125133432-0" ||
+		info.RecordedFirstEntryID.String() != "1638125136000-0" {
+		t.Errorf("error IDs: %+v", info)
+	}
+	if info.FirstEntry == nil || info.FirstEntry.Fields[1] != "banana" || info.LastEntry == nil || info.LastEntry.Fields[1] != "mango" {
+		t.Errorf("error entries: %+v %+v", info.FirstEntry, info.LastEntry)
+	}
+}
+
+func TestParseXInfoStreamEmpty(t *testing.T) {
+	// redis 5.0 reply for an empty stream
+	reply := resp.Array(
+		b("length"), resp.Int(0),
+		b("radix-tree-keys"), resp.Int(0),
+		b("radix-tree-nodes"), resp.Int(1),
+		b("groups"), resp.Int(0),
+		b("last-generated-id"), b("0-0"),
+		b("first-entry"), resp.Nil(),
+		b("last-entry"), resp.Nil(),
+	)
+	info, err := ParseXInfoStream(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Length != 0 || !info.LastGeneratedID.IsZero() || info.FirstEntry != nil || info.LastEntry != nil {
+		t.Errorf("error empty stream: %+v", info)
+	}
+}
+
+func TestParseXInfoStreamInvalid(t *testing.T) {
+	replies := []*resp.Message{
+		resp.Str("OK"),
+		resp.Array(b("length")),
+		resp.Array(resp.Int(1), resp.Int(2)),
+		resp.Array(b("length"), b("2")),
+		resp.Array(b("first-entry"), b("1-1")),
+	}
+	for i, reply := range replies {
+		if _, err := ParseXInfoStream(reply); err != ErrInvalidReply {
+			t.Errorf("error parsing reply %d: %v", i, err)
+		}
+	}
+	if _, err := ParseXInfoStream(resp.Array(b("last-generated-id"), b("x"))); err != ErrInvalidID {
+		t.Errorf("error parsing an invalid ID: %v", err)
+	}
+}
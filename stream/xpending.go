@@ -0,0 +1,117 @@
+package stream
+
+import (
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// XPending is the summary of the pending entries of a consumer group, the
+// reply to XPENDING key group.
+type XPending struct {
+	Count int64
+	// Lowest and Highest are the lowest and highest pending IDs, zero when
+	// no entry is pending
+	Lowest    XMessageID
+	Highest   XMessageID
+	Consumers []PendingConsumer
+}
+
+// PendingConsumer is the number of pending entries of a consumer.
+type PendingConsumer struct {
+	Name  string
+	Count int64
+}
+
+// XPendingEntry is a pending entry, as replied to the extended form of
+// XPENDING, key group [IDLE min-idle-time] start end count [consumer].
+type XPendingEntry struct {
+	ID       XMessageID
+	Consumer string
+	// Idle is the time elapsed since the entry was last delivered
+	Idle time.Duration
+	// Deliveries is the number of times the entry was delivered
+	Deliveries int64
+}
+
+// ParseXPending parses the reply to the summary form of XPENDING. An error
+// reply is returned as the error.
+func ParseXPending(m *resp.Message) (*XPending, error) {
+	if err := checkArray(m); err != nil {
+		return nil, err
+	}
+	if len(m.Array) != 4 {
+		return nil, ErrInvalidReply
+	}
+	p := &XPending{}
+	var err error
+	if p.Count, err = integer(m.Array[0]); err != nil {
+		return nil, err
+	}
+	if p.Count == 0 {
+		return p, nil
+	}
+	if p.Lowest, err = parseIDMessage(m.Array[1]); err != nil {
+		return nil, err
+	}
+	if p.Highest, err = parseIDMessage(m.Array[2]); err != nil {
+		return nil, err
+	}
+	consumers := m.Array[3]
+	if consumers.Type != resp.ArrayHeader {
+		return nil, ErrInvalidReply
+	}
+	for _, c := range consumers.Array {
+		if c.Type != resp.ArrayHeader || len(c.Array) != 2 {
+			return nil, ErrInvalidReply
+		}
+		name, ok := text(c.Array[0])
+		if !ok {
+			return nil, ErrInvalidReply
+		}
+		// the count is sent as a bulk string
+		count, err := c.Array[1].Int64()
+		if err != nil {
+			return nil, ErrInvalidReply
+		}
+		p.Consumers = append(p.Consumers, PendingConsumer{Name: name, Count: count})
+	}
+	return p, nil
+}
+
+// ParseXPendingEntries parses the reply to the extended form of XPENDING.
+// An error reply is returned as the error.
+func ParseXPendingEntries(m *resp.Message) ([]XPendingEntry, error) {
+	if err := checkArray(m); err != nil {
+		return nil, err
+	}
+	entries := make([]XPendingEntry, len(m.Array))
+	for i, item := range m.Array {
+		if item.Type != resp.ArrayHeader || len(item.Array) != 4 {
+			return nil, ErrInvalidReply
+		}
+		id, err := parseIDMessage(item.Array[0])
+		if err != nil {
+			return nil, err
+		}
+		consumer, ok := text(item.Array[1])
+		if !ok {
+			return nil, ErrInvalidReply
+		}
+		idle, err := integer(item.Array[2])
+		if err != nil {
+			return nil, err
+		}
+		deliveries, err := integer(item.Array[3])
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = XPendingEntry{
+			ID:         id,
+			Consumer:   consumer,
+			Idle:       time.Duration(idle) * time.Millisecond,
+			Deliveries: deliveries,
+		}
+	}
+	return entries, nil
+}
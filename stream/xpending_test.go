@@ -0,0 +1,81 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+func TestParseXPending(t *testing.T) {
+	reply := resp.Array(
+		resp.Int(3),
+		b("1526984818136-0"),
+		b("1526984818138-0"),
+		resp.Array(resp.Array(b("Alice"), b("2")), resp.Array(b("Bob"), b("1"))),
+	)
+	p, err := ParseXPending(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Count != 3 || p.Lowest.String() != "1526984818136-0" || p.Highest.String() != "1526984818138-0" {
+		t.Errorf("error summary: %+v", p)
+	}
+	if len(p.Consumers) != 2 || p.Consumers[0] != (PendingConsumer{"Alice", 2}) || p.Consumers[1] != (PendingConsumer{"Bob", 1}) {
+		t.Errorf("error consumers: %+v", p.Consumers)
+	}
+
+	p, err = ParseXPending(resp.Array(resp.Int(0), resp.Nil(), resp.Nil(), resp.NilArray()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Count != 0 || !p.Lowest.IsZero() || p.Consumers != nil {
+		t.Errorf("error empty summary: %+v", p)
+	}
+}
+
+func TestParseXPendingEntries(t *testing.T) {
+	reply := resp.Array(
+		resp.Array(b("1526984818136-0"), b("Alice"), resp.Int(196415), resp.Int(1)),
+		resp.Array(b("1526984818138-0"), b("Bob"), resp.Int(20), resp.Int(3)),
+	)
+	entries, err := ParseXPendingEntries(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("error entries: %d", len(entries))
+	}
+	e := entries[0]
+	if e.ID.String() != "1526984818136-0" || e.Consumer != "Alice" || e.Idle != 196415*time.Millisecond || e.Deliveries != 1 {
+		t.Errorf("error entry: %+v", e)
+	}
+}
+
+func TestParseXPendingInvalid(t *testing.T) {
+	summaries := []*resp.Message{
+		resp.Nil(),
+		resp.Array(resp.Int(1), b("1-1"), b("1-1")),
+		resp.Array(b("1"), b("1-1"), b("1-1"), resp.Array()),
+		resp.Array(resp.Int(1), resp.Nil(), b("1-1"), resp.Array()),
+		resp.Array(resp.Int(1), b("1-1"), b("1-1"), resp.Nil()),
+		resp.Array(resp.Int(1), b("1-1"), b("1-1"), resp.Array(resp.Array(b("Alice")))),
+		resp.Array(resp.Int(1), b("1-1"), b("1-1"), resp.Array(resp.Array(b("Alice"), b("x")))),
+	}
+	for i, reply := range summaries {
+		if _, err := ParseXPending(reply); err != ErrInvalidReply {
+			t.Errorf("error parsing summary %d: %v", i, err)
+		}
+	}
+	entries := []*resp.Message{
+		resp.Array(resp.Array(b("1-1"), b("Alice"), resp.Int(1))),
+		resp.Array(resp.Array(b("1-1"), resp.Int(1), resp.Int(1), resp.Int(1))),
+		resp.Array(resp.Array(b("1-1"), b("Alice"), b("1"), resp.Int(1))),
+		resp.Array(resp.Array(b("1-1"), b("Alice"), resp.Int(1), b("1"))),
+	}
+	for i, reply := range entries {
+		if _, err := ParseXPendingEntries(reply); err != ErrInvalidReply {
+			t.Errorf("error parsing entries %d: %v", i, err)
+		}
+	}
+}
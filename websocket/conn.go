@@ -0,0 +1,328 @@
+// Package websocket carries RESP over WebSocket connections (RFC 6455), so
+// that browser based tools can talk to RESP servers.
+//
+// A Conn is a net.Conn whose data is the payload of the binary messages of
+// the connection: each Write is sent as a message, and Read returns the
+// payload of the messages received one after the other. The RESP messages
+// of a stream may therefore be split across WebSocket frames and messages,
+// or several of them sent in one, the resp.Reader reading them waiting for
+// the rest of an incomplete message as with any stream, on the segment
+// errors of the decoder. Conns can be given to resp.NewConn on the client
+// side and to resp.Server.ServeConn on the server side.
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrProtocol is returned when the peer breaks the WebSocket protocol,
+	// the connection being closed with the protocol error status
+	ErrProtocol = errors.New("websocket: protocol error")
+
+	// ErrClosed is returned when writing to a connection after its close
+	// frame was sent
+	ErrClosed = errors.New("websocket: connection closed")
+
+	// ErrBadHandshake is returned by Dial when the server does not accept
+	// the WebSocket handshake
+	ErrBadHandshake = errors.New("websocket: bad handshake")
+)
+
+// Opcodes of the frames.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// Close status codes.
+const (
+	closeNormal        = 1000
+	closeProtocolError = 1002
+)
+
+// maxControlPayload is the maximum payload of a control frame.
+const maxControlPayload = 125
+
+// Conn is a WebSocket connection. Reads and writes may happen concurrently,
+// but Read, like Write, must not be called concurrently with itself.
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	// client is true for the connections opened by Dial, which mask the
+	// frames they send and expect unmasked frames
+	client bool
+
+	// read state: the payload left in the current data frame and whether
+	// the current message continues in another frame
+	remaining int64
+	mask      [4]byte
+	masked    bool
+	maskPos   int
+	fragment  bool
+	readErr   error
+
+	wmu       sync.Mutex
+	closeSent bool
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, client bool) *Conn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	return &Conn{conn: conn, br: br, client: client}
+}
+
+// Read reads the payload of the data messages received, answering the pings
+// on the way. It returns io.EOF once the peer closed the connection. The
+// errors are returned again by the next reads, but for the timeouts of the
+// read deadline: reading again after extending the deadline goes on with the
+// frame being received.
+func (c *Conn) Read(b []byte) (int, error) {
+	for c.remaining == 0 {
+		if c.readErr != nil {
+			return 0, c.readErr
+		}
+		if err := c.nextFrame(); err != nil {
+			if !isTimeout(err) {
+				c.readErr = err
+			}
+			return 0, err
+		}
+	}
+	if int64(len(b)) > c.remaining {
+		b = b[:c.remaining]
+	}
+	n, err := c.br.Read(b)
+	if c.masked {
+		c.maskPos = maskBytes(c.mask, c.maskPos, b[:n])
+	}
+	c.remaining -= int64(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func isTimeout(err error) bool {
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
+
+// nextFrame reads the header of the next data frame, handling the control
+// frames before it. The header, and the payload of the control frames, are
+// peeked before being consumed, so that a read timing out leaves the frame
+// to the next read.
+func (c *Conn) nextFrame() error {
+	for {
+		head, err := c.br.Peek(2)
+		if err != nil {
+			return err
+		}
+		fin := head[0]&0x80 != 0
+		opcode := head[0] & 0x0f
+		masked := head[1]&0x80 != 0
+		length := int64(head[1] & 0x7f)
+		// no extension is negotiated, the reserved bits must be zero, and
+		// only clients mask their frames
+		if head[0]&0x70 != 0 || masked == c.client {
+			return c.fail()
+		}
+		size := 2
+		switch length {
+		case 126:
+			size += 2
+		case 127:
+			size += 8
+		}
+		if masked {
+			size += 4
+		}
+		if head, err = c.br.Peek(size); err != nil {
+			return err
+		}
+		switch length {
+		case 126:
+			length = int64(binary.BigEndian.Uint16(head[2:]))
+		case 127:
+			length = int64(binary.BigEndian.Uint64(head[2:]))
+			if length < 0 {
+				return c.fail()
+			}
+		}
+		var mask [4]byte
+		if masked {
+			copy(mask[:], head[size-4:])
+		}
+
+		if opcode >= opClose {
+			if !fin || length > maxControlPayload {
+				return c.fail()
+			}
+			frame, err := c.br.Peek(size + int(length))
+			if err != nil {
+				return err
+			}
+			payload := append([]byte(nil), frame[size:]...)
+			c.br.Discard(len(frame))
+			if masked {
+				maskBytes(mask, 0, payload)
+			}
+			if err := c.control(opcode, payload); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch {
+		case opcode == opContinuation && !c.fragment:
+			return c.fail()
+		case (opcode == opText || opcode == opBinary) && c.fragment:
+			return c.fail()
+		case opcode != opContinuation && opcode != opText && opcode != opBinary:
+			return c.fail()
+		}
+		c.br.Discard(size)
+		c.fragment = !fin
+		c.remaining, c.mask, c.masked, c.maskPos = length, mask, masked, 0
+		if length > 0 {
+			return nil
+		}
+	}
+}
+
+// control handles a control frame.
+func (c *Conn) control(opcode byte, payload []byte) error {
+	switch opcode {
+	case opPing:
+		return c.writeFrame(opPong, payload)
+	case opPong:
+		return nil
+	case opClose:
+		// the close frame is echoed with the status received
+		status := payload
+		if len(status) > 2 {
+			status = status[:2]
+		}
+		c.writeClose(status)
+		return io.EOF
+	}
+	return c.fail()
+}
+
+// fail sends a close frame with the protocol error status.
+func (c *Conn) fail() error {
+	c.writeClose(closeStatus(closeProtocolError))
+	return ErrProtocol
+}
+
+// Write sends b as a binary message.
+func (c *Conn) Write(b []byte) (int, error) {
+	if err := c.writeFrame(opBinary, b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return ErrClosed
+	}
+	return c.writeFrameLocked(opcode, payload)
+}
+
+func (c *Conn) writeFrameLocked(opcode byte, payload []byte) error {
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, 0x80|opcode)
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126, byte(n>>8), byte(n))
+	default:
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(n))
+		frame = append(frame, maskBit|127)
+		frame = append(frame, ext[:]...)
+	}
+	if !c.client {
+		frame = append(frame, payload...)
+	} else {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		maskBytes(mask, 0, frame[start:])
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// writeClose sends a close frame, once.
+func (c *Conn) writeClose(status []byte) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	c.writeFrameLocked(opClose, status)
+	c.closeSent = true
+}
+
+// Close sends a close frame with the normal closure status and closes the
+// underlying connection, without waiting for the close frame of the peer.
+func (c *Conn) Close() error {
+	c.writeClose(closeStatus(closeNormal))
+	return c.conn.Close()
+}
+
+// LocalAddr returns the local network address.
+func (c *Conn) LocalAddr() net.Addr { return c.conn.LocalAddr() }
+
+// RemoteAddr returns the remote network address.
+func (c *Conn) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+
+// SetDeadline sets the read and write deadlines of the underlying
+// connection.
+func (c *Conn) SetDeadline(t time.Time) error { return c.conn.SetDeadline(t) }
+
+// SetReadDeadline sets the read deadline of the underlying connection.
+func (c *Conn) SetReadDeadline(t time.Time) error { return c.conn.SetReadDeadline(t) }
+
+// SetWriteDeadline sets the write deadline of the underlying connection.
+func (c *Conn) SetWriteDeadline(t time.Time) error { return c.conn.SetWriteDeadline(t) }
+
+func closeStatus(code int) []byte {
+	return []byte{byte(code >> 8), byte(code)}
+}
+
+// maskBytes applies the mask to b, starting at the position pos of the
+// mask, and returns the position following b.
+func maskBytes(mask [4]byte, pos int, b []byte) int {
+	for i := range b {
+		b[i] ^= mask[pos&3]
+		pos++
+	}
+	return pos & 3
+}
@@ -0,0 +1,247 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// pair returns the two ends of a loopback TCP connection.
+func pair(t *testing.T) (net.Conn, net.Conn) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, _ := l.Accept()
+		accepted <- c
+	}()
+	c, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, <-accepted
+}
+
+// frame encodes a frame as sent by a client when masked, by a server
+// otherwise.
+func frame(fin bool, opcode byte, payload []byte, masked bool) []byte {
+	b := []byte{opcode, 0}
+	if fin {
+		b[0] |= 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		b[1] = byte(n)
+	case n <= 0xffff:
+		b[1] = 126
+		b = append(b, byte(n>>8), byte(n))
+	default:
+		b[1] = 127
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(n))
+		b = append(b, ext[:]...)
+	}
+	if !masked {
+		return append(b, payload...)
+	}
+	b[1] |= 0x80
+	mask := [4]byte{0x12, 0x34, 0x56, 0x78}
+	b = append(b, mask[:]...)
+	start := len(b)
+	b = append(b, payload...)
+	maskBytes(mask, 0, b[start:])
+	return b
+}
+
+// readFrame reads an unmasked frame sent by a server.
+func readFrame(t *testing.T, r *bufio.Reader) (byte, []byte) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		t.Fatal(err)
+	}
+	if head[1]&0x80 != 0 || head[1]&0x7f > 125 {
+		t.Fatalf("error frame header: %x", head)
+	}
+	payload := make([]byte, head[1]&0x7f)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatal(err)
+	}
+	return head[0], payload
+}
+
+func TestConnFragmented(t *testing.T) {
+	raw, s := pair(t)
+	defer raw.Close()
+	c := newConn(s, nil, false)
+	defer c.Close()
+
+	// a command split in three frames with a ping between them, then a
+	// command split across two messages
+	cmd := []byte("*2\r\n$3\r\nGET\r\n$1\r\nk\r\n")
+	var data []byte
+	data = append(data, frame(false, opBinary, cmd[:5], true)...)
+	data = append(data, frame(true, opPing, []byte("hi"), true)...)
+	data = append(data, frame(false, opContinuation, cmd[5:12], true)...)
+	data = append(data, frame(true, opContinuation, cmd[12:], true)...)
+	data = append(data, frame(true, opBinary, []byte("*1\r\n$4\r\nPI"), true)...)
+	data = append(data, frame(true, opText, []byte("NG\r\n"), true)...)
+	go raw.Write(data)
+
+	r := resp.NewReader(c)
+	for _, want := range []string{"GET k", "PING"} {
+		m, err := r.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		args, _ := m.Strings()
+		if got := string(bytes.Join(toBytes(args), []byte(" "))); got != want {
+			t.Errorf("error command: %q", got)
+		}
+	}
+	head, payload := readFrame(t, bufio.NewReader(raw))
+	if head != 0x80|opPong || string(payload) != "hi" {
+		t.Errorf("error pong frame: %x %q", head, payload)
+	}
+}
+
+func toBytes(args []string) [][]byte {
+	b := make([][]byte, len(args))
+	for i, arg := range args {
+		b[i] = []byte(arg)
+	}
+	return b
+}
+
+func TestConnRoundTrip(t *testing.T) {
+	a, b := pair(t)
+	client, server := newConn(a, nil, true), newConn(b, nil, false)
+	defer client.Close()
+	defer server.Close()
+
+	for _, size := range []int{1, 125, 126, 65535, 65536, 200000} {
+		payload := bytes.Repeat([]byte{'x'}, size)
+		payload[size-1] = 'y'
+		go client.Write(payload)
+		got := make([]byte, size)
+		if _, err := io.ReadFull(server, got); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, payload) {
+			t.Errorf("error payload of %d bytes from the client", size)
+		}
+		go server.Write(payload)
+		if _, err := io.ReadFull(client, got); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, payload) {
+			t.Errorf("error payload of %d bytes from the server", size)
+		}
+	}
+}
+
+func TestConnClose(t *testing.T) {
+	raw, s := pair(t)
+	defer raw.Close()
+	c := newConn(s, nil, false)
+	defer c.Close()
+
+	go raw.Write(frame(true, opClose, []byte{0x03, 0xe8, 'b', 'y', 'e'}, true))
+	if _, err := c.Read(make([]byte, 10)); err != io.EOF {
+		t.Errorf("should return io.EOF, not: %v", err)
+	}
+	head, payload := readFrame(t, bufio.NewReader(raw))
+	if head != 0x80|opClose || !bytes.Equal(payload, []byte{0x03, 0xe8}) {
+		t.Errorf("error close frame: %x %x", head, payload)
+	}
+	if _, err := c.Write([]byte("x")); err != ErrClosed {
+		t.Errorf("should return ErrClosed, not: %v", err)
+	}
+}
+
+func TestConnProtocolErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"unmasked", frame(true, opBinary, []byte("x"), false)},
+		{"continuation", frame(true, opContinuation, []byte("x"), true)},
+		{"interleaved", append(frame(false, opBinary, []byte("x"), true), frame(true, opBinary, []byte("y"), true)...)},
+		{"reserved bits", append([]byte{0xc2}, frame(true, opBinary, []byte("x"), true)[1:]...)},
+		{"fragmented ping", frame(false, opPing, nil, true)},
+		{"large ping", frame(true, opPing, make([]byte, 126), true)},
+		{"opcode", frame(true, 0x3, []byte("x"), true)},
+	}
+	for _, test := range tests {
+		raw, s := pair(t)
+		c := newConn(s, nil, false)
+		go raw.Write(test.data)
+		s.SetReadDeadline(time.Now().Add(time.Second))
+		var err error
+		for err == nil {
+			_, err = c.Read(make([]byte, 10))
+		}
+		if err != ErrProtocol {
+			t.Errorf("error %s: should return ErrProtocol, not: %v", test.name, err)
+		}
+		head, payload := readFrame(t, bufio.NewReader(raw))
+		if head != 0x80|opClose || !bytes.Equal(payload, []byte{0x03, 0xea}) {
+			t.Errorf("error %s: close frame %x %x", test.name, head, payload)
+		}
+		c.Close()
+		raw.Close()
+	}
+}
+
+func TestConnReadTimeout(t *testing.T) {
+	raw, s := pair(t)
+	defer raw.Close()
+	c := newConn(s, nil, false)
+	defer c.Close()
+
+	data := frame(true, opBinary, []byte("hello"), true)
+	// the deadline expires in the middle of the header, then of the payload
+	for _, split := range []int{3, len(data) - 2} {
+		if _, err := raw.Write(data[:split]); err != nil {
+			t.Fatal(err)
+		}
+		c.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+		var (
+			buf = make([]byte, 10)
+			n   int
+			err error
+		)
+		for err == nil {
+			var m int
+			m, err = c.Read(buf[n:])
+			n += m
+		}
+		if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+			t.Fatalf("should return a timeout, not: %v", err)
+		}
+		if _, err := raw.Write(data[split:]); err != nil {
+			t.Fatal(err)
+		}
+		c.SetReadDeadline(time.Now().Add(time.Second))
+		var got []byte
+		got = append(got, buf[:n]...)
+		for len(got) < 5 {
+			m, err := c.Read(buf)
+			if err != nil {
+				t.Fatalf("error reading after a timeout: %v", err)
+			}
+			got = append(got, buf[:m]...)
+		}
+		if string(got) != "hello" {
+			t.Errorf("error data after a timeout: %q", got)
+		}
+	}
+}
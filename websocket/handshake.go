@@ -0,0 +1,194 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// Subprotocol is the WebSocket subprotocol name of RESP, agreed on when the
+// client offers it.
+const Subprotocol = "resp"
+
+// acceptGUID is the GUID appended to the key of the client to compute the
+// accept key of the server.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerContains reports whether the comma separated list of the header
+// holds token, compared case insensitively.
+func headerContains(h http.Header, name, token string) bool {
+	for _, value := range h[http.CanonicalHeaderKey(name)] {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sameOrigin reports whether the request has no Origin header, as sent by
+// non browser clients, or one whose host is the host of the request.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// Upgrade switches an HTTP request to the WebSocket protocol and returns
+// the server side of the connection. On failure, it replies with an HTTP
+// error and returns the error. The RESP subprotocol is selected when the
+// client offers it. Origins are not checked.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	key := r.Header.Get("Sec-Websocket-Key")
+	switch {
+	case r.Method != http.MethodGet:
+		http.Error(w, "websocket: method not allowed", http.StatusMethodNotAllowed)
+		return nil, ErrBadHandshake
+	case !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket"):
+		http.Error(w, "websocket: not a websocket handshake", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	case r.Header.Get("Sec-Websocket-Version") != "13":
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "websocket: unsupported version", http.StatusUpgradeRequired)
+		return nil, ErrBadHandshake
+	case key == "":
+		http.Error(w, "websocket: missing key", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket: connection can not be hijacked", http.StatusInternalServerError)
+		return nil, ErrBadHandshake
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// the deadlines of the HTTP server no longer apply
+	conn.SetDeadline(time.Time{})
+
+	var buf bytes.Buffer
+	buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	buf.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	if headerContains(r.Header, "Sec-Websocket-Protocol", Subprotocol) {
+		buf.WriteString("Sec-WebSocket-Protocol: " + Subprotocol + "\r\n")
+	}
+	buf.WriteString("\r\n")
+	if _, err := conn.Write(buf.Bytes()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// the client may have sent frames along with the handshake
+	return newConn(conn, rw.Reader, false), nil
+}
+
+// Dial opens a WebSocket connection to the ws:// or wss:// URL, offering
+// the RESP subprotocol. The header, if not nil, is added to the handshake
+// request, such as an Origin or an Authorization header.
+func Dial(ctx context.Context, rawurl string, header http.Header) (*Conn, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Host
+	switch u.Scheme {
+	case "ws":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		}
+	case "wss":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "443")
+		}
+	default:
+		return nil, ErrBadHandshake
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if u.Scheme == "wss" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	c, err := handshake(conn, u, header)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+// DialRESP opens a WebSocket connection with Dial and returns a RESP client
+// connection using it.
+func DialRESP(ctx context.Context, rawurl string, header http.Header) (*resp.Conn, error) {
+	c, err := Dial(ctx, rawurl, header)
+	if err != nil {
+		return nil, err
+	}
+	return resp.NewConn(c), nil
+}
+
+func handshake(conn net.Conn, u *url.URL, header http.Header) (*Conn, error) {
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+
+	var buf bytes.Buffer
+	buf.WriteString("GET " + u.RequestURI() + " HTTP/1.1\r\nHost: " + u.Host + "\r\n")
+	buf.WriteString("Upgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Version: 13\r\n")
+	buf.WriteString("Sec-WebSocket-Key: " + key + "\r\nSec-WebSocket-Protocol: " + Subprotocol + "\r\n")
+	if header != nil {
+		header.Write(&buf)
+	}
+	buf.WriteString("\r\n")
+	if _, err := conn.Write(buf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, &http.Request{Method: http.MethodGet, URL: u})
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols ||
+		!headerContains(res.Header, "Upgrade", "websocket") ||
+		!headerContains(res.Header, "Connection", "upgrade") ||
+		res.Header.Get("Sec-Websocket-Accept") != acceptKey(key) {
+		return nil, ErrBadHandshake
+	}
+	return newConn(conn, br, true), nil
+}
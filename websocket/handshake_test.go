@@ -0,0 +1,78 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amyangfei/resp-go/resptest"
+)
+
+func TestUpgradeErrors(t *testing.T) {
+	srv := httptest.NewServer(&Server{Backend: "127.0.0.1:1"})
+	defer srv.Close()
+
+	tests := []struct {
+		method  string
+		headers map[string]string
+		status  int
+	}{
+		{"GET", nil, http.StatusBadRequest},
+		{"POST", map[string]string{"Connection": "Upgrade", "Upgrade": "websocket"}, http.StatusMethodNotAllowed},
+		{"GET", map[string]string{"Connection": "keep-alive, Upgrade", "Upgrade": "websocket",
+			"Sec-WebSocket-Version": "8", "Sec-WebSocket-Key": "x"}, http.StatusUpgradeRequired},
+		{"GET", map[string]string{"Connection": "Upgrade", "Upgrade": "websocket",
+			"Sec-WebSocket-Version": "13"}, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, srv.URL, nil)
+		for k, v := range test.headers {
+			req.Header.Set(k, v)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != test.status {
+			t.Errorf("error status of %s %v: %d", test.method, test.headers, res.StatusCode)
+		}
+	}
+
+	if _, err := Dial(context.Background(), "http://"+strings.TrimPrefix(srv.URL, "http://"), nil); err != ErrBadHandshake {
+		t.Errorf("should return ErrBadHandshake, not: %v", err)
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	// the example of RFC 6455
+	if got := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("error accept key: %s", got)
+	}
+
+	backend := resptest.NewServer()
+	defer backend.Close()
+	srv := httptest.NewServer(&Server{Backend: backend.Addr()})
+	defer srv.Close()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET /resp HTTP/1.1\r\nHost: example.com\r\nUpgrade: websocket\r\n" +
+		"Connection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Sec-WebSocket-Protocol: chat, resp\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+	res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols ||
+		res.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" ||
+		res.Header.Get("Sec-WebSocket-Protocol") != Subprotocol {
+		t.Errorf("error handshake response: %d %v", res.StatusCode, res.Header)
+	}
+}
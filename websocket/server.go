@@ -0,0 +1,118 @@
+package websocket
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/amyangfei/resp-go/resp"
+)
+
+// Server is an http.Handler accepting WebSocket connections carrying RESP,
+// served by a resp.Server or bridged to a RESP server over TCP.
+//
+// When bridged, the commands are forwarded to the backend as received, and
+// so are its replies, only cut at the end of the RESP messages so that each
+// WebSocket message sent to the client holds whole replies: the replies
+// available at once are sent in one message.
+type Server struct {
+	// RESP serves the connections, if not nil
+	RESP *resp.Server
+	// Backend is the TCP address of the server the connections are bridged
+	// to when RESP is nil
+	Backend string
+	// CheckOrigin returns false for the requests whose origin is not
+	// allowed. When nil, browsers are only allowed to connect from pages of
+	// the same host.
+	CheckOrigin func(r *http.Request) bool
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	checkOrigin := s.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		http.Error(w, "websocket: origin not allowed", http.StatusForbidden)
+		return
+	}
+	c, err := Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	if s.RESP != nil {
+		s.RESP.ServeConn(c)
+		return
+	}
+	bridge(c, s.Backend)
+}
+
+// bridge forwards the data of c to the backend, and the replies of the
+// backend to c, until either side closes its connection.
+func bridge(c *Conn, backend string) {
+	defer c.Close()
+	b, err := net.Dial("tcp", backend)
+	if err != nil {
+		return
+	}
+	defer b.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// closing the connections stops the copy of the other direction
+		defer c.Close()
+		defer b.Close()
+		forwardReplies(c, b)
+	}()
+	buf := make([]byte, 4096)
+	for {
+		n, err := c.Read(buf)
+		if n > 0 {
+			if _, werr := b.Write(buf[:n]); werr != nil {
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	b.Close()
+	wg.Wait()
+}
+
+// forwardReplies sends the data read from r to c as is, each WebSocket
+// message ending at the end of a RESP message. It returns the error ending
+// the forwarding: that of a read or a write, or the decoding error of
+// invalid data, which is not forwarded.
+func forwardReplies(c *Conn, r io.Reader) error {
+	var f resp.Framer
+	buf := make([]byte, 0, 4096)
+	for {
+		if len(buf) == cap(buf) {
+			buf = append(buf, 0)[:len(buf)]
+		}
+		n, err := r.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		end, ok := f.Scan(buf)
+		if end > 0 {
+			if _, werr := c.Write(buf[:end]); werr != nil {
+				return werr
+			}
+			buf = buf[:copy(buf, buf[end:])]
+			f.Discard(end)
+		}
+		if !ok {
+			if _, _, derr := resp.Decode(buf); derr != nil {
+				return derr
+			}
+			return resp.ErrRespData
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amyangfei/resp-go/resp"
+	"github.com/amyangfei/resp-go/resptest"
+)
+
+func wsURL(srv *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + "/resp"
+}
+
+// exchange sends commands through c, pipelined, and checks their replies.
+func exchange(t *testing.T, c *resp.Conn) {
+	c.Send(resp.Cmd("SET", "k", strings.Repeat("v", 70000)))
+	c.Send(resp.Cmd("STRLEN", "k"))
+	c.Send(resp.Cmd("LPUSH", "k", "x"))
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	var replies []string
+	for i := 0; i < 3; i++ {
+		reply, err := c.Receive()
+		if err != nil {
+			t.Fatal(err)
+		}
+		replies = append(replies, reply.String())
+	}
+	want := "OK|(integer) 70000|(error) WRONGTYPE Operation against a key holding the wrong kind of value"
+	if got := strings.Join(replies, "|"); got != want {
+		t.Errorf("error replies: %s", got)
+	}
+}
+
+func TestServerRESP(t *testing.T) {
+	backend := resptest.NewServer()
+	defer backend.Close()
+	rs := &resp.Server{Handler: backend}
+	defer rs.Close()
+	srv := httptest.NewServer(&Server{RESP: rs})
+	defer srv.Close()
+
+	c, err := DialRESP(context.Background(), wsURL(srv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	exchange(t, c)
+}
+
+func TestServerBridge(t *testing.T) {
+	backend := resptest.NewServer()
+	defer backend.Close()
+	srv := httptest.NewServer(&Server{Backend: backend.Addr()})
+	defer srv.Close()
+
+	c, err := DialRESP(context.Background(), wsURL(srv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	exchange(t, c)
+
+	// each message sent by the bridge holds whole replies
+	ws, err := Dial(context.Background(), wsURL(srv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	ws.Write([]byte("*1\r\n$4\r\nPING\r\n*2\r\n$4\r\nECHO\r\n$2\r\nhi\r\n"))
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	var got string
+	buf := make([]byte, 64)
+	for len(got) < len("+PONG\r\n$2\r\nhi\r\n") {
+		n, err := ws.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got += string(buf[:n])
+		if ws.remaining != 0 || ws.fragment {
+			t.Errorf("error reply message split: %q", got)
+		}
+	}
+	if got != "+PONG\r\n$2\r\nhi\r\n" {
+		t.Errorf("error replies: %q", got)
+	}
+}
+
+func TestServerOrigin(t *testing.T) {
+	backend := resptest.NewServer()
+	defer backend.Close()
+	srv := httptest.NewServer(&Server{Backend: backend.Addr()})
+	defer srv.Close()
+
+	ctx := context.Background()
+	if _, err := Dial(ctx, wsURL(srv), http.Header{"Origin": {"http://evil.example"}}); err != ErrBadHandshake {
+		t.Errorf("should return ErrBadHandshake, not: %v", err)
+	}
+	host := strings.TrimPrefix(srv.URL, "http://")
+	c, err := Dial(ctx, wsURL(srv), http.Header{"Origin": {"http://" + host}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	allowed := httptest.NewServer(&Server{
+		Backend:     backend.Addr(),
+		CheckOrigin: func(r *http.Request) bool { return r.Header.Get("Origin") == "http://admin.example" },
+	})
+	defer allowed.Close()
+	if c, err = Dial(ctx, wsURL(allowed), http.Header{"Origin": {"http://admin.example"}}); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+}
+
+// chunkReader returns its data a few bytes at a time.
+type chunkReader struct {
+	data string
+	size int
+}
+
+func (r *chunkReader) Read(b []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(b[:r.size], r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestForwardReplies(t *testing.T) {
+	// the attribute, verbatim string and blob error with a CRLF are
+	// forwarded as received
+	replies := []string{"|1\r\n+ttl\r\n:3\r\n$1\r\nv\r\n", "=7\r\nmkd:abc\r\n",
+		"!6\r\nERR\r\nx\r\n", "*2\r\n:1\r\n$-1\r\n"}
+	ends := map[int]bool{}
+	var data string
+	for _, reply := range replies {
+		data += reply
+		ends[len(data)] = true
+	}
+
+	raw, s := pair(t)
+	defer raw.Close()
+	c := newConn(s, nil, false)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- forwardReplies(c, &chunkReader{data: data, size: 5})
+		c.Close()
+	}()
+	br := bufio.NewReader(raw)
+	var got string
+	for len(got) < len(data) {
+		head, payload := readFrame(t, br)
+		if head != 0x80|opBinary {
+			t.Fatalf("error frame header: %x", head)
+		}
+		got += string(payload)
+		if !ends[len(got)] {
+			t.Errorf("error message not ending a reply: %q", payload)
+		}
+	}
+	if got != data {
+		t.Errorf("error replies forwarded: %q", got)
+	}
+	if err := <-errc; err != io.EOF {
+		t.Errorf("should return io.EOF, not: %v", err)
+	}
+}
+
+func TestForwardRepliesErrors(t *testing.T) {
+	raw, s := pair(t)
+	defer raw.Close()
+	c := newConn(s, nil, false)
+	defer c.Close()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- forwardReplies(c, strings.NewReader("+OK\r\n?x\r\n"))
+	}()
+	if _, payload := readFrame(t, bufio.NewReader(raw)); string(payload) != "+OK\r\n" {
+		t.Errorf("error reply before invalid data: %q", payload)
+	}
+	if err := <-errc; err == nil || err == io.EOF {
+		t.Errorf("error invalid data should fail: %v", err)
+	}
+
+	// the write errors end the forwarding
+	c.Close()
+	if err := forwardReplies(c, strings.NewReader("+OK\r\n")); err != ErrClosed {
+		t.Errorf("should return ErrClosed, not: %v", err)
+	}
+}